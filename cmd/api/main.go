@@ -1,6 +1,9 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"log"
 	"maryan_api/config"
 	"maryan_api/internal/infrastructure/clients/stripe"
 	dataStore "maryan_api/internal/infrastructure/persistence"
@@ -8,6 +11,8 @@ import (
 	"maryan_api/pkg/languages"
 	"maryan_api/pkg/timezone"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"net/http"
 
@@ -16,6 +21,9 @@ import (
 )
 
 func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	config.LoadConfig("../../.env")
 	timezone.Load()
 
@@ -23,7 +31,7 @@ func main() {
 	dataStore.Migrate(db)
 	config.LoadCountries(db)
 
-	stripe.InitStripe()
+	payments := stripe.NewStripeProvider()
 	server := gin.Default()
 	server.Use(cors.New(cors.Config{
 		AllowOrigins: []string{"*"},
@@ -33,7 +41,7 @@ func main() {
 
 	server.Use(languages.GinMiddlewear)
 	client := http.DefaultClient
-	router.RegisterRoutes(server, db, client)
+	router.RegisterRoutes(server, db, client, payments)
 	server.Static("/imgs", "../../static/images")
 	server.GET("", func(ctx *gin.Context) {
 		ctx.JSON(
@@ -46,5 +54,29 @@ func main() {
 	})
 	gin.SetMode(gin.ReleaseMode)
 
-	server.Run(os.Getenv("PORT"))
+	jobs := make(chan struct{})
+	go func() {
+		router.RunJobs(ctx, db, payments)
+		close(jobs)
+	}()
+
+	httpServer := &http.Server{Addr: os.Getenv("PORT"), Handler: server}
+	go func() {
+		err := httpServer.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+
+	// The requests in flight and the running jobs are let finish before the process exits.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+
+	err := httpServer.Shutdown(shutdownCtx)
+	if err != nil {
+		log.Printf("server shutdown: %v", err)
+	}
+	<-jobs
 }
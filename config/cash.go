@@ -0,0 +1,8 @@
+package config
+
+// MaxUnpaidCashSeats is how many seats of a connection can be booked to be paid to the driver
+// before the cash is collected.
+const MaxUnpaidCashSeats = 6
+
+// MaxCashOnDeliveryAmount is the most a recipient can be asked to pay the driver for a parcel, in minor units.
+const MaxCashOnDeliveryAmount = 500000
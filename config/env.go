@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -88,6 +89,20 @@ func StripSekretKey() string {
 	return mustGetEnv("STRIPE_SECRET_KEY")
 }
 
+func StripeWebhookSecret() string {
+	return mustGetEnv("STRIPE_WEBHOOK_SECRET")
+}
+
+// QRSigningKeys returns comma separated "keyID:base64Seed" Ed25519 keys, the first one signs new codes.
+// The keys are read from QR_SIGNING_KEYS_FILE when it is set, so they can be rotated without a restart.
+func QRSigningKeys() (string, error) {
+	if path := os.Getenv("QR_SIGNING_KEYS_FILE"); path != "" {
+		keys, err := os.ReadFile(path)
+		return strings.TrimSpace(string(keys)), err
+	}
+	return mustGetEnv("QR_SIGNING_KEYS"), nil
+}
+
 type db struct {
 	User     string
 	Password string
@@ -106,6 +121,44 @@ func DB() db {
 	}
 }
 
+type smtp struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func SMTP() smtp {
+	return smtp{
+		Host:     mustGetEnv("SMTP_HOST"),
+		Port:     mustGetEnv("SMTP_PORT"),
+		Username: mustGetEnv("SMTP_USERNAME"),
+		Password: mustGetEnv("SMTP_PASSWORD"),
+		From:     mustGetEnv("SMTP_FROM"),
+	}
+}
+
+type sms struct {
+	AccountSID string
+	AuthToken  string
+	From       string
+}
+
+// SMS returns the Twilio account the text messages are sent from.
+func SMS() sms {
+	return sms{
+		AccountSID: mustGetEnv("TWILIO_ACCOUNT_SID"),
+		AuthToken:  mustGetEnv("TWILIO_AUTH_TOKEN"),
+		From:       mustGetEnv("TWILIO_FROM"),
+	}
+}
+
+// PDFFontPath returns the path of the TrueType font embedded into generated PDF documents.
+func PDFFontPath() string {
+	return mustGetEnv("PDF_FONT_PATH")
+}
+
 func FrontendURL() string {
 	return mustGetEnv("FRONTEND_URL")
 }
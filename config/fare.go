@@ -0,0 +1,28 @@
+package config
+
+import "time"
+
+// defaultFareDiscounts are used for the lines that have no discount of their own for the category.
+var defaultFareDiscounts = map[string]int{
+	"Adult":    0,
+	"Teenager": 20,
+	"Child":    50,
+}
+
+// DefaultFareDiscount returns the discount percentage of the passenger category.
+func DefaultFareDiscount(category string) int {
+	return defaultFareDiscounts[category]
+}
+
+// The dynamic fare of the lines without bounds of their own is kept between these percentages of the base fare.
+const (
+	DefaultMinFarePercentage = 50
+	DefaultMaxFarePercentage = 200
+)
+
+// PriceQuoteDuration is how long a quoted fare is honored at the checkout.
+const PriceQuoteDuration = time.Minute * 15
+
+// RoundTripDiscountPercentage is taken off the fares of both legs of a round trip bought in one
+// checkout, 0 sells a round trip at the price of two tickets.
+const RoundTripDiscountPercentage = 10
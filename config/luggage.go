@@ -1,11 +1,7 @@
 package config
 
-import (
-	"github.com/d3code/uuid"
-)
-
+// Luggage is the built-in tariff of one luggage size, used on the routes with no tariff in the database.
 type Luggage struct {
-	ID     uuid.UUID
 	Height uint
 	Width  uint
 	Length uint
@@ -24,7 +20,6 @@ var luggageConfig = createTestLuggageConfig()
 func createTestLuggageConfig() LuggageConfig {
 
 	small := Luggage{
-		ID:     uuid.New(),
 		Height: 40,
 		Width:  20,
 		Length: 30,
@@ -33,7 +28,6 @@ func createTestLuggageConfig() LuggageConfig {
 	}
 
 	medium := Luggage{
-		ID:     uuid.New(),
 		Height: 50,
 		Width:  40,
 		Length: 30,
@@ -42,7 +36,6 @@ func createTestLuggageConfig() LuggageConfig {
 	}
 
 	large := Luggage{
-		ID:     uuid.New(),
 		Height: 100,
 		Width:  50,
 		Length: 40,
@@ -1,9 +1,5 @@
 package config
 
-import (
-	"slices"
-)
-
 type Parcel struct {
 	Type       parcelType
 	Height     uint
@@ -21,24 +17,25 @@ const (
 	Documents               parcelType = "Documents"
 )
 
+// ParcelWeight is the price of the parcels whose chargeable weight in grams does not exceed MaxWeight.
+type ParcelWeight struct {
+	MaxWeight uint
+	Price     uint
+}
+
 type ParcelsConfig struct {
 	ParcelTypes   []Parcel
 	OverSizePrice uint
+	// VolumetricDivisor is the number of cubic centimetres counted as one kilogram.
+	VolumetricDivisor uint
+	Weights           []ParcelWeight
 }
 
 var parcelConfig = GenerateTestParcelsConfig()
 
-func CalulateParcelPrice(widht, height, length uint) uint {
-	var position = []uint{widht, height, length}
-	slices.Sort(position)
-
-	for _, parcelConfig := range parcelConfig.ParcelTypes {
-		if parcelConfig.SizeParams[0] >= position[0] && parcelConfig.SizeParams[1] >= position[1] && parcelConfig.SizeParams[2] >= position[2] {
-			return parcelConfig.Price
-		}
-	}
-
-	return parcelConfig.OverSizePrice
+// GetParcelConfig returns the built-in parcel tariff, used on the routes with no tariff in the database.
+func GetParcelConfig() ParcelsConfig {
+	return parcelConfig
 }
 
 func GenerateTestParcelsConfig() ParcelsConfig {
@@ -104,7 +101,16 @@ func GenerateTestParcelsConfig() ParcelsConfig {
 	}
 
 	return ParcelsConfig{
-		ParcelTypes:   parcels,
-		OverSizePrice: 15000, // Fallback for parcels larger than 100x100x100
+		ParcelTypes:       parcels,
+		OverSizePrice:     15000, // Fallback for parcels larger than 100x100x100
+		VolumetricDivisor: 5000,
+		Weights: []ParcelWeight{
+			{MaxWeight: 2000, Price: 7000},
+			{MaxWeight: 5000, Price: 8000},
+			{MaxWeight: 10000, Price: 10000},
+			{MaxWeight: 20000, Price: 12000},
+			{MaxWeight: 30000, Price: 13000},
+			{MaxWeight: 50000, Price: 14000},
+		},
 	}
 }
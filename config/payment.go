@@ -0,0 +1,14 @@
+package config
+
+import "time"
+
+// PaymentSessionDuration is how long the customer has to pay for a booking at the checkout. Stripe does not
+// open a session for less than 30 minutes, the extra minute keeps the expiry above it when the request arrives.
+const PaymentSessionDuration = time.Minute * 31
+
+// SeatHoldDuration is how long the seats of a booking are held, they outlive the checkout session so a
+// payment made at its last moment still finds them.
+const SeatHoldDuration = PaymentSessionDuration + time.Minute*5
+
+// AbandonedBookingSweepInterval is how often the bookings left unpaid after the session are removed.
+const AbandonedBookingSweepInterval = time.Minute * 5
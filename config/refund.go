@@ -0,0 +1,25 @@
+package config
+
+import "time"
+
+type RefundRule struct {
+	BeforeDeparture time.Duration
+	Percentage      int
+}
+
+// refundPolicy has to be sorted from the longest period before departure to the shortest.
+var refundPolicy = []RefundRule{
+	{BeforeDeparture: time.Hour * 72, Percentage: 100},
+	{BeforeDeparture: time.Hour * 24, Percentage: 50},
+	{BeforeDeparture: time.Hour * 2, Percentage: 25},
+}
+
+func CalculateRefund(price int, beforeDeparture time.Duration) int {
+	for _, rule := range refundPolicy {
+		if beforeDeparture >= rule.BeforeDeparture {
+			return price * rule.Percentage / 100
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,6 @@
+package config
+
+import "time"
+
+// ShutdownTimeout is how long the requests in flight are given to finish once the server is stopped.
+const ShutdownTimeout = time.Second * 30
@@ -0,0 +1,13 @@
+package config
+
+import "time"
+
+// ParcelTrackingMaxFailures is how many wrong tracking attempts of a tracking number or of a
+// client are allowed within ParcelTrackingWindow.
+const ParcelTrackingMaxFailures = 5
+
+// ParcelTrackingWindow is how long the wrong tracking attempts are counted for.
+const ParcelTrackingWindow = time.Minute * 15
+
+// ParcelTrackingLockout is how long the tracking stays locked once there have been too many wrong attempts.
+const ParcelTrackingLockout = time.Hour
@@ -0,0 +1,9 @@
+package config
+
+import "time"
+
+// WaitlistOfferDuration is how long the seats offered to the next customer on the waitlist are reserved.
+const WaitlistOfferDuration = time.Minute * 30
+
+// WaitlistOfferInterval is how often the waitlists are checked for the seats that have freed up.
+const WaitlistOfferInterval = time.Minute
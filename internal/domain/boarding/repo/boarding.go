@@ -0,0 +1,62 @@
+package repo
+
+import (
+	"context"
+	"maryan_api/internal/entity"
+	dataStore "maryan_api/internal/infrastructure/persistence"
+	"time"
+
+	"github.com/d3code/uuid"
+	"gorm.io/gorm"
+)
+
+type Boarding interface {
+	GetStops(ctx context.Context, id, driverID uuid.UUID, from, to time.Time) ([]entity.Stop, error)
+	CompleteStop(ctx context.Context, stopID uuid.UUID, update *entity.ParcelStatusUpdate) error
+	CompleteDelivery(ctx context.Context, stopID uuid.UUID, proof *entity.ParcelDeliveryProof, update *entity.ParcelStatusUpdate) error
+	GetCurrentConnections(ctx context.Context, arrivingAfter time.Time) ([]entity.Connection, error)
+	UpdateQRCodes(ctx context.Context, tickets, parcels map[uuid.UUID][]byte) error
+	CollectCash(ctx context.Context, ticketID, driverID uuid.UUID) error
+	GetCashTickets(ctx context.Context, connectionID uuid.UUID) ([]entity.CashTicket, error)
+	CollectParcelCash(ctx context.Context, parcelID, driverID uuid.UUID) error
+}
+
+type boardingRepo struct {
+	ds dataStore.Boarding
+}
+
+func (r *boardingRepo) GetStops(ctx context.Context, id, driverID uuid.UUID, from, to time.Time) ([]entity.Stop, error) {
+	return r.ds.GetStops(ctx, id, driverID, from, to)
+}
+
+func (r *boardingRepo) CompleteStop(ctx context.Context, stopID uuid.UUID, update *entity.ParcelStatusUpdate) error {
+	return r.ds.CompleteStop(ctx, stopID, update)
+}
+
+func (r *boardingRepo) GetCurrentConnections(ctx context.Context, arrivingAfter time.Time) ([]entity.Connection, error) {
+	return r.ds.GetCurrentConnections(ctx, arrivingAfter)
+}
+
+func (r *boardingRepo) UpdateQRCodes(ctx context.Context, tickets, parcels map[uuid.UUID][]byte) error {
+	return r.ds.UpdateQRCodes(ctx, tickets, parcels)
+}
+
+func (r *boardingRepo) CollectCash(ctx context.Context, ticketID, driverID uuid.UUID) error {
+	return r.ds.CollectCash(ctx, ticketID, driverID)
+}
+
+func (r *boardingRepo) GetCashTickets(ctx context.Context, connectionID uuid.UUID) ([]entity.CashTicket, error) {
+	return r.ds.GetCashTickets(ctx, connectionID)
+}
+
+func (r *boardingRepo) CompleteDelivery(ctx context.Context, stopID uuid.UUID, proof *entity.ParcelDeliveryProof, update *entity.ParcelStatusUpdate) error {
+	return r.ds.CompleteDelivery(ctx, stopID, proof, update)
+}
+
+func (r *boardingRepo) CollectParcelCash(ctx context.Context, parcelID, driverID uuid.UUID) error {
+	return r.ds.CollectParcelCash(ctx, parcelID, driverID)
+}
+
+func NewBoardingRepo(db *gorm.DB) Boarding {
+	return &boardingRepo{dataStore.NewBoarding(db)}
+}
@@ -0,0 +1,313 @@
+package service
+
+import (
+	"context"
+	"maryan_api/internal/domain/boarding/repo"
+	"maryan_api/internal/entity"
+	rfc7807 "maryan_api/pkg/problem"
+	"maryan_api/pkg/security"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/d3code/uuid"
+)
+
+type Boarding interface {
+	PickUp(ctx context.Context, driverID uuid.UUID, scan entity.ScanJSON) (entity.ScannedStop, error)
+	DropOff(ctx context.Context, driverID uuid.UUID, scan entity.ScanJSON) (entity.ScannedStop, error)
+	Deliver(ctx context.Context, driverID uuid.UUID, proofJSON entity.DeliveryProofJSON, evidence *multipart.FileHeader, saveFileFunc func(file *multipart.FileHeader, dst string) error) (entity.ScannedStop, error)
+	CollectCash(ctx context.Context, driverID uuid.UUID, scan entity.ScanJSON) (entity.ScannedStop, error)
+	GetCashReconciliation(ctx context.Context, connectionIDStr string) ([]entity.CashReconciliation, error)
+	GetPublicKeys() ([]security.QRPublicKey, error)
+	ReissueQRCodes(ctx context.Context) (int, error)
+}
+
+// currentConnectionMargin is how long before the departure and after the arrival
+// a connection is still considered current for its drivers.
+const currentConnectionMargin = time.Hour * 12
+
+type serviceImpl struct {
+	repo repo.Boarding
+}
+
+func (s *serviceImpl) PickUp(ctx context.Context, driverID uuid.UUID, scan entity.ScanJSON) (entity.ScannedStop, error) {
+	pickUp, _, err := s.getStops(ctx, driverID, scan)
+	if err != nil {
+		return entity.ScannedStop{}, err
+	}
+
+	if pickUp.HasStatus(entity.CompletedStopStatus) {
+		return entity.ScannedStop{}, rfc7807.New(http.StatusConflict, "used-code", "Used Code Error", "The code has already been used for the pick-up.")
+	}
+
+	return pickUp.Scanned(), s.repo.CompleteStop(ctx, pickUp.ID, parcelStatusUpdate(pickUp, entity.NewParcelStatusUpdate(pickUp.Parcel.ID, entity.PickedUpParcelStatus, "")))
+}
+
+// DropOff completes the drop-off of a ticket, the parcels are handed over with a proof of delivery.
+func (s *serviceImpl) DropOff(ctx context.Context, driverID uuid.UUID, scan entity.ScanJSON) (entity.ScannedStop, error) {
+	pickUp, dropOff, err := s.getDropOffStops(ctx, driverID, scan)
+	if err != nil {
+		return entity.ScannedStop{}, err
+	}
+
+	if pickUp.Type == entity.ParcelStopType {
+		return entity.ScannedStop{}, rfc7807.BadRequest("delivery-proof-required", "Delivery Proof Required Error", "The parcel can only be dropped off with a proof of delivery.")
+	}
+
+	return dropOff.Scanned(), s.repo.CompleteStop(ctx, dropOff.ID, nil)
+}
+
+// Deliver completes the drop-off of a parcel with the code texted to the recipient, or with
+// their signature or a photo of the handover, and keeps the proof for the disputes.
+func (s *serviceImpl) Deliver(ctx context.Context, driverID uuid.UUID, proofJSON entity.DeliveryProofJSON, evidence *multipart.FileHeader, saveFileFunc func(file *multipart.FileHeader, dst string) error) (entity.ScannedStop, error) {
+	pickUp, dropOff, err := s.getDropOffStops(ctx, driverID, entity.ScanJSON{Code: proofJSON.Code})
+	if err != nil {
+		return entity.ScannedStop{}, err
+	}
+
+	if pickUp.Type != entity.ParcelStopType {
+		return entity.ScannedStop{}, rfc7807.BadRequest("not-parcel", "Not Parcel Error", "The code does not belong to a parcel.")
+	}
+
+	if pickUp.Parcel.UncollectedCash() {
+		return entity.ScannedStop{}, rfc7807.BadRequest("uncollected-cash", "Uncollected Cash Error", "The cash on delivery has to be collected before the drop-off.")
+	}
+
+	proof, err := proofJSON.Parse(pickUp.Parcel, driverID, evidence != nil)
+	if err != nil {
+		return entity.ScannedStop{}, err
+	}
+
+	if proof.HasEvidence() {
+		if err = saveFileFunc(evidence, proof.EvidencePath); err != nil {
+			return entity.ScannedStop{}, rfc7807.Internal("Evidence Saving Error", err.Error())
+		}
+	}
+
+	err = s.repo.CompleteDelivery(ctx, dropOff.ID, &proof, parcelStatusUpdate(pickUp, entity.NewParcelStatusUpdate(pickUp.Parcel.ID, entity.DeliveredParcelStatus, "")))
+	if err != nil {
+		// The evidence of a drop-off that has not been recorded is never shown, so it is not kept.
+		if proof.HasEvidence() {
+			os.Remove(proof.EvidencePath)
+		}
+		return entity.ScannedStop{}, err
+	}
+
+	return dropOff.Scanned(), nil
+}
+
+// getDropOffStops returns the stops of the code whose pick-up is done and drop-off is not.
+func (s *serviceImpl) getDropOffStops(ctx context.Context, driverID uuid.UUID, scan entity.ScanJSON) (entity.Stop, entity.Stop, error) {
+	pickUp, dropOff, err := s.getStops(ctx, driverID, scan)
+	if err != nil {
+		return entity.Stop{}, entity.Stop{}, err
+	}
+
+	return pickUp, dropOff, droppable(pickUp, dropOff)
+}
+
+func droppable(pickUp, dropOff entity.Stop) error {
+	if !pickUp.HasStatus(entity.CompletedStopStatus) {
+		return rfc7807.BadRequest("not-picked-up", "Not Picked Up Error", "The code has not been used for the pick-up.")
+	}
+
+	if dropOff.HasStatus(entity.CompletedStopStatus) {
+		return rfc7807.New(http.StatusConflict, "used-code", "Used Code Error", "The code has already been used for the drop-off.")
+	}
+
+	return nil
+}
+
+// parcelStatusUpdate returns the update moving the parcel of the stop forward on its timeline, it is
+// completed with the stop. There is none when a status set by the staff further on is to be kept.
+func parcelStatusUpdate(stop entity.Stop, update entity.ParcelStatusUpdate) *entity.ParcelStatusUpdate {
+	if stop.Type != entity.ParcelStopType || !stop.Parcel.Status.Precedes(update.Status) {
+		return nil
+	}
+
+	return &update
+}
+
+// CollectCash records the fare of a ticket booked with the cash payment as paid to the driver,
+// it has to be done before the pick-up. The cash on delivery of a parcel is collected before the drop-off.
+func (s *serviceImpl) CollectCash(ctx context.Context, driverID uuid.UUID, scan entity.ScanJSON) (entity.ScannedStop, error) {
+	pickUp, dropOff, err := s.findStops(ctx, driverID, scan)
+	if err != nil {
+		return entity.ScannedStop{}, err
+	}
+
+	if pickUp.Type == entity.ParcelStopType {
+		return s.collectParcelCash(ctx, driverID, pickUp, dropOff)
+	}
+
+	if pickUp.Type != entity.PassengerStopType || pickUp.Ticket.Payment.Method != entity.PaymentMethodCash {
+		return entity.ScannedStop{}, rfc7807.BadRequest("not-cash-ticket", "Not Cash Ticket Error", "The ticket is not paid to the driver.")
+	}
+
+	if pickUp.Ticket.CanceledAt.Valid {
+		return entity.ScannedStop{}, rfc7807.BadRequest("canceled-ticket", "Canceled Ticket Error", "The ticket has been canceled.")
+	}
+
+	if pickUp.Ticket.Payment.Succeeded {
+		return entity.ScannedStop{}, rfc7807.New(http.StatusConflict, "collected-cash", "Collected Cash Error", "The cash for the ticket has already been collected.")
+	}
+
+	return pickUp.Scanned(), s.repo.CollectCash(ctx, pickUp.Ticket.ID, driverID)
+}
+
+func (s *serviceImpl) collectParcelCash(ctx context.Context, driverID uuid.UUID, pickUp, dropOff entity.Stop) (entity.ScannedStop, error) {
+	if err := validStop(pickUp); err != nil {
+		return entity.ScannedStop{}, err
+	}
+
+	if err := droppable(pickUp, dropOff); err != nil {
+		return entity.ScannedStop{}, err
+	}
+
+	if pickUp.Parcel.CashOnDelivery == nil || pickUp.Parcel.CashOnDelivery.Status == entity.CanceledCodStatus {
+		return entity.ScannedStop{}, rfc7807.BadRequest("not-cash-parcel", "Not Cash Parcel Error", "The parcel is not paid for on the delivery.")
+	}
+
+	if !pickUp.Parcel.UncollectedCash() {
+		return entity.ScannedStop{}, rfc7807.New(http.StatusConflict, "collected-cash", "Collected Cash Error", "The cash on delivery has already been collected.")
+	}
+
+	return pickUp.Scanned(), s.repo.CollectParcelCash(ctx, pickUp.Parcel.ID, driverID)
+}
+
+func (s *serviceImpl) GetCashReconciliation(ctx context.Context, connectionIDStr string) ([]entity.CashReconciliation, error) {
+	connectionID, err := uuid.Parse(connectionIDStr)
+	if err != nil {
+		return nil, rfc7807.BadRequest("invalid-id", "Invalid ID Error", "Provided connection id is not a valid UUID.")
+	}
+
+	tickets, err := s.repo.GetCashTickets(ctx, connectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return entity.NewCashReconciliations(connectionID, tickets), nil
+}
+
+func (s *serviceImpl) getStops(ctx context.Context, driverID uuid.UUID, scan entity.ScanJSON) (entity.Stop, entity.Stop, error) {
+	pickUp, dropOff, err := s.findStops(ctx, driverID, scan)
+	if err != nil {
+		return entity.Stop{}, entity.Stop{}, err
+	}
+
+	return pickUp, dropOff, validStop(pickUp)
+}
+
+// validStop checks that the ticket or the parcel of the pick-up can still be carried.
+func validStop(pickUp entity.Stop) error {
+	switch pickUp.Type {
+	case entity.PassengerStopType:
+		if pickUp.Ticket.CanceledAt.Valid {
+			return rfc7807.BadRequest("canceled-ticket", "Canceled Ticket Error", "The ticket has been canceled.")
+		}
+
+		if pickUp.Ticket.Payment.UncollectedCash() {
+			return rfc7807.BadRequest("uncollected-cash", "Uncollected Cash Error", "The cash for the ticket has to be collected before the pick-up.")
+		}
+
+		if !pickUp.Ticket.Payment.Succeeded {
+			return rfc7807.BadRequest("unpaid-ticket", "Unpaid Ticket Error", "The ticket has not been paid.")
+		}
+	case entity.ParcelStopType:
+		if pickUp.Parcel.ID == uuid.Nil || !pickUp.Parcel.Payment.Succeeded {
+			return rfc7807.BadRequest("unpaid-parcel", "Unpaid Parcel Error", "The parcel has not been paid.")
+		}
+
+		if pickUp.Parcel.Status == entity.ReturnedParcelStatus {
+			return rfc7807.BadRequest("returned-parcel", "Returned Parcel Error", "The parcel has been returned to the sender.")
+		}
+	}
+
+	return nil
+}
+
+// findStops returns the pick-up and the drop-off of the code on the current connections of the driver.
+func (s *serviceImpl) findStops(ctx context.Context, driverID uuid.UUID, scan entity.ScanJSON) (entity.Stop, entity.Stop, error) {
+	claims, err := scan.ParseCode()
+	if err != nil {
+		return entity.Stop{}, entity.Stop{}, err
+	}
+
+	now := time.Now().UTC()
+	stops, err := s.repo.GetStops(ctx, claims.ID, driverID, now.Add(-currentConnectionMargin), now.Add(currentConnectionMargin))
+	if err != nil {
+		return entity.Stop{}, entity.Stop{}, err
+	}
+
+	var pickUp, dropOff entity.Stop
+	for _, stop := range stops {
+		if stop.LocationType == entity.PickUpStopType {
+			pickUp = stop
+		} else {
+			dropOff = stop
+		}
+	}
+
+	if pickUp.ID == uuid.Nil || dropOff.ID == uuid.Nil || pickUp.ConnectionID != claims.ConnectionID {
+		return entity.Stop{}, entity.Stop{}, rfc7807.BadRequest("unknown-code", "Unknown Code Error", "The code does not belong to any of your current connections.")
+	}
+
+	return pickUp, dropOff, nil
+}
+
+func (s *serviceImpl) GetPublicKeys() ([]security.QRPublicKey, error) {
+	keys, err := security.QRPublicKeys()
+	if err != nil {
+		return nil, rfc7807.Internal("QR Keys Error", err.Error())
+	}
+	return keys, nil
+}
+
+// ReissueQRCodes signs the codes of the paid tickets and parcels on the connections that have not
+// arrived yet with the current key, so an old key can be removed after the rotation. The unpaid,
+// canceled and finished bookings keep their codes, there is nothing to board with them.
+func (s *serviceImpl) ReissueQRCodes(ctx context.Context) (int, error) {
+	connections, err := s.repo.GetCurrentConnections(ctx, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	var tickets = make(map[uuid.UUID][]byte)
+	var parcels = make(map[uuid.UUID][]byte)
+
+	for _, connection := range connections {
+		for _, stop := range connection.Stops {
+			if stop.LocationType != entity.PickUpStopType {
+				continue
+			}
+
+			if stop.Type == entity.ParcelStopType {
+				if stop.Parcel.ID == uuid.Nil || !stop.Parcel.Active() {
+					continue
+				}
+
+				parcels[stop.Parcel.ID], err = entity.ParcelQRCode(stop.Parcel.ID, connection)
+			} else {
+				if stop.Ticket.ID == uuid.Nil || !stop.Ticket.Active() {
+					continue
+				}
+
+				tickets[stop.Ticket.ID], err = entity.TicketQRCode(stop.Ticket.ID, connection, stop.Ticket.Seats)
+			}
+
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return len(tickets) + len(parcels), s.repo.UpdateQRCodes(ctx, tickets, parcels)
+}
+
+func NewBoardingService(repo repo.Boarding) Boarding {
+	return &serviceImpl{
+		repo,
+	}
+}
@@ -0,0 +1,154 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"maryan_api/internal/domain/boarding/service"
+	"maryan_api/internal/entity"
+	ginutil "maryan_api/pkg/ginutils"
+	"maryan_api/pkg/hypermedia"
+	rfc7807 "maryan_api/pkg/problem"
+	"maryan_api/pkg/security"
+	"net/http"
+	"time"
+
+	"github.com/d3code/uuid"
+	"github.com/gin-gonic/gin"
+)
+
+type boardingHandler struct {
+	service service.Boarding
+}
+
+func newBoardingHandler(service service.Boarding) *boardingHandler {
+	return &boardingHandler{service}
+}
+
+func (b *boardingHandler) scan(serviceFunc func(ctx context.Context, driverID uuid.UUID, scan entity.ScanJSON) (entity.ScannedStop, error)) func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		var request entity.ScanJSON
+
+		err := ctx.ShouldBindJSON(&request)
+		if err != nil {
+			ginutil.HandlerProblemAbort(ctx, rfc7807.BadRequest("body-parsing", "Body Parsing Error", err.Error()))
+			return
+		}
+
+		ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+		defer cancel()
+
+		stop, err := serviceFunc(ctxWithTimeout, ctx.MustGet("userID").(uuid.UUID), request)
+		if err != nil {
+			ginutil.ServiceErrorAbort(ctx, err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, struct {
+			ginutil.Response
+			Stop entity.ScannedStop `json:"stop"`
+		}{
+			ginutil.Response{
+				"The stop has successfuly been completed.",
+				hypermedia.Links{},
+			},
+			stop,
+		})
+	}
+}
+
+func (b *boardingHandler) deliver(ctx *gin.Context) {
+	var proof entity.DeliveryProofJSON
+	if err := ctx.ShouldBind(&proof); err != nil {
+		ginutil.HandlerProblemAbort(ctx, rfc7807.BadRequest("form-parsing-error", "Form Parsing Error", err.Error()))
+		return
+	}
+
+	evidence, err := ctx.FormFile("evidence")
+	if err != nil && !errors.Is(err, http.ErrMissingFile) {
+		ginutil.HandlerProblemAbort(ctx, rfc7807.BadRequest("form-parsing-error", "Form Parsing Error", err.Error()))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*20)
+	defer cancel()
+
+	stop, err := b.service.Deliver(ctxWithTimeout, ctx.MustGet("userID").(uuid.UUID), proof, evidence, ctx.SaveUploadedFile)
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		ginutil.Response
+		Stop entity.ScannedStop `json:"stop"`
+	}{
+		ginutil.Response{
+			"The parcel has successfuly been delivered.",
+			hypermedia.Links{},
+		},
+		stop,
+	})
+}
+
+func (b *boardingHandler) getCashReconciliation(ctx *gin.Context) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	reconciliations, err := b.service.GetCashReconciliation(ctxWithTimeout, ctx.Param("connectionId"))
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		ginutil.Response
+		Reconciliations []entity.CashReconciliation `json:"reconciliations"`
+	}{
+		ginutil.Response{
+			"The cash reconciliation has successfuly been found.",
+			hypermedia.Links{},
+		},
+		reconciliations,
+	})
+}
+
+func (b *boardingHandler) getPublicKeys(ctx *gin.Context) {
+	keys, err := b.service.GetPublicKeys()
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		ginutil.Response
+		Keys []security.QRPublicKey `json:"keys"`
+	}{
+		ginutil.Response{
+			"The public keys have successfuly been found.",
+			hypermedia.Links{},
+		},
+		keys,
+	})
+}
+
+func (b *boardingHandler) reissueQRCodes(ctx *gin.Context) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*60)
+	defer cancel()
+
+	reissued, err := b.service.ReissueQRCodes(ctxWithTimeout)
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		ginutil.Response
+		Reissued int `json:"reissued"`
+	}{
+		ginutil.Response{
+			"The QR codes have successfuly been reissued.",
+			hypermedia.Links{},
+		},
+		reissued,
+	})
+}
@@ -0,0 +1,30 @@
+package http
+
+import (
+	"maryan_api/internal/domain/boarding/repo"
+	"maryan_api/internal/domain/boarding/service"
+	"maryan_api/pkg/auth"
+	ginutil "maryan_api/pkg/ginutils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func RegisterRoutes(db *gorm.DB, s *gin.Engine, client *http.Client) {
+	driverRouter := ginutil.CreateAuthRouter("/driver", auth.Driver.SecretKey(), s)
+	adminRouter := ginutil.CreateAuthRouter("/admin", auth.Admin.SecretKey(), s)
+
+	handler := newBoardingHandler(service.NewBoardingService(repo.NewBoardingRepo(db)))
+
+	//-----------------------Boarding Routes---------------------------------------
+
+	driverRouter.POST("/boarding/pick-up", handler.scan(handler.service.PickUp))
+	driverRouter.POST("/boarding/drop-off", handler.scan(handler.service.DropOff))
+	driverRouter.POST("/boarding/deliver", handler.deliver)
+	driverRouter.POST("/boarding/collect-cash", handler.scan(handler.service.CollectCash))
+	driverRouter.GET("/boarding/keys", handler.getPublicKeys)
+
+	adminRouter.POST("/boarding/qr-codes/reissue", handler.reissueQRCodes)
+	adminRouter.GET("/boarding/cash/:connectionId", handler.getCashReconciliation)
+}
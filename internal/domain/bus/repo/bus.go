@@ -22,6 +22,7 @@ type Bus interface {
 	GetAvailable(ctx context.Context, dates []time.Time, pagination dbutil.Pagination) ([]entity.Bus, int, error, bool)
 	SetSchedule(ctx context.Context, schedule []entity.BusAvailability) error
 	Exists(ctx context.Context, id uuid.UUID) (bool, error)
+	SetSeatSurcharges(ctx context.Context, busID uuid.UUID, surcharges entity.SeatSurcharges) error
 }
 
 type Driver interface {
@@ -81,6 +82,10 @@ func (b *busRepo) Exists(ctx context.Context, id uuid.UUID) (bool, error) {
 }
 
 // ------------------------Repos Initialization Functions--------------
+func (b *busRepo) SetSeatSurcharges(ctx context.Context, busID uuid.UUID, surcharges entity.SeatSurcharges) error {
+	return b.store.SetSeatSurcharges(ctx, busID, surcharges)
+}
+
 func NewBusRepo(db *gorm.DB) Bus {
 	return &busRepo{dataStore.NewBus(db)}
 }
@@ -27,6 +27,7 @@ type Bus interface {
 	ChangeDriver(driverType driverType) func(ctx context.Context, busIDStr, driverIDStr string) error
 	GetAvailable(ctx context.Context, paginationStr dbutil.PaginationStr, fromStr, toStr string) ([]entity.Bus, hypermedia.Links, error)
 	SetSchedule(ctx context.Context, schedule []entity.BusAvailability) error
+	SetSeatSurcharges(ctx context.Context, busIDStr string, surcharges entity.SeatSurchargesJSON) (entity.SeatSurcharges, error)
 }
 
 type busServiceImpl struct {
@@ -218,6 +219,26 @@ func (b *busServiceImpl) SetSchedule(ctx context.Context, schedule []entity.BusA
 	return b.bus.SetSchedule(ctx, schedule)
 }
 
+// SetSeatSurcharges replaces the surcharges of the seat types and of the single seats of the bus.
+func (b *busServiceImpl) SetSeatSurcharges(ctx context.Context, busIDStr string, surchargesJSON entity.SeatSurchargesJSON) (entity.SeatSurcharges, error) {
+	busID, err := uuid.Parse(busIDStr)
+	if err != nil {
+		return entity.SeatSurcharges{}, rfc7807.UUID(err.Error())
+	}
+
+	bus, err := b.bus.GetByID(ctx, busID)
+	if err != nil {
+		return entity.SeatSurcharges{}, err
+	}
+
+	surcharges, err := surchargesJSON.Parse(bus)
+	if err != nil {
+		return entity.SeatSurcharges{}, err
+	}
+
+	return surcharges, b.bus.SetSeatSurcharges(ctx, busID, surcharges)
+}
+
 // --------------------Services Initialization Functions
 
 func NewBusService(bus repo.Bus, driver repo.Driver) Bus {
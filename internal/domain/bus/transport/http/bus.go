@@ -116,6 +116,36 @@ func (b *busHandler) setBusSchedule(ctx *gin.Context) {
 	})
 }
 
+func (b *busHandler) setSeatSurcharges(ctx *gin.Context) {
+	var request entity.SeatSurchargesJSON
+
+	err := ctx.ShouldBindJSON(&request)
+	if err != nil {
+		ginutil.HandlerProblemAbort(ctx, rfc7807.BadRequest("body-parsing", "Body Parsing Error", err.Error()))
+		return
+	}
+
+	ctxWithTimeout, cancel := ginutil.ContextWithTimeout(ctx, time.Second*20)
+	defer cancel()
+
+	surcharges, err := b.service.SetSeatSurcharges(ctxWithTimeout, ctx.Param("id"), request)
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		ginutil.Response
+		SeatSurcharges entity.SeatSurcharges `json:"seatSurcharges"`
+	}{
+		ginutil.Response{
+			"The seat surcharges of the bus have successfuly been updated.",
+			hypermedia.Links{},
+		},
+		surcharges,
+	})
+}
+
 func (b *busHandler) createBus(ctx *gin.Context) {
 
 	form, err := ctx.MultipartForm()
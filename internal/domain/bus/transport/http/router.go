@@ -25,6 +25,7 @@ func RegisterRoutes(db *gorm.DB, s *gin.Engine, client *http.Client) {
 	adminRouter.PATCH("/bus/:id/lead-driver", handler.changeDriver(leadDriverType))
 	adminRouter.PATCH("/bus/:id/assistant-driver", handler.changeDriver(assistantDriverType))
 	adminRouter.GET("/buses/available", handler.getAvailableBuses)
+	adminRouter.PUT("/bus/:id/seat-surcharges", handler.setSeatSurcharges)
 }
 
 // -------------Links-----------------
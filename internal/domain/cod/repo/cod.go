@@ -0,0 +1,48 @@
+package repo
+
+import (
+	"context"
+	"maryan_api/internal/entity"
+	dataStore "maryan_api/internal/infrastructure/persistence"
+	"maryan_api/pkg/dbutil"
+	"time"
+
+	"github.com/d3code/uuid"
+	"gorm.io/gorm"
+)
+
+type CashOnDelivery interface {
+	GetLedger(ctx context.Context, pagination dbutil.Pagination) ([]entity.ParcelCashOnDelivery, int, error, bool)
+	CreatePayoutBatch(ctx context.Context, currency entity.Currency, collectedBefore time.Time, adminID uuid.UUID) (entity.CodPayoutBatch, error)
+	PayOutBatch(ctx context.Context, id uuid.UUID, reference string) error
+	GetPayoutBatch(ctx context.Context, id uuid.UUID) (entity.CodPayoutBatch, error)
+	GetPayoutBatches(ctx context.Context, pagination dbutil.Pagination) ([]entity.CodPayoutBatch, int, error, bool)
+}
+
+type cashOnDeliveryRepo struct {
+	ds dataStore.CashOnDelivery
+}
+
+func (r *cashOnDeliveryRepo) GetLedger(ctx context.Context, pagination dbutil.Pagination) ([]entity.ParcelCashOnDelivery, int, error, bool) {
+	return r.ds.GetLedger(ctx, pagination)
+}
+
+func (r *cashOnDeliveryRepo) CreatePayoutBatch(ctx context.Context, currency entity.Currency, collectedBefore time.Time, adminID uuid.UUID) (entity.CodPayoutBatch, error) {
+	return r.ds.CreatePayoutBatch(ctx, currency, collectedBefore, adminID)
+}
+
+func (r *cashOnDeliveryRepo) PayOutBatch(ctx context.Context, id uuid.UUID, reference string) error {
+	return r.ds.PayOutBatch(ctx, id, reference)
+}
+
+func (r *cashOnDeliveryRepo) GetPayoutBatch(ctx context.Context, id uuid.UUID) (entity.CodPayoutBatch, error) {
+	return r.ds.GetPayoutBatch(ctx, id)
+}
+
+func (r *cashOnDeliveryRepo) GetPayoutBatches(ctx context.Context, pagination dbutil.Pagination) ([]entity.CodPayoutBatch, int, error, bool) {
+	return r.ds.GetPayoutBatches(ctx, pagination)
+}
+
+func NewCashOnDeliveryRepo(db *gorm.DB) CashOnDelivery {
+	return &cashOnDeliveryRepo{dataStore.NewCashOnDelivery(db)}
+}
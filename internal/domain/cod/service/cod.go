@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"maryan_api/internal/domain/cod/repo"
+	"maryan_api/internal/entity"
+	"maryan_api/pkg/dbutil"
+	"maryan_api/pkg/hypermedia"
+	rfc7807 "maryan_api/pkg/problem"
+
+	"github.com/d3code/uuid"
+)
+
+type CashOnDelivery interface {
+	GetLedger(ctx context.Context, paginationStr dbutil.PaginationStr) ([]entity.ParcelCashOnDelivery, hypermedia.Links, error)
+	CreatePayoutBatch(ctx context.Context, adminID uuid.UUID, batchJSON entity.CodPayoutBatchJSON) (entity.CodPayoutBatch, error)
+	PayOutBatch(ctx context.Context, idStr string, paidJSON entity.CodPayoutPaidJSON) (entity.CodPayoutBatch, error)
+	GetPayoutBatch(ctx context.Context, idStr string) (entity.CodPayoutBatch, error)
+	GetPayoutBatches(ctx context.Context, paginationStr dbutil.PaginationStr) ([]entity.CodPayoutBatch, hypermedia.Links, error)
+}
+
+type serviceImpl struct {
+	repo repo.CashOnDelivery
+}
+
+func parseID(idStr string) (uuid.UUID, error) {
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return uuid.Nil, rfc7807.BadRequest("invalid-id", "Invalid ID Error", err.Error())
+	}
+	return id, nil
+}
+
+// GetLedger returns the cash on delivery of the paid parcels of all the senders.
+func (s *serviceImpl) GetLedger(ctx context.Context, paginationStr dbutil.PaginationStr) ([]entity.ParcelCashOnDelivery, hypermedia.Links, error) {
+	pagination, err := paginationStr.ParseWithCondition(dbutil.Condition{"parcel_id IN (SELECT parcel_id FROM parcel_payments WHERE succeeded = ?)", []any{true}}, []string{"status"}, "created_at", "collected_at", "amount")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entries, total, err, empty := s.repo.GetLedger(ctx, pagination)
+	if err != nil || empty {
+		return nil, nil, err
+	}
+
+	return entries, hypermedia.Pagination(paginationStr, total), nil
+}
+
+// CreatePayoutBatch schedules the collected cash for the payout to the senders.
+func (s *serviceImpl) CreatePayoutBatch(ctx context.Context, adminID uuid.UUID, batchJSON entity.CodPayoutBatchJSON) (entity.CodPayoutBatch, error) {
+	currency, collectedBefore, err := batchJSON.Parse()
+	if err != nil {
+		return entity.CodPayoutBatch{}, err
+	}
+
+	return s.repo.CreatePayoutBatch(ctx, currency, collectedBefore, adminID)
+}
+
+// PayOutBatch records the batch as transferred to the senders.
+func (s *serviceImpl) PayOutBatch(ctx context.Context, idStr string, paidJSON entity.CodPayoutPaidJSON) (entity.CodPayoutBatch, error) {
+	id, err := parseID(idStr)
+	if err != nil {
+		return entity.CodPayoutBatch{}, err
+	}
+
+	reference, err := paidJSON.Parse()
+	if err != nil {
+		return entity.CodPayoutBatch{}, err
+	}
+
+	batch, err := s.repo.GetPayoutBatch(ctx, id)
+	if err != nil {
+		return entity.CodPayoutBatch{}, err
+	}
+
+	if err = batch.Payable(); err != nil {
+		return entity.CodPayoutBatch{}, err
+	}
+
+	err = s.repo.PayOutBatch(ctx, id, reference)
+	if err != nil {
+		return entity.CodPayoutBatch{}, err
+	}
+
+	return s.repo.GetPayoutBatch(ctx, id)
+}
+
+func (s *serviceImpl) GetPayoutBatch(ctx context.Context, idStr string) (entity.CodPayoutBatch, error) {
+	id, err := parseID(idStr)
+	if err != nil {
+		return entity.CodPayoutBatch{}, err
+	}
+
+	return s.repo.GetPayoutBatch(ctx, id)
+}
+
+func (s *serviceImpl) GetPayoutBatches(ctx context.Context, paginationStr dbutil.PaginationStr) ([]entity.CodPayoutBatch, hypermedia.Links, error) {
+	pagination, err := paginationStr.Parse([]string{"status", "reference"}, "created_at", "amount")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	batches, total, err, empty := s.repo.GetPayoutBatches(ctx, pagination)
+	if err != nil || empty {
+		return nil, nil, err
+	}
+
+	return batches, hypermedia.Pagination(paginationStr, total), nil
+}
+
+func NewCashOnDeliveryService(repo repo.CashOnDelivery) CashOnDelivery {
+	return &serviceImpl{
+		repo,
+	}
+}
@@ -0,0 +1,164 @@
+package http
+
+import (
+	"context"
+	"maryan_api/internal/domain/cod/service"
+	"maryan_api/internal/entity"
+	"maryan_api/pkg/dbutil"
+	ginutil "maryan_api/pkg/ginutils"
+	"maryan_api/pkg/hypermedia"
+	rfc7807 "maryan_api/pkg/problem"
+	"net/http"
+	"time"
+
+	"github.com/d3code/uuid"
+	"github.com/gin-gonic/gin"
+)
+
+type cashOnDeliveryHandler struct {
+	service service.CashOnDelivery
+}
+
+func newCashOnDeliveryHandler(service service.CashOnDelivery) *cashOnDeliveryHandler {
+	return &cashOnDeliveryHandler{service}
+}
+
+func (c *cashOnDeliveryHandler) getLedger(ctx *gin.Context) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	entries, links, err := c.service.GetLedger(ctxWithTimeout, dbutil.PaginationStr{
+		"/admin/cod-ledger",
+		ctx.DefaultQuery("page", "1"),
+		ctx.DefaultQuery("size", "20"),
+		ctx.DefaultQuery("order_by", "created_at"),
+		ctx.DefaultQuery("order_way", "desc"),
+		ctx.DefaultQuery("search", ""),
+	})
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		ginutil.Response
+		Entries []entity.ParcelCashOnDelivery `json:"entries"`
+	}{
+		ginutil.Response{
+			"The cash on delivery ledger has successfuly been found.",
+			links,
+		},
+		entries,
+	})
+}
+
+func (c *cashOnDeliveryHandler) createPayoutBatch(ctx *gin.Context) {
+	var request entity.CodPayoutBatchJSON
+
+	err := ctx.ShouldBindJSON(&request)
+	if err != nil {
+		ginutil.HandlerProblemAbort(ctx, rfc7807.JSON(err.Error()))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*20)
+	defer cancel()
+
+	batch, err := c.service.CreatePayoutBatch(ctxWithTimeout, ctx.MustGet("userID").(uuid.UUID), request)
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, struct {
+		ginutil.Response
+		Batch entity.CodPayoutBatch `json:"batch"`
+	}{
+		ginutil.Response{
+			"The payout batch has successfuly been created.",
+			hypermedia.Links{},
+		},
+		batch,
+	})
+}
+
+func (c *cashOnDeliveryHandler) payOutBatch(ctx *gin.Context) {
+	var request entity.CodPayoutPaidJSON
+
+	err := ctx.ShouldBindJSON(&request)
+	if err != nil {
+		ginutil.HandlerProblemAbort(ctx, rfc7807.JSON(err.Error()))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	batch, err := c.service.PayOutBatch(ctxWithTimeout, ctx.Param("id"), request)
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		ginutil.Response
+		Batch entity.CodPayoutBatch `json:"batch"`
+	}{
+		ginutil.Response{
+			"The payout batch has successfuly been paid out.",
+			hypermedia.Links{},
+		},
+		batch,
+	})
+}
+
+func (c *cashOnDeliveryHandler) getPayoutBatch(ctx *gin.Context) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	batch, err := c.service.GetPayoutBatch(ctxWithTimeout, ctx.Param("id"))
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		ginutil.Response
+		Batch entity.CodPayoutBatch `json:"batch"`
+	}{
+		ginutil.Response{
+			"The payout batch has successfuly been found.",
+			hypermedia.Links{},
+		},
+		batch,
+	})
+}
+
+func (c *cashOnDeliveryHandler) getPayoutBatches(ctx *gin.Context) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	batches, links, err := c.service.GetPayoutBatches(ctxWithTimeout, dbutil.PaginationStr{
+		"/admin/cod-payouts",
+		ctx.DefaultQuery("page", "1"),
+		ctx.DefaultQuery("size", "20"),
+		ctx.DefaultQuery("order_by", "created_at"),
+		ctx.DefaultQuery("order_way", "desc"),
+		ctx.DefaultQuery("search", ""),
+	})
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		ginutil.Response
+		Batches []entity.CodPayoutBatch `json:"batches"`
+	}{
+		ginutil.Response{
+			"The payout batches have successfuly been found.",
+			links,
+		},
+		batches,
+	})
+}
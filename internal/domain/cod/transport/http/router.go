@@ -0,0 +1,26 @@
+package http
+
+import (
+	"maryan_api/internal/domain/cod/repo"
+	"maryan_api/internal/domain/cod/service"
+	"maryan_api/pkg/auth"
+	ginutil "maryan_api/pkg/ginutils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func RegisterRoutes(db *gorm.DB, s *gin.Engine, client *http.Client) {
+	adminRouter := ginutil.CreateAuthRouter("/admin", auth.Admin.SecretKey(), s)
+
+	adminHandler := newCashOnDeliveryHandler(service.NewCashOnDeliveryService(repo.NewCashOnDeliveryRepo(db)))
+
+	//-----------------------Cash On Delivery Routes---------------------------------------
+
+	adminRouter.GET("/cod-ledger", adminHandler.getLedger)
+	adminRouter.GET("/cod-payouts", adminHandler.getPayoutBatches)
+	adminRouter.GET("/cod-payouts/:id", adminHandler.getPayoutBatch)
+	adminRouter.POST("/cod-payouts", adminHandler.createPayoutBatch)
+	adminRouter.POST("/cod-payouts/:id/paid", adminHandler.payOutBatch)
+}
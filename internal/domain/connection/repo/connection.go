@@ -14,6 +14,7 @@ import (
 
 type Connection interface {
 	GetByID(ctx context.Context, id uuid.UUID, passengerNumber int) (entity.Connection, []uuid.UUID, error)
+	GetSegmentByID(ctx context.Context, id uuid.UUID, passengerNumber, fromStop, toStop int) (entity.Connection, entity.Segment, []uuid.UUID, error)
 	GetConnections(ctx context.Context, pagination dbutil.Pagination) ([]entity.Connection, int, error, bool)
 	ChangeDepartureTime(ctx context.Context, id uuid.UUID, departureTime time.Time) error
 	ChangeGoogleMapsURL(ctx context.Context, id uuid.UUID, url string) error
@@ -22,10 +23,22 @@ type Connection interface {
 	RegisterUpdate(ctx context.Context, update *entity.ConnectionUpdate) error
 	ChangeType(ctx context.Context, id uuid.UUID, connectionType entity.ConnectionType) error
 	FindConnections(ctx context.Context, request entity.FindConnectionsRequest) (dataStore.FoundConnections, error)
+	GetFareDiscounts(ctx context.Context, lines ...int) (entity.FareDiscounts, error)
+	SaveFareDiscounts(ctx context.Context, discounts entity.FareDiscounts) error
+	GetPricing(ctx context.Context, lines ...int) (entity.Pricing, error)
+	SavePricing(ctx context.Context, line int, pricing entity.Pricing) error
+	GetExchangeRate(ctx context.Context, currency entity.Currency) (entity.ExchangeRate, error)
+	GetExchangeRates(ctx context.Context) ([]entity.ExchangeRate, error)
+	SaveExchangeRate(ctx context.Context, rate *entity.ExchangeRate) error
+	GetLuggageTariff(ctx context.Context, departureCountryID, destinationCountryID uuid.UUID, at time.Time) (entity.LuggageTariff, error)
 }
 
 type connectionRepo struct {
-	ds dataStore.Connection
+	ds       dataStore.Connection
+	discount dataStore.FareDiscount
+	pricing  dataStore.Pricing
+	rate     dataStore.ExchangeRate
+	tariff   dataStore.Tariff
 }
 
 func (r *connectionRepo) FindConnections(ctx context.Context, request entity.FindConnectionsRequest) (dataStore.FoundConnections, error) {
@@ -36,6 +49,10 @@ func (r *connectionRepo) GetByID(ctx context.Context, id uuid.UUID, passengerNum
 	return r.ds.GetByID(ctx, id, passengerNumber)
 }
 
+func (r *connectionRepo) GetSegmentByID(ctx context.Context, id uuid.UUID, passengerNumber, fromStop, toStop int) (entity.Connection, entity.Segment, []uuid.UUID, error) {
+	return r.ds.GetSegmentByID(ctx, id, passengerNumber, fromStop, toStop)
+}
+
 func (r *connectionRepo) GetConnections(ctx context.Context, pagination dbutil.Pagination) ([]entity.Connection, int, error, bool) {
 	return r.ds.GetConnections(ctx, pagination)
 }
@@ -64,7 +81,39 @@ func (r *connectionRepo) ChangeType(ctx context.Context, id uuid.UUID, connectio
 	return r.ds.ChangeType(ctx, id, connectionType)
 }
 
+func (r *connectionRepo) GetFareDiscounts(ctx context.Context, lines ...int) (entity.FareDiscounts, error) {
+	return r.discount.GetByLines(ctx, lines...)
+}
+
+func (r *connectionRepo) SaveFareDiscounts(ctx context.Context, discounts entity.FareDiscounts) error {
+	return r.discount.Save(ctx, discounts)
+}
+
+func (r *connectionRepo) GetPricing(ctx context.Context, lines ...int) (entity.Pricing, error) {
+	return r.pricing.GetByLines(ctx, lines...)
+}
+
+func (r *connectionRepo) SavePricing(ctx context.Context, line int, pricing entity.Pricing) error {
+	return r.pricing.Save(ctx, line, pricing)
+}
+
+func (r *connectionRepo) GetExchangeRate(ctx context.Context, currency entity.Currency) (entity.ExchangeRate, error) {
+	return r.rate.Get(ctx, currency)
+}
+
+func (r *connectionRepo) GetExchangeRates(ctx context.Context) ([]entity.ExchangeRate, error) {
+	return r.rate.GetAll(ctx)
+}
+
+func (r *connectionRepo) SaveExchangeRate(ctx context.Context, rate *entity.ExchangeRate) error {
+	return r.rate.Save(ctx, rate)
+}
+
 // Constructor
+func (r *connectionRepo) GetLuggageTariff(ctx context.Context, departureCountryID, destinationCountryID uuid.UUID, at time.Time) (entity.LuggageTariff, error) {
+	return r.tariff.GetActiveLuggageTariff(ctx, departureCountryID, destinationCountryID, at)
+}
+
 func NewConnectionRepo(db *gorm.DB) Connection {
-	return &connectionRepo{dataStore.NewConnection(db)}
+	return &connectionRepo{dataStore.NewConnection(db), dataStore.NewFareDiscount(db), dataStore.NewPricing(db), dataStore.NewExchangeRate(db), dataStore.NewTariff(db)}
 }
@@ -20,10 +20,16 @@ type AdminConnection interface {
 	GetByID(ctx context.Context, id string, passengerNumber string) (entity.Connection, error)
 	GetConnections(ctx context.Context, pagination dbutil.PaginationStr, complete string) ([]entity.ConnectionSimplified, hypermedia.Links, error)
 	RegisterUpdate(ctx context.Context, update entity.ConnectionUpdate) error
+	GetFareDiscounts(ctx context.Context, lineStr string) (entity.FareDiscounts, error)
+	SetFareDiscounts(ctx context.Context, lineStr string, discounts []entity.FareDiscountJSON) (entity.FareDiscounts, error)
+	GetPricing(ctx context.Context, lineStr string) (entity.Pricing, error)
+	SetPricing(ctx context.Context, lineStr string, pricing entity.PricingJSON) (entity.Pricing, error)
+	GetExchangeRates(ctx context.Context) ([]entity.ExchangeRate, error)
+	SetExchangeRate(ctx context.Context, currency string, rate entity.ExchangeRateJSON) (entity.ExchangeRate, error)
 }
 
 type CustomerConnection interface {
-	GetByID(ctx context.Context, id string, passengerNumber string) (entity.CustomerConnection, error)
+	GetByID(ctx context.Context, id string, passengerNumber, fromStop, toStop, currency string) (entity.CustomerConnection, error)
 	GetConnections(ctx context.Context, userID uuid.UUID, pagination dbutil.PaginationStr, complete string) ([]entity.CustomerConnection, hypermedia.Links, error)
 	FindConnections(ctx context.Context, request entity.FindConnectionsRequestJSON) (entity.FindConnectionsResponse, error)
 }
@@ -44,8 +50,29 @@ func (c *connectionService) FindConnections(ctx context.Context, requestJSON ent
 		return entity.FindConnectionsResponse{}, err
 	}
 
+	var lines = make([]int, 0, len(found.Connections)+len(found.RangeConnections))
+	for _, connection := range slices.Concat(found.Connections, found.RangeConnections) {
+		lines = append(lines, connection.Line)
+	}
+
+	discounts, err := c.repo.GetFareDiscounts(ctx, lines...)
+	if err != nil {
+		return entity.FindConnectionsResponse{}, err
+	}
+
+	pricing, err := c.repo.GetPricing(ctx, lines...)
+	if err != nil {
+		return entity.FindConnectionsResponse{}, err
+	}
+
+	rate, err := c.repo.GetExchangeRate(ctx, request.Currency)
+	if err != nil {
+		return entity.FindConnectionsResponse{}, err
+	}
+
 	var response = entity.FindConnectionsResponse{
 		Connections: make([]entity.FoundConnection, len(found.Connections)),
+		Currency:    rate.Currency,
 	}
 
 	for i, connection := range found.Connections {
@@ -53,19 +80,38 @@ func (c *connectionService) FindConnections(ctx context.Context, requestJSON ent
 			return ticketsLeft.ID == connection.ID
 		})]
 
+		foundSegment := found.Segments[slices.IndexFunc(found.Segments, func(segment dataStore.FoundSegment) bool {
+			return segment.ID == connection.ID
+		})]
+
+		segment, err := connection.Segment(foundSegment.FromStop, foundSegment.ToStop)
+		if err != nil {
+			segment = connection.FullRoute()
+		}
+
+		fare := pricing.SegmentFare(connection.Line, segment, ticketsLeft.Seats, ticketsLeft.Seats-int(ticketsLeft.Number))
+		simplified := connection.SimplifySegment(segment)
+		simplified.Price = rate.Convert(fare).Amount
+
 		response.Connections[i] = entity.FoundConnection{
-			ConnectionSimplified: connection.Simplify(),
+			ConnectionSimplified: simplified,
+			FromStop:             segment.From.Sequence,
+			ToStop:               segment.To.Sequence,
 			TicketsLeft:          int(ticketsLeft.Number),
 			Fits:                 int(ticketsLeft.Number)-request.Adults-request.Children-request.Teenagers >= 0,
 			Available:            config.MustParseToLocal(time.Now(), connection.DepartureCountry.Name).UTC().Before(connection.SellBefore),
+			TotalPrice:           rate.Convert(discounts.Total(connection.Line, fare, request.Adults, request.Teenagers, request.Children)).Amount,
 		}
 	}
 
+	minPrices := rangeMinPrices(found, pricing)
+
 	response.LeftRange = make([]entity.ConnectionsRange, request.Range)
 	length := len(found.LeftRange)
 	for i := 0; i < request.Range; i++ {
 		if i < length {
 			response.LeftRange[i] = found.LeftRange[i]
+			response.LeftRange[i].MinPrice = rate.Convert(minPrices[found.LeftRange[i].Date.Format(time.DateOnly)]).Amount
 			response.LeftRange[i].Available = !response.LeftRange[i].SellBefore.Before(config.MustParseToLocalByUUID(time.Now(), request.From).UTC())
 			response.LeftRange[i].Date = response.LeftRange[i].Date.In(config.MustGetLocationFromCountryID(request.From))
 		} else if i == 0 {
@@ -84,6 +130,7 @@ func (c *connectionService) FindConnections(ctx context.Context, requestJSON ent
 	for i := 0; i < request.Range; i++ {
 		if i < length {
 			response.RightRange[i] = found.RightRange[i]
+			response.RightRange[i].MinPrice = rate.Convert(minPrices[found.RightRange[i].Date.Format(time.DateOnly)]).Amount
 			response.RightRange[i].Available = !response.RightRange[i].SellBefore.Before(config.MustParseToLocalByUUID(time.Now(), request.From).UTC())
 			response.RightRange[i].Date = response.RightRange[i].Date.In(config.MustGetLocationFromCountryID(request.From))
 		} else if i == 0 {
@@ -99,6 +146,33 @@ func (c *connectionService) FindConnections(ctx context.Context, requestJSON ent
 	return response, nil
 }
 
+// rangeMinPrices returns the lowest fare of the segments found on every date of the ranges,
+// priced the same way as the found connections.
+func rangeMinPrices(found dataStore.FoundConnections, pricing entity.Pricing) map[string]int {
+	var minPrices = make(map[string]int)
+	for _, rangeSegment := range found.RangeSegments {
+		index := slices.IndexFunc(found.RangeConnections, func(connection entity.Connection) bool {
+			return connection.ID == rangeSegment.ID
+		})
+		if index == -1 {
+			continue
+		}
+
+		connection := found.RangeConnections[index]
+		segment, err := connection.Segment(rangeSegment.FromStop, rangeSegment.ToStop)
+		if err != nil {
+			continue
+		}
+
+		date := rangeSegment.Date.Format(time.DateOnly)
+		fare := pricing.SegmentFare(connection.Line, segment, rangeSegment.Seats, rangeSegment.TakenSeats)
+		if minPrice, ok := minPrices[date]; !ok || fare < minPrice {
+			minPrices[date] = fare
+		}
+	}
+	return minPrices
+}
+
 func (c *connectionService) getByID(ctx context.Context, idStr string, passengerNumber string) (entity.Connection, []uuid.UUID, error) {
 	passengers, err := strconv.Atoi(passengerNumber)
 	if err != nil {
@@ -179,16 +253,153 @@ func (c *adminService) RegisterUpdate(ctx context.Context, update entity.Connect
 	return c.repo.RegisterUpdate(ctx, &update)
 }
 
-func (c *customerService) GetByID(ctx context.Context, connectionIDStr string, passengersNumber string) (entity.CustomerConnection, error) {
+func (c *adminService) GetFareDiscounts(ctx context.Context, lineStr string) (entity.FareDiscounts, error) {
+	line, err := strconv.Atoi(lineStr)
+	if err != nil {
+		return nil, rfc7807.BadRequest("parsing", "Parsing Error", err.Error())
+	}
+
+	discounts, err := c.repo.GetFareDiscounts(ctx, line)
+	if err != nil {
+		return nil, err
+	}
+
+	return discounts.Effective(line), nil
+}
+
+func (c *adminService) SetFareDiscounts(ctx context.Context, lineStr string, discountsJSON []entity.FareDiscountJSON) (entity.FareDiscounts, error) {
+	line, err := strconv.Atoi(lineStr)
+	if err != nil {
+		return nil, rfc7807.BadRequest("parsing", "Parsing Error", err.Error())
+	}
+
+	discounts, err := entity.ParseFareDiscounts(line, discountsJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.repo.SaveFareDiscounts(ctx, discounts)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetFareDiscounts(ctx, lineStr)
+}
+
+func (c *adminService) GetPricing(ctx context.Context, lineStr string) (entity.Pricing, error) {
+	line, err := strconv.Atoi(lineStr)
+	if err != nil {
+		return entity.Pricing{}, rfc7807.BadRequest("parsing", "Parsing Error", err.Error())
+	}
+
+	pricing, err := c.repo.GetPricing(ctx, line)
+	if err != nil {
+		return entity.Pricing{}, err
+	}
+
+	return pricing.Line(line), nil
+}
+
+func (c *adminService) SetPricing(ctx context.Context, lineStr string, pricingJSON entity.PricingJSON) (entity.Pricing, error) {
+	line, err := strconv.Atoi(lineStr)
+	if err != nil {
+		return entity.Pricing{}, rfc7807.BadRequest("parsing", "Parsing Error", err.Error())
+	}
+
+	pricing, err := pricingJSON.Parse(line)
+	if err != nil {
+		return entity.Pricing{}, err
+	}
+
+	err = c.repo.SavePricing(ctx, line, pricing)
+	if err != nil {
+		return entity.Pricing{}, err
+	}
+
+	return c.GetPricing(ctx, lineStr)
+}
+
+func (c *adminService) GetExchangeRates(ctx context.Context) ([]entity.ExchangeRate, error) {
+	rates, err := c.repo.GetExchangeRates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]entity.ExchangeRate{entity.BaseExchangeRate}, rates...), nil
+}
+
+func (c *adminService) SetExchangeRate(ctx context.Context, currency string, rateJSON entity.ExchangeRateJSON) (entity.ExchangeRate, error) {
+	rate, err := rateJSON.Parse(currency)
+	if err != nil {
+		return entity.ExchangeRate{}, err
+	}
+
+	return rate, c.repo.SaveExchangeRate(ctx, &rate)
+}
+
+func (c *customerService) GetByID(ctx context.Context, connectionIDStr string, passengersNumber, fromStopStr, toStopStr, currencyStr string) (entity.CustomerConnection, error) {
+	currency, err := entity.ParseCurrency(currencyStr)
+	if err != nil {
+		return entity.CustomerConnection{}, err
+	}
+
+	rate, err := c.repo.GetExchangeRate(ctx, currency)
+	if err != nil {
+		return entity.CustomerConnection{}, err
+	}
+
+	passengers, err := strconv.Atoi(passengersNumber)
+	if err != nil {
+		return entity.CustomerConnection{}, rfc7807.BadRequest("parsing", "Parsing Error", err.Error())
+	}
+
+	fromStop, err := strconv.Atoi(fromStopStr)
+	if err != nil {
+		return entity.CustomerConnection{}, rfc7807.BadRequest("parsing", "Parsing Error", err.Error())
+	}
+
+	toStop, err := strconv.Atoi(toStopStr)
+	if err != nil {
+		return entity.CustomerConnection{}, rfc7807.BadRequest("parsing", "Parsing Error", err.Error())
+	}
+
+	id, err := uuid.Parse(connectionIDStr)
+	if err != nil {
+		return entity.CustomerConnection{}, rfc7807.UUID(err.Error())
+	}
+
+	connection, segment, takedSeatsIDs, err := c.repo.GetSegmentByID(ctx, id, passengers, fromStop, toStop)
+	if err != nil {
+		return entity.CustomerConnection{}, err
+	}
+
+	discounts, err := c.repo.GetFareDiscounts(ctx, connection.Line)
+	if err != nil {
+		return entity.CustomerConnection{}, err
+	}
+
+	pricing, err := c.repo.GetPricing(ctx, connection.Line)
+	if err != nil {
+		return entity.CustomerConnection{}, err
+	}
 
-	connection, takedSeatsIDs, err := c.getByID(ctx, connectionIDStr, passengersNumber)
+	// The fare is only shown here, it is quoted when the customer starts the checkout.
+	fare := pricing.SegmentFare(connection.Line, segment, len(connection.Bus.Seats), len(takedSeatsIDs))
 
+	tariff, err := c.repo.GetLuggageTariff(ctx, segment.From.CountryID, segment.To.CountryID, time.Now().UTC())
 	if err != nil {
 		return entity.CustomerConnection{}, err
 	}
 
-	luggage := config.GetLoggageConfig()
-	return connection.ToCustomer(takedSeatsIDs, int(luggage.Small.Price), int(luggage.Medium.Price), int(luggage.Large.Price)), nil
+	customerConnection := connection.ToCustomer(takedSeatsIDs, tariff.BackpackPrice, tariff.SmallLuggagePrice, tariff.LargeLuggagePrice)
+	customerConnection.ConnectionSimplified = connection.SimplifySegment(segment)
+	customerConnection.Price = fare
+	customerConnection.Bus = connection.Bus.ToCustomerBus(takedSeatsIDs, fare)
+	customerConnection.FromStop = segment.From.Sequence
+	customerConnection.ToStop = segment.To.Sequence
+	customerConnection.Fares = discounts.Fares(connection.Line, fare)
+	customerConnection.ConvertPrices(rate)
+	return customerConnection, nil
 }
 
 func (c *customerService) GetConnections(ctx context.Context, userID uuid.UUID, paginationStr dbutil.PaginationStr, completed string) ([]entity.CustomerConnection, hypermedia.Links, error) {
@@ -96,6 +96,155 @@ func (ch *adminHandler) RegisterUpdate(ctx *gin.Context) {
 
 }
 
+func (ch *adminHandler) GetFareDiscounts(ctx *gin.Context) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	discounts, err := ch.service.GetFareDiscounts(ctxWithTimeout, ctx.Param("line"))
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		Discounts entity.FareDiscounts `json:"discounts"`
+		ginutil.Response
+	}{
+		discounts,
+		ginutil.Response{
+			Message: "The fare discounts have successfuly been found.",
+		},
+	})
+}
+
+func (ch *adminHandler) SetFareDiscounts(ctx *gin.Context) {
+	var request struct {
+		Discounts []entity.FareDiscountJSON `json:"discounts"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ginutil.HandlerProblemAbort(ctx, rfc7807.JSON(err.Error()))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	discounts, err := ch.service.SetFareDiscounts(ctxWithTimeout, ctx.Param("line"), request.Discounts)
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		Discounts entity.FareDiscounts `json:"discounts"`
+		ginutil.Response
+	}{
+		discounts,
+		ginutil.Response{
+			Message: "The fare discounts have successfuly been saved.",
+		},
+	})
+}
+
+func (ch *adminHandler) GetPricing(ctx *gin.Context) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	pricing, err := ch.service.GetPricing(ctxWithTimeout, ctx.Param("line"))
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		Pricing entity.Pricing `json:"pricing"`
+		ginutil.Response
+	}{
+		pricing,
+		ginutil.Response{
+			Message: "The pricing has successfuly been found.",
+		},
+	})
+}
+
+func (ch *adminHandler) SetPricing(ctx *gin.Context) {
+	var request entity.PricingJSON
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ginutil.HandlerProblemAbort(ctx, rfc7807.JSON(err.Error()))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	pricing, err := ch.service.SetPricing(ctxWithTimeout, ctx.Param("line"), request)
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		Pricing entity.Pricing `json:"pricing"`
+		ginutil.Response
+	}{
+		pricing,
+		ginutil.Response{
+			Message: "The pricing has successfuly been saved.",
+		},
+	})
+}
+
+func (ch *adminHandler) GetExchangeRates(ctx *gin.Context) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	rates, err := ch.service.GetExchangeRates(ctxWithTimeout)
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		Rates []entity.ExchangeRate `json:"rates"`
+		ginutil.Response
+	}{
+		rates,
+		ginutil.Response{
+			Message: "The exchange rates have successfuly been found.",
+		},
+	})
+}
+
+func (ch *adminHandler) SetExchangeRate(ctx *gin.Context) {
+	var request entity.ExchangeRateJSON
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ginutil.HandlerProblemAbort(ctx, rfc7807.JSON(err.Error()))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	rate, err := ch.service.SetExchangeRate(ctxWithTimeout, ctx.Param("currency"), request)
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		Rate entity.ExchangeRate `json:"rate"`
+		ginutil.Response
+	}{
+		rate,
+		ginutil.Response{
+			Message: "The exchange rate has successfuly been saved.",
+		},
+	})
+}
+
 func newAdminHandler(service service.AdminConnection) adminHandler {
 	return adminHandler{service}
 }
@@ -108,7 +257,7 @@ func (ch *customerHandler) GetByID(ctx *gin.Context) {
 	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
 	defer cancel()
 
-	connection, err := ch.service.GetByID(ctxWithTimeout, ctx.Param("id"), ctx.DefaultQuery("passengers", "0"))
+	connection, err := ch.service.GetByID(ctxWithTimeout, ctx.Param("id"), ctx.DefaultQuery("passengers", "0"), ctx.DefaultQuery("fromStop", "0"), ctx.DefaultQuery("toStop", "0"), ctx.Query("currency"))
 	if err != nil {
 		ginutil.ServiceErrorAbort(ctx, err)
 		return
@@ -173,6 +322,9 @@ func (ch *customerHandler) FindConnections(ctx *gin.Context) {
 			Children:  ctx.Param("children"),
 			Teenagers: ctx.Param("teenagers"),
 			Range:     ctx.DefaultQuery("range", "5"),
+			FromCity:  ctx.Query("fromCity"),
+			ToCity:    ctx.Query("toCity"),
+			Currency:  ctx.Query("currency"),
 		},
 	)
 
@@ -22,6 +22,12 @@ func RegisterRoutes(db *gorm.DB, s *gin.Engine, client *http.Client) {
 	adminRouter.GET("/connection/:id", adminHandler.GetByID)
 	adminRouter.GET("/connections", adminHandler.GetConnections)
 	adminRouter.POST("/connection/update", adminHandler.RegisterUpdate)
+	adminRouter.GET("/fare-discounts/:line", adminHandler.GetFareDiscounts)
+	adminRouter.PUT("/fare-discounts/:line", adminHandler.SetFareDiscounts)
+	adminRouter.GET("/pricing/:line", adminHandler.GetPricing)
+	adminRouter.PUT("/pricing/:line", adminHandler.SetPricing)
+	adminRouter.GET("/exchange-rates", adminHandler.GetExchangeRates)
+	adminRouter.PUT("/exchange-rates/:currency", adminHandler.SetExchangeRate)
 
 	customerRouter.GET("/connection/:id", customerHandler.GetByID)
 	customerRouter.GET("/connections", customerHandler.GetConnections)
@@ -5,6 +5,7 @@ import (
 	"maryan_api/internal/entity"
 	dataStore "maryan_api/internal/infrastructure/persistence"
 	"maryan_api/pkg/dbutil"
+	"time"
 
 	"github.com/d3code/uuid"
 	"gorm.io/gorm"
@@ -15,15 +16,25 @@ type Parcel interface {
 	CreateParcelStops(ctx context.Context, paymentSessionID string) error
 	Create(ctx context.Context, parcel *entity.Parcel) error
 	GetParcels(ctx context.Context, pagination dbutil.Pagination) ([]entity.Parcel, []entity.Connection, int, error, bool)
-	DeleteParcels(ctx context.Context, paymentSessionID string) error
-	RemoveParcelStops(ctx context.Context, paymentSessionID string) error
-	PaymentSucceeded(ctx context.Context, paymentSessionID string) error
 	GetConnectionByID(ctx context.Context, id uuid.UUID) (entity.Connection, error)
+	ApplyPromoCode(ctx context.Context, code string, userID uuid.UUID, target entity.PromoCodeTarget) (uuid.NullUUID, int, error)
+	GetExchangeRate(ctx context.Context, currency entity.Currency) (entity.ExchangeRate, error)
+	GetByID(ctx context.Context, id uuid.UUID) (entity.Parcel, error)
+	GetByTrackingNumber(ctx context.Context, trackingNumber string) ([]entity.Parcel, error)
+	UpdateStatus(ctx context.Context, update entity.ParcelStatusUpdate) error
+	SetDeliveryCode(ctx context.Context, id uuid.UUID, code string) error
+	GetCodBalances(ctx context.Context, senderID uuid.UUID) ([]entity.CodBalance, error)
+	GetTariff(ctx context.Context, departureCountryID, destinationCountryID uuid.UUID, parcelType entity.ParcelType, at time.Time) (entity.ParcelTariff, error)
+	TrackingLocked(ctx context.Context, keys []string, now time.Time) (bool, error)
+	RegisterTrackingFailure(ctx context.Context, keys []string, now time.Time, maxFailures uint, window, lockout time.Duration) error
 }
 
 type parcelRepo struct {
 	parcel     dataStore.Parsel
 	connection dataStore.Connection
+	promoCode  dataStore.PromoCode
+	rate       dataStore.ExchangeRate
+	tariff     dataStore.Tariff
 }
 
 func (r *parcelRepo) GetConnectionByID(ctx context.Context, id uuid.UUID) (entity.Connection, error) {
@@ -47,20 +58,48 @@ func (r *parcelRepo) GetParcels(ctx context.Context, pagination dbutil.Paginatio
 	return r.parcel.GetParcels(ctx, pagination)
 }
 
-func (r *parcelRepo) DeleteParcels(ctx context.Context, paymentSessionID string) error {
-	return r.parcel.DeleteParcels(ctx, paymentSessionID)
+func (r *parcelRepo) ApplyPromoCode(ctx context.Context, code string, userID uuid.UUID, target entity.PromoCodeTarget) (uuid.NullUUID, int, error) {
+	return r.promoCode.Apply(ctx, code, userID, target)
 }
 
-func (r *parcelRepo) PaymentSucceeded(ctx context.Context, paymentSessionID string) error {
-	return r.parcel.PaymentSucceeded(ctx, paymentSessionID)
+func (r *parcelRepo) GetExchangeRate(ctx context.Context, currency entity.Currency) (entity.ExchangeRate, error) {
+	return r.rate.Get(ctx, currency)
 }
 
-func (r *parcelRepo) RemoveParcelStops(ctx context.Context, paymentSessionID string) error {
-	return r.parcel.RemoveParcelStops(ctx, paymentSessionID)
+func (r *parcelRepo) GetByID(ctx context.Context, id uuid.UUID) (entity.Parcel, error) {
+	return r.parcel.GetByID(ctx, id)
+}
+
+func (r *parcelRepo) GetByTrackingNumber(ctx context.Context, trackingNumber string) ([]entity.Parcel, error) {
+	return r.parcel.GetByTrackingNumber(ctx, trackingNumber)
+}
+
+func (r *parcelRepo) UpdateStatus(ctx context.Context, update entity.ParcelStatusUpdate) error {
+	return r.parcel.UpdateStatus(ctx, update)
+}
+
+func (r *parcelRepo) SetDeliveryCode(ctx context.Context, id uuid.UUID, code string) error {
+	return r.parcel.SetDeliveryCode(ctx, id, code)
+}
+
+func (r *parcelRepo) GetCodBalances(ctx context.Context, senderID uuid.UUID) ([]entity.CodBalance, error) {
+	return r.parcel.GetCodBalances(ctx, senderID)
+}
+
+func (r *parcelRepo) GetTariff(ctx context.Context, departureCountryID, destinationCountryID uuid.UUID, parcelType entity.ParcelType, at time.Time) (entity.ParcelTariff, error) {
+	return r.tariff.GetActiveParcelTariff(ctx, departureCountryID, destinationCountryID, parcelType, at)
+}
+
+func (r *parcelRepo) TrackingLocked(ctx context.Context, keys []string, now time.Time) (bool, error) {
+	return r.parcel.TrackingLocked(ctx, keys, now)
+}
+
+func (r *parcelRepo) RegisterTrackingFailure(ctx context.Context, keys []string, now time.Time, maxFailures uint, window, lockout time.Duration) error {
+	return r.parcel.RegisterTrackingFailure(ctx, keys, now, maxFailures, window, lockout)
 }
 
 func NewParcelRepo(db *gorm.DB) Parcel {
 	return &parcelRepo{
-		dataStore.NewParsel(db), dataStore.NewConnection(db),
+		dataStore.NewParsel(db), dataStore.NewConnection(db), dataStore.NewPromoCode(db), dataStore.NewExchangeRate(db), dataStore.NewTariff(db),
 	}
 }
@@ -5,7 +5,8 @@ import (
 	"maryan_api/config"
 	"maryan_api/internal/domain/parcel/repo"
 	"maryan_api/internal/entity"
-	"maryan_api/internal/infrastructure/clients/stripe"
+	"maryan_api/internal/infrastructure/clients/payment"
+	"maryan_api/internal/infrastructure/clients/verification"
 	"maryan_api/pkg/auth"
 	"maryan_api/pkg/dbutil"
 	"maryan_api/pkg/hypermedia"
@@ -13,26 +14,28 @@ import (
 	"net/http"
 	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/d3code/uuid"
 	"github.com/golang-jwt/jwt/v5"
-
-	"github.com/skip2/go-qrcode"
 )
 
 type Parcel interface {
 	FindConnections(ctx context.Context, request entity.FindParcelConnectionsRequest) ([]entity.ConnectionParcel, error)
 	Purchase(ctx context.Context, userID uuid.UUID, connectionID string, newParcel entity.PurchaseParcelRequest) (string, error)
-	PurchaseSucceded(ctx context.Context, sessionID, token string) error
 	PurchaseFailed(ctx context.Context, sessionID, token string) error
-	GetParcels(ctx context.Context, paginationStr dbutil.PaginationStr, userID uuid.UUID) ([]entity.CustomerParcel, hypermedia.Links, error)
-	GetConnectionByID(ctx context.Context, idStr, widthStr, heightStr, lengthStr string) (entity.CustomerConnection, error)
+	GetParcels(ctx context.Context, paginationStr dbutil.PaginationStr, userID uuid.UUID) ([]entity.CustomerParcel, []entity.CodBalance, hypermedia.Links, error)
+	GetConnectionByID(ctx context.Context, idStr, widthStr, heightStr, lengthStr, weightStr, typeStr, currency string) (entity.CustomerConnection, error)
+	Track(ctx context.Context, clientIP, trackingNumber, code, phoneSuffix string) (entity.ParcelTracking, error)
+	UpdateStatus(ctx context.Context, idStr string, status entity.ParcelStatusJSON) error
+	GetDeliveryProof(ctx context.Context, idStr string) (entity.ParcelDeliveryProof, error)
 }
 
 type serviceImpl struct {
-	repo   repo.Parcel
-	client *http.Client
+	repo     repo.Parcel
+	client   *http.Client
+	payments payment.PaymentProvider
 }
 
 func (s *serviceImpl) fetchConnections(ctx context.Context, req entity.FindParcelConnectionsRequestParsed) ([]entity.Connection, error) {
@@ -61,7 +64,7 @@ func pickBestConnectionsPerDay(connections []entity.Connection) map[int]entity.C
 	return bestPerDay
 }
 
-func buildCurrentMonth(req entity.FindParcelConnectionsRequestParsed, bestPerDay map[int]entity.Connection) []entity.ConnectionParcel {
+func buildCurrentMonth(req entity.FindParcelConnectionsRequestParsed, bestPerDay map[int]entity.Connection, price int) []entity.ConnectionParcel {
 	daysCount := daysIn(req.Month, req.Year)
 	connections := make([]entity.ConnectionParcel, daysCount)
 
@@ -71,7 +74,7 @@ func buildCurrentMonth(req entity.FindParcelConnectionsRequestParsed, bestPerDay
 		weekday := normalizeWeekday(date.Weekday())
 
 		if value, ok := bestPerDay[dayNum]; ok {
-			connections[i] = value.ToParcelConnection(true, weekday, dayNum, true, int(config.CalulateParcelPrice(uint(req.Width), uint(req.Height), uint(req.Length))))
+			connections[i] = value.ToParcelConnection(true, weekday, dayNum, true, price)
 
 		} else {
 			connections[i] = entity.ConnectionParcel{
@@ -146,8 +149,13 @@ func (s *serviceImpl) FindConnections(ctx context.Context, requestUnparsed entit
 		return nil, err
 	}
 
+	tariff, err := s.repo.GetTariff(ctx, req.From, req.To, req.Type, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
 	bestPerDay := pickBestConnectionsPerDay(connections)
-	connectionsMonth := buildCurrentMonth(req, bestPerDay)
+	connectionsMonth := buildCurrentMonth(req, bestPerDay, tariff.Price(req.Width, req.Height, req.Length, req.Weight))
 	connectionsMonth = fillPreviousMonth(connectionsMonth, req)
 	connectionsMonth = fillNextMonth(connectionsMonth, req)
 
@@ -165,16 +173,23 @@ func daysIn(month time.Month, year int) int {
 	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
 }
 
-func (s *serviceImpl) GetParcels(ctx context.Context, paginationStr dbutil.PaginationStr, userID uuid.UUID) ([]entity.CustomerParcel, hypermedia.Links, error) {
+// GetParcels returns the parcels of the sender along with what the recipients have paid on
+// delivery so far and what of it has been paid out.
+func (s *serviceImpl) GetParcels(ctx context.Context, paginationStr dbutil.PaginationStr, userID uuid.UUID) ([]entity.CustomerParcel, []entity.CodBalance, hypermedia.Links, error) {
 	pagination, err := paginationStr.ParseWithCondition(dbutil.Condition{"user_id = ? AND (SELECT succeeded FROM parcel_payments WHERE parcel_id = `parcels`.id)", []any{userID}}, []string{}, "created_at")
 	if err != nil {
 
-		return nil, nil, err
+		return nil, nil, nil, err
+	}
+
+	balances, err := s.repo.GetCodBalances(ctx, userID)
+	if err != nil {
+		return nil, nil, nil, err
 	}
 
 	parcels, connections, total, err, empty := s.repo.GetParcels(ctx, pagination)
 	if err != nil || empty {
-		return nil, nil, err
+		return nil, balances, nil, err
 	}
 
 	var respose = make([]entity.CustomerParcel, len(parcels))
@@ -185,7 +200,7 @@ func (s *serviceImpl) GetParcels(ctx context.Context, paginationStr dbutil.Pagin
 		})
 
 		if connectionIndex == -1 {
-			return nil, nil, rfc7807.DB("internal")
+			return nil, nil, nil, rfc7807.DB("internal")
 		}
 
 		respose[i] = entity.CustomerParcel{
@@ -194,7 +209,7 @@ func (s *serviceImpl) GetParcels(ctx context.Context, paginationStr dbutil.Pagin
 		}
 	}
 
-	return respose, hypermedia.Pagination(paginationStr, total), nil
+	return respose, balances, hypermedia.Pagination(paginationStr, total), nil
 }
 
 func (s *serviceImpl) PurchaseFailed(ctx context.Context, sessionID, token string) error {
@@ -202,35 +217,16 @@ func (s *serviceImpl) PurchaseFailed(ctx context.Context, sessionID, token strin
 	if err != nil {
 		return rfc7807.Unauthorized("unauthorized", "Unauthorized Error", "Unauthorized Error")
 	}
-	err = stripe.CancelPaymentIntent(sessionID)
+	err = s.payments.CancelSession(sessionID)
 	if err != nil {
 
 		return rfc7807.BadGateway("payment-cancelation",
 			"Payment Cancelation Error", err.Error())
 	}
-	err = s.repo.RemoveParcelStops(ctx, sessionID)
-	if err != nil {
-
-		return err
-	}
-
-	err = s.repo.DeleteParcels(ctx, sessionID)
-	if err != nil {
-
-		return err
-	}
 
 	return nil
 }
 
-func (s *serviceImpl) PurchaseSucceded(ctx context.Context, sessionID, token string) error {
-	_, err := auth.VerifyAccessToken(token, config.PaymentSecretKey())
-	if err != nil {
-		return rfc7807.Unauthorized("unauthorized", "Unauthorized Error", "Unauthorized Error")
-	}
-	return s.repo.PaymentSucceeded(ctx, sessionID)
-}
-
 func (s *serviceImpl) Purchase(ctx context.Context, userID uuid.UUID, connectionID string, newParcel entity.PurchaseParcelRequest) (string, error) {
 	req, params := newParcel.Parse(connectionID)
 	if params != nil {
@@ -242,10 +238,19 @@ func (s *serviceImpl) Purchase(ctx context.Context, userID uuid.UUID, connection
 		return "", err
 	}
 
+	rate, err := s.repo.GetExchangeRate(ctx, req.Currency)
+	if err != nil {
+		return "", err
+	}
+
 	if connection.LuggageVolumeLeft < uint(req.Height*req.Length*req.Width) {
 		return "", rfc7807.New(http.StatusConflict, "too-big-lugage-volume", "Too big Luggage Volume Error", "Provided luggage params makes volume that exceeds the remainig.")
 	}
 
+	if connection.ParcelWeightLeft < req.Weight {
+		return "", rfc7807.New(http.StatusConflict, "too-big-parcel-weight", "Too Big Parcel Weight Error", "The weight of the parcel exceeds the weight the connection can still carry.")
+	}
+
 	pickUpAdress := req.PickUpAdress.ToAddress(connection.DepartureCountryID)
 	params = pickUpAdress.Validate()
 	dropOffAdress := req.DropOffAdress.ToAddress(connection.DepartureCountryID)
@@ -263,22 +268,46 @@ func (s *serviceImpl) Purchase(ctx context.Context, userID uuid.UUID, connection
 	}
 
 	token, err := auth.GenerateAccessToken(config.PaymentSecretKey(), jwt.MapClaims{
-		"expires": time.Now().Add(time.Minute * 15).Unix(),
+		"expires": time.Now().Add(config.PaymentSessionDuration).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	tariff, price, err := s.quote(ctx, connection, req.Type, req.Width, req.Height, req.Length, req.Weight)
+	if err != nil {
+		return "", err
+	}
+
+	promoCodeID, promoDiscount, err := s.repo.ApplyPromoCode(ctx, req.PromoCode, userID, entity.PromoCodeTarget{
+		Product:              entity.ParcelPromoProduct,
+		Line:                 connection.Line,
+		DepartureCountryID:   connection.DepartureCountryID,
+		DestinationCountryID: connection.DestinationCountryID,
+		Price:                price,
 	})
 	if err != nil {
 		return "", err
 	}
+	price -= promoDiscount
+	charge := rate.Convert(price)
 
-	redirectURL, sessionID, err := stripe.CreateStripeCheckoutSession(int64(config.CalulateParcelPrice(uint(req.Height), uint(req.Length), uint(req.Width))), "/connection/purchase-parcel", token)
+	redirectURL, sessionID, err := s.payments.CreateSession(int64(charge.Amount), string(charge.Currency), "/connection/purchase-parcel", token)
 	if err != nil {
 		return "", rfc7807.BadGateway("payment", "Payment Error", err.Error())
 	}
 
 	parcelID := uuid.New()
+	route := connection.FullRoute()
 
-	qrCode, err := qrcode.Encode(parcelID.String(), qrcode.Highest, 256)
+	qrCode, err := entity.ParcelQRCode(parcelID, connection)
 	if err != nil {
-		return "", rfc7807.Internal("QR-Code Encoding Error", err.Error())
+		return "", err
+	}
+
+	trackingCode, err := entity.NewParcelTrackingCode()
+	if err != nil {
+		return "", err
 	}
 
 	parcel := entity.Parcel{
@@ -299,18 +328,31 @@ func (s *serviceImpl) Purchase(ctx context.Context, userID uuid.UUID, connection
 		DropOffAdressID:   dropOffAdress.ID,
 		DropOffAdress:     dropOffAdress,
 		Payment: entity.ParcelPayment{
-			ParcelID:  parcelID,
-			Price:     int(config.CalulateParcelPrice(uint(req.Height), uint(req.Length), uint(req.Width))),
-			Method:    entity.PaymentMethodCard,
-			SessionID: sessionID,
+			ParcelID:      parcelID,
+			Price:         charge.Amount,
+			Method:        entity.PaymentMethodCard,
+			SessionID:     sessionID,
+			PromoCodeID:   promoCodeID,
+			Discount:      rate.Convert(promoDiscount).Amount,
+			Currency:      charge.Currency,
+			ExchangeRate:  rate.Rate,
+			TariffID:      tariff.Ref(),
+			TariffVersion: tariff.Version,
 		},
-		LuggageVolume: uint(req.Height * req.Length * req.Width),
-		Width:         req.Width,
-		Height:        req.Height,
-		Length:        req.Length,
-		QRCode:        qrCode,
-		Weight:        req.Weight,
-		Type:          req.Type,
+		LuggageVolume:  uint(req.Height * req.Length * req.Width),
+		Width:          req.Width,
+		Height:         req.Height,
+		Length:         req.Length,
+		QRCode:         qrCode,
+		Weight:         req.Weight,
+		Type:           req.Type,
+		FromStop:       route.From.Sequence,
+		ToStop:         route.To.Sequence,
+		TrackingNumber: entity.ParcelTrackingNumber(parcelID),
+		TrackingCode:   trackingCode,
+		Status:         entity.AcceptedParcelStatus,
+		Updates:        []entity.ParcelStatusUpdate{entity.NewParcelStatusUpdate(parcelID, entity.AcceptedParcelStatus, "")},
+		CashOnDelivery: entity.NewParcelCashOnDelivery(parcelID, userID, req.CashOnDelivery, req.Currency),
 	}
 
 	err = s.repo.Create(ctx, &parcel)
@@ -325,7 +367,27 @@ func (s *serviceImpl) Purchase(ctx context.Context, userID uuid.UUID, connection
 	return redirectURL, nil
 }
 
-func (c *serviceImpl) GetConnectionByID(ctx context.Context, idStr, widthStr, heightStr, lengthStr string) (entity.CustomerConnection, error) {
+// quote prices the parcel on the connection by the tariff in effect, the connection page and the
+// checkout share it so that the price shown is the one charged.
+func (s *serviceImpl) quote(ctx context.Context, connection entity.Connection, parcelType entity.ParcelType, width, height, length, weight int) (entity.ParcelTariff, int, error) {
+	tariff, err := s.repo.GetTariff(ctx, connection.DepartureCountryID, connection.DestinationCountryID, parcelType, time.Now().UTC())
+	if err != nil {
+		return entity.ParcelTariff{}, 0, err
+	}
+
+	return tariff, tariff.Price(width, height, length, weight), nil
+}
+
+func (c *serviceImpl) GetConnectionByID(ctx context.Context, idStr, widthStr, heightStr, lengthStr, weightStr, typeStr, currencyStr string) (entity.CustomerConnection, error) {
+	currency, err := entity.ParseCurrency(currencyStr)
+	if err != nil {
+		return entity.CustomerConnection{}, err
+	}
+
+	parcelType, err := entity.ParseOptionalParcelType(typeStr)
+	if err != nil {
+		return entity.CustomerConnection{}, rfc7807.BadRequest("parsing", "Parsing Error", err.Error())
+	}
 
 	id, err := uuid.Parse(idStr)
 	if err != nil {
@@ -343,20 +405,160 @@ func (c *serviceImpl) GetConnectionByID(ctx context.Context, idStr, widthStr, he
 	if err != nil {
 		return entity.CustomerConnection{}, rfc7807.BadRequest("parsing", "Parsing Error", err.Error())
 	}
+	weight, err := entity.ParseOptionalParcelWeight(weightStr)
+	if err != nil {
+		return entity.CustomerConnection{}, rfc7807.BadRequest("parsing", "Parsing Error", err.Error())
+	}
 
 	connetion, err := c.repo.GetConnectionByID(ctx, id)
 	if err != nil {
 		return entity.CustomerConnection{}, err
 	}
 
+	rate, err := c.repo.GetExchangeRate(ctx, currency)
+	if err != nil {
+		return entity.CustomerConnection{}, err
+	}
+
+	_, price, err := c.quote(ctx, connetion, parcelType, width, height, length, weight)
+	if err != nil {
+		return entity.CustomerConnection{}, err
+	}
+
 	customeConnection := connetion.ToCustomer(nil, 0, 0, 0)
-	customeConnection.Price = int(config.CalulateParcelPrice(uint(width), uint(height), uint(length)))
+	customeConnection.Price = price
+	customeConnection.ConvertPrices(rate)
 	return customeConnection, nil
 }
 
-func NewParcelService(repo repo.Parcel, client *http.Client) Parcel {
+// Track returns the timeline of the paid parcel to whoever knows its tracking code and the
+// last digits of the phone number of the recipient. The tracking number and the client are locked
+// out after too many wrong attempts, so the codes can not be guessed.
+func (s *serviceImpl) Track(ctx context.Context, clientIP, trackingNumber, code, phoneSuffix string) (entity.ParcelTracking, error) {
+	trackingNumber = strings.ToUpper(trackingNumber)
+	keys := entity.ParcelTrackingLockKeys(trackingNumber, clientIP)
+
+	locked, err := s.repo.TrackingLocked(ctx, keys, time.Now().UTC())
+	if err != nil {
+		return entity.ParcelTracking{}, err
+	}
+	if locked {
+		return entity.ParcelTracking{}, rfc7807.New(http.StatusTooManyRequests, "tracking-locked", "Tracking Locked Error", "There have been too many wrong tracking attempts, try again later.")
+	}
+
+	parcels, err := s.repo.GetByTrackingNumber(ctx, trackingNumber)
+	if err != nil {
+		return entity.ParcelTracking{}, err
+	}
+
+	for _, parcel := range parcels {
+		if !parcel.Trackable(code, phoneSuffix) {
+			continue
+		}
+
+		connection, err := s.repo.GetConnectionByID(ctx, parcel.ConnectionID)
+		if err != nil {
+			return entity.ParcelTracking{}, err
+		}
+
+		segment, err := connection.Segment(parcel.FromStop, parcel.ToStop)
+		if err != nil {
+			return entity.ParcelTracking{}, err
+		}
+
+		return parcel.Tracking(segment), nil
+	}
+
+	err = s.repo.RegisterTrackingFailure(ctx, keys, time.Now().UTC(), config.ParcelTrackingMaxFailures, config.ParcelTrackingWindow, config.ParcelTrackingLockout)
+	if err != nil {
+		return entity.ParcelTracking{}, err
+	}
+
+	// A wrong code or phone number is reported as an unknown parcel, so the tracking numbers can not be probed.
+	return entity.ParcelTracking{}, rfc7807.New(http.StatusNotFound, "unknown-parcel", "Unknown Parcel Error", "There is no parcel with provided tracking number, code and phone number.")
+}
+
+// UpdateStatus adds the status set by the staff to the timeline of the parcel.
+func (s *serviceImpl) UpdateStatus(ctx context.Context, idStr string, statusJSON entity.ParcelStatusJSON) error {
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return rfc7807.BadRequest("invalid-id", "Invalid ID Error", "Provided parcel id is not a valid UUID.")
+	}
+
+	update, err := statusJSON.Parse(id)
+	if err != nil {
+		return err
+	}
+
+	parcel, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if !parcel.Payment.Succeeded {
+		return rfc7807.BadRequest("unpaid-parcel", "Unpaid Parcel Error", "The parcel has not been paid.")
+	}
+
+	if parcel.Status.Final() {
+		return rfc7807.New(http.StatusConflict, "final-parcel-status", "Final Parcel Status Error", "The parcel has already been delivered or returned.")
+	}
+
+	if parcel.Status == update.Status {
+		return rfc7807.New(http.StatusConflict, "same-parcel-status", "Same Parcel Status Error", "The parcel already has the status.")
+	}
+
+	if update.Status == entity.OutForDeliveryParcelStatus {
+		if err = s.sendDeliveryCode(ctx, parcel); err != nil {
+			return err
+		}
+	}
+
+	return s.repo.UpdateStatus(ctx, update)
+}
+
+// sendDeliveryCode texts the recipient a new code to give the driver on the drop-off.
+func (s *serviceImpl) sendDeliveryCode(ctx context.Context, parcel entity.Parcel) error {
+	code, err := entity.NewParcelDeliveryCode()
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.SetDeliveryCode(ctx, parcel.ID, code)
+	if err != nil {
+		return err
+	}
+
+	err = verification.SendDeliveryCode(ctx, parcel.RecieverPhoneNumber, code)
+	if err != nil {
+		return rfc7807.BadGateway("delivery-code", "Delivery Code Error", err.Error())
+	}
+
+	return nil
+}
+
+// GetDeliveryProof returns what the driver collected from the recipient of the delivered parcel.
+func (s *serviceImpl) GetDeliveryProof(ctx context.Context, idStr string) (entity.ParcelDeliveryProof, error) {
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return entity.ParcelDeliveryProof{}, rfc7807.BadRequest("invalid-id", "Invalid ID Error", "Provided parcel id is not a valid UUID.")
+	}
+
+	parcel, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return entity.ParcelDeliveryProof{}, err
+	}
+
+	if parcel.DeliveryProof == nil {
+		return entity.ParcelDeliveryProof{}, rfc7807.New(http.StatusNotFound, "no-delivery-proof", "No Delivery Proof Error", "The parcel has not been delivered by a driver.")
+	}
+
+	return *parcel.DeliveryProof, nil
+}
+
+func NewParcelService(repo repo.Parcel, client *http.Client, payments payment.PaymentProvider) Parcel {
 	return &serviceImpl{
 		repo,
 		client,
+		payments,
 	}
 }
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"maryan_api/internal/domain/parcel/repo"
+	"maryan_api/internal/entity"
+	rfc7807 "maryan_api/pkg/problem"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/d3code/uuid"
+)
+
+// parcelRepoStub keeps the wrong tracking attempts in memory, only the methods the tests
+// reach are implemented.
+type parcelRepoStub struct {
+	repo.Parcel
+	parcels  []entity.Parcel
+	locked   bool
+	failures []string
+	lookups  int
+}
+
+func (r *parcelRepoStub) TrackingLocked(ctx context.Context, keys []string, now time.Time) (bool, error) {
+	return r.locked, nil
+}
+
+func (r *parcelRepoStub) RegisterTrackingFailure(ctx context.Context, keys []string, now time.Time, maxFailures uint, window, lockout time.Duration) error {
+	r.failures = append(r.failures, keys...)
+	return nil
+}
+
+func (r *parcelRepoStub) GetByTrackingNumber(ctx context.Context, trackingNumber string) ([]entity.Parcel, error) {
+	r.lookups++
+	return r.parcels, nil
+}
+
+func paidParcel() entity.Parcel {
+	return entity.Parcel{
+		TrackingNumber:      "A1B2C3D4E5F6",
+		TrackingCode:        "123456",
+		RecieverPhoneNumber: "+380501234567",
+		Payment:             entity.ParcelPayment{Succeeded: true},
+	}
+}
+
+func TestTrackWrongCodeIsCounted(t *testing.T) {
+	t.Setenv("API_URL", "http://localhost:8080")
+
+	stub := &parcelRepoStub{parcels: []entity.Parcel{paidParcel()}}
+	s := NewParcelService(stub, nil, nil)
+
+	_, err := s.Track(context.Background(), "203.0.113.7", "a1b2c3d4e5f6", "654321", "4567")
+
+	problem, ok := rfc7807.Is(err)
+	if !ok || problem.Status != http.StatusNotFound {
+		t.Fatalf("Track() error = %v, want a %d problem", err, http.StatusNotFound)
+	}
+
+	want := []string{"number:A1B2C3D4E5F6", "ip:203.0.113.7"}
+	if !slices.Equal(stub.failures, want) {
+		t.Errorf("counted the failures of %v, want %v", stub.failures, want)
+	}
+}
+
+func TestTrackLockedIsRejected(t *testing.T) {
+	t.Setenv("API_URL", "http://localhost:8080")
+
+	stub := &parcelRepoStub{parcels: []entity.Parcel{paidParcel()}, locked: true}
+	s := NewParcelService(stub, nil, nil)
+
+	// Even the right code is rejected, so the locked out client learns nothing.
+	_, err := s.Track(context.Background(), "203.0.113.7", "A1B2C3D4E5F6", "123456", "4567")
+
+	problem, ok := rfc7807.Is(err)
+	if !ok || problem.Status != http.StatusTooManyRequests {
+		t.Fatalf("Track() error = %v, want a %d problem", err, http.StatusTooManyRequests)
+	}
+
+	if stub.lookups != 0 {
+		t.Error("the parcels have been looked up for a locked tracking")
+	}
+}
+
+func TestUpdateStatusRejectsDelivered(t *testing.T) {
+	t.Setenv("API_URL", "http://localhost:8080")
+
+	s := NewParcelService(&parcelRepoStub{}, nil, nil)
+
+	// The parcel is only delivered by the driver, with the proof of delivery.
+	err := s.UpdateStatus(context.Background(), uuid.New().String(), entity.ParcelStatusJSON{Status: string(entity.DeliveredParcelStatus)})
+
+	problem, ok := rfc7807.Is(err)
+	if !ok || problem.Status != http.StatusBadRequest {
+		t.Fatalf("UpdateStatus() error = %v, want a %d problem", err, http.StatusBadRequest)
+	}
+}
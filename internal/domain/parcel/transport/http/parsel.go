@@ -36,6 +36,8 @@ func (ch *parcelHandler) findConnections(ctx *gin.Context) {
 		Length: ctx.Param("length"),
 		Height: ctx.Param("height"),
 		Width:  ctx.Param("width"),
+		Weight: ctx.Query("weight"),
+		Type:   ctx.Query("type"),
 	}
 	connections, err := ch.service.FindConnections(
 		ctxWithTimeout,
@@ -88,28 +90,9 @@ func (p *parcelHandler) purchase(ctx *gin.Context) {
 	})
 }
 
+// purchaseSucceded only brings the customer back to the frontend, the payment
+// itself is confirmed by the webhook of the payment provider.
 func (p *parcelHandler) purchaseSucceded(ctx *gin.Context) {
-	var sessionID = ctx.Param("id")
-	if sessionID == "" {
-		ginutil.HandlerProblemAbort(ctx, rfc7807.Unauthorized("unauthorized", "Unauthorized Error", "Unauthorized."))
-		return
-	}
-
-	var token = ctx.Param("token")
-	if token == "" {
-		ginutil.HandlerProblemAbort(ctx, rfc7807.Unauthorized("unauthorized", "Unauthorized Error", "Unauthorized."))
-		return
-	}
-
-	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
-	defer cancel()
-
-	err := p.service.PurchaseSucceded(ctxWithTimeout, sessionID, token)
-	if err != nil {
-		ginutil.ServiceErrorAbort(ctx, err)
-		return
-	}
-
 	ctx.Redirect(http.StatusFound, config.FrontendURL()+"/profile/parcels")
 }
 
@@ -118,7 +101,7 @@ func (p *parcelHandler) getParcels(ctx *gin.Context) {
 	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
 	defer cancel()
 
-	parcels, links, err := p.service.GetParcels(ctxWithTimeout, dbutil.PaginationStr{
+	parcels, balances, links, err := p.service.GetParcels(ctxWithTimeout, dbutil.PaginationStr{
 		"/customer/parcels",
 		ctx.DefaultQuery("page", "1"),
 		ctx.DefaultQuery("size", "9"),
@@ -134,8 +117,9 @@ func (p *parcelHandler) getParcels(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, struct {
 		ginutil.Response
-		Links   hypermedia.Links        `json:"links"`
-		Parcels []entity.CustomerParcel `json:"parcels"`
+		Links          hypermedia.Links        `json:"links"`
+		Parcels        []entity.CustomerParcel `json:"parcels"`
+		CashOnDelivery []entity.CodBalance     `json:"cashOnDelivery"`
 	}{
 		ginutil.Response{
 			"The parcels have successfuly beeen found.",
@@ -143,6 +127,7 @@ func (p *parcelHandler) getParcels(ctx *gin.Context) {
 		},
 		links,
 		parcels,
+		balances,
 	})
 
 }
@@ -172,7 +157,7 @@ func (p *parcelHandler) purchaseFailed(ctx *gin.Context) {
 func (ch *parcelHandler) GetByID(ctx *gin.Context) {
 	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
 	defer cancel()
-	connection, err := ch.service.GetConnectionByID(ctxWithTimeout, ctx.Param("id"), ctx.Param("width"), ctx.Param("height"), ctx.Param("length"))
+	connection, err := ch.service.GetConnectionByID(ctxWithTimeout, ctx.Param("id"), ctx.Param("width"), ctx.Param("height"), ctx.Param("length"), ctx.Query("weight"), ctx.Query("type"), ctx.Query("currency"))
 	if err != nil {
 		ginutil.ServiceErrorAbort(ctx, err)
 		return
@@ -188,3 +173,96 @@ func (ch *parcelHandler) GetByID(ctx *gin.Context) {
 		},
 	})
 }
+
+func (p *parcelHandler) track(ctx *gin.Context) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	tracking, err := p.service.Track(ctxWithTimeout, ctx.ClientIP(), ctx.Param("trackingNumber"), ctx.Query("code"), ctx.Query("phone"))
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		Tracking entity.ParcelTracking `json:"tracking"`
+		ginutil.Response
+	}{
+		tracking,
+		ginutil.Response{
+			Message: "The parcel has successfuly been found.",
+		},
+	})
+}
+
+func (p *parcelHandler) updateStatus(ctx *gin.Context) {
+	var status entity.ParcelStatusJSON
+	if err := ctx.ShouldBindJSON(&status); err != nil {
+		ginutil.HandlerProblemAbort(ctx, rfc7807.JSON(err.Error()))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	err := p.service.UpdateStatus(ctxWithTimeout, ctx.Param("id"), status)
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, ginutil.Response{
+		"The status of the parcel has successfuly been updated.",
+		hypermedia.Links{},
+	})
+}
+
+func (p *parcelHandler) getDeliveryProof(ctx *gin.Context) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	proof, err := p.service.GetDeliveryProof(ctxWithTimeout, ctx.Param("id"))
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	var links = hypermedia.Links{}
+	if proof.HasEvidence() {
+		links = append(links, hypermedia.Link{
+			"evidence", hypermedia.LinkData{
+				config.APIURL() + "/admin/parcels/" + proof.ParcelID.String() + "/delivery-proof/evidence",
+				"GET",
+			},
+		})
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		Proof entity.ParcelDeliveryProof `json:"proof"`
+		ginutil.Response
+	}{
+		proof,
+		ginutil.Response{
+			"The proof of delivery has successfuly been found.",
+			links,
+		},
+	})
+}
+
+func (p *parcelHandler) getDeliveryEvidence(ctx *gin.Context) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	proof, err := p.service.GetDeliveryProof(ctxWithTimeout, ctx.Param("id"))
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	if !proof.HasEvidence() {
+		ginutil.HandlerProblemAbort(ctx, rfc7807.New(http.StatusNotFound, "no-delivery-evidence", "No Delivery Evidence Error", "The parcel was delivered with the code, there is no signature or photo."))
+		return
+	}
+
+	ctx.File(proof.EvidencePath)
+}
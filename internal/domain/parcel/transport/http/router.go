@@ -3,6 +3,7 @@ package http
 import (
 	"maryan_api/internal/domain/parcel/repo"
 	"maryan_api/internal/domain/parcel/service"
+	"maryan_api/internal/infrastructure/clients/payment"
 	"maryan_api/pkg/auth"
 	ginutil "maryan_api/pkg/ginutils"
 	"net/http"
@@ -11,15 +12,23 @@ import (
 	"gorm.io/gorm"
 )
 
-func RegisterRoutes(db *gorm.DB, s *gin.Engine, client *http.Client) {
+func RegisterRoutes(db *gorm.DB, s *gin.Engine, client *http.Client, payments payment.PaymentProvider) {
+	adminRouter := ginutil.CreateAuthRouter("/admin", auth.Admin.SecretKey(), s)
 	customerRouter := ginutil.CreateAuthRouter("/customer", auth.Customer.SecretKey(), s)
+	guestRouter := ginutil.CreateAuthRouter("/guest", auth.Guest.SecretKey(), s)
 
-	customerHandler := newHandler(service.NewParcelService(repo.NewParcelRepo(db), client))
+	customerHandler := newHandler(service.NewParcelService(repo.NewParcelRepo(db), client, payments))
 
 	s.GET("/connection/available-parcel-dates/:from/:to/:year/:month/:width/:height/:length", customerHandler.findConnections)
 	customerRouter.POST("/connection/:id/purchase-parcel", customerHandler.purchase)
 	customerRouter.GET("/connection-parcel/:id/:width/:height/:length", customerHandler.GetByID)
 	customerRouter.GET("/parcels", customerHandler.getParcels)
+	guestRouter.POST("/connection/:id/purchase-parcel", customerHandler.purchase)
+	guestRouter.GET("/parcels", customerHandler.getParcels)
 	s.GET("/connection/purchase-parcel/failed/:id/:token", customerHandler.purchaseFailed)
 	s.GET("/connection/purchase-parcel/succeded/:id/:token", customerHandler.purchaseSucceded)
+	s.GET("/parcels/track/:trackingNumber", customerHandler.track)
+	adminRouter.POST("/parcels/:id/status", customerHandler.updateStatus)
+	adminRouter.GET("/parcels/:id/delivery-proof", customerHandler.getDeliveryProof)
+	adminRouter.GET("/parcels/:id/delivery-proof/evidence", customerHandler.getDeliveryEvidence)
 }
@@ -0,0 +1,120 @@
+package repo
+
+import (
+	"context"
+	"maryan_api/internal/entity"
+	dataStore "maryan_api/internal/infrastructure/persistence"
+
+	"gorm.io/gorm"
+)
+
+type Payment interface {
+	RegisterEvent(ctx context.Context, event *entity.StripeEvent) (bool, error)
+	ForgetEvent(ctx context.Context, id string) error
+	DefineProduct(ctx context.Context, paymentSessionID string) (entity.PaymentProduct, bool, error)
+	RegisterFailure(ctx context.Context, product entity.PaymentProduct, paymentSessionID, message string) error
+
+	TicketPaymentSucceeded(ctx context.Context, paymentSessionID string) (bool, error)
+	GetTicketsBySession(ctx context.Context, paymentSessionID string) ([]entity.Ticket, []entity.Connection, error)
+	DeleteTickets(ctx context.Context, paymentSessionID string) error
+
+	ParcelPaymentSucceeded(ctx context.Context, paymentSessionID string) error
+	RemoveParcelStops(ctx context.Context, paymentSessionID string) error
+	DeleteParcels(ctx context.Context, paymentSessionID string) error
+
+	TicketExchangePaymentSucceeded(ctx context.Context, paymentSessionID string) error
+	ExpireTicketExchange(ctx context.Context, paymentSessionID string) error
+
+	LuggageOrderPaymentSucceeded(ctx context.Context, paymentSessionID string) error
+	ExpireLuggageOrder(ctx context.Context, paymentSessionID string) error
+}
+
+type paymentRepo struct {
+	payment    dataStore.Payment
+	ticket     dataStore.Ticket
+	parcel     dataStore.Parsel
+	exchange   dataStore.TicketExchange
+	connection dataStore.Connection
+	luggage    dataStore.LuggageOrder
+}
+
+func (r *paymentRepo) RegisterEvent(ctx context.Context, event *entity.StripeEvent) (bool, error) {
+	return r.payment.RegisterEvent(ctx, event)
+}
+
+func (r *paymentRepo) ForgetEvent(ctx context.Context, id string) error {
+	return r.payment.ForgetEvent(ctx, id)
+}
+
+func (r *paymentRepo) DefineProduct(ctx context.Context, paymentSessionID string) (entity.PaymentProduct, bool, error) {
+	return r.payment.DefineProduct(ctx, paymentSessionID)
+}
+
+func (r *paymentRepo) RegisterFailure(ctx context.Context, product entity.PaymentProduct, paymentSessionID, message string) error {
+	return r.payment.RegisterFailure(ctx, product, paymentSessionID, message)
+}
+
+func (r *paymentRepo) TicketPaymentSucceeded(ctx context.Context, paymentSessionID string) (bool, error) {
+	return r.ticket.PaymentSucceeded(ctx, paymentSessionID)
+}
+
+func (r *paymentRepo) GetTicketsBySession(ctx context.Context, paymentSessionID string) ([]entity.Ticket, []entity.Connection, error) {
+	tickets, err := r.ticket.GetBySession(ctx, paymentSessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var connections = make([]entity.Connection, len(tickets))
+	for i, ticket := range tickets {
+		connections[i], _, err = r.connection.GetByID(ctx, ticket.ConnectionID, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return tickets, connections, nil
+}
+
+func (r *paymentRepo) DeleteTickets(ctx context.Context, paymentSessionID string) error {
+	return r.ticket.DeleteTickets(ctx, paymentSessionID)
+}
+
+func (r *paymentRepo) ParcelPaymentSucceeded(ctx context.Context, paymentSessionID string) error {
+	return r.parcel.PaymentSucceeded(ctx, paymentSessionID)
+}
+
+func (r *paymentRepo) RemoveParcelStops(ctx context.Context, paymentSessionID string) error {
+	return r.parcel.RemoveParcelStops(ctx, paymentSessionID)
+}
+
+func (r *paymentRepo) DeleteParcels(ctx context.Context, paymentSessionID string) error {
+	return r.parcel.DeleteParcels(ctx, paymentSessionID)
+}
+
+func (r *paymentRepo) TicketExchangePaymentSucceeded(ctx context.Context, paymentSessionID string) error {
+	return r.exchange.CompletePaid(ctx, paymentSessionID)
+}
+
+func (r *paymentRepo) ExpireTicketExchange(ctx context.Context, paymentSessionID string) error {
+	return r.exchange.Expire(ctx, paymentSessionID)
+}
+
+func (r *paymentRepo) LuggageOrderPaymentSucceeded(ctx context.Context, paymentSessionID string) error {
+	order, err := r.luggage.GetBySession(ctx, paymentSessionID)
+	if err != nil {
+		return err
+	}
+
+	return r.luggage.Complete(ctx, order.ID)
+}
+
+func (r *paymentRepo) ExpireLuggageOrder(ctx context.Context, paymentSessionID string) error {
+	return r.luggage.Expire(ctx, paymentSessionID)
+}
+
+func NewPaymentRepo(db *gorm.DB) Payment {
+	return &paymentRepo{
+		dataStore.NewPayment(db), dataStore.NewTicket(db), dataStore.NewParsel(db), dataStore.NewTicketExchange(db), dataStore.NewConnection(db),
+		dataStore.NewLuggageOrder(db),
+	}
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"maryan_api/internal/domain/payment/repo"
+	"maryan_api/internal/entity"
+	"maryan_api/internal/infrastructure/clients/email"
+	"maryan_api/internal/infrastructure/clients/payment"
+	"maryan_api/pkg/log"
+	rfc7807 "maryan_api/pkg/problem"
+	"strconv"
+	"time"
+)
+
+type Payment interface {
+	HandleWebhook(ctx context.Context, payload []byte, signature string) error
+}
+
+type serviceImpl struct {
+	repo     repo.Payment
+	payments payment.PaymentProvider
+	reporter log.Reporter
+}
+
+func (s *serviceImpl) HandleWebhook(ctx context.Context, payload []byte, signature string) error {
+	event, err := s.payments.VerifyWebhook(payload, signature)
+	if err != nil {
+		return rfc7807.BadRequest("webhook-signature", "Webhook Signature Error", err.Error())
+	}
+
+	firstDelivery, err := s.repo.RegisterEvent(ctx, &entity.StripeEvent{ID: event.ID, Type: event.Type})
+	if err != nil || !firstDelivery {
+		return err
+	}
+
+	err = s.handleEvent(ctx, event)
+	if err != nil {
+		s.repo.ForgetEvent(context.WithoutCancel(ctx), event.ID)
+		return err
+	}
+
+	return nil
+}
+
+func (s *serviceImpl) handleEvent(ctx context.Context, event payment.Event) error {
+	if event.SessionID == "" && event.PaymentIntentID != "" {
+		sessionID, err := s.payments.SessionByPaymentIntent(event.PaymentIntentID)
+		if err != nil {
+			return rfc7807.BadGateway("payment", "Payment Error", err.Error())
+		}
+		event.SessionID = sessionID
+	}
+
+	if event.SessionID == "" {
+		return nil
+	}
+
+	product, exists, err := s.repo.DefineProduct(ctx, event.SessionID)
+	if err != nil || !exists {
+		return err
+	}
+
+	switch event.Type {
+	case payment.EventSessionCompleted:
+		if !event.Paid {
+			return nil
+		}
+		return s.paymentSucceeded(ctx, product, event.SessionID)
+	case payment.EventSessionExpired:
+		return s.paymentExpired(ctx, product, event.SessionID)
+	case payment.EventPaymentFailed:
+		return s.repo.RegisterFailure(ctx, product, event.SessionID, event.FailureMessage)
+	default:
+		return nil
+	}
+}
+
+func (s *serviceImpl) paymentSucceeded(ctx context.Context, product entity.PaymentProduct, sessionID string) error {
+	switch product {
+	case entity.ParcelPaymentProduct:
+		return s.repo.ParcelPaymentSucceeded(ctx, sessionID)
+	case entity.TicketExchangePaymentProduct:
+		return s.repo.TicketExchangePaymentSucceeded(ctx, sessionID)
+	case entity.LuggageOrderPaymentProduct:
+		return s.repo.LuggageOrderPaymentSucceeded(ctx, sessionID)
+	default:
+		recorded, err := s.repo.TicketPaymentSucceeded(ctx, sessionID)
+		if err != nil || !recorded {
+			return err
+		}
+
+		go s.sendBoardingPass(context.WithoutCancel(ctx), sessionID)
+		return nil
+	}
+}
+
+// sendBoardingPass emails the PDF boarding passes of the session to the customer, a round trip
+// has one for every leg. A failure here must not fail the webhook, the passes can still be
+// downloaded from the profile.
+func (s *serviceImpl) sendBoardingPass(ctx context.Context, sessionID string) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*30)
+	defer cancel()
+
+	tickets, connections, err := s.repo.GetTicketsBySession(ctx, sessionID)
+	if err != nil {
+		s.reporter.Report("boarding-pass", fmt.Errorf("session %s: %w", sessionID, err))
+		return
+	}
+
+	var attachments = make([]email.Attachment, len(tickets))
+	for i, ticket := range tickets {
+		pdf, err := ticket.BoardingPass(connections[i], ticket.Language)
+		if err != nil {
+			s.reporter.Report("boarding-pass", fmt.Errorf("ticket %s: %w", ticket.ID, err))
+			return
+		}
+
+		attachments[i] = email.Attachment{
+			Name:        "boarding-pass.pdf",
+			ContentType: "application/pdf",
+			Data:        pdf,
+		}
+		if len(tickets) > 1 {
+			attachments[i].Name = "boarding-pass-" + strconv.Itoa(i+1) + ".pdf"
+		}
+	}
+
+	ticket := tickets[0]
+	subject, body := boardingPassEmail(ticket.Language)
+	err = email.Send(ticket.Email, subject, body, attachments...)
+	if err != nil {
+		s.reporter.Report("boarding-pass", fmt.Errorf("ticket %s: %w", ticket.ID, err))
+	}
+}
+
+func boardingPassEmail(lang string) (subject, body string) {
+	if lang == "uk" {
+		return "Ваш посадковий талон", "Дякуємо за покупку! Посадковий талон додано до цього листа."
+	}
+	return "Your boarding pass", "Thank you for your purchase! Your boarding pass is attached to this email."
+}
+
+func (s *serviceImpl) paymentExpired(ctx context.Context, product entity.PaymentProduct, sessionID string) error {
+	if product == entity.TicketExchangePaymentProduct {
+		return s.repo.ExpireTicketExchange(ctx, sessionID)
+	}
+
+	if product == entity.LuggageOrderPaymentProduct {
+		return s.repo.ExpireLuggageOrder(ctx, sessionID)
+	}
+
+	if product == entity.ParcelPaymentProduct {
+		err := s.repo.RemoveParcelStops(ctx, sessionID)
+		if err != nil {
+			return err
+		}
+
+		return s.repo.DeleteParcels(ctx, sessionID)
+	}
+
+	return s.repo.DeleteTickets(ctx, sessionID)
+}
+
+func NewPaymentService(repo repo.Payment, payments payment.PaymentProvider, reporter log.Reporter) Payment {
+	return &serviceImpl{
+		repo,
+		payments,
+		reporter,
+	}
+}
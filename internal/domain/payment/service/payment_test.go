@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"maryan_api/internal/entity"
+	"maryan_api/internal/infrastructure/clients/stripe"
+	rfc7807 "maryan_api/pkg/problem"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stripe/stripe-go/v76/webhook"
+)
+
+const testWebhookSecret = "whsec_test_secret"
+
+// paymentRepoStub records what the webhooks have done, the events are deduplicated by their ids
+// the way the stripe_events table does it.
+type paymentRepoStub struct {
+	product       entity.PaymentProduct
+	events        map[string]bool
+	forgotten     []string
+	ticketsPaid   []string
+	parcelsPaid   []string
+	deleted       []string
+	failures      []string
+	ticketsLoaded int
+}
+
+func newPaymentRepoStub(product entity.PaymentProduct) *paymentRepoStub {
+	return &paymentRepoStub{product: product, events: make(map[string]bool)}
+}
+
+func (r *paymentRepoStub) RegisterEvent(ctx context.Context, event *entity.StripeEvent) (bool, error) {
+	if r.events[event.ID] {
+		return false, nil
+	}
+	r.events[event.ID] = true
+	return true, nil
+}
+
+func (r *paymentRepoStub) ForgetEvent(ctx context.Context, id string) error {
+	delete(r.events, id)
+	r.forgotten = append(r.forgotten, id)
+	return nil
+}
+
+func (r *paymentRepoStub) DefineProduct(ctx context.Context, paymentSessionID string) (entity.PaymentProduct, bool, error) {
+	return r.product, true, nil
+}
+
+func (r *paymentRepoStub) RegisterFailure(ctx context.Context, product entity.PaymentProduct, paymentSessionID, message string) error {
+	r.failures = append(r.failures, paymentSessionID)
+	return nil
+}
+
+// TicketPaymentSucceeded records the payment only once, like the succeeded condition of the update does.
+func (r *paymentRepoStub) TicketPaymentSucceeded(ctx context.Context, paymentSessionID string) (bool, error) {
+	r.ticketsPaid = append(r.ticketsPaid, paymentSessionID)
+	return len(r.ticketsPaid) == 1, nil
+}
+
+func (r *paymentRepoStub) GetTicketsBySession(ctx context.Context, paymentSessionID string) ([]entity.Ticket, []entity.Connection, error) {
+	r.ticketsLoaded++
+	return nil, nil, errors.New("no tickets in the stub")
+}
+
+func (r *paymentRepoStub) DeleteTickets(ctx context.Context, paymentSessionID string) error {
+	r.deleted = append(r.deleted, paymentSessionID)
+	return nil
+}
+
+func (r *paymentRepoStub) ParcelPaymentSucceeded(ctx context.Context, paymentSessionID string) error {
+	r.parcelsPaid = append(r.parcelsPaid, paymentSessionID)
+	return nil
+}
+
+func (r *paymentRepoStub) RemoveParcelStops(ctx context.Context, paymentSessionID string) error {
+	return nil
+}
+
+func (r *paymentRepoStub) DeleteParcels(ctx context.Context, paymentSessionID string) error {
+	return nil
+}
+
+func (r *paymentRepoStub) TicketExchangePaymentSucceeded(ctx context.Context, paymentSessionID string) error {
+	return nil
+}
+
+func (r *paymentRepoStub) ExpireTicketExchange(ctx context.Context, paymentSessionID string) error {
+	return nil
+}
+
+func (r *paymentRepoStub) LuggageOrderPaymentSucceeded(ctx context.Context, paymentSessionID string) error {
+	return nil
+}
+
+func (r *paymentRepoStub) ExpireLuggageOrder(ctx context.Context, paymentSessionID string) error {
+	return nil
+}
+
+// offlineStripe verifies the webhooks like Stripe does, the session lookup never reaches the network.
+type offlineStripe struct {
+	stripe.Provider
+	lookupErr error
+}
+
+func (p offlineStripe) SessionByPaymentIntent(paymentIntentID string) (string, error) {
+	if p.lookupErr != nil {
+		return "", p.lookupErr
+	}
+	return "cs_test_failed", nil
+}
+
+// reporterStub keeps the reported errors instead of logging them to the database.
+type reporterStub struct {
+	reported []error
+}
+
+func (r *reporterStub) Report(route string, err error) {
+	r.reported = append(r.reported, err)
+}
+
+func setupEnv(t *testing.T, webhookSecret string) {
+	t.Setenv("STRIPE_WEBHOOK_SECRET", webhookSecret)
+	t.Setenv("API_URL", "http://localhost:8080")
+}
+
+func replay(t *testing.T, s Payment, fixture string) error {
+	t.Helper()
+
+	payload, err := os.ReadFile(filepath.Join("..", "..", "..", "infrastructure", "clients", "stripe", "testdata", fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed := webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{Payload: payload, Secret: testWebhookSecret})
+	return s.HandleWebhook(context.Background(), signed.Payload, signed.Header)
+}
+
+func TestHandleWebhookReplayedCompletionIsHandledOnce(t *testing.T) {
+	setupEnv(t, testWebhookSecret)
+
+	repo := newPaymentRepoStub(entity.ParcelPaymentProduct)
+	s := NewPaymentService(repo, offlineStripe{}, &reporterStub{})
+
+	for range 2 {
+		err := replay(t, s, "checkout_session_completed.json")
+		if err != nil {
+			t.Fatalf("HandleWebhook() error = %v", err)
+		}
+	}
+
+	if len(repo.parcelsPaid) != 1 {
+		t.Errorf("the payment has been recorded %d times, want 1", len(repo.parcelsPaid))
+	}
+}
+
+func TestHandleWebhookRecordedTicketPaymentIsNoOp(t *testing.T) {
+	setupEnv(t, testWebhookSecret)
+
+	repo := newPaymentRepoStub(entity.TicketPaymentProduct)
+	repo.ticketsPaid = []string{"recorded by an earlier event"}
+	s := NewPaymentService(repo, offlineStripe{}, &reporterStub{})
+
+	err := replay(t, s, "checkout_session_completed.json")
+	if err != nil {
+		t.Fatalf("HandleWebhook() error = %v", err)
+	}
+
+	if repo.ticketsLoaded != 0 {
+		t.Error("the boarding pass has been sent again for an already recorded payment")
+	}
+}
+
+func TestHandleWebhookExpiredSessionDeletesTickets(t *testing.T) {
+	setupEnv(t, testWebhookSecret)
+
+	repo := newPaymentRepoStub(entity.TicketPaymentProduct)
+	s := NewPaymentService(repo, offlineStripe{}, &reporterStub{})
+
+	err := replay(t, s, "checkout_session_expired.json")
+	if err != nil {
+		t.Fatalf("HandleWebhook() error = %v", err)
+	}
+
+	want := "cs_test_b2DsxtrXlSxyR4SHb8ZQsOfszR4arFFPShXnzGuh9XOY2yQAjHR2CipRuy"
+	if len(repo.deleted) != 1 || repo.deleted[0] != want {
+		t.Errorf("deleted the tickets of %v, want [%s]", repo.deleted, want)
+	}
+}
+
+func TestHandleWebhookFailedPaymentLooksUpSession(t *testing.T) {
+	setupEnv(t, testWebhookSecret)
+
+	repo := newPaymentRepoStub(entity.TicketPaymentProduct)
+	s := NewPaymentService(repo, offlineStripe{}, &reporterStub{})
+
+	err := replay(t, s, "payment_intent_payment_failed.json")
+	if err != nil {
+		t.Fatalf("HandleWebhook() error = %v", err)
+	}
+
+	if len(repo.failures) != 1 || repo.failures[0] != "cs_test_failed" {
+		t.Errorf("registered the failures of %v, want [cs_test_failed]", repo.failures)
+	}
+}
+
+func TestHandleWebhookLookupErrorIsNotBadRequest(t *testing.T) {
+	setupEnv(t, testWebhookSecret)
+
+	repo := newPaymentRepoStub(entity.TicketPaymentProduct)
+	s := NewPaymentService(repo, offlineStripe{lookupErr: errors.New("connection reset by peer")}, &reporterStub{})
+
+	err := replay(t, s, "payment_intent_payment_failed.json")
+
+	problem, ok := rfc7807.Is(err)
+	if !ok || problem.Status != http.StatusBadGateway {
+		t.Fatalf("HandleWebhook() error = %v, want a %d problem", err, http.StatusBadGateway)
+	}
+
+	// The event is forgotten, so the retried delivery is handled again.
+	if len(repo.forgotten) != 1 {
+		t.Errorf("forgot %d events, want 1", len(repo.forgotten))
+	}
+}
+
+func TestHandleWebhookForgedSignatureIsBadRequest(t *testing.T) {
+	setupEnv(t, "whsec_another_secret")
+
+	repo := newPaymentRepoStub(entity.TicketPaymentProduct)
+	s := NewPaymentService(repo, offlineStripe{}, &reporterStub{})
+
+	err := replay(t, s, "checkout_session_completed.json")
+
+	problem, ok := rfc7807.Is(err)
+	if !ok || problem.Status != http.StatusBadRequest {
+		t.Fatalf("HandleWebhook() error = %v, want a %d problem", err, http.StatusBadRequest)
+	}
+
+	if len(repo.events) != 0 {
+		t.Error("the forged event has been registered")
+	}
+}
+
+func TestSendBoardingPassReportsFailure(t *testing.T) {
+	setupEnv(t, testWebhookSecret)
+
+	reporter := &reporterStub{}
+	s := NewPaymentService(newPaymentRepoStub(entity.TicketPaymentProduct), offlineStripe{}, reporter)
+
+	s.(*serviceImpl).sendBoardingPass(context.Background(), "cs_test_paid")
+
+	if len(reporter.reported) != 1 {
+		t.Errorf("reported %d errors, want 1", len(reporter.reported))
+	}
+}
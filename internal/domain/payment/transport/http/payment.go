@@ -0,0 +1,42 @@
+package http
+
+import (
+	"context"
+	"maryan_api/internal/domain/payment/service"
+	ginutil "maryan_api/pkg/ginutils"
+	rfc7807 "maryan_api/pkg/problem"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type paymentHandler struct {
+	service         service.Payment
+	signatureHeader string
+}
+
+func newHandler(service service.Payment, signatureHeader string) *paymentHandler {
+	return &paymentHandler{service, signatureHeader}
+}
+
+func (p *paymentHandler) webhook(ctx *gin.Context) {
+	payload, err := ctx.GetRawData()
+	if err != nil {
+		ginutil.HandlerProblemAbort(ctx, rfc7807.JSON(err.Error()))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	err = p.service.HandleWebhook(ctxWithTimeout, payload, ctx.GetHeader(p.signatureHeader))
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, ginutil.Response{
+		Message: "The event has successfuly been processed.",
+	})
+}
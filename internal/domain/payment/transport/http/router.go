@@ -0,0 +1,20 @@
+package http
+
+import (
+	"maryan_api/internal/domain/payment/repo"
+	"maryan_api/internal/domain/payment/service"
+	"maryan_api/internal/infrastructure/clients/payment"
+	"maryan_api/pkg/log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func RegisterRoutes(db *gorm.DB, s *gin.Engine, client *http.Client, payments payment.PaymentProvider) {
+	handler := newHandler(service.NewPaymentService(repo.NewPaymentRepo(db), payments, log.NewReporter(db)), payments.SignatureHeader())
+
+	//-----------------------Payment Routes---------------------------------------
+
+	s.POST("/payment/"+payments.Name()+"/webhook", handler.webhook)
+}
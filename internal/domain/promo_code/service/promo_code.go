@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"maryan_api/internal/domain/promo_code/repo"
+	"maryan_api/internal/entity"
+	"maryan_api/pkg/dbutil"
+	"maryan_api/pkg/hypermedia"
+	rfc7807 "maryan_api/pkg/problem"
+
+	"github.com/d3code/uuid"
+)
+
+type PromoCode interface {
+	Create(ctx context.Context, promoCodeJSON entity.PromoCodeJSON) (entity.PromoCode, error)
+	Update(ctx context.Context, idStr string, promoCodeJSON entity.PromoCodeJSON) (entity.PromoCode, error)
+	Delete(ctx context.Context, idStr string) error
+	GetByID(ctx context.Context, idStr string) (entity.PromoCode, error)
+	GetPromoCodes(ctx context.Context, paginationStr dbutil.PaginationStr) ([]entity.PromoCode, hypermedia.Links, error)
+}
+
+type serviceImpl struct {
+	repo repo.PromoCode
+}
+
+func (s *serviceImpl) Create(ctx context.Context, promoCodeJSON entity.PromoCodeJSON) (entity.PromoCode, error) {
+	promoCode, err := promoCodeJSON.Parse()
+	if err != nil {
+		return entity.PromoCode{}, err
+	}
+
+	promoCode.ID = uuid.New()
+	return promoCode, s.repo.Create(ctx, &promoCode)
+}
+
+func (s *serviceImpl) Update(ctx context.Context, idStr string, promoCodeJSON entity.PromoCodeJSON) (entity.PromoCode, error) {
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return entity.PromoCode{}, rfc7807.BadRequest("invalid-id", "Invalid ID Error", err.Error())
+	}
+
+	promoCode, err := promoCodeJSON.Parse()
+	if err != nil {
+		return entity.PromoCode{}, err
+	}
+
+	promoCode.ID = id
+	err = s.repo.Update(ctx, &promoCode)
+	if err != nil {
+		return entity.PromoCode{}, err
+	}
+
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *serviceImpl) Delete(ctx context.Context, idStr string) error {
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return rfc7807.BadRequest("invalid-id", "Invalid ID Error", err.Error())
+	}
+
+	return s.repo.Delete(ctx, id)
+}
+
+func (s *serviceImpl) GetByID(ctx context.Context, idStr string) (entity.PromoCode, error) {
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return entity.PromoCode{}, rfc7807.BadRequest("invalid-id", "Invalid ID Error", err.Error())
+	}
+
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *serviceImpl) GetPromoCodes(ctx context.Context, paginationStr dbutil.PaginationStr) ([]entity.PromoCode, hypermedia.Links, error) {
+	pagination, err := paginationStr.Parse([]string{"code"}, "created_at", "valid_from", "valid_until")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	promoCodes, total, err, empty := s.repo.GetPromoCodes(ctx, pagination)
+	if err != nil || empty {
+		return nil, nil, err
+	}
+
+	return promoCodes, hypermedia.Pagination(paginationStr, total), nil
+}
+
+func NewPromoCodeService(repo repo.PromoCode) PromoCode {
+	return &serviceImpl{
+		repo,
+	}
+}
@@ -0,0 +1,150 @@
+package http
+
+import (
+	"context"
+	"maryan_api/internal/domain/promo_code/service"
+	"maryan_api/internal/entity"
+	"maryan_api/pkg/dbutil"
+	ginutil "maryan_api/pkg/ginutils"
+	"maryan_api/pkg/hypermedia"
+	rfc7807 "maryan_api/pkg/problem"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type promoCodeHandler struct {
+	service service.PromoCode
+}
+
+func newPromoCodeHandler(service service.PromoCode) *promoCodeHandler {
+	return &promoCodeHandler{service}
+}
+
+func (p *promoCodeHandler) create(ctx *gin.Context) {
+	var request entity.PromoCodeJSON
+
+	err := ctx.ShouldBindJSON(&request)
+	if err != nil {
+		ginutil.HandlerProblemAbort(ctx, rfc7807.JSON(err.Error()))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	promoCode, err := p.service.Create(ctxWithTimeout, request)
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, struct {
+		ginutil.Response
+		PromoCode entity.PromoCode `json:"promoCode"`
+	}{
+		ginutil.Response{
+			"The promo code has successfuly been created.",
+			hypermedia.Links{},
+		},
+		promoCode,
+	})
+}
+
+func (p *promoCodeHandler) update(ctx *gin.Context) {
+	var request entity.PromoCodeJSON
+
+	err := ctx.ShouldBindJSON(&request)
+	if err != nil {
+		ginutil.HandlerProblemAbort(ctx, rfc7807.JSON(err.Error()))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	promoCode, err := p.service.Update(ctxWithTimeout, ctx.Param("id"), request)
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		ginutil.Response
+		PromoCode entity.PromoCode `json:"promoCode"`
+	}{
+		ginutil.Response{
+			"The promo code has successfuly been updated.",
+			hypermedia.Links{},
+		},
+		promoCode,
+	})
+}
+
+func (p *promoCodeHandler) delete(ctx *gin.Context) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	err := p.service.Delete(ctxWithTimeout, ctx.Param("id"))
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, ginutil.Response{
+		"The promo code has successfuly been deleted.",
+		hypermedia.Links{},
+	})
+}
+
+func (p *promoCodeHandler) getByID(ctx *gin.Context) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	promoCode, err := p.service.GetByID(ctxWithTimeout, ctx.Param("id"))
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		ginutil.Response
+		PromoCode entity.PromoCode `json:"promoCode"`
+	}{
+		ginutil.Response{
+			"The promo code has successfuly been found.",
+			hypermedia.Links{},
+		},
+		promoCode,
+	})
+}
+
+func (p *promoCodeHandler) getPromoCodes(ctx *gin.Context) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	promoCodes, links, err := p.service.GetPromoCodes(ctxWithTimeout, dbutil.PaginationStr{
+		"/admin/promo-codes",
+		ctx.DefaultQuery("page", "1"),
+		ctx.DefaultQuery("size", "20"),
+		ctx.DefaultQuery("order_by", "created_at"),
+		ctx.DefaultQuery("order_way", "desc"),
+		ctx.DefaultQuery("search", ""),
+	})
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		ginutil.Response
+		PromoCodes []entity.PromoCode `json:"promoCodes"`
+	}{
+		ginutil.Response{
+			"The promo codes have successfuly been found.",
+			links,
+		},
+		promoCodes,
+	})
+}
@@ -0,0 +1,26 @@
+package http
+
+import (
+	"maryan_api/internal/domain/promo_code/repo"
+	"maryan_api/internal/domain/promo_code/service"
+	"maryan_api/pkg/auth"
+	ginutil "maryan_api/pkg/ginutils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func RegisterRoutes(db *gorm.DB, s *gin.Engine, client *http.Client) {
+	adminRouter := ginutil.CreateAuthRouter("/admin", auth.Admin.SecretKey(), s)
+
+	adminHandler := newPromoCodeHandler(service.NewPromoCodeService(repo.NewPromoCodeRepo(db)))
+
+	//-----------------------Promo Code Routes---------------------------------------
+
+	adminRouter.GET("/promo-codes", adminHandler.getPromoCodes)
+	adminRouter.GET("/promo-codes/:id", adminHandler.getByID)
+	adminRouter.POST("/promo-codes", adminHandler.create)
+	adminRouter.PUT("/promo-codes/:id", adminHandler.update)
+	adminRouter.DELETE("/promo-codes/:id", adminHandler.delete)
+}
@@ -0,0 +1,47 @@
+package repo
+
+import (
+	"context"
+	"maryan_api/internal/entity"
+	dataStore "maryan_api/internal/infrastructure/persistence"
+	"maryan_api/pkg/dbutil"
+
+	"github.com/d3code/uuid"
+	"gorm.io/gorm"
+)
+
+type Refaund interface {
+	GetByID(ctx context.Context, id uuid.UUID) (entity.Refaund, error)
+	GetRefaunds(ctx context.Context, pagination dbutil.Pagination) ([]entity.Refaund, int, error, bool)
+	ChangeStatus(ctx context.Context, id uuid.UUID, from []entity.RefaundStatus, to entity.RefaundStatus) error
+	Complete(ctx context.Context, id uuid.UUID, providerRefundID string) error
+	Fail(ctx context.Context, id uuid.UUID, message string) error
+}
+
+type refaundRepo struct {
+	ds dataStore.Refaund
+}
+
+func (r *refaundRepo) GetByID(ctx context.Context, id uuid.UUID) (entity.Refaund, error) {
+	return r.ds.GetByID(ctx, id)
+}
+
+func (r *refaundRepo) GetRefaunds(ctx context.Context, pagination dbutil.Pagination) ([]entity.Refaund, int, error, bool) {
+	return r.ds.GetRefaunds(ctx, pagination)
+}
+
+func (r *refaundRepo) ChangeStatus(ctx context.Context, id uuid.UUID, from []entity.RefaundStatus, to entity.RefaundStatus) error {
+	return r.ds.ChangeStatus(ctx, id, from, to)
+}
+
+func (r *refaundRepo) Complete(ctx context.Context, id uuid.UUID, providerRefundID string) error {
+	return r.ds.Complete(ctx, id, providerRefundID)
+}
+
+func (r *refaundRepo) Fail(ctx context.Context, id uuid.UUID, message string) error {
+	return r.ds.Fail(ctx, id, message)
+}
+
+func NewRefaundRepo(db *gorm.DB) Refaund {
+	return &refaundRepo{dataStore.NewRefaund(db)}
+}
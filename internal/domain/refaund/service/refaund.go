@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"maryan_api/internal/domain/refaund/repo"
+	"maryan_api/internal/entity"
+	"maryan_api/internal/infrastructure/clients/payment"
+	"maryan_api/pkg/dbutil"
+	"maryan_api/pkg/hypermedia"
+	rfc7807 "maryan_api/pkg/problem"
+
+	"github.com/d3code/uuid"
+)
+
+type Refaund interface {
+	GetRefaunds(ctx context.Context, paginationStr dbutil.PaginationStr, status string) ([]entity.Refaund, hypermedia.Links, error)
+	Approve(ctx context.Context, idStr string) (entity.Refaund, error)
+	Reject(ctx context.Context, idStr string) error
+}
+
+type serviceImpl struct {
+	repo     repo.Refaund
+	payments payment.PaymentProvider
+}
+
+func (s *serviceImpl) GetRefaunds(ctx context.Context, paginationStr dbutil.PaginationStr, status string) ([]entity.Refaund, hypermedia.Links, error) {
+	var pagination dbutil.Pagination
+	var err error
+
+	if status == "" {
+		pagination, err = paginationStr.Parse([]string{}, "created_at", "amount")
+	} else {
+		refaundStatus, ok := entity.DefineRefaundStatus(status)
+		if !ok {
+			return nil, nil, rfc7807.BadRequest("invalid-refaund-status", "Invalid Refaund Status Error", "Refaund status provided is not valid.")
+		}
+
+		pagination, err = paginationStr.ParseWithCondition(dbutil.Condition{"status = ?", []any{refaundStatus}}, []string{}, "created_at", "amount")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refaunds, total, err, empty := s.repo.GetRefaunds(ctx, pagination)
+	if err != nil || empty {
+		return nil, nil, err
+	}
+
+	return refaunds, hypermedia.Pagination(paginationStr, total, hypermedia.DefaultParam{
+		Name:    "status",
+		Default: "",
+		Value:   status,
+	}), nil
+}
+
+// Approve issues the refund through the payment provider. A refaund that has failed on the provider side
+// can be approved again. A refaund returned at the office is approved once the cash has been handed back.
+func (s *serviceImpl) Approve(ctx context.Context, idStr string) (entity.Refaund, error) {
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return entity.Refaund{}, rfc7807.BadRequest("invalid-id", "Invalid ID Error", err.Error())
+	}
+
+	refaund, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return entity.Refaund{}, err
+	}
+
+	err = s.repo.ChangeStatus(ctx, id, []entity.RefaundStatus{entity.PendingRefaundStatus, entity.FailedRefaundStatus}, entity.ApprovedRefaundStatus)
+	if err != nil {
+		return entity.Refaund{}, err
+	}
+
+	var providerRefundID string
+	if refaund.Amount > 0 && !refaund.AtOffice() {
+		providerRefundID, err = s.payments.Refund(refaund.PaymentSessionID(), int64(refaund.Amount), refaund.ID.String())
+		if err != nil {
+			s.repo.Fail(context.WithoutCancel(ctx), id, err.Error())
+			return entity.Refaund{}, rfc7807.BadGateway("refund", "Refund Error", err.Error())
+		}
+	}
+
+	err = s.repo.Complete(context.WithoutCancel(ctx), id, providerRefundID)
+	if err != nil {
+		return entity.Refaund{}, err
+	}
+
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *serviceImpl) Reject(ctx context.Context, idStr string) error {
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return rfc7807.BadRequest("invalid-id", "Invalid ID Error", err.Error())
+	}
+
+	return s.repo.ChangeStatus(ctx, id, []entity.RefaundStatus{entity.PendingRefaundStatus, entity.FailedRefaundStatus}, entity.RejectedRefaundStatus)
+}
+
+func NewRefaundService(repo repo.Refaund, payments payment.PaymentProvider) Refaund {
+	return &serviceImpl{
+		repo,
+		payments,
+	}
+}
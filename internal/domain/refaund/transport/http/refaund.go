@@ -0,0 +1,89 @@
+package http
+
+import (
+	"context"
+	"maryan_api/internal/domain/refaund/service"
+	"maryan_api/internal/entity"
+	"maryan_api/pkg/dbutil"
+	ginutil "maryan_api/pkg/ginutils"
+	"maryan_api/pkg/hypermedia"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type refaundHandler struct {
+	service service.Refaund
+}
+
+func newRefaundHandler(service service.Refaund) *refaundHandler {
+	return &refaundHandler{service}
+}
+
+func (r *refaundHandler) getRefaunds(ctx *gin.Context) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	refaunds, links, err := r.service.GetRefaunds(ctxWithTimeout, dbutil.PaginationStr{
+		"/admin/refunds",
+		ctx.DefaultQuery("page", "1"),
+		ctx.DefaultQuery("size", "20"),
+		ctx.DefaultQuery("order_by", "created_at"),
+		ctx.DefaultQuery("order_way", "desc"),
+		"",
+	}, ctx.DefaultQuery("status", ""))
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		ginutil.Response
+		Refaunds []entity.Refaund `json:"refaunds"`
+	}{
+		ginutil.Response{
+			"The refaunds have successfuly been found.",
+			links,
+		},
+		refaunds,
+	})
+}
+
+func (r *refaundHandler) approve(ctx *gin.Context) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*20)
+	defer cancel()
+
+	refaund, err := r.service.Approve(ctxWithTimeout, ctx.Param("id"))
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		ginutil.Response
+		Refaund entity.Refaund `json:"refaund"`
+	}{
+		ginutil.Response{
+			"The refaund has successfuly been completed.",
+			hypermedia.Links{},
+		},
+		refaund,
+	})
+}
+
+func (r *refaundHandler) reject(ctx *gin.Context) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	err := r.service.Reject(ctxWithTimeout, ctx.Param("id"))
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, ginutil.Response{
+		"The refaund has successfuly been rejected.",
+		hypermedia.Links{},
+	})
+}
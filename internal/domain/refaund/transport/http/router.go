@@ -0,0 +1,25 @@
+package http
+
+import (
+	"maryan_api/internal/domain/refaund/repo"
+	"maryan_api/internal/domain/refaund/service"
+	"maryan_api/internal/infrastructure/clients/payment"
+	"maryan_api/pkg/auth"
+	ginutil "maryan_api/pkg/ginutils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func RegisterRoutes(db *gorm.DB, s *gin.Engine, client *http.Client, payments payment.PaymentProvider) {
+	adminRouter := ginutil.CreateAuthRouter("/admin", auth.Admin.SecretKey(), s)
+
+	adminHandler := newRefaundHandler(service.NewRefaundService(repo.NewRefaundRepo(db), payments))
+
+	//-----------------------Refaund Routes---------------------------------------
+
+	adminRouter.GET("/refunds", adminHandler.getRefaunds)
+	adminRouter.POST("/refunds/:id/approve", adminHandler.approve)
+	adminRouter.POST("/refunds/:id/reject", adminHandler.reject)
+}
@@ -0,0 +1,49 @@
+package repo
+
+import (
+	"context"
+	"maryan_api/internal/entity"
+	dataStore "maryan_api/internal/infrastructure/persistence"
+	"time"
+
+	"github.com/d3code/uuid"
+	"gorm.io/gorm"
+)
+
+type Sweeper interface {
+	GetAbandonedTickets(ctx context.Context, createdBefore time.Time) ([]entity.AbandonedBooking, error)
+	AbandonTicket(ctx context.Context, id uuid.UUID) error
+	GetAbandonedParcels(ctx context.Context, createdBefore time.Time) ([]entity.AbandonedBooking, error)
+	AbandonParcel(ctx context.Context, id uuid.UUID) error
+	DeleteExpiredPriceQuotes(ctx context.Context, expiredBefore time.Time) (int, error)
+}
+
+type sweeperRepo struct {
+	ticket  dataStore.Ticket
+	parcel  dataStore.Parsel
+	pricing dataStore.Pricing
+}
+
+func (r *sweeperRepo) GetAbandonedTickets(ctx context.Context, createdBefore time.Time) ([]entity.AbandonedBooking, error) {
+	return r.ticket.GetAbandoned(ctx, createdBefore)
+}
+
+func (r *sweeperRepo) AbandonTicket(ctx context.Context, id uuid.UUID) error {
+	return r.ticket.Abandon(ctx, id)
+}
+
+func (r *sweeperRepo) GetAbandonedParcels(ctx context.Context, createdBefore time.Time) ([]entity.AbandonedBooking, error) {
+	return r.parcel.GetAbandoned(ctx, createdBefore)
+}
+
+func (r *sweeperRepo) AbandonParcel(ctx context.Context, id uuid.UUID) error {
+	return r.parcel.Abandon(ctx, id)
+}
+
+func (r *sweeperRepo) DeleteExpiredPriceQuotes(ctx context.Context, expiredBefore time.Time) (int, error) {
+	return r.pricing.DeleteExpiredQuotes(ctx, expiredBefore)
+}
+
+func NewSweeperRepo(db *gorm.DB) Sweeper {
+	return &sweeperRepo{dataStore.NewTicket(db), dataStore.NewParsel(db), dataStore.NewPricing(db)}
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"maryan_api/config"
+	"maryan_api/internal/domain/sweeper/repo"
+	"maryan_api/internal/entity"
+	"maryan_api/internal/infrastructure/clients/payment"
+	"maryan_api/pkg/log"
+	"time"
+
+	"github.com/d3code/uuid"
+)
+
+type Sweeper interface {
+	Sweep(ctx context.Context) (entity.SweepReport, error)
+	Run(ctx context.Context, interval time.Duration)
+}
+
+type serviceImpl struct {
+	repo     repo.Sweeper
+	payments payment.PaymentProvider
+	reporter log.Reporter
+}
+
+// Sweep removes the tickets and parcels left unpaid after their checkout session, so they stop
+// holding the seats and the luggage volume of the connection, and the expired price quotes.
+func (s *serviceImpl) Sweep(ctx context.Context) (entity.SweepReport, error) {
+	report := entity.NewSweepReport(time.Now().UTC())
+	createdBefore := report.SweptAt.Add(-config.SeatHoldDuration)
+
+	tickets, err := s.repo.GetAbandonedTickets(ctx, createdBefore)
+	if err != nil {
+		return entity.SweepReport{}, err
+	}
+
+	for _, ticket := range tickets {
+		if s.sweep(ctx, &report, ticket, s.repo.AbandonTicket) {
+			report.Tickets = append(report.Tickets, ticket.ID)
+			report.ReleasedSeats += ticket.Seats
+		}
+	}
+
+	parcels, err := s.repo.GetAbandonedParcels(ctx, createdBefore)
+	if err != nil {
+		return report, err
+	}
+
+	for _, parcel := range parcels {
+		if s.sweep(ctx, &report, parcel, s.repo.AbandonParcel) {
+			report.Parcels = append(report.Parcels, parcel.ID)
+		}
+	}
+
+	report.ExpiredQuotes, err = s.repo.DeleteExpiredPriceQuotes(ctx, report.SweptAt)
+	return report, err
+}
+
+// sweep expires the session of the booking and abandons it. A session that can not be expired
+// is swept only when it has not been paid, a paid one is left to the webhook.
+func (s *serviceImpl) sweep(ctx context.Context, report *entity.SweepReport, booking entity.AbandonedBooking, abandon func(ctx context.Context, id uuid.UUID) error) bool {
+	err := s.payments.CancelSession(booking.SessionID)
+	if err != nil {
+		paid, err := s.payments.SessionPaid(booking.SessionID)
+		if err != nil {
+			report.Skip(booking, err.Error())
+			return false
+		}
+
+		if paid {
+			report.Skip(booking, "The session has been paid, the booking is left to the webhook.")
+			return false
+		}
+	}
+
+	err = abandon(ctx, booking.ID)
+	if err != nil {
+		report.Skip(booking, err.Error())
+		return false
+	}
+
+	return true
+}
+
+// Run sweeps the abandoned bookings every interval until the context is done. Nobody waits for
+// the result, so the failed sweeps and the bookings skipped by them are reported.
+func (s *serviceImpl) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sweepCtx, cancel := context.WithTimeout(ctx, interval)
+			report, err := s.Sweep(sweepCtx)
+			cancel()
+
+			if err != nil {
+				s.reporter.Report("bookings-sweep", err)
+			}
+
+			for _, skipped := range report.Skipped {
+				s.reporter.Report("bookings-sweep", fmt.Errorf("booking %s of session %s: %s", skipped.ID, skipped.SessionID, skipped.Reason))
+			}
+		}
+	}
+}
+
+func NewSweeperService(repo repo.Sweeper, payments payment.PaymentProvider, reporter log.Reporter) Sweeper {
+	return &serviceImpl{
+		repo,
+		payments,
+		reporter,
+	}
+}
@@ -0,0 +1,31 @@
+package http
+
+import (
+	"context"
+	"maryan_api/config"
+	"maryan_api/internal/domain/sweeper/repo"
+	"maryan_api/internal/domain/sweeper/service"
+	"maryan_api/internal/infrastructure/clients/payment"
+	"maryan_api/pkg/auth"
+	ginutil "maryan_api/pkg/ginutils"
+	"maryan_api/pkg/log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func RegisterRoutes(db *gorm.DB, s *gin.Engine, client *http.Client, payments payment.PaymentProvider) {
+	adminRouter := ginutil.CreateAuthRouter("/admin", auth.Admin.SecretKey(), s)
+
+	handler := newSweeperHandler(service.NewSweeperService(repo.NewSweeperRepo(db), payments, log.NewReporter(db)))
+
+	//-----------------------Sweeper Routes---------------------------------------
+
+	adminRouter.POST("/bookings/sweep", handler.sweep)
+}
+
+// Run sweeps the abandoned bookings until the context is done.
+func Run(ctx context.Context, db *gorm.DB, payments payment.PaymentProvider) {
+	service.NewSweeperService(repo.NewSweeperRepo(db), payments, log.NewReporter(db)).Run(ctx, config.AbandonedBookingSweepInterval)
+}
@@ -0,0 +1,43 @@
+package http
+
+import (
+	"context"
+	"maryan_api/internal/domain/sweeper/service"
+	"maryan_api/internal/entity"
+	ginutil "maryan_api/pkg/ginutils"
+	"maryan_api/pkg/hypermedia"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type sweeperHandler struct {
+	service service.Sweeper
+}
+
+func newSweeperHandler(service service.Sweeper) *sweeperHandler {
+	return &sweeperHandler{service}
+}
+
+func (s *sweeperHandler) sweep(ctx *gin.Context) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*60)
+	defer cancel()
+
+	report, err := s.service.Sweep(ctxWithTimeout)
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		ginutil.Response
+		Report entity.SweepReport `json:"report"`
+	}{
+		ginutil.Response{
+			"The abandoned bookings have successfuly been swept.",
+			hypermedia.Links{},
+		},
+		report,
+	})
+}
@@ -0,0 +1,72 @@
+package repo
+
+import (
+	"context"
+	"maryan_api/internal/entity"
+	dataStore "maryan_api/internal/infrastructure/persistence"
+	"maryan_api/pkg/dbutil"
+
+	"github.com/d3code/uuid"
+	"gorm.io/gorm"
+)
+
+type Tariff interface {
+	CreateParcelTariff(ctx context.Context, tariff *entity.ParcelTariff) error
+	UpdateParcelTariff(ctx context.Context, tariff *entity.ParcelTariff) error
+	DeleteParcelTariff(ctx context.Context, id uuid.UUID) error
+	GetParcelTariff(ctx context.Context, id uuid.UUID) (entity.ParcelTariff, error)
+	GetParcelTariffs(ctx context.Context, pagination dbutil.Pagination) ([]entity.ParcelTariff, int, error, bool)
+	CreateLuggageTariff(ctx context.Context, tariff *entity.LuggageTariff) error
+	UpdateLuggageTariff(ctx context.Context, tariff *entity.LuggageTariff) error
+	DeleteLuggageTariff(ctx context.Context, id uuid.UUID) error
+	GetLuggageTariff(ctx context.Context, id uuid.UUID) (entity.LuggageTariff, error)
+	GetLuggageTariffs(ctx context.Context, pagination dbutil.Pagination) ([]entity.LuggageTariff, int, error, bool)
+}
+
+type tariffRepo struct {
+	ds dataStore.Tariff
+}
+
+func (r *tariffRepo) CreateParcelTariff(ctx context.Context, tariff *entity.ParcelTariff) error {
+	return r.ds.CreateParcelTariff(ctx, tariff)
+}
+
+func (r *tariffRepo) UpdateParcelTariff(ctx context.Context, tariff *entity.ParcelTariff) error {
+	return r.ds.UpdateParcelTariff(ctx, tariff)
+}
+
+func (r *tariffRepo) DeleteParcelTariff(ctx context.Context, id uuid.UUID) error {
+	return r.ds.DeleteParcelTariff(ctx, id)
+}
+
+func (r *tariffRepo) GetParcelTariff(ctx context.Context, id uuid.UUID) (entity.ParcelTariff, error) {
+	return r.ds.GetParcelTariff(ctx, id)
+}
+
+func (r *tariffRepo) GetParcelTariffs(ctx context.Context, pagination dbutil.Pagination) ([]entity.ParcelTariff, int, error, bool) {
+	return r.ds.GetParcelTariffs(ctx, pagination)
+}
+
+func (r *tariffRepo) CreateLuggageTariff(ctx context.Context, tariff *entity.LuggageTariff) error {
+	return r.ds.CreateLuggageTariff(ctx, tariff)
+}
+
+func (r *tariffRepo) UpdateLuggageTariff(ctx context.Context, tariff *entity.LuggageTariff) error {
+	return r.ds.UpdateLuggageTariff(ctx, tariff)
+}
+
+func (r *tariffRepo) DeleteLuggageTariff(ctx context.Context, id uuid.UUID) error {
+	return r.ds.DeleteLuggageTariff(ctx, id)
+}
+
+func (r *tariffRepo) GetLuggageTariff(ctx context.Context, id uuid.UUID) (entity.LuggageTariff, error) {
+	return r.ds.GetLuggageTariff(ctx, id)
+}
+
+func (r *tariffRepo) GetLuggageTariffs(ctx context.Context, pagination dbutil.Pagination) ([]entity.LuggageTariff, int, error, bool) {
+	return r.ds.GetLuggageTariffs(ctx, pagination)
+}
+
+func NewTariffRepo(db *gorm.DB) Tariff {
+	return &tariffRepo{dataStore.NewTariff(db)}
+}
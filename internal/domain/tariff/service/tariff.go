@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"maryan_api/internal/domain/tariff/repo"
+	"maryan_api/internal/entity"
+	"maryan_api/pkg/dbutil"
+	"maryan_api/pkg/hypermedia"
+	rfc7807 "maryan_api/pkg/problem"
+
+	"github.com/d3code/uuid"
+)
+
+type Tariff interface {
+	CreateParcelTariff(ctx context.Context, tariffJSON entity.ParcelTariffJSON) (entity.ParcelTariff, error)
+	UpdateParcelTariff(ctx context.Context, idStr string, tariffJSON entity.ParcelTariffJSON) (entity.ParcelTariff, error)
+	DeleteParcelTariff(ctx context.Context, idStr string) error
+	GetParcelTariff(ctx context.Context, idStr string) (entity.ParcelTariff, error)
+	GetParcelTariffs(ctx context.Context, paginationStr dbutil.PaginationStr) ([]entity.ParcelTariff, hypermedia.Links, error)
+	CreateLuggageTariff(ctx context.Context, tariffJSON entity.LuggageTariffJSON) (entity.LuggageTariff, error)
+	UpdateLuggageTariff(ctx context.Context, idStr string, tariffJSON entity.LuggageTariffJSON) (entity.LuggageTariff, error)
+	DeleteLuggageTariff(ctx context.Context, idStr string) error
+	GetLuggageTariff(ctx context.Context, idStr string) (entity.LuggageTariff, error)
+	GetLuggageTariffs(ctx context.Context, paginationStr dbutil.PaginationStr) ([]entity.LuggageTariff, hypermedia.Links, error)
+}
+
+type serviceImpl struct {
+	repo repo.Tariff
+}
+
+func parseID(idStr string) (uuid.UUID, error) {
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return uuid.Nil, rfc7807.BadRequest("invalid-id", "Invalid ID Error", err.Error())
+	}
+	return id, nil
+}
+
+// CreateParcelTariff adds a new version of the tariff of the route and the parcel type.
+func (s *serviceImpl) CreateParcelTariff(ctx context.Context, tariffJSON entity.ParcelTariffJSON) (entity.ParcelTariff, error) {
+	tariff, err := tariffJSON.Parse()
+	if err != nil {
+		return entity.ParcelTariff{}, err
+	}
+
+	return tariff, s.repo.CreateParcelTariff(ctx, &tariff)
+}
+
+// UpdateParcelTariff changes the version until it comes into effect, its route and type stay the same.
+func (s *serviceImpl) UpdateParcelTariff(ctx context.Context, idStr string, tariffJSON entity.ParcelTariffJSON) (entity.ParcelTariff, error) {
+	id, err := parseID(idStr)
+	if err != nil {
+		return entity.ParcelTariff{}, err
+	}
+
+	existing, err := s.repo.GetParcelTariff(ctx, id)
+	if err != nil {
+		return entity.ParcelTariff{}, err
+	}
+
+	if err = existing.Editable(); err != nil {
+		return entity.ParcelTariff{}, err
+	}
+
+	tariff, err := tariffJSON.Parse()
+	if err != nil {
+		return entity.ParcelTariff{}, err
+	}
+
+	if tariff.DepartureCountryID != existing.DepartureCountryID || tariff.DestinationCountryID != existing.DestinationCountryID || tariff.Type != existing.Type {
+		return entity.ParcelTariff{}, rfc7807.BadRequest("changed-tariff-route", "Changed Tariff Route Error", "The route and the type of the tariff can not be changed, create a new tariff instead.")
+	}
+
+	tariff.ID = id
+	for i := range tariff.Sizes {
+		tariff.Sizes[i].TariffID = id
+	}
+	for i := range tariff.Weights {
+		tariff.Weights[i].TariffID = id
+	}
+
+	err = s.repo.UpdateParcelTariff(ctx, &tariff)
+	if err != nil {
+		return entity.ParcelTariff{}, err
+	}
+
+	return s.repo.GetParcelTariff(ctx, id)
+}
+
+// DeleteParcelTariff removes the version until it comes into effect.
+func (s *serviceImpl) DeleteParcelTariff(ctx context.Context, idStr string) error {
+	id, err := parseID(idStr)
+	if err != nil {
+		return err
+	}
+
+	tariff, err := s.repo.GetParcelTariff(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err = tariff.Editable(); err != nil {
+		return err
+	}
+
+	return s.repo.DeleteParcelTariff(ctx, id)
+}
+
+func (s *serviceImpl) GetParcelTariff(ctx context.Context, idStr string) (entity.ParcelTariff, error) {
+	id, err := parseID(idStr)
+	if err != nil {
+		return entity.ParcelTariff{}, err
+	}
+
+	return s.repo.GetParcelTariff(ctx, id)
+}
+
+func (s *serviceImpl) GetParcelTariffs(ctx context.Context, paginationStr dbutil.PaginationStr) ([]entity.ParcelTariff, hypermedia.Links, error) {
+	pagination, err := paginationStr.Parse([]string{"type"}, "created_at", "effective_from", "version")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tariffs, total, err, empty := s.repo.GetParcelTariffs(ctx, pagination)
+	if err != nil || empty {
+		return nil, nil, err
+	}
+
+	return tariffs, hypermedia.Pagination(paginationStr, total), nil
+}
+
+// CreateLuggageTariff adds a new version of the tariff of the route.
+func (s *serviceImpl) CreateLuggageTariff(ctx context.Context, tariffJSON entity.LuggageTariffJSON) (entity.LuggageTariff, error) {
+	tariff, err := tariffJSON.Parse()
+	if err != nil {
+		return entity.LuggageTariff{}, err
+	}
+
+	return tariff, s.repo.CreateLuggageTariff(ctx, &tariff)
+}
+
+// UpdateLuggageTariff changes the version until it comes into effect, its route stays the same.
+func (s *serviceImpl) UpdateLuggageTariff(ctx context.Context, idStr string, tariffJSON entity.LuggageTariffJSON) (entity.LuggageTariff, error) {
+	id, err := parseID(idStr)
+	if err != nil {
+		return entity.LuggageTariff{}, err
+	}
+
+	existing, err := s.repo.GetLuggageTariff(ctx, id)
+	if err != nil {
+		return entity.LuggageTariff{}, err
+	}
+
+	if err = existing.Editable(); err != nil {
+		return entity.LuggageTariff{}, err
+	}
+
+	tariff, err := tariffJSON.Parse()
+	if err != nil {
+		return entity.LuggageTariff{}, err
+	}
+
+	if tariff.DepartureCountryID != existing.DepartureCountryID || tariff.DestinationCountryID != existing.DestinationCountryID {
+		return entity.LuggageTariff{}, rfc7807.BadRequest("changed-tariff-route", "Changed Tariff Route Error", "The route of the tariff can not be changed, create a new tariff instead.")
+	}
+
+	tariff.ID = id
+	err = s.repo.UpdateLuggageTariff(ctx, &tariff)
+	if err != nil {
+		return entity.LuggageTariff{}, err
+	}
+
+	return s.repo.GetLuggageTariff(ctx, id)
+}
+
+// DeleteLuggageTariff removes the version until it comes into effect.
+func (s *serviceImpl) DeleteLuggageTariff(ctx context.Context, idStr string) error {
+	id, err := parseID(idStr)
+	if err != nil {
+		return err
+	}
+
+	tariff, err := s.repo.GetLuggageTariff(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err = tariff.Editable(); err != nil {
+		return err
+	}
+
+	return s.repo.DeleteLuggageTariff(ctx, id)
+}
+
+func (s *serviceImpl) GetLuggageTariff(ctx context.Context, idStr string) (entity.LuggageTariff, error) {
+	id, err := parseID(idStr)
+	if err != nil {
+		return entity.LuggageTariff{}, err
+	}
+
+	return s.repo.GetLuggageTariff(ctx, id)
+}
+
+func (s *serviceImpl) GetLuggageTariffs(ctx context.Context, paginationStr dbutil.PaginationStr) ([]entity.LuggageTariff, hypermedia.Links, error) {
+	pagination, err := paginationStr.Parse([]string{"version"}, "created_at", "effective_from", "version")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tariffs, total, err, empty := s.repo.GetLuggageTariffs(ctx, pagination)
+	if err != nil || empty {
+		return nil, nil, err
+	}
+
+	return tariffs, hypermedia.Pagination(paginationStr, total), nil
+}
+
+func NewTariffService(repo repo.Tariff) Tariff {
+	return &serviceImpl{
+		repo,
+	}
+}
@@ -0,0 +1,34 @@
+package http
+
+import (
+	"maryan_api/internal/domain/tariff/repo"
+	"maryan_api/internal/domain/tariff/service"
+	"maryan_api/pkg/auth"
+	ginutil "maryan_api/pkg/ginutils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func RegisterRoutes(db *gorm.DB, s *gin.Engine, client *http.Client) {
+	adminRouter := ginutil.CreateAuthRouter("/admin", auth.Admin.SecretKey(), s)
+
+	adminHandler := newTariffHandler(service.NewTariffService(repo.NewTariffRepo(db)))
+
+	//-----------------------Parcel Tariff Routes---------------------------------------
+
+	adminRouter.GET("/parcel-tariffs", adminHandler.getParcelTariffs)
+	adminRouter.GET("/parcel-tariffs/:id", adminHandler.getParcelTariff)
+	adminRouter.POST("/parcel-tariffs", adminHandler.createParcelTariff)
+	adminRouter.PUT("/parcel-tariffs/:id", adminHandler.updateParcelTariff)
+	adminRouter.DELETE("/parcel-tariffs/:id", adminHandler.deleteParcelTariff)
+
+	//-----------------------Luggage Tariff Routes---------------------------------------
+
+	adminRouter.GET("/luggage-tariffs", adminHandler.getLuggageTariffs)
+	adminRouter.GET("/luggage-tariffs/:id", adminHandler.getLuggageTariff)
+	adminRouter.POST("/luggage-tariffs", adminHandler.createLuggageTariff)
+	adminRouter.PUT("/luggage-tariffs/:id", adminHandler.updateLuggageTariff)
+	adminRouter.DELETE("/luggage-tariffs/:id", adminHandler.deleteLuggageTariff)
+}
@@ -0,0 +1,277 @@
+package http
+
+import (
+	"context"
+	"maryan_api/internal/domain/tariff/service"
+	"maryan_api/internal/entity"
+	"maryan_api/pkg/dbutil"
+	ginutil "maryan_api/pkg/ginutils"
+	"maryan_api/pkg/hypermedia"
+	rfc7807 "maryan_api/pkg/problem"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type tariffHandler struct {
+	service service.Tariff
+}
+
+func newTariffHandler(service service.Tariff) *tariffHandler {
+	return &tariffHandler{service}
+}
+
+func (t *tariffHandler) createParcelTariff(ctx *gin.Context) {
+	var request entity.ParcelTariffJSON
+
+	err := ctx.ShouldBindJSON(&request)
+	if err != nil {
+		ginutil.HandlerProblemAbort(ctx, rfc7807.JSON(err.Error()))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	tariff, err := t.service.CreateParcelTariff(ctxWithTimeout, request)
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, struct {
+		ginutil.Response
+		Tariff entity.ParcelTariff `json:"tariff"`
+	}{
+		ginutil.Response{
+			"The parcel tariff has successfuly been created.",
+			hypermedia.Links{},
+		},
+		tariff,
+	})
+}
+
+func (t *tariffHandler) updateParcelTariff(ctx *gin.Context) {
+	var request entity.ParcelTariffJSON
+
+	err := ctx.ShouldBindJSON(&request)
+	if err != nil {
+		ginutil.HandlerProblemAbort(ctx, rfc7807.JSON(err.Error()))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	tariff, err := t.service.UpdateParcelTariff(ctxWithTimeout, ctx.Param("id"), request)
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		ginutil.Response
+		Tariff entity.ParcelTariff `json:"tariff"`
+	}{
+		ginutil.Response{
+			"The parcel tariff has successfuly been updated.",
+			hypermedia.Links{},
+		},
+		tariff,
+	})
+}
+
+func (t *tariffHandler) deleteParcelTariff(ctx *gin.Context) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	err := t.service.DeleteParcelTariff(ctxWithTimeout, ctx.Param("id"))
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, ginutil.Response{
+		"The parcel tariff has successfuly been deleted.",
+		hypermedia.Links{},
+	})
+}
+
+func (t *tariffHandler) getParcelTariff(ctx *gin.Context) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	tariff, err := t.service.GetParcelTariff(ctxWithTimeout, ctx.Param("id"))
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		ginutil.Response
+		Tariff entity.ParcelTariff `json:"tariff"`
+	}{
+		ginutil.Response{
+			"The parcel tariff has successfuly been found.",
+			hypermedia.Links{},
+		},
+		tariff,
+	})
+}
+
+func (t *tariffHandler) getParcelTariffs(ctx *gin.Context) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	tariffs, links, err := t.service.GetParcelTariffs(ctxWithTimeout, dbutil.PaginationStr{
+		"/admin/parcel-tariffs",
+		ctx.DefaultQuery("page", "1"),
+		ctx.DefaultQuery("size", "20"),
+		ctx.DefaultQuery("order_by", "effective_from"),
+		ctx.DefaultQuery("order_way", "desc"),
+		ctx.DefaultQuery("search", ""),
+	})
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		ginutil.Response
+		Tariffs []entity.ParcelTariff `json:"tariffs"`
+	}{
+		ginutil.Response{
+			"The parcel tariffs have successfuly been found.",
+			links,
+		},
+		tariffs,
+	})
+}
+
+func (t *tariffHandler) createLuggageTariff(ctx *gin.Context) {
+	var request entity.LuggageTariffJSON
+
+	err := ctx.ShouldBindJSON(&request)
+	if err != nil {
+		ginutil.HandlerProblemAbort(ctx, rfc7807.JSON(err.Error()))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	tariff, err := t.service.CreateLuggageTariff(ctxWithTimeout, request)
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, struct {
+		ginutil.Response
+		Tariff entity.LuggageTariff `json:"tariff"`
+	}{
+		ginutil.Response{
+			"The luggage tariff has successfuly been created.",
+			hypermedia.Links{},
+		},
+		tariff,
+	})
+}
+
+func (t *tariffHandler) updateLuggageTariff(ctx *gin.Context) {
+	var request entity.LuggageTariffJSON
+
+	err := ctx.ShouldBindJSON(&request)
+	if err != nil {
+		ginutil.HandlerProblemAbort(ctx, rfc7807.JSON(err.Error()))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	tariff, err := t.service.UpdateLuggageTariff(ctxWithTimeout, ctx.Param("id"), request)
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		ginutil.Response
+		Tariff entity.LuggageTariff `json:"tariff"`
+	}{
+		ginutil.Response{
+			"The luggage tariff has successfuly been updated.",
+			hypermedia.Links{},
+		},
+		tariff,
+	})
+}
+
+func (t *tariffHandler) deleteLuggageTariff(ctx *gin.Context) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	err := t.service.DeleteLuggageTariff(ctxWithTimeout, ctx.Param("id"))
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, ginutil.Response{
+		"The luggage tariff has successfuly been deleted.",
+		hypermedia.Links{},
+	})
+}
+
+func (t *tariffHandler) getLuggageTariff(ctx *gin.Context) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	tariff, err := t.service.GetLuggageTariff(ctxWithTimeout, ctx.Param("id"))
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		ginutil.Response
+		Tariff entity.LuggageTariff `json:"tariff"`
+	}{
+		ginutil.Response{
+			"The luggage tariff has successfuly been found.",
+			hypermedia.Links{},
+		},
+		tariff,
+	})
+}
+
+func (t *tariffHandler) getLuggageTariffs(ctx *gin.Context) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	tariffs, links, err := t.service.GetLuggageTariffs(ctxWithTimeout, dbutil.PaginationStr{
+		"/admin/luggage-tariffs",
+		ctx.DefaultQuery("page", "1"),
+		ctx.DefaultQuery("size", "20"),
+		ctx.DefaultQuery("order_by", "effective_from"),
+		ctx.DefaultQuery("order_way", "desc"),
+		ctx.DefaultQuery("search", ""),
+	})
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		ginutil.Response
+		Tariffs []entity.LuggageTariff `json:"tariffs"`
+	}{
+		ginutil.Response{
+			"The luggage tariffs have successfuly been found.",
+			links,
+		},
+		tariffs,
+	})
+}
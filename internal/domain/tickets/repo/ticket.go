@@ -5,6 +5,7 @@ import (
 	"maryan_api/internal/entity"
 	dataStore "maryan_api/internal/infrastructure/persistence"
 	"maryan_api/pkg/dbutil"
+	"time"
 
 	"github.com/d3code/uuid"
 	"gorm.io/gorm"
@@ -12,14 +13,37 @@ import (
 
 type Ticket interface {
 	GetConnectionByID(ctx context.Context, id uuid.UUID, passengersNumber int) (entity.Connection, []uuid.UUID, error)
+	GetConnectionSegment(ctx context.Context, id uuid.UUID, passengersNumber, fromStop, toStop int) (entity.Connection, entity.Segment, []uuid.UUID, error)
 	CreateAdress(ctx context.Context, a *entity.Address) error
 	CreatePassenger(ctx context.Context, p *entity.Passenger) error
 	SaveTicket(ctx context.Context, ticket *entity.Ticket) error
+	SaveTickets(ctx context.Context, tickets []*entity.Ticket, purchases []entity.WaitlistPurchase) error
+	SaveCashTicket(ctx context.Context, ticket *entity.Ticket, holds []entity.SeatHold, maxUnpaidSeats int, offerID uuid.UUID) error
 	DeleteTickets(ctx context.Context, paymentSessionID string) error
 	CreatePassengerStops(ctx context.Context, paymentSessionID string) error
-	PaymentSucceeded(ctx context.Context, paymentSessionID string) error
-	RemovePassengerStops(ctx context.Context, paymentSessionID string) error
 	GetTickets(ctx context.Context, pagination dbutil.Pagination) ([]entity.Ticket, []entity.Connection, int, error, bool)
+	HoldSeats(ctx context.Context, holds []entity.SeatHold) error
+	HoldAllSeats(ctx context.Context, groups ...[]entity.SeatHold) error
+	ReleaseSeats(ctx context.Context, ticketID uuid.UUID) error
+	GetTicket(ctx context.Context, id uuid.UUID) (entity.Ticket, error)
+	CancelTicket(ctx context.Context, id uuid.UUID, refaunds []entity.Refaund) error
+	CreateTicketExchange(ctx context.Context, exchange *entity.TicketExchange) error
+	CompleteTicketExchange(ctx context.Context, id uuid.UUID, refaund *entity.Refaund) error
+	GetTicketExchanges(ctx context.Context, ticketID uuid.UUID) ([]entity.TicketExchange, error)
+	CompleteRefaund(ctx context.Context, id uuid.UUID, providerRefundID string) error
+	FailRefaund(ctx context.Context, id uuid.UUID, message string) error
+	GetFareDiscounts(ctx context.Context, lines ...int) (entity.FareDiscounts, error)
+	ApplyPromoCode(ctx context.Context, code string, userID uuid.UUID, target entity.PromoCodeTarget) (uuid.NullUUID, int, error)
+	GetPricing(ctx context.Context, lines ...int) (entity.Pricing, error)
+	CreatePriceQuote(ctx context.Context, quote *entity.PriceQuote) error
+	GetPriceQuote(ctx context.Context, id uuid.UUID) (entity.PriceQuote, error)
+	GetExchangeRate(ctx context.Context, currency entity.Currency) (entity.ExchangeRate, error)
+	GetTrip(ctx context.Context, id uuid.UUID) (entity.Trip, error)
+	GetWaitlistOffer(ctx context.Context, id uuid.UUID) (entity.WaitlistEntry, []uuid.UUID, error)
+	CreateLuggageOrder(ctx context.Context, order *entity.LuggageOrder, ticket entity.Ticket) error
+	CompleteLuggageOrder(ctx context.Context, id uuid.UUID) error
+	GetLuggageOrders(ctx context.Context, ticketID uuid.UUID) ([]entity.LuggageOrder, error)
+	GetLuggageTariff(ctx context.Context, departureCountryID, destinationCountryID uuid.UUID, at time.Time) (entity.LuggageTariff, error)
 }
 
 type ticketRepo struct {
@@ -27,6 +51,17 @@ type ticketRepo struct {
 	adress     dataStore.Address
 	passenger  dataStore.Passenger
 	connection dataStore.Connection
+	seatHold   dataStore.SeatHold
+	exchange   dataStore.TicketExchange
+	refaund    dataStore.Refaund
+	discount   dataStore.FareDiscount
+	promoCode  dataStore.PromoCode
+	pricing    dataStore.Pricing
+	rate       dataStore.ExchangeRate
+	trip       dataStore.Trip
+	waitlist   dataStore.Waitlist
+	luggage    dataStore.LuggageOrder
+	tariff     dataStore.Tariff
 }
 
 func (r *ticketRepo) GetTickets(ctx context.Context, pagination dbutil.Pagination) ([]entity.Ticket, []entity.Connection, int, error, bool) {
@@ -37,20 +72,80 @@ func (r *ticketRepo) GetConnectionByID(ctx context.Context, id uuid.UUID, passen
 	return r.connection.GetByID(ctx, id, passengersNumber)
 }
 
-func (r *ticketRepo) DeleteTickets(ctx context.Context, paymentSessionID string) error {
-	return r.ticket.DeleteTickets(ctx, paymentSessionID)
+func (r *ticketRepo) GetConnectionSegment(ctx context.Context, id uuid.UUID, passengersNumber, fromStop, toStop int) (entity.Connection, entity.Segment, []uuid.UUID, error) {
+	return r.connection.GetSegmentByID(ctx, id, passengersNumber, fromStop, toStop)
 }
 
-func (r *ticketRepo) PaymentSucceeded(ctx context.Context, paymentSessionID string) error {
-	return r.ticket.PaymentSucceeded(ctx, paymentSessionID)
+func (r *ticketRepo) DeleteTickets(ctx context.Context, paymentSessionID string) error {
+	return r.ticket.DeleteTickets(ctx, paymentSessionID)
 }
 
 func (r *ticketRepo) CreatePassengerStops(ctx context.Context, paymentSessionID string) error {
 	return r.ticket.CreatePassengerStops(ctx, paymentSessionID)
 }
 
-func (r *ticketRepo) RemovePassengerStops(ctx context.Context, paymentSessionID string) error {
-	return r.ticket.RemovePassengerStops(ctx, paymentSessionID)
+func (r *ticketRepo) HoldSeats(ctx context.Context, holds []entity.SeatHold) error {
+	return r.seatHold.Hold(ctx, holds)
+}
+
+func (r *ticketRepo) HoldAllSeats(ctx context.Context, groups ...[]entity.SeatHold) error {
+	return r.seatHold.HoldAll(ctx, groups...)
+}
+
+func (r *ticketRepo) ReleaseSeats(ctx context.Context, ticketID uuid.UUID) error {
+	return r.seatHold.ReleaseByTicket(ctx, ticketID)
+}
+
+func (r *ticketRepo) GetTicket(ctx context.Context, id uuid.UUID) (entity.Ticket, error) {
+	return r.ticket.GetByID(ctx, id)
+}
+
+func (r *ticketRepo) CancelTicket(ctx context.Context, id uuid.UUID, refaunds []entity.Refaund) error {
+	return r.ticket.Cancel(ctx, id, refaunds)
+}
+
+func (r *ticketRepo) CreateTicketExchange(ctx context.Context, exchange *entity.TicketExchange) error {
+	return r.exchange.Create(ctx, exchange)
+}
+
+func (r *ticketRepo) CompleteTicketExchange(ctx context.Context, id uuid.UUID, refaund *entity.Refaund) error {
+	return r.exchange.Complete(ctx, id, refaund)
+}
+
+func (r *ticketRepo) GetTicketExchanges(ctx context.Context, ticketID uuid.UUID) ([]entity.TicketExchange, error) {
+	return r.exchange.GetByTicket(ctx, ticketID)
+}
+
+func (r *ticketRepo) CompleteRefaund(ctx context.Context, id uuid.UUID, providerRefundID string) error {
+	return r.refaund.Complete(ctx, id, providerRefundID)
+}
+
+func (r *ticketRepo) FailRefaund(ctx context.Context, id uuid.UUID, message string) error {
+	return r.refaund.Fail(ctx, id, message)
+}
+
+func (r *ticketRepo) GetFareDiscounts(ctx context.Context, lines ...int) (entity.FareDiscounts, error) {
+	return r.discount.GetByLines(ctx, lines...)
+}
+
+func (r *ticketRepo) ApplyPromoCode(ctx context.Context, code string, userID uuid.UUID, target entity.PromoCodeTarget) (uuid.NullUUID, int, error) {
+	return r.promoCode.Apply(ctx, code, userID, target)
+}
+
+func (r *ticketRepo) GetPricing(ctx context.Context, lines ...int) (entity.Pricing, error) {
+	return r.pricing.GetByLines(ctx, lines...)
+}
+
+func (r *ticketRepo) CreatePriceQuote(ctx context.Context, quote *entity.PriceQuote) error {
+	return r.pricing.CreateQuote(ctx, quote)
+}
+
+func (r *ticketRepo) GetPriceQuote(ctx context.Context, id uuid.UUID) (entity.PriceQuote, error) {
+	return r.pricing.GetQuote(ctx, id)
+}
+
+func (r *ticketRepo) GetExchangeRate(ctx context.Context, currency entity.Currency) (entity.ExchangeRate, error) {
+	return r.rate.Get(ctx, currency)
 }
 
 func (r *ticketRepo) CreateAdress(ctx context.Context, a *entity.Address) error {
@@ -65,8 +160,50 @@ func (r *ticketRepo) SaveTicket(ctx context.Context, ticket *entity.Ticket) erro
 	return r.ticket.Create(ctx, ticket)
 }
 
+func (r *ticketRepo) SaveTickets(ctx context.Context, tickets []*entity.Ticket, purchases []entity.WaitlistPurchase) error {
+	return r.ticket.CreateAll(ctx, tickets, purchases)
+}
+
+func (r *ticketRepo) GetTrip(ctx context.Context, id uuid.UUID) (entity.Trip, error) {
+	return r.trip.GetByID(ctx, id)
+}
+
+func (r *ticketRepo) SaveCashTicket(ctx context.Context, ticket *entity.Ticket, holds []entity.SeatHold, maxUnpaidSeats int, offerID uuid.UUID) error {
+	return r.ticket.CreateCash(ctx, ticket, holds, maxUnpaidSeats, offerID)
+}
+
+// GetWaitlistOffer returns the waitlist entry with the seats held for its offer.
+func (r *ticketRepo) GetWaitlistOffer(ctx context.Context, id uuid.UUID) (entity.WaitlistEntry, []uuid.UUID, error) {
+	entry, err := r.waitlist.GetByID(ctx, id)
+	if err != nil {
+		return entity.WaitlistEntry{}, nil, err
+	}
+
+	seatIDs, err := r.seatHold.GetHeldSeatIDs(ctx, id)
+	return entry, seatIDs, err
+}
+
+func (r *ticketRepo) CreateLuggageOrder(ctx context.Context, order *entity.LuggageOrder, ticket entity.Ticket) error {
+	return r.luggage.Create(ctx, order, ticket)
+}
+
+func (r *ticketRepo) CompleteLuggageOrder(ctx context.Context, id uuid.UUID) error {
+	return r.luggage.Complete(ctx, id)
+}
+
+func (r *ticketRepo) GetLuggageOrders(ctx context.Context, ticketID uuid.UUID) ([]entity.LuggageOrder, error) {
+	return r.luggage.GetByTicket(ctx, ticketID)
+}
+
+func (r *ticketRepo) GetLuggageTariff(ctx context.Context, departureCountryID, destinationCountryID uuid.UUID, at time.Time) (entity.LuggageTariff, error) {
+	return r.tariff.GetActiveLuggageTariff(ctx, departureCountryID, destinationCountryID, at)
+}
+
 func NewTicketRepo(db *gorm.DB) Ticket {
 	return &ticketRepo{
-		dataStore.NewTicket(db), dataStore.NewAddress(db), dataStore.NewPassenger(db), dataStore.NewConnection(db),
+		dataStore.NewTicket(db), dataStore.NewAddress(db), dataStore.NewPassenger(db), dataStore.NewConnection(db), dataStore.NewSeatHold(db),
+		dataStore.NewTicketExchange(db), dataStore.NewRefaund(db), dataStore.NewFareDiscount(db),
+		dataStore.NewPromoCode(db), dataStore.NewPricing(db), dataStore.NewExchangeRate(db), dataStore.NewTrip(db),
+		dataStore.NewWaitlist(db), dataStore.NewLuggageOrder(db), dataStore.NewTariff(db),
 	}
 }
@@ -2,13 +2,15 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"maryan_api/config"
 	"maryan_api/internal/domain/tickets/repo"
 	"maryan_api/internal/entity"
-	"maryan_api/internal/infrastructure/clients/stripe"
+	"maryan_api/internal/infrastructure/clients/payment"
 	"maryan_api/pkg/auth"
 	"maryan_api/pkg/dbutil"
 	"maryan_api/pkg/hypermedia"
+	"maryan_api/pkg/log"
 	rfc7807 "maryan_api/pkg/problem"
 	"net/http"
 	"slices"
@@ -16,23 +18,33 @@ import (
 
 	"github.com/d3code/uuid"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/skip2/go-qrcode"
 )
 
 type Ticket interface {
-	Purchase(ctx context.Context, userID uuid.UUID, newTicket entity.NewTicketJSON) (string, error)
+	QuotePrice(ctx context.Context, userID uuid.UUID, request entity.PriceQuoteJSON) (entity.PriceQuote, error)
+	Purchase(ctx context.Context, userID uuid.UUID, newTicket entity.NewTicketJSON, lang string) (string, error)
+	PurchaseRoundTrip(ctx context.Context, userID uuid.UUID, roundTrip entity.NewRoundTripJSON, lang string) (string, error)
 	PurchaseFailed(ctx context.Context, sessionID, token string) error
-	PurchaseSucceded(ctx context.Context, sessionID, token string) error
 	GetTickets(ctx context.Context, paginationStr dbutil.PaginationStr, userID uuid.UUID) ([]entity.CustomerTicket, hypermedia.Links, error)
+	Cancel(ctx context.Context, userID uuid.UUID, ticketIDStr string) ([]entity.Refaund, error)
+	Rebook(ctx context.Context, userID uuid.UUID, ticketIDStr string, request entity.TicketExchangeJSON) (string, error)
+	GetExchanges(ctx context.Context, userID uuid.UUID, ticketIDStr string) ([]entity.TicketExchange, error)
+	AddLuggage(ctx context.Context, userID uuid.UUID, ticketIDStr string, request entity.NewLuggageOrderJSON) (string, error)
+	GetLuggageOrders(ctx context.Context, userID uuid.UUID, ticketIDStr string) ([]entity.LuggageOrder, error)
+	GetPDF(ctx context.Context, userID uuid.UUID, ticketIDStr, lang string) ([]byte, error)
 }
 
+const paymentSessionDuration = config.PaymentSessionDuration
+
 type serviceImpl struct {
-	repo   repo.Ticket
-	client *http.Client
+	repo     repo.Ticket
+	client   *http.Client
+	payments payment.PaymentProvider
+	reporter log.Reporter
 }
 
 func (s *serviceImpl) GetTickets(ctx context.Context, paginationStr dbutil.PaginationStr, userID uuid.UUID) ([]entity.CustomerTicket, hypermedia.Links, error) {
-	pagination, err := paginationStr.ParseWithCondition(dbutil.Condition{"user_id = ? AND (SELECT succeeded FROM ticket_payments WHERE ticket_id = `tickets`.id)", []any{userID}}, []string{}, "created_at")
+	pagination, err := paginationStr.ParseWithCondition(dbutil.Condition{"user_id = ? AND (SELECT succeeded OR method = ? FROM ticket_payments WHERE ticket_id = `tickets`.id)", []any{userID, entity.PaymentMethodCash}}, []string{}, "created_at")
 	if err != nil {
 
 		return nil, nil, err
@@ -54,121 +66,738 @@ func (s *serviceImpl) GetTickets(ctx context.Context, paginationStr dbutil.Pagin
 			return nil, nil, rfc7807.DB("internal")
 		}
 
+		segment, err := connections[connectionIndex].Segment(ticket.FromStop, ticket.ToStop)
+		if err != nil {
+			return nil, nil, err
+		}
+
 		respose[i] = entity.CustomerTicket{
 			Ticket:     ticket,
-			Connection: connections[connectionIndex].Simplify(),
-			Expired:    connections[connectionIndex].DepartureTime.Before(time.Now().UTC()),
+			Connection: connections[connectionIndex].SimplifySegment(segment),
+			Expired:    segment.From.DepartureTime.Before(time.Now().UTC()),
 		}
 	}
 
 	return respose, hypermedia.Pagination(paginationStr, total), nil
 }
 
+// Cancel cancels the ticket and refunds its payment together with the luggage bought for it later,
+// every luggage order is refunded against the session it has been paid through.
+func (s *serviceImpl) Cancel(ctx context.Context, userID uuid.UUID, ticketIDStr string) ([]entity.Refaund, error) {
+	ticket, err := s.getActiveTicket(ctx, userID, ticketIDStr)
+	if err != nil {
+		return nil, err
+	}
+
+	connection, _, err := s.repo.GetConnectionByID(ctx, ticket.ConnectionID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	segment, err := connection.Segment(ticket.FromStop, ticket.ToStop)
+	if err != nil {
+		return nil, err
+	}
+
+	beforeDeparture := segment.From.DepartureTime.Sub(time.Now().UTC())
+	if beforeDeparture <= 0 {
+		return nil, rfc7807.BadRequest("departed-connection", "Departed Connection Error", "The ticket can not be canceled after the departure.")
+	}
+
+	// Nothing has been paid for a ticket whose cash has not been collected, so there is nothing to refund.
+	if ticket.Payment.UncollectedCash() {
+		return []entity.Refaund{}, s.repo.CancelTicket(ctx, ticket.ID, nil)
+	}
+
+	orders, err := s.repo.GetLuggageOrders(ctx, ticket.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	refaunds := []entity.Refaund{entity.NewTicketRefaund(ticket, config.CalculateRefund(ticket.Payment.Price, beforeDeparture))}
+	for _, order := range orders {
+		if order.Status == entity.CompletedLuggageOrderStatus && order.SessionID != "" {
+			refaunds = append(refaunds, entity.NewLuggageRefaund(order, config.CalculateRefund(order.Price, beforeDeparture)))
+		}
+	}
+
+	return refaunds, s.repo.CancelTicket(ctx, ticket.ID, refaunds)
+}
+
+// Rebook moves the ticket to another connection. When the new connection is more expensive
+// the customer is redirected to the payment provider and the exchange is completed by the webhook,
+// otherwise it is completed right away and the difference is refunded.
+func (s *serviceImpl) Rebook(ctx context.Context, userID uuid.UUID, ticketIDStr string, request entity.TicketExchangeJSON) (string, error) {
+	ticket, err := s.getActiveTicket(ctx, userID, ticketIDStr)
+	if err != nil {
+		return "", err
+	}
+
+	if ticket.Payment.UncollectedCash() {
+		return "", rfc7807.BadRequest("uncollected-cash", "Uncollected Cash Error", "A ticket paid to the driver can not be rebooked before the cash is collected.")
+	}
+
+	currentConnection, _, err := s.repo.GetConnectionByID(ctx, ticket.ConnectionID, 0)
+	if err != nil {
+		return "", err
+	}
+
+	currentSegment, err := currentConnection.Segment(ticket.FromStop, ticket.ToStop)
+	if err != nil {
+		return "", err
+	}
+
+	if currentSegment.From.DepartureTime.Before(time.Now().UTC()) {
+		return "", rfc7807.BadRequest("departed-connection", "Departed Connection Error", "The ticket can not be rebooked after the departure.")
+	}
+
+	connection, segment, takenSeats, err := s.repo.GetConnectionSegment(ctx, request.ConnectionID, len(ticket.Seats), request.FromStop, request.ToStop)
+	if err != nil {
+		return "", err
+	}
+
+	seats, err := request.Validate(ticket, currentSegment, connection, segment, takenSeats, connection.LuggageVolumeLeft)
+	if err != nil {
+		return "", err
+	}
+
+	discounts, err := s.repo.GetFareDiscounts(ctx, currentConnection.Line, connection.Line)
+	if err != nil {
+		return "", err
+	}
+
+	quote, err := s.quote(ctx, userID, request.PriceQuoteID, connection, segment, len(ticket.Passengers), ticket.Payment.Currency)
+	if err != nil {
+		return "", err
+	}
+	fare := quote.Fare
+
+	// Tickets sold before the dynamic pricing keep the base fare of their segment.
+	currentFare := ticket.Payment.Fare
+	if currentFare == 0 {
+		currentFare = currentSegment.Price()
+	}
+
+	seatSurcharge := connection.Bus.SeatsSurcharge(seats)
+	fareDifference := discounts.PassengersTotal(connection.Line, fare, ticket.Passengers) + seatSurcharge -
+		discounts.PassengersTotal(currentConnection.Line, currentFare, ticket.Passengers) - ticket.Payment.SeatSurcharge
+
+	// The difference is paid or refunded in the currency and at the rate the ticket was paid at,
+	// so a changed rate does not move the money of the fare that stays the same.
+	exchange := entity.NewTicketExchange(ticket, connection.ID, segment, seats, fare, ticket.Payment.Rate().Convert(fareDifference).Amount)
+	exchange.SeatSurcharge = seatSurcharge
+
+	exchange.QRCode, err = entity.TicketQRCode(ticket.ID, connection, seats)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.repo.HoldSeats(ctx, entity.NewSeatHolds(connection.ID, ticket.ID, seats, segment, time.Now().UTC().Add(config.SeatHoldDuration)))
+	if err != nil {
+		return "", err
+	}
+
+	redirectURL, err := s.rebook(ctx, ticket, &exchange)
+	if err != nil {
+		s.releaseSeats(ctx, ticket.ID)
+		return "", err
+	}
+
+	return redirectURL, nil
+}
+
+func (s *serviceImpl) rebook(ctx context.Context, ticket entity.Ticket, exchange *entity.TicketExchange) (string, error) {
+	if exchange.FareDifference > 0 {
+		token, err := auth.GenerateAccessToken(config.PaymentSecretKey(), jwt.MapClaims{
+			"expires": time.Now().Add(paymentSessionDuration).Unix(),
+		})
+		if err != nil {
+			return "", err
+		}
+
+		redirectURL, sessionID, err := s.payments.CreateSession(int64(exchange.FareDifference), string(ticket.Payment.Currency), "/connection/rebook-ticket", token)
+		if err != nil {
+			return "", rfc7807.BadGateway("payment", "Payment Error", err.Error())
+		}
+
+		exchange.SessionID = sessionID
+
+		return redirectURL, s.repo.CreateTicketExchange(ctx, exchange)
+	}
+
+	err := s.repo.CreateTicketExchange(ctx, exchange)
+	if err != nil {
+		return "", err
+	}
+
+	if exchange.FareDifference == 0 {
+		return "", s.repo.CompleteTicketExchange(ctx, exchange.ID, nil)
+	}
+
+	// The difference of a ticket paid in cash waits at the office for the customer.
+	refaund := entity.NewTicketRefaund(ticket, -exchange.FareDifference)
+	if refaund.AtOffice() {
+		return "", s.repo.CompleteTicketExchange(ctx, exchange.ID, &refaund)
+	}
+	refaund.Status = entity.ApprovedRefaundStatus
+
+	err = s.repo.CompleteTicketExchange(ctx, exchange.ID, &refaund)
+	if err != nil {
+		return "", err
+	}
+
+	// The ticket has already been moved, so a failed refund is left for the admins to retry.
+	providerRefundID, err := s.payments.Refund(ticket.Payment.SessionID, int64(refaund.Amount), refaund.ID.String())
+	if err != nil {
+		return "", s.repo.FailRefaund(context.WithoutCancel(ctx), refaund.ID, err.Error())
+	}
+
+	return "", s.repo.CompleteRefaund(context.WithoutCancel(ctx), refaund.ID, providerRefundID)
+}
+
+func (s *serviceImpl) GetExchanges(ctx context.Context, userID uuid.UUID, ticketIDStr string) ([]entity.TicketExchange, error) {
+	ticketID, err := uuid.Parse(ticketIDStr)
+	if err != nil {
+		return nil, rfc7807.BadRequest("invalid-id", "Invalid ID Error", err.Error())
+	}
+
+	ticket, err := s.repo.GetTicket(ctx, ticketID)
+	if err != nil {
+		return nil, err
+	}
+
+	if ticket.UserID != userID {
+		return nil, rfc7807.New(http.StatusForbidden, "foreign-ticket", "Foreign Ticket Error", "The ticket belongs to another user.")
+	}
+
+	return s.repo.GetTicketExchanges(ctx, ticketID)
+}
+
+// AddLuggage adds items of luggage to a paid ticket until the sales of the connection close.
+// The items are paid through a checkout session of their own, in the currency of the ticket.
+func (s *serviceImpl) AddLuggage(ctx context.Context, userID uuid.UUID, ticketIDStr string, request entity.NewLuggageOrderJSON) (string, error) {
+	ticket, err := s.getActiveTicket(ctx, userID, ticketIDStr)
+	if err != nil {
+		return "", err
+	}
+
+	if !ticket.Payment.Succeeded {
+		return "", rfc7807.BadRequest("unpaid-ticket", "Unpaid Ticket Error", "The luggage can only be added to a paid ticket.")
+	}
+
+	connection, segment, _, err := s.repo.GetConnectionSegment(ctx, ticket.ConnectionID, 0, ticket.FromStop, ticket.ToStop)
+	if err != nil {
+		return "", err
+	}
+
+	if !config.MustParseToLocalByUUID(time.Now(), connection.DepartureCountryID).UTC().Before(connection.SellBefore) {
+		return "", rfc7807.BadRequest("closed-sales", "Closed Sales Error", "The luggage can not be added after the sales of the connection have closed.")
+	}
+
+	rate, err := s.repo.GetExchangeRate(ctx, ticket.Payment.Currency)
+	if err != nil {
+		return "", err
+	}
+
+	tariff, err := s.repo.GetLuggageTariff(ctx, segment.From.CountryID, segment.To.CountryID, time.Now().UTC())
+	if err != nil {
+		return "", err
+	}
+
+	order, err := request.Parse(ticket, rate, tariff)
+	if err != nil {
+		return "", err
+	}
+
+	// The space is checked again under the lock of the connection when the order is saved,
+	// this check only keeps a session from being opened for luggage that does not fit.
+	if uint(order.Volume) > connection.LuggageVolumeLeft {
+		return "", rfc7807.New(http.StatusConflict, "luggage-volume", "Luggage Volume Error", "There is not enough space left to fit provided luggage volume.")
+	}
+
+	// The items the passengers are entitled to for free need no payment.
+	if order.Price == 0 {
+		err = s.repo.CreateLuggageOrder(ctx, &order, ticket)
+		if err != nil {
+			return "", err
+		}
+		return "", s.repo.CompleteLuggageOrder(ctx, order.ID)
+	}
+
+	token, err := auth.GenerateAccessToken(config.PaymentSecretKey(), jwt.MapClaims{
+		"expires": time.Now().Add(paymentSessionDuration).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	redirectURL, sessionID, err := s.payments.CreateSession(int64(order.Price), string(order.Currency), "/connection/ticket-luggage", token)
+	if err != nil {
+		return "", rfc7807.BadGateway("payment", "Payment Error", err.Error())
+	}
+
+	order.SessionID = sessionID
+	err = s.repo.CreateLuggageOrder(ctx, &order, ticket)
+	if err != nil {
+		s.cancelSession(sessionID)
+		return "", err
+	}
+
+	return redirectURL, nil
+}
+
+func (s *serviceImpl) GetLuggageOrders(ctx context.Context, userID uuid.UUID, ticketIDStr string) ([]entity.LuggageOrder, error) {
+	ticketID, err := uuid.Parse(ticketIDStr)
+	if err != nil {
+		return nil, rfc7807.BadRequest("invalid-id", "Invalid ID Error", err.Error())
+	}
+
+	ticket, err := s.repo.GetTicket(ctx, ticketID)
+	if err != nil {
+		return nil, err
+	}
+
+	if ticket.UserID != userID {
+		return nil, rfc7807.New(http.StatusForbidden, "foreign-ticket", "Foreign Ticket Error", "The ticket belongs to another user.")
+	}
+
+	return s.repo.GetLuggageOrders(ctx, ticketID)
+}
+
+func (s *serviceImpl) GetPDF(ctx context.Context, userID uuid.UUID, ticketIDStr, lang string) ([]byte, error) {
+	ticket, err := s.getActiveTicket(ctx, userID, ticketIDStr)
+	if err != nil {
+		return nil, err
+	}
+
+	connection, _, err := s.repo.GetConnectionByID(ctx, ticket.ConnectionID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return ticket.BoardingPass(connection, lang)
+}
+
+// getActiveTicket returns the ticket only if it belongs to the user, is paid or is to be paid to the driver,
+// and has not been canceled.
+func (s *serviceImpl) getActiveTicket(ctx context.Context, userID uuid.UUID, ticketIDStr string) (entity.Ticket, error) {
+	ticketID, err := uuid.Parse(ticketIDStr)
+	if err != nil {
+		return entity.Ticket{}, rfc7807.BadRequest("invalid-id", "Invalid ID Error", err.Error())
+	}
+
+	ticket, err := s.repo.GetTicket(ctx, ticketID)
+	if err != nil {
+		return entity.Ticket{}, err
+	}
+
+	if ticket.UserID != userID {
+		return entity.Ticket{}, rfc7807.New(http.StatusForbidden, "foreign-ticket", "Foreign Ticket Error", "The ticket belongs to another user.")
+	}
+
+	if !ticket.Payment.Succeeded && !ticket.Payment.UncollectedCash() {
+		return entity.Ticket{}, rfc7807.BadRequest("unpaid-ticket", "Unpaid Ticket Error", "The ticket has not been paid.")
+	}
+
+	if ticket.CanceledAt.Valid {
+		return entity.Ticket{}, rfc7807.BadRequest("canceled-ticket", "Canceled Ticket Error", "The ticket has already been canceled.")
+	}
+
+	return ticket, nil
+}
+
 func (s *serviceImpl) PurchaseFailed(ctx context.Context, sessionID, token string) error {
 	_, err := auth.VerifyAccessToken(token, config.PaymentSecretKey())
 	if err != nil {
 		return rfc7807.Unauthorized("unauthorized", "Unauthorized Error", "Unauthorized Error")
 	}
-	err = stripe.CancelPaymentIntent(sessionID)
+	err = s.payments.CancelSession(sessionID)
 	if err != nil {
 		return rfc7807.BadGateway("payment-cancelation",
 			"Payment Cancelation Error", err.Error())
 	}
-	err = s.repo.RemovePassengerStops(ctx, sessionID)
+	return nil
+}
+
+func (s *serviceImpl) Purchase(ctx context.Context, userID uuid.UUID, newTicket entity.NewTicketJSON, lang string) (string, error) {
+	email, phoneNumber, err := newTicket.ParseContaanctInfo()
 	if err != nil {
-		return err
+		return "", err
 	}
-	return nil
+
+	currency, err := entity.ParseCurrency(newTicket.Currency)
+	if err != nil {
+		return "", err
+	}
+
+	cash, err := newTicket.PaysCash()
+	if err != nil {
+		return "", err
+	}
+
+	leg, err := s.prepareLeg(ctx, userID, newTicket, currency)
+	if err != nil {
+		return "", err
+	}
+
+	// The seats of a ticket paid to the driver are held together with the booking.
+	if cash {
+		ticket, err := s.book(ctx, userID, leg, false, email, phoneNumber, lang)
+		if err != nil {
+			return "", err
+		}
+
+		ticket.Payment.Method = entity.PaymentMethodCash
+		ticket.Payment.SessionID = entity.CashPaymentReference(ticket.ID)
+		return "", s.repo.SaveCashTicket(ctx, ticket, leg.holds, config.MaxUnpaidCashSeats, leg.offerID)
+	}
+
+	err = s.repo.HoldSeats(ctx, leg.newHolds())
+	if err != nil {
+		return "", err
+	}
+
+	return s.checkout(ctx, userID, []ticketLeg{leg}, email, phoneNumber, lang)
 }
 
-func (s *serviceImpl) PurchaseSucceded(ctx context.Context, sessionID, token string) error {
-	_, err := auth.VerifyAccessToken(token, config.PaymentSecretKey())
+// cancelSession expires the session opened for the order that could not be saved. The customer is
+// answered with the error of the order, so a failure here is reported, the session expires on its own.
+func (s *serviceImpl) cancelSession(sessionID string) {
+	err := s.payments.CancelSession(sessionID)
 	if err != nil {
-		return rfc7807.Unauthorized("unauthorized", "Unauthorized Error", "Unauthorized Error")
+		s.reporter.Report("cancel-session", fmt.Errorf("session %s: %w", sessionID, err))
 	}
-	return s.repo.PaymentSucceeded(ctx, sessionID)
 }
 
-func (s *serviceImpl) Purchase(ctx context.Context, userID uuid.UUID, newTicket entity.NewTicketJSON) (string, error) {
-	email, phoneNumber, err := newTicket.ParseContaanctInfo()
+// releaseSeats frees the seats held for the failed checkout. The customer is answered with the error
+// of the checkout, so a failure here is reported, the holds then expire on their own.
+func (s *serviceImpl) releaseSeats(ctx context.Context, ticketID uuid.UUID) {
+	err := s.repo.ReleaseSeats(context.WithoutCancel(ctx), ticketID)
+	if err != nil {
+		s.reporter.Report("release-seats", err)
+	}
+}
 
-	connection, takenSeats, err := s.repo.GetConnectionByID(ctx, newTicket.ConnectionID, len(newTicket.Passengers))
+// PurchaseRoundTrip books both legs in one checkout with one payment. Every leg gets a ticket of
+// its own with its share of the payment, so one of them can be canceled or rebooked alone.
+func (s *serviceImpl) PurchaseRoundTrip(ctx context.Context, userID uuid.UUID, roundTrip entity.NewRoundTripJSON, lang string) (string, error) {
+	email, phoneNumber, err := roundTrip.Outbound.ParseContaanctInfo()
 	if err != nil {
 		return "", err
 	}
 
-	ticketID := uuid.New()
+	err = roundTrip.Validate()
+	if err != nil {
+		return "", err
+	}
 
-	seats, err := newTicket.Validate(connection, takenSeats, ticketID, connection.LuggageVolumeLeft)
+	currency, err := entity.ParseCurrency(roundTrip.Outbound.Currency)
 	if err != nil {
 		return "", err
 	}
 
-	pickUpAdress, dropOffAdress, err := newTicket.ParseAdresses(ctx, s.client, connection.DepartureCountryID, connection.DestinationCountryID)
+	var trip *entity.Trip
+	if roundTrip.TripID != uuid.Nil {
+		found, err := s.repo.GetTrip(ctx, roundTrip.TripID)
+		if err != nil {
+			return "", err
+		}
+		trip = &found
+	}
+
+	outbound, err := s.prepareLeg(ctx, userID, roundTrip.Outbound, currency)
+	if err != nil {
+		return "", err
+	}
+
+	back, err := s.prepareLeg(ctx, userID, roundTrip.Return, currency)
+	if err != nil {
+		return "", err
+	}
+
+	err = roundTrip.ValidateLegs(trip, outbound.segment, back.segment)
 	if err != nil {
 		return "", err
 	}
 
-	passengers, err := newTicket.ParsePassengers(ticketID)
+	err = s.repo.HoldAllSeats(ctx, outbound.newHolds(), back.newHolds())
+	if err != nil {
+		return "", err
+	}
+
+	return s.checkout(ctx, userID, []ticketLeg{outbound, back}, email, phoneNumber, lang)
+}
+
+// ticketLeg is a ticket of the checkout with the segment, the quoted fare and the seats chosen for it.
+type ticketLeg struct {
+	newTicket  entity.NewTicketJSON
+	connection entity.Connection
+	segment    entity.Segment
+	fare       int
+	rate       entity.ExchangeRate
+	ticketID   uuid.UUID
+	seats      []entity.TicketSeat
+	holds      []entity.SeatHold
+	offerID    uuid.UUID
+}
+
+// newHolds returns the seats to hold before the checkout. The seats of a waitlist offer are already
+// held for it, they are moved to the ticket when the ticket is saved.
+func (l ticketLeg) newHolds() []entity.SeatHold {
+	if l.offerID != uuid.Nil {
+		return nil
+	}
+	return l.holds
+}
+
+func (s *serviceImpl) prepareLeg(ctx context.Context, userID uuid.UUID, newTicket entity.NewTicketJSON, currency entity.Currency) (ticketLeg, error) {
+	connection, segment, takenSeats, err := s.repo.GetConnectionSegment(ctx, newTicket.ConnectionID, len(newTicket.Passengers), newTicket.FromStop, newTicket.ToStop)
 	if err != nil {
+		return ticketLeg{}, err
+	}
+
+	// The seats held for the waitlist offer are free for the customer it was made to.
+	if newTicket.WaitlistOfferID != uuid.Nil {
+		offer, offeredSeats, err := s.repo.GetWaitlistOffer(ctx, newTicket.WaitlistOfferID)
+		if err != nil {
+			return ticketLeg{}, err
+		}
+
+		err = offer.ValidateOffer(userID, newTicket, segment)
+		if err != nil {
+			return ticketLeg{}, err
+		}
+
+		takenSeats = slices.DeleteFunc(takenSeats, func(id uuid.UUID) bool { return slices.Contains(offeredSeats, id) })
+	}
+
+	ticketID := uuid.New()
+
+	seats, err := newTicket.Validate(connection, segment, takenSeats, ticketID, connection.LuggageVolumeLeft)
+	if err != nil {
+		return ticketLeg{}, err
+	}
+
+	quote, err := s.quote(ctx, userID, newTicket.PriceQuoteID, connection, segment, len(newTicket.Passengers), currency)
+	if err != nil {
+		return ticketLeg{}, err
+	}
+
+	return ticketLeg{
+		newTicket:  newTicket,
+		connection: connection,
+		segment:    segment,
+		fare:       quote.Fare,
+		rate:       quote.Rate(),
+		ticketID:   ticketID,
+		seats:      seats,
+		holds:      entity.NewSeatHolds(connection.ID, ticketID, seats, segment, time.Now().UTC().Add(config.SeatHoldDuration)),
+		offerID:    newTicket.WaitlistOfferID,
+	}, nil
+}
+
+// checkout saves the tickets of the legs and opens one payment session for all of them.
+// More than one leg makes a round trip, its legs get the round trip discount. The seats held
+// for the legs are released when the checkout fails, the session opened for it is canceled.
+func (s *serviceImpl) checkout(ctx context.Context, userID uuid.UUID, legs []ticketLeg, email, phoneNumber, lang string) (string, error) {
+	fail := func(err error) (string, error) {
+		for _, leg := range legs {
+			s.releaseSeats(ctx, leg.ticketID)
+		}
 		return "", err
 	}
 
 	token, err := auth.GenerateAccessToken(config.PaymentSecretKey(), jwt.MapClaims{
-		"expires": time.Now().Add(time.Minute * 15).Unix(),
+		"expires": time.Now().Add(paymentSessionDuration).Unix(),
 	})
 	if err != nil {
-		return "", err
+		return fail(err)
 	}
 
-	redirectURL, sessionID, err := stripe.CreateStripeCheckoutSession((int64(connection.Price)*int64(len(newTicket.SeatIDs)))+int64(newTicket.LuggagePrice()), "/connection/purchase-ticket", token)
+	roundTrip := len(legs) > 1
+	roundTripID := uuid.NullUUID{UUID: uuid.New(), Valid: roundTrip}
+
+	var tickets = make([]*entity.Ticket, len(legs))
+	var purchases []entity.WaitlistPurchase
+	var charge int
+	for i, leg := range legs {
+		tickets[i], err = s.book(ctx, userID, leg, roundTrip, email, phoneNumber, lang)
+		if err != nil {
+			return fail(err)
+		}
+
+		tickets[i].RoundTripID = roundTripID
+		charge += tickets[i].Payment.Price
+
+		if leg.offerID != uuid.Nil {
+			purchases = append(purchases, entity.WaitlistPurchase{OfferID: leg.offerID, TicketID: leg.ticketID, Holds: leg.holds})
+		}
+	}
+
+	// The legs are quoted in the currency of the checkout.
+	redirectURL, sessionID, err := s.payments.CreateSession(int64(charge), string(legs[0].rate.Currency), "/connection/purchase-ticket", token)
 	if err != nil {
-		return "", rfc7807.BadGateway("payment", "Payment Error", err.Error())
+		return fail(rfc7807.BadGateway("payment", "Payment Error", err.Error()))
+	}
+
+	for _, ticket := range tickets {
+		ticket.Payment.SessionID = sessionID
+	}
+
+	err = s.repo.SaveTickets(ctx, tickets, purchases)
+	if err != nil {
+		s.cancelSession(sessionID)
+		return fail(err)
+	}
+
+	err = s.repo.CreatePassengerStops(ctx, sessionID)
+	if err != nil {
+		s.cancelSession(sessionID)
+		return fail(err)
+	}
+	return redirectURL, nil
+}
+
+// book prices the ticket of the leg at the quoted rate, the payment session is left to the caller.
+func (s *serviceImpl) book(ctx context.Context, userID uuid.UUID, leg ticketLeg, roundTrip bool, email, phoneNumber, lang string) (*entity.Ticket, error) {
+	rate := leg.rate
+
+	pickUpAdress, dropOffAdress, err := leg.newTicket.ParseAdresses(ctx, s.client, leg.segment.From.CountryID, leg.segment.To.CountryID)
+	if err != nil {
+		return nil, err
+	}
+
+	passengers, err := leg.newTicket.ParsePassengers(leg.ticketID)
+	if err != nil {
+		return nil, err
 	}
 
-	qrCode, err := qrcode.Encode(ticketID.String(), qrcode.Highest, 256)
+	discounts, err := s.repo.GetFareDiscounts(ctx, leg.connection.Line)
 	if err != nil {
-		return "", rfc7807.Internal("QR-Code Encoding Error", err.Error())
+		return nil, err
 	}
 
-	ticket := &entity.Ticket{
-		ID:              ticketID,
+	tariff, err := s.repo.GetLuggageTariff(ctx, leg.segment.From.CountryID, leg.segment.To.CountryID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	luggage := leg.newTicket.LuggageItems(leg.ticketID, tariff)
+	seatSurcharge := leg.connection.Bus.SeatsSurcharge(leg.seats)
+	price := discounts.PassengersTotal(leg.connection.Line, leg.fare, passengers) + seatSurcharge + entity.LuggagePrice(luggage)
+
+	var discount int
+	if roundTrip {
+		discount = entity.RoundTripDiscount(price)
+		price -= discount
+	}
+
+	promoCodeID, promoDiscount, err := s.repo.ApplyPromoCode(ctx, leg.newTicket.PromoCode, userID, entity.PromoCodeTarget{
+		Product:              entity.TicketPromoProduct,
+		Line:                 leg.connection.Line,
+		DepartureCountryID:   leg.segment.From.CountryID,
+		DestinationCountryID: leg.segment.To.CountryID,
+		Price:                price,
+	})
+	if err != nil {
+		return nil, err
+	}
+	price -= promoDiscount
+	discount += promoDiscount
+
+	qrCode, err := entity.TicketQRCode(leg.ticketID, leg.connection, leg.seats)
+	if err != nil {
+		return nil, err
+	}
+
+	charge := rate.Convert(price)
+
+	return &entity.Ticket{
+		ID:              leg.ticketID,
 		UserID:          userID,
 		PhoneNumber:     phoneNumber,
 		Email:           email,
-		ConnectionID:    connection.ID,
-		Seats:           seats,
+		ConnectionID:    leg.connection.ID,
+		Seats:           leg.seats,
 		Passengers:      passengers,
 		PickUpAdressID:  pickUpAdress.ID,
 		PickUpAdress:    *pickUpAdress,
 		DropOffAdressID: dropOffAdress.ID,
 		DropOffAdress:   *dropOffAdress,
 		Payment: entity.TicketPayment{
-			TicketID:  ticketID,
-			Price:     connection.Price + newTicket.LuggagePrice(),
-			Method:    entity.PaymentMethodCard,
-			SessionID: sessionID,
-			Succeeded: false,
+			TicketID:             leg.ticketID,
+			Price:                charge.Amount,
+			Method:               entity.PaymentMethodCard,
+			Succeeded:            false,
+			PromoCodeID:          promoCodeID,
+			Discount:             rate.Convert(discount).Amount,
+			Fare:                 leg.fare,
+			SeatSurcharge:        seatSurcharge,
+			LuggageTariffID:      tariff.Ref(),
+			LuggageTariffVersion: tariff.Version,
+			Currency:             charge.Currency,
+			ExchangeRate:         rate.Rate,
 		},
-		LuggageVolume: newTicket.LuggageVolume(),
+		LuggageVolume: leg.newTicket.LuggageVolume(),
+		Luggage:       luggage,
 		QRCode:        qrCode,
+		Language:      lang,
+		FromStop:      leg.segment.From.Sequence,
+		ToStop:        leg.segment.To.Sequence,
+	}, nil
+}
+
+// QuotePrice starts the checkout by fixing the fare of the segment for the user and the passengers.
+func (s *serviceImpl) QuotePrice(ctx context.Context, userID uuid.UUID, request entity.PriceQuoteJSON) (entity.PriceQuote, error) {
+	err := request.Validate()
+	if err != nil {
+		return entity.PriceQuote{}, err
 	}
 
-	err = s.repo.SaveTicket(ctx, ticket)
+	connection, segment, takenSeats, err := s.repo.GetConnectionSegment(ctx, request.ConnectionID, request.Passengers, request.FromStop, request.ToStop)
 	if err != nil {
-		return "", err
+		return entity.PriceQuote{}, err
 	}
 
-	err = s.repo.CreatePassengerStops(ctx, sessionID)
+	currency, err := entity.ParseCurrency(request.Currency)
 	if err != nil {
-		return "", err
+		return entity.PriceQuote{}, err
 	}
-	return redirectURL, nil
+
+	rate, err := s.repo.GetExchangeRate(ctx, currency)
+	if err != nil {
+		return entity.PriceQuote{}, err
+	}
+
+	pricing, err := s.repo.GetPricing(ctx, connection.Line)
+	if err != nil {
+		return entity.PriceQuote{}, err
+	}
+
+	fare := pricing.SegmentFare(connection.Line, segment, len(connection.Bus.Seats), len(takenSeats))
+	quote := entity.NewPriceQuote(userID, connection.ID, segment, request.Passengers, fare, rate)
+	return quote, s.repo.CreatePriceQuote(ctx, &quote)
+}
+
+// quote returns the quote the user has been given for the passengers on the segment in the currency.
+func (s *serviceImpl) quote(ctx context.Context, userID, quoteID uuid.UUID, connection entity.Connection, segment entity.Segment, passengers int, currency entity.Currency) (entity.PriceQuote, error) {
+	if quoteID == uuid.Nil {
+		return entity.PriceQuote{}, rfc7807.BadRequest("missing-price-quote", "Missing Price Quote Error", "The price has to be quoted at the start of the checkout.")
+	}
+
+	quote, err := s.repo.GetPriceQuote(ctx, quoteID)
+	if err != nil {
+		return entity.PriceQuote{}, err
+	}
+
+	return quote, quote.Covers(userID, connection.ID, segment, passengers, currency)
 }
 
-func NewTicketService(repo repo.Ticket, client *http.Client) Ticket {
+func NewTicketService(repo repo.Ticket, client *http.Client, payments payment.PaymentProvider, reporter log.Reporter) Ticket {
 	return &serviceImpl{
 		repo,
 		client,
+		payments,
+		reporter,
 	}
 }
@@ -0,0 +1,464 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	paymentRepo "maryan_api/internal/domain/payment/repo"
+	paymentService "maryan_api/internal/domain/payment/service"
+	"maryan_api/internal/domain/tickets/repo"
+	"maryan_api/internal/entity"
+	"maryan_api/internal/infrastructure/clients/payment/paymenttest"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/d3code/uuid"
+)
+
+// offlineStore keeps the tickets of the checkout in memory and serves both the ticket service and
+// the webhooks of the payments. The methods the purchase does not need are left to the embedded
+// interfaces, so calling one of them fails the test with a panic.
+type offlineStore struct {
+	repo.Ticket
+	paymentRepo.Payment
+
+	connection entity.Connection
+	segment    entity.Segment
+	quote      entity.PriceQuote
+	offer      entity.WaitlistEntry
+	holds      []entity.SeatHold
+	purchases  []entity.WaitlistPurchase
+	tickets    []*entity.Ticket
+	orders     []entity.LuggageOrder
+	refaunds   []entity.Refaund
+	released   []uuid.UUID
+	saveErr    error
+	events     map[string]bool
+}
+
+func newOfflineStore(userID uuid.UUID) *offlineStore {
+	seats := []entity.Seat{{ID: uuid.New(), Number: 1}, {ID: uuid.New(), Number: 2}}
+	departure := time.Now().UTC().Add(48 * time.Hour)
+
+	connection := entity.Connection{
+		ID:                uuid.New(),
+		Line:              1,
+		Bus:               entity.Bus{Seats: seats},
+		ArrivalTime:       departure.Add(20 * time.Hour),
+		LuggageVolumeLeft: 100,
+	}
+	segment := entity.Segment{
+		From: entity.ConnectionStop{ConnectionID: connection.ID, Sequence: 1, CountryID: uuid.New(), DepartureTime: departure},
+		To:   entity.ConnectionStop{ConnectionID: connection.ID, Sequence: 2, CountryID: uuid.New(), ArrivalTime: connection.ArrivalTime},
+	}
+
+	return &offlineStore{
+		connection: connection,
+		segment:    segment,
+		quote:      entity.NewPriceQuote(userID, connection.ID, segment, 1, 2500, entity.ExchangeRate{Currency: entity.EUR, Rate: 1}),
+		events:     make(map[string]bool),
+	}
+}
+
+func (s *offlineStore) GetConnectionSegment(ctx context.Context, id uuid.UUID, passengersNumber, fromStop, toStop int) (entity.Connection, entity.Segment, []uuid.UUID, error) {
+	return s.connection, s.segment, nil, nil
+}
+
+func (s *offlineStore) GetPriceQuote(ctx context.Context, id uuid.UUID) (entity.PriceQuote, error) {
+	return s.quote, nil
+}
+
+func (s *offlineStore) HoldSeats(ctx context.Context, holds []entity.SeatHold) error {
+	s.holds = append(s.holds, holds...)
+	return nil
+}
+
+func (s *offlineStore) GetFareDiscounts(ctx context.Context, lines ...int) (entity.FareDiscounts, error) {
+	return nil, nil
+}
+
+func (s *offlineStore) GetLuggageTariff(ctx context.Context, departureCountryID, destinationCountryID uuid.UUID, at time.Time) (entity.LuggageTariff, error) {
+	return entity.LuggageTariff{}, nil
+}
+
+func (s *offlineStore) ApplyPromoCode(ctx context.Context, code string, userID uuid.UUID, target entity.PromoCodeTarget) (uuid.NullUUID, int, error) {
+	return uuid.NullUUID{}, 0, nil
+}
+
+func (s *offlineStore) GetWaitlistOffer(ctx context.Context, id uuid.UUID) (entity.WaitlistEntry, []uuid.UUID, error) {
+	return s.offer, []uuid.UUID{s.connection.Bus.Seats[0].ID}, nil
+}
+
+func (s *offlineStore) SaveTickets(ctx context.Context, tickets []*entity.Ticket, purchases []entity.WaitlistPurchase) error {
+	if s.saveErr != nil {
+		return s.saveErr
+	}
+	s.tickets = append(s.tickets, tickets...)
+	s.purchases = append(s.purchases, purchases...)
+	return nil
+}
+
+func (s *offlineStore) ReleaseSeats(ctx context.Context, ticketID uuid.UUID) error {
+	s.released = append(s.released, ticketID)
+	return nil
+}
+
+func (s *offlineStore) CreatePassengerStops(ctx context.Context, paymentSessionID string) error {
+	return nil
+}
+
+func (s *offlineStore) GetTicket(ctx context.Context, id uuid.UUID) (entity.Ticket, error) {
+	for _, ticket := range s.tickets {
+		if ticket.ID == id {
+			return *ticket, nil
+		}
+	}
+	return entity.Ticket{}, errors.New("no such ticket")
+}
+
+func (s *offlineStore) GetConnectionByID(ctx context.Context, id uuid.UUID, passengersNumber int) (entity.Connection, []uuid.UUID, error) {
+	connection := s.connection
+	connection.Route = []entity.ConnectionStop{{Sequence: 0}, s.segment.From, s.segment.To}
+	return connection, nil, nil
+}
+
+func (s *offlineStore) GetLuggageOrders(ctx context.Context, ticketID uuid.UUID) ([]entity.LuggageOrder, error) {
+	return s.orders, nil
+}
+
+func (s *offlineStore) CancelTicket(ctx context.Context, id uuid.UUID, refaunds []entity.Refaund) error {
+	s.refaunds = refaunds
+	return nil
+}
+
+func (s *offlineStore) RegisterEvent(ctx context.Context, event *entity.StripeEvent) (bool, error) {
+	if s.events[event.ID] {
+		return false, nil
+	}
+	s.events[event.ID] = true
+	return true, nil
+}
+
+func (s *offlineStore) DefineProduct(ctx context.Context, paymentSessionID string) (entity.PaymentProduct, bool, error) {
+	for _, ticket := range s.tickets {
+		if ticket.Payment.SessionID == paymentSessionID {
+			return entity.TicketPaymentProduct, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+func (s *offlineStore) TicketPaymentSucceeded(ctx context.Context, paymentSessionID string) (bool, error) {
+	var recorded bool
+	for _, ticket := range s.tickets {
+		if ticket.Payment.SessionID == paymentSessionID && !ticket.Payment.Succeeded {
+			ticket.Payment.Succeeded = true
+			recorded = true
+		}
+	}
+	return recorded, nil
+}
+
+// GetTicketsBySession stops the boarding pass from being emailed, there is no mail server offline.
+func (s *offlineStore) GetTicketsBySession(ctx context.Context, paymentSessionID string) ([]entity.Ticket, []entity.Connection, error) {
+	return nil, nil, errors.New("no boarding passes are sent offline")
+}
+
+func (s *offlineStore) DeleteTickets(ctx context.Context, paymentSessionID string) error {
+	s.tickets = slices.DeleteFunc(s.tickets, func(ticket *entity.Ticket) bool { return ticket.Payment.SessionID == paymentSessionID })
+	return nil
+}
+
+type discardReporter struct{}
+
+func (discardReporter) Report(route string, err error) {}
+
+// placesStub answers every lookup of the Google Places API with a found place.
+type placesStub struct{}
+
+func (placesStub) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+}
+
+func setupEnv(t *testing.T) {
+	seed := base64.StdEncoding.EncodeToString(make([]byte, ed25519.SeedSize))
+	t.Setenv("API_URL", "http://localhost:8080")
+	t.Setenv("PAYMENT_SECRET_KEY", "test-payment-secret")
+	t.Setenv("GOOGLE_PLACES_API_KEY", "test-places-key")
+	t.Setenv("QR_SIGNING_KEYS", "test:"+seed)
+	t.Setenv("QR_SIGNING_KEYS_FILE", "")
+}
+
+func newTicketJSON(store *offlineStore) entity.NewTicketJSON {
+	address := entity.NewAddress{City: "Lviv", Street: "Shevchenka", HouseNumber: "1", GoogleMapsID: "test-place"}
+	return entity.NewTicketJSON{
+		ConnectionID:  store.connection.ID,
+		SeatIDs:       []uuid.UUID{store.connection.Bus.Seats[0].ID},
+		Passengers:    []entity.NewPassenger{{FirstName: "Taras", LastName: "Koval", Category: "Adult"}},
+		PickUpAdress:  address,
+		DropOffAdress: address,
+		Email:         "customer@example.com",
+		PhoneNumber:   "+380671234567",
+		FromStop:      store.segment.From.Sequence,
+		ToStop:        store.segment.To.Sequence,
+		PriceQuoteID:  store.quote.ID,
+		Currency:      string(entity.EUR),
+	}
+}
+
+// purchase books a ticket with a card through the fake provider and returns the session of its payment.
+func purchase(t *testing.T, store *offlineStore, payments *paymenttest.Fake) string {
+	t.Helper()
+
+	userID := store.quote.UserID
+	s := NewTicketService(store, &http.Client{Transport: placesStub{}}, payments, discardReporter{})
+
+	redirectURL, err := s.Purchase(context.Background(), userID, newTicketJSON(store), "en")
+	if err != nil {
+		t.Fatalf("Purchase() error = %v", err)
+	}
+
+	if len(store.tickets) != 1 || len(store.holds) != 1 {
+		t.Fatalf("Purchase() saved %d tickets and held %d seats, want 1 and 1", len(store.tickets), len(store.holds))
+	}
+
+	ticket := store.tickets[0]
+	if !strings.Contains(redirectURL, "/succeded/"+ticket.Payment.SessionID+"/") {
+		t.Errorf("Purchase() redirects to %s, not to the session %s", redirectURL, ticket.Payment.SessionID)
+	}
+
+	if ticket.Payment.Succeeded || ticket.Payment.Price != 2500 {
+		t.Errorf("Purchase() saved the payment %+v, want an unpaid one of 2500", ticket.Payment)
+	}
+
+	return ticket.Payment.SessionID
+}
+
+func TestPurchasePaidThroughWebhook(t *testing.T) {
+	setupEnv(t)
+
+	payments := paymenttest.NewFakeProvider()
+	store := newOfflineStore(uuid.New())
+	sessionID := purchase(t, store, payments)
+
+	payload, signature, err := payments.Complete(sessionID)
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+
+	webhooks := paymentService.NewPaymentService(store, payments, discardReporter{})
+	for range 2 {
+		err = webhooks.HandleWebhook(context.Background(), payload, signature)
+		if err != nil {
+			t.Fatalf("HandleWebhook() error = %v", err)
+		}
+	}
+
+	if !store.tickets[0].Payment.Succeeded {
+		t.Error("the ticket is not paid after the webhook of the payment")
+	}
+
+	paid, err := payments.SessionPaid(sessionID)
+	if err != nil || !paid {
+		t.Errorf("SessionPaid() = %v, %v, want the session paid", paid, err)
+	}
+
+	_, err = payments.Refund(sessionID, 2500, store.tickets[0].ID.String())
+	if err != nil {
+		t.Errorf("Refund() of the paid ticket error = %v", err)
+	}
+}
+
+func TestPurchaseExpiredThroughWebhook(t *testing.T) {
+	setupEnv(t)
+
+	payments := paymenttest.NewFakeProvider()
+	store := newOfflineStore(uuid.New())
+	sessionID := purchase(t, store, payments)
+
+	payload, signature, err := payments.Expire(sessionID)
+	if err != nil {
+		t.Fatalf("Expire() error = %v", err)
+	}
+
+	err = paymentService.NewPaymentService(store, payments, discardReporter{}).HandleWebhook(context.Background(), payload, signature)
+	if err != nil {
+		t.Fatalf("HandleWebhook() error = %v", err)
+	}
+
+	if len(store.tickets) != 0 {
+		t.Error("the unpaid ticket has been kept after its session expired")
+	}
+
+	_, err = payments.Refund(sessionID, 2500, "refund")
+	if err == nil {
+		t.Error("Refund() refunded a session that has never been paid")
+	}
+}
+
+func TestPurchaseForgedWebhookIsRejected(t *testing.T) {
+	setupEnv(t)
+
+	payments := paymenttest.NewFakeProvider()
+	store := newOfflineStore(uuid.New())
+	sessionID := purchase(t, store, payments)
+
+	payload, _, err := payments.Complete(sessionID)
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+
+	err = paymentService.NewPaymentService(store, payments, discardReporter{}).HandleWebhook(context.Background(), payload, "forged")
+	if err == nil {
+		t.Fatal("HandleWebhook() accepted a forged webhook")
+	}
+
+	if store.tickets[0].Payment.Succeeded {
+		t.Error("the ticket has been paid by a forged webhook")
+	}
+}
+
+func TestPurchaseFailedSaveCancelsSession(t *testing.T) {
+	setupEnv(t)
+
+	payments := paymenttest.NewFakeProvider()
+	store := newOfflineStore(uuid.New())
+	store.saveErr = errors.New("connection reset by peer")
+	s := NewTicketService(store, &http.Client{Transport: placesStub{}}, payments, discardReporter{})
+
+	_, err := s.Purchase(context.Background(), store.quote.UserID, newTicketJSON(store), "en")
+	if err == nil {
+		t.Fatal("Purchase() succeeded without saving the ticket")
+	}
+
+	// The session has been opened before the save, it can not be paid anymore.
+	_, _, err = payments.Complete("fake_cs_1")
+	if err == nil {
+		t.Error("the session of the unsaved ticket can still be paid")
+	}
+
+	if len(store.holds) != 1 || len(store.released) != 1 || store.released[0] != store.holds[0].TicketID {
+		t.Errorf("released the seats of %v, want the held seats of the ticket", store.released)
+	}
+}
+
+func TestPurchaseRejectsInvalidContact(t *testing.T) {
+	setupEnv(t)
+
+	store := newOfflineStore(uuid.New())
+	newTicket := newTicketJSON(store)
+	newTicket.Email = "not an email"
+
+	s := NewTicketService(store, &http.Client{Transport: placesStub{}}, paymenttest.NewFakeProvider(), discardReporter{})
+
+	_, err := s.Purchase(context.Background(), store.quote.UserID, newTicket, "en")
+	if err == nil {
+		t.Fatal("Purchase() accepted an invalid email")
+	}
+
+	if len(store.holds) != 0 || len(store.tickets) != 0 {
+		t.Error("the seats have been held for a checkout with an invalid contact")
+	}
+}
+
+func TestPurchaseThroughWaitlistOfferMovesItsSeats(t *testing.T) {
+	setupEnv(t)
+
+	store := newOfflineStore(uuid.New())
+	newTicket := newTicketJSON(store)
+
+	store.offer = entity.WaitlistEntry{
+		ID:             uuid.New(),
+		ConnectionID:   store.connection.ID,
+		UserID:         store.quote.UserID,
+		Passengers:     1,
+		FromStop:       store.segment.From.Sequence,
+		ToStop:         store.segment.To.Sequence,
+		Status:         entity.OfferedWaitlistStatus,
+		OfferExpiresAt: sql.NullTime{Time: time.Now().UTC().Add(time.Hour), Valid: true},
+	}
+	newTicket.WaitlistOfferID = store.offer.ID
+
+	s := NewTicketService(store, &http.Client{Transport: placesStub{}}, paymenttest.NewFakeProvider(), discardReporter{})
+
+	_, err := s.Purchase(context.Background(), store.quote.UserID, newTicket, "en")
+	if err != nil {
+		t.Fatalf("Purchase() error = %v", err)
+	}
+
+	// The seats held for the offer are not held again, they are moved to the ticket when it is saved.
+	if len(store.holds) != 0 {
+		t.Errorf("held %d seats apart from the offer, want 0", len(store.holds))
+	}
+
+	if len(store.purchases) != 1 || store.purchases[0].OfferID != store.offer.ID || store.purchases[0].TicketID != store.tickets[0].ID {
+		t.Fatalf("saved the waitlist purchases %+v, want the one of the offer", store.purchases)
+	}
+}
+
+func TestCancelRefundsLuggageOrdersAgainstTheirSessions(t *testing.T) {
+	setupEnv(t)
+
+	payments := paymenttest.NewFakeProvider()
+	store := newOfflineStore(uuid.New())
+	purchase(t, store, payments)
+	store.tickets[0].Payment.Succeeded = true
+
+	ticket := store.tickets[0]
+	store.orders = []entity.LuggageOrder{
+		{ID: uuid.New(), TicketID: ticket.ID, Price: 800, SessionID: "fake_cs_luggage", Status: entity.CompletedLuggageOrderStatus},
+		{ID: uuid.New(), TicketID: ticket.ID, Price: 800, SessionID: "fake_cs_expired", Status: entity.ExpiredLuggageOrderStatus},
+		{ID: uuid.New(), TicketID: ticket.ID, Status: entity.CompletedLuggageOrderStatus},
+	}
+
+	s := NewTicketService(store, http.DefaultClient, payments, discardReporter{})
+
+	refaunds, err := s.Cancel(context.Background(), ticket.UserID, ticket.ID.String())
+	if err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+
+	if len(refaunds) != 2 || len(store.refaunds) != 2 {
+		t.Fatalf("Cancel() returned %d refaunds and saved %d, want the ticket and the paid luggage", len(refaunds), len(store.refaunds))
+	}
+
+	// The refaund of the ticket falls back to the session of the ticket's payment.
+	if refaunds[0].TicketID != ticket.ID || refaunds[0].SessionID != "" {
+		t.Errorf("the ticket is refunded against %q, want the session of its payment", refaunds[0].SessionID)
+	}
+
+	if refaunds[1].SessionID != "fake_cs_luggage" || refaunds[1].AtOffice() {
+		t.Errorf("the luggage is refunded against %q, want its own session through the provider", refaunds[1].SessionID)
+	}
+}
+
+func TestCancelCollectedCashTicketIsRefundedAtOffice(t *testing.T) {
+	setupEnv(t)
+
+	store := newOfflineStore(uuid.New())
+	ticket := &entity.Ticket{
+		ID:           uuid.New(),
+		UserID:       store.quote.UserID,
+		ConnectionID: store.connection.ID,
+		FromStop:     store.segment.From.Sequence,
+		ToStop:       store.segment.To.Sequence,
+	}
+	ticket.Payment = entity.TicketPayment{TicketID: ticket.ID, Price: 2500, Method: entity.PaymentMethodCash, Succeeded: true, SessionID: entity.CashPaymentReference(ticket.ID)}
+	store.tickets = []*entity.Ticket{ticket}
+
+	s := NewTicketService(store, http.DefaultClient, paymenttest.NewFakeProvider(), discardReporter{})
+
+	refaunds, err := s.Cancel(context.Background(), ticket.UserID, ticket.ID.String())
+	if err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+
+	if len(refaunds) != 1 || !refaunds[0].AtOffice() {
+		t.Fatalf("Cancel() returned %+v, want one refaund at the office", refaunds)
+	}
+}
@@ -3,23 +3,46 @@ package http
 import (
 	"maryan_api/internal/domain/tickets/repo"
 	"maryan_api/internal/domain/tickets/service"
+	"maryan_api/internal/infrastructure/clients/payment"
 	"maryan_api/pkg/auth"
 	ginutil "maryan_api/pkg/ginutils"
+	"maryan_api/pkg/log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func RegisterRoutes(db *gorm.DB, s *gin.Engine, client *http.Client) {
+func RegisterRoutes(db *gorm.DB, s *gin.Engine, client *http.Client, payments payment.PaymentProvider) {
 	customerRouter := ginutil.CreateAuthRouter("/customer", auth.Customer.SecretKey(), s)
+	guestRouter := ginutil.CreateAuthRouter("/guest", auth.Guest.SecretKey(), s)
 
-	customerHandler := newHandler(service.NewTicketService(repo.NewTicketRepo(db), client))
+	customerHandler := newHandler(service.NewTicketService(repo.NewTicketRepo(db), client, payments, log.NewReporter(db)))
 
 	//-----------------------Ticket Routes---------------------------------------
 
+	customerRouter.POST("/connection/price-quote", customerHandler.quotePrice)
 	customerRouter.POST("/connection/purchase-ticket", customerHandler.purchase)
+	customerRouter.POST("/connection/purchase-round-trip", customerHandler.purchaseRoundTrip)
 	customerRouter.GET("/tickets", customerHandler.getTickets)
+	customerRouter.POST("/tickets/:id/cancel", customerHandler.cancel)
+	customerRouter.POST("/tickets/:id/rebook", customerHandler.rebook)
+	customerRouter.GET("/tickets/:id/exchanges", customerHandler.getExchanges)
+	customerRouter.GET("/tickets/:id/pdf", customerHandler.getPDF)
+	customerRouter.POST("/tickets/:id/luggage", customerHandler.addLuggage)
+	customerRouter.GET("/tickets/:id/luggage", customerHandler.getLuggageOrders)
+
+	guestRouter.POST("/connection/price-quote", customerHandler.quotePrice)
+	guestRouter.POST("/connection/purchase-ticket", customerHandler.purchase)
+	guestRouter.POST("/connection/purchase-round-trip", customerHandler.purchaseRoundTrip)
+	guestRouter.GET("/tickets", customerHandler.getTickets)
+	guestRouter.GET("/tickets/:id/pdf", customerHandler.getPDF)
+	guestRouter.POST("/tickets/:id/luggage", customerHandler.addLuggage)
+	guestRouter.GET("/tickets/:id/luggage", customerHandler.getLuggageOrders)
 	s.GET("/connection/purchase-ticket/failed/:id/:token", customerHandler.purchaseFailed)
 	s.GET("/connection/purchase-ticket/succeded/:id/:token", customerHandler.purchaseSucceded)
+	s.GET("/connection/rebook-ticket/failed/:id/:token", customerHandler.purchaseFailed)
+	s.GET("/connection/rebook-ticket/succeded/:id/:token", customerHandler.purchaseSucceded)
+	s.GET("/connection/ticket-luggage/failed/:id/:token", customerHandler.purchaseFailed)
+	s.GET("/connection/ticket-luggage/succeded/:id/:token", customerHandler.purchaseSucceded)
 }
@@ -8,6 +8,7 @@ import (
 	"maryan_api/pkg/dbutil"
 	ginutil "maryan_api/pkg/ginutils"
 	"maryan_api/pkg/hypermedia"
+	"maryan_api/pkg/languages"
 	rfc7807 "maryan_api/pkg/problem"
 	"net/http"
 	"time"
@@ -37,12 +38,20 @@ func (p *passengerHandler) purchase(ctx *gin.Context) {
 	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
 	defer cancel()
 
-	redirectURL, err := p.service.Purchase(ctxWithTimeout, ctx.MustGet("userID").(uuid.UUID), request)
+	redirectURL, err := p.service.Purchase(ctxWithTimeout, ctx.MustGet("userID").(uuid.UUID), request, ctx.MustGet("lang").(languages.Language).Code())
 	if err != nil {
 		ginutil.ServiceErrorAbort(ctx, err)
 		return
 	}
 
+	if redirectURL == "" {
+		ctx.JSON(http.StatusCreated, ginutil.Response{
+			"The seats have successfuly been booked, the fare is to be paid to the driver.",
+			hypermedia.Links{},
+		})
+		return
+	}
+
 	ctx.JSON(http.StatusOK, ginutil.Response{
 		"The purchase procces has started",
 		hypermedia.Links{
@@ -54,28 +63,38 @@ func (p *passengerHandler) purchase(ctx *gin.Context) {
 	})
 }
 
-func (p *passengerHandler) purchaseSucceded(ctx *gin.Context) {
-	var sessionID = ctx.Param("id")
-	if sessionID == "" {
-		ginutil.HandlerProblemAbort(ctx, rfc7807.Unauthorized("unauthorized", "Unauthorized Error", "Unauthorized."))
-		return
-	}
+func (p *passengerHandler) purchaseRoundTrip(ctx *gin.Context) {
+	var request entity.NewRoundTripJSON
 
-	var token = ctx.Param("token")
-	if token == "" {
-		ginutil.HandlerProblemAbort(ctx, rfc7807.Unauthorized("unauthorized", "Unauthorized Error", "Unauthorized."))
+	err := ctx.ShouldBindJSON(&request)
+	if err != nil {
+		ginutil.HandlerProblemAbort(ctx, rfc7807.JSON(err.Error()))
 		return
 	}
 
 	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
 	defer cancel()
 
-	err := p.service.PurchaseSucceded(ctxWithTimeout, sessionID, token)
+	redirectURL, err := p.service.PurchaseRoundTrip(ctxWithTimeout, ctx.MustGet("userID").(uuid.UUID), request, ctx.MustGet("lang").(languages.Language).Code())
 	if err != nil {
 		ginutil.ServiceErrorAbort(ctx, err)
 		return
 	}
 
+	ctx.JSON(http.StatusOK, ginutil.Response{
+		"The purchase procces has started",
+		hypermedia.Links{
+			{"redirect", hypermedia.LinkData{
+				Href:   redirectURL,
+				Method: "",
+			}},
+		},
+	})
+}
+
+// purchaseSucceded only brings the customer back to the frontend, the payment
+// itself is confirmed by the webhook of the payment provider.
+func (p *passengerHandler) purchaseSucceded(ctx *gin.Context) {
 	ctx.Redirect(http.StatusFound, config.FrontendURL()+"/profile/tickets")
 }
 
@@ -113,6 +132,190 @@ func (p *passengerHandler) getTickets(ctx *gin.Context) {
 
 }
 
+func (p *passengerHandler) cancel(ctx *gin.Context) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	refaunds, err := p.service.Cancel(ctxWithTimeout, ctx.MustGet("userID").(uuid.UUID), ctx.Param("id"))
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		ginutil.Response
+		Refaunds []entity.Refaund `json:"refaunds"`
+	}{
+		ginutil.Response{
+			"The ticket has successfuly been canceled.",
+			hypermedia.Links{},
+		},
+		refaunds,
+	})
+}
+
+func (p *passengerHandler) quotePrice(ctx *gin.Context) {
+	var request entity.PriceQuoteJSON
+
+	err := ctx.ShouldBindJSON(&request)
+	if err != nil {
+		ginutil.HandlerProblemAbort(ctx, rfc7807.JSON(err.Error()))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	quote, err := p.service.QuotePrice(ctxWithTimeout, ctx.MustGet("userID").(uuid.UUID), request)
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, struct {
+		ginutil.Response
+		PriceQuote entity.PriceQuote `json:"priceQuote"`
+	}{
+		ginutil.Response{
+			"The price has successfuly been quoted.",
+			hypermedia.Links{},
+		},
+		quote,
+	})
+}
+
+func (p *passengerHandler) rebook(ctx *gin.Context) {
+	var request entity.TicketExchangeJSON
+
+	err := ctx.ShouldBindJSON(&request)
+	if err != nil {
+		ginutil.HandlerProblemAbort(ctx, rfc7807.JSON(err.Error()))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	redirectURL, err := p.service.Rebook(ctxWithTimeout, ctx.MustGet("userID").(uuid.UUID), ctx.Param("id"), request)
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	if redirectURL == "" {
+		ctx.JSON(http.StatusOK, ginutil.Response{
+			"The ticket has successfuly been rebooked.",
+			hypermedia.Links{},
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, ginutil.Response{
+		"The rebooking procces has started",
+		hypermedia.Links{
+			{"redirect", hypermedia.LinkData{
+				Href:   redirectURL,
+				Method: "",
+			}},
+		},
+	})
+}
+
+func (p *passengerHandler) getExchanges(ctx *gin.Context) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	exchanges, err := p.service.GetExchanges(ctxWithTimeout, ctx.MustGet("userID").(uuid.UUID), ctx.Param("id"))
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		ginutil.Response
+		Exchanges []entity.TicketExchange `json:"exchanges"`
+	}{
+		ginutil.Response{
+			"The ticket exchanges have successfuly been found.",
+			hypermedia.Links{},
+		},
+		exchanges,
+	})
+}
+
+func (p *passengerHandler) addLuggage(ctx *gin.Context) {
+	var request entity.NewLuggageOrderJSON
+
+	err := ctx.ShouldBindJSON(&request)
+	if err != nil {
+		ginutil.HandlerProblemAbort(ctx, rfc7807.JSON(err.Error()))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	redirectURL, err := p.service.AddLuggage(ctxWithTimeout, ctx.MustGet("userID").(uuid.UUID), ctx.Param("id"), request)
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	if redirectURL == "" {
+		ctx.JSON(http.StatusCreated, ginutil.Response{
+			"The luggage has successfuly been added to the ticket.",
+			hypermedia.Links{},
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, ginutil.Response{
+		"The luggage payment procces has started",
+		hypermedia.Links{
+			{"redirect", hypermedia.LinkData{
+				Href:   redirectURL,
+				Method: "",
+			}},
+		},
+	})
+}
+
+func (p *passengerHandler) getLuggageOrders(ctx *gin.Context) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	orders, err := p.service.GetLuggageOrders(ctxWithTimeout, ctx.MustGet("userID").(uuid.UUID), ctx.Param("id"))
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		ginutil.Response
+		Orders []entity.LuggageOrder `json:"orders"`
+	}{
+		ginutil.Response{
+			"The luggage orders have successfuly been found.",
+			hypermedia.Links{},
+		},
+		orders,
+	})
+}
+
+func (p *passengerHandler) getPDF(ctx *gin.Context) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	pdf, err := p.service.GetPDF(ctxWithTimeout, ctx.MustGet("userID").(uuid.UUID), ctx.Param("id"), ctx.MustGet("lang").(languages.Language).Code())
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.Header("Content-Disposition", `inline; filename="boarding-pass.pdf"`)
+	ctx.Data(http.StatusOK, "application/pdf", pdf)
+}
+
 func (p *passengerHandler) purchaseFailed(ctx *gin.Context) {
 	var sessionID = ctx.Param("id")
 	if sessionID == "" {
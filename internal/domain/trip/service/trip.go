@@ -104,6 +104,11 @@ func (s *tripService) CreateTestTrips(ctx context.Context) error {
 		}
 	}
 
+	for _, trip := range trips {
+		trip.OutboundConnection.PrepareRoute()
+		trip.ReturnConnection.PrepareRoute()
+	}
+
 	return s.tripRepo.TestInsert(ctx, trips)
 }
 
@@ -29,12 +29,16 @@ type CustomerRepo interface {
 
 	UpdatePersonalInfo(ctx context.Context, firstName, lastName string, dateOfBirth time.Time, id uuid.UUID) error
 	UpdateContactInfo(ctx context.Context, email, phoneNumber string, id uuid.UUID) error
+
+	FindOrCreateGuest(ctx context.Context, guest *entity.Guest) error
+	ClaimGuestBookings(ctx context.Context, email, phoneNumber string, userID uuid.UUID) error
 }
 
 // MySQL implementation of CustomerRepo
 type customerRepo struct {
 	UserRepo
 	store dataStore.Customer
+	guest dataStore.Guest
 }
 
 func (cr *customerRepo) Create(ctx context.Context, u *entity.User) error {
@@ -81,10 +85,19 @@ func (cr *customerRepo) UpdateContactInfo(ctx context.Context, email, phoneNumbe
 	return cr.store.UpdateContantInfo(ctx, email, phoneNumber, id)
 }
 
+func (cr *customerRepo) FindOrCreateGuest(ctx context.Context, guest *entity.Guest) error {
+	return cr.guest.FindOrCreate(ctx, guest)
+}
+
+func (cr *customerRepo) ClaimGuestBookings(ctx context.Context, email, phoneNumber string, userID uuid.UUID) error {
+	return cr.guest.Claim(ctx, email, phoneNumber, userID)
+}
+
 // Constructor function
 func NewCustomerRepo(db *gorm.DB) CustomerRepo {
 	return &customerRepo{
 		UserRepo: NewUserRepo(db),
 		store:    dataStore.NewCustomer(db),
+		guest:    dataStore.NewGuest(db),
 	}
 }
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"maryan_api/config"
 	"maryan_api/internal/domain/user/repo"
 	"maryan_api/internal/entity"
@@ -10,6 +11,7 @@ import (
 	"maryan_api/internal/infrastructure/clients/verification"
 	"maryan_api/internal/valueobject"
 	"maryan_api/pkg/auth"
+	"maryan_api/pkg/log"
 	rfc7807 "maryan_api/pkg/problem"
 	"maryan_api/pkg/security"
 	"mime/multipart"
@@ -27,7 +29,7 @@ type CustomerService interface {
 	UserService
 
 	//----------Not authenticated------------------
-	Register(ctx context.Context, u entity.RegistrantionUser, image *multipart.FileHeader, saveImageFunc func(file *multipart.FileHeader, dst string) error, emailAccessToken string) (string, error)
+	Register(ctx context.Context, u entity.RegistrantionUser, image *multipart.FileHeader, saveImageFunc func(file *multipart.FileHeader, dst string) error, emailAccessToken, numberAccessToken string) (string, error)
 
 	VerifyEmailIfExists(ctx context.Context, email string) (string, bool, error)
 	VerifyEmailCode(ctx context.Context, code, token string) (string, error)
@@ -38,6 +40,7 @@ type CustomerService interface {
 	VerifyNumberCode(ctx context.Context, code, token string) (string, error)
 
 	GoogleOAUTH(ctx context.Context, code string) (string, bool, error)
+	Guest(ctx context.Context, emailAccessToken, numberAccessToken string) (string, error)
 	ChangePassword(ctx context.Context, newPassword, email, emailAccessToken string) error
 	//------------Authenticated--------------------
 	Delete(ctx context.Context, id uuid.UUID) error
@@ -49,8 +52,9 @@ type CustomerService interface {
 
 type customerServiceImpl struct {
 	UserService
-	repo   repo.CustomerRepo
-	client *http.Client
+	repo     repo.CustomerRepo
+	client   *http.Client
+	reporter log.Reporter
 }
 
 func (cs *customerServiceImpl) verifyEmailToken(token string, email string, secretKey []byte) error {
@@ -105,7 +109,7 @@ func (cs *customerServiceImpl) VerifyNumberToken(token string, number string) er
 	return nil
 }
 
-func (cs *customerServiceImpl) Register(ctx context.Context, ru entity.RegistrantionUser, image *multipart.FileHeader, saveImageFunc func(file *multipart.FileHeader, dst string) error, emailAccessToken string) (string, error) {
+func (cs *customerServiceImpl) Register(ctx context.Context, ru entity.RegistrantionUser, image *multipart.FileHeader, saveImageFunc func(file *multipart.FileHeader, dst string) error, emailAccessToken, numberAccessToken string) (string, error) {
 	u := ru.ToUser(cs.Role())
 	invalidParams := u.PrepareNew()
 
@@ -114,10 +118,15 @@ func (cs *customerServiceImpl) Register(ctx context.Context, ru entity.Registran
 		invalidParams.SetInvalidParam("EmailToken", err.Error())
 	}
 
-	// err = cs.VerifyNumberToken(numberAccessToken, u.PhoneNumber)
-	// if err != nil {
-	// 	invalidParams.SetInvalidParam("NumberToken", err.Error())
-	// }
+	// The number is optional to verify, a verified one claims the bookings made as a guest with it.
+	var verifiedNumber string
+	if numberAccessToken != "" {
+		err = cs.VerifyNumberToken(numberAccessToken, u.PhoneNumber)
+		if err != nil {
+			invalidParams.SetInvalidParam("NumberToken", err.Error())
+		}
+		verifiedNumber = u.PhoneNumber
+	}
 
 	if invalidParams != nil {
 		return "", rfc7807.BadRequest(
@@ -146,6 +155,8 @@ func (cs *customerServiceImpl) Register(ctx context.Context, ru entity.Registran
 		return "", err
 	}
 
+	cs.claimGuestBookings(ctx, u.Email, verifiedNumber, u.ID)
+
 	token, err := u.Role.Val.GenerateToken(u.Email, u.ID)
 	return token, err
 }
@@ -389,10 +400,53 @@ func (cs *customerServiceImpl) GoogleOAUTH(ctx context.Context, code string) (st
 		return "", false, err
 	}
 
+	// Only a verified email proves the bookings made with it belong to the account.
+	if credentials.EmailVerified {
+		cs.claimGuestBookings(ctx, user.Email, "", user.ID)
+	}
+
 	token, err := cs.Role().GenerateToken(user.Email, user.ID)
 	return token, false, err
 }
 
+func (cs *customerServiceImpl) Guest(ctx context.Context, emailAccessToken, numberAccessToken string) (string, error) {
+	var email, number string
+
+	switch {
+	case emailAccessToken != "":
+		claims, err := auth.VerifyAccessToken(emailAccessToken, config.EmailAccessTokenSecretKey(), auth.ClaimValidation{"email", true, auth.ClaimString})
+		if err != nil {
+			return "", rfc7807.Unauthorized("email-validation-acccess-token", "Email Access Token Error", "Invalid token.")
+		}
+		email = claims[0].(string)
+	case numberAccessToken != "":
+		claims, err := auth.VerifyAccessToken(numberAccessToken, config.NumberAccessTokenSecretKey(), auth.ClaimValidation{"number", true, auth.ClaimString})
+		if err != nil {
+			return "", rfc7807.Unauthorized("number-validation-acccess-token", "Number Access Token Error", "Invalid token.")
+		}
+		number = claims[0].(string)
+	default:
+		return "", rfc7807.BadRequest("missing-contact-verification", "Missing Contact Verification Error", "A verified email or phone number is required to continue as a guest.")
+	}
+
+	guest := entity.NewGuest(email, number)
+	err := cs.repo.FindOrCreateGuest(ctx, &guest)
+	if err != nil {
+		return "", err
+	}
+
+	return auth.Guest.GenerateToken(guest.Email, guest.ID)
+}
+
+// claimGuestBookings moves the bookings made as a guest to the new account, the email and the phone number
+// have to be verified by the caller. The account is already created at this point, so a failure is only reported.
+func (cs *customerServiceImpl) claimGuestBookings(ctx context.Context, verifiedEmail, verifiedNumber string, userID uuid.UUID) {
+	err := cs.repo.ClaimGuestBookings(ctx, verifiedEmail, verifiedNumber, userID)
+	if err != nil {
+		cs.reporter.Report("claim-guest-bookings", fmt.Errorf("user %s: %w", userID, err))
+	}
+}
+
 func (cs *customerServiceImpl) UpdatePersonalInfo(ctx context.Context, user entity.UserPersonalInfo, id uuid.UUID) error {
 	params := user.Validate()
 
@@ -429,10 +483,11 @@ func (cs *customerServiceImpl) UpdateContactInfo(ctx context.Context, id uuid.UU
 
 }
 
-func NewCustomerServiceImpl(repo repo.CustomerRepo, client *http.Client) CustomerService {
+func NewCustomerServiceImpl(repo repo.CustomerRepo, client *http.Client, reporter log.Reporter) CustomerService {
 	return &customerServiceImpl{
 		UserService: NewUserService(auth.Customer, repo),
 		repo:        repo,
 		client:      client,
+		reporter:    reporter,
 	}
 }
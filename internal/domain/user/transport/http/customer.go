@@ -306,7 +306,8 @@ func (ch *customerHandler) register(ctx *gin.Context) {
 	// }
 
 	type Headers struct {
-		EmailToken string `header:"X-Email-Access-Token"`
+		EmailToken  string `header:"X-Email-Access-Token"`
+		NumberToken string `header:"X-Number-Access-Token"`
 	}
 
 	var headers Headers
@@ -318,7 +319,7 @@ func (ch *customerHandler) register(ctx *gin.Context) {
 	ctxWithTimeout, cancel := ginutil.ContextWithTimeout(ctx, time.Second*20)
 	defer cancel()
 
-	token, err := ch.service.Register(ctxWithTimeout, user, image, ctx.SaveUploadedFile, headers.EmailToken)
+	token, err := ch.service.Register(ctxWithTimeout, user, image, ctx.SaveUploadedFile, headers.EmailToken, headers.NumberToken)
 	if err != nil {
 		ginutil.ServiceErrorAbort(ctx, err)
 		return
@@ -338,6 +339,41 @@ func (ch *customerHandler) register(ctx *gin.Context) {
 	})
 }
 
+func (ch *customerHandler) guest(ctx *gin.Context) {
+	type Headers struct {
+		EmailToken  string `header:"X-Email-Access-Token"`
+		NumberToken string `header:"X-Number-Access-Token"`
+	}
+
+	var headers Headers
+	if err := ctx.ShouldBindHeader(&headers); err != nil {
+		ginutil.HandlerProblemAbort(ctx, rfc7807.BadRequest("headers-parsing-error", "Headers Error", err.Error()))
+		return
+	}
+
+	ctxWithTimeout, cancel := ginutil.ContextWithTimeout(ctx, time.Second*20)
+	defer cancel()
+
+	token, err := ch.service.Guest(ctxWithTimeout, headers.EmailToken, headers.NumberToken)
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		ginutil.Response
+		Token string `json:"token"`
+	}{
+		ginutil.Response{
+			Message: "The guest session has successfully been started.",
+			Links: hypermedia.Links{
+				registerUserLink,
+			},
+		},
+		token,
+	})
+}
+
 func (ch *customerHandler) changePassword(ctx *gin.Context) {
 
 	var request struct {
@@ -6,6 +6,7 @@ import (
 	"maryan_api/pkg/auth"
 	ginutil "maryan_api/pkg/ginutils"
 	"maryan_api/pkg/hypermedia"
+	"maryan_api/pkg/log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -27,7 +28,7 @@ type Driver struct {
 func RegisterRoutes(db *gorm.DB, s *gin.Engine, client *http.Client) {
 
 	//CUSTOMER ROUTES
-	customer := Customer{newcustomerHandler(service.NewCustomerServiceImpl(repo.NewCustomerRepo(db), client))}
+	customer := Customer{newcustomerHandler(service.NewCustomerServiceImpl(repo.NewCustomerRepo(db), client, log.NewReporter(db)))}
 	authCustomerRouter := ginutil.CreateAuthRouter("/customer", customer.customerHandler.service.SecretKey(), s)
 	customerRouter := s.Group("/customer")
 
@@ -42,6 +43,7 @@ func RegisterRoutes(db *gorm.DB, s *gin.Engine, client *http.Client) {
 
 	customerRouter.POST("/login", customer.customerHandler.login)
 	customerRouter.POST("/google-oauth", customer.customerHandler.googleOAUTH)
+	customerRouter.POST("/guest", customer.customerHandler.guest)
 
 	authCustomerRouter.POST("/login-jwt", customer.customerHandler.loginJWT)
 	authCustomerRouter.GET("", customer.customerHandler.get)
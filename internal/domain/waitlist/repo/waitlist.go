@@ -0,0 +1,68 @@
+package repo
+
+import (
+	"context"
+	"maryan_api/internal/entity"
+	dataStore "maryan_api/internal/infrastructure/persistence"
+	"time"
+
+	"github.com/d3code/uuid"
+	"gorm.io/gorm"
+)
+
+type Waitlist interface {
+	GetConnectionSegment(ctx context.Context, id uuid.UUID, fromStop, toStop int) (entity.Connection, entity.Segment, []uuid.UUID, error)
+	Create(ctx context.Context, entry *entity.WaitlistEntry) error
+	GetByUser(ctx context.Context, userID uuid.UUID) ([]entity.WaitlistEntry, error)
+	IsQueued(ctx context.Context, connectionID, userID uuid.UUID) (bool, error)
+	Leave(ctx context.Context, id, userID uuid.UUID) error
+	GetQueues(ctx context.Context, departingAfter time.Time) ([]entity.WaitlistEntry, error)
+	ExpireOffers(ctx context.Context, now time.Time) error
+	Offer(ctx context.Context, id uuid.UUID, holds []entity.SeatHold, expiresAt time.Time) error
+	GetDepths(ctx context.Context) ([]entity.WaitlistDepth, error)
+}
+
+type waitlistRepo struct {
+	waitlist   dataStore.Waitlist
+	connection dataStore.Connection
+}
+
+func (r *waitlistRepo) GetConnectionSegment(ctx context.Context, id uuid.UUID, fromStop, toStop int) (entity.Connection, entity.Segment, []uuid.UUID, error) {
+	return r.connection.GetSegmentByID(ctx, id, 0, fromStop, toStop)
+}
+
+func (r *waitlistRepo) Create(ctx context.Context, entry *entity.WaitlistEntry) error {
+	return r.waitlist.Create(ctx, entry)
+}
+
+func (r *waitlistRepo) GetByUser(ctx context.Context, userID uuid.UUID) ([]entity.WaitlistEntry, error) {
+	return r.waitlist.GetByUser(ctx, userID)
+}
+
+func (r *waitlistRepo) IsQueued(ctx context.Context, connectionID, userID uuid.UUID) (bool, error) {
+	return r.waitlist.IsQueued(ctx, connectionID, userID)
+}
+
+func (r *waitlistRepo) Leave(ctx context.Context, id, userID uuid.UUID) error {
+	return r.waitlist.Leave(ctx, id, userID)
+}
+
+func (r *waitlistRepo) GetQueues(ctx context.Context, departingAfter time.Time) ([]entity.WaitlistEntry, error) {
+	return r.waitlist.GetQueues(ctx, departingAfter)
+}
+
+func (r *waitlistRepo) ExpireOffers(ctx context.Context, now time.Time) error {
+	return r.waitlist.ExpireOffers(ctx, now)
+}
+
+func (r *waitlistRepo) Offer(ctx context.Context, id uuid.UUID, holds []entity.SeatHold, expiresAt time.Time) error {
+	return r.waitlist.Offer(ctx, id, holds, expiresAt)
+}
+
+func (r *waitlistRepo) GetDepths(ctx context.Context) ([]entity.WaitlistDepth, error) {
+	return r.waitlist.GetDepths(ctx)
+}
+
+func NewWaitlistRepo(db *gorm.DB) Waitlist {
+	return &waitlistRepo{dataStore.NewWaitlist(db), dataStore.NewConnection(db)}
+}
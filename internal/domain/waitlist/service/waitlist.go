@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"maryan_api/config"
+	"maryan_api/internal/domain/waitlist/repo"
+	"maryan_api/internal/entity"
+	"maryan_api/internal/infrastructure/clients/email"
+	"maryan_api/pkg/log"
+	rfc7807 "maryan_api/pkg/problem"
+	"net/http"
+	"time"
+
+	"github.com/d3code/uuid"
+)
+
+type Waitlist interface {
+	Join(ctx context.Context, userID uuid.UUID, connectionIDStr string, newEntry entity.NewWaitlistEntryJSON, lang string) (entity.WaitlistEntry, error)
+	GetEntries(ctx context.Context, userID uuid.UUID) ([]entity.WaitlistEntry, error)
+	Leave(ctx context.Context, userID uuid.UUID, idStr string) error
+	GetDepths(ctx context.Context) ([]entity.WaitlistDepth, error)
+	OfferFreedSeats(ctx context.Context) (int, error)
+	Run(ctx context.Context, interval time.Duration)
+}
+
+type serviceImpl struct {
+	repo     repo.Waitlist
+	reporter log.Reporter
+}
+
+// Join queues the customer for the segment, only when there are not enough free seats on it.
+func (s *serviceImpl) Join(ctx context.Context, userID uuid.UUID, connectionIDStr string, newEntry entity.NewWaitlistEntryJSON, lang string) (entity.WaitlistEntry, error) {
+	connectionID, err := uuid.Parse(connectionIDStr)
+	if err != nil {
+		return entity.WaitlistEntry{}, rfc7807.BadRequest("invalid-id", "Invalid ID Error", "Provided connection id is not a valid UUID.")
+	}
+
+	connection, _, takenSeats, err := s.repo.GetConnectionSegment(ctx, connectionID, newEntry.FromStop, newEntry.ToStop)
+	if err != nil {
+		return entity.WaitlistEntry{}, err
+	}
+
+	entry, err := newEntry.Parse(connection, userID, lang)
+	if err != nil {
+		return entity.WaitlistEntry{}, err
+	}
+
+	if len(connection.FreeSeats(takenSeats)) >= entry.Passengers {
+		return entity.WaitlistEntry{}, rfc7807.BadRequest("available-seats", "Available Seats Error", "There are enough free seats on the connection, the ticket can be bought right away.")
+	}
+
+	queued, err := s.repo.IsQueued(ctx, connectionID, userID)
+	if err != nil {
+		return entity.WaitlistEntry{}, err
+	}
+
+	if queued {
+		return entity.WaitlistEntry{}, rfc7807.New(http.StatusConflict, "queued-waitlist", "Queued Waitlist Error", "You are already on the waitlist of the connection.")
+	}
+
+	return entry, s.repo.Create(ctx, &entry)
+}
+
+func (s *serviceImpl) GetEntries(ctx context.Context, userID uuid.UUID) ([]entity.WaitlistEntry, error) {
+	return s.repo.GetByUser(ctx, userID)
+}
+
+func (s *serviceImpl) Leave(ctx context.Context, userID uuid.UUID, idStr string) error {
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return rfc7807.BadRequest("invalid-id", "Invalid ID Error", err.Error())
+	}
+
+	return s.repo.Leave(ctx, id, userID)
+}
+
+func (s *serviceImpl) GetDepths(ctx context.Context) ([]entity.WaitlistDepth, error) {
+	return s.repo.GetDepths(ctx)
+}
+
+// OfferFreedSeats goes through every queue in the order the customers have joined it and offers
+// the free seats to the waiting entries. A queue stops at the first entry that does not fit,
+// so the ones behind it can not jump the queue.
+func (s *serviceImpl) OfferFreedSeats(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	err := s.repo.ExpireOffers(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	entries, err := s.repo.GetQueues(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	var offered int
+	var stopped uuid.UUID
+	for _, entry := range entries {
+		if entry.Status != entity.WaitingWaitlistStatus || entry.ConnectionID == stopped {
+			continue
+		}
+
+		connection, segment, takenSeats, err := s.repo.GetConnectionSegment(ctx, entry.ConnectionID, entry.FromStop, entry.ToStop)
+		if err != nil {
+			return offered, err
+		}
+
+		seats := connection.FreeSeats(takenSeats)
+		if len(seats) < entry.Passengers {
+			stopped = entry.ConnectionID
+			continue
+		}
+
+		expiresAt := now.Add(config.WaitlistOfferDuration)
+		err = s.repo.Offer(ctx, entry.ID, entity.NewSeatHolds(connection.ID, entry.ID, seats[:entry.Passengers], segment, expiresAt), expiresAt)
+		if err != nil {
+			// The seats have just been taken by someone else, the queue waits for the next run.
+			stopped = entry.ConnectionID
+			continue
+		}
+
+		offered++
+		go s.sendOffer(entry, expiresAt)
+	}
+
+	return offered, nil
+}
+
+// Run offers the freed seats every interval until the context is done, the failed runs are reported.
+func (s *serviceImpl) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			offerCtx, cancel := context.WithTimeout(ctx, interval)
+			_, err := s.OfferFreedSeats(offerCtx)
+			cancel()
+
+			if err != nil {
+				s.reporter.Report("waitlist-offers", err)
+			}
+		}
+	}
+}
+
+// sendOffer emails the link of the offer to the customer. The seats stay held for the offer anyway,
+// so a failure is only reported.
+func (s *serviceImpl) sendOffer(entry entity.WaitlistEntry, expiresAt time.Time) {
+	link := config.FrontendURL() + "/waitlist/" + entry.ID.String()
+	subject, body := offerEmail(entry.Language, link, expiresAt)
+
+	err := email.Send(entry.Email, subject, body)
+	if err != nil {
+		s.reporter.Report("waitlist-offers", fmt.Errorf("waitlist entry %s: %w", entry.ID, err))
+	}
+}
+
+func offerEmail(lang, link string, expiresAt time.Time) (subject, body string) {
+	until := expiresAt.Format("15:04 MST")
+	if lang == "uk" {
+		return "Місця звільнилися", "Місця на рейс, на який ви чекали, заброньовано для вас до " + until + ". Придбайте квиток за посиланням: " + link
+	}
+	return "Seats are available", "The seats on the connection you have been waiting for are reserved for you until " + until + ". Buy the ticket here: " + link
+}
+
+func NewWaitlistService(repo repo.Waitlist, reporter log.Reporter) Waitlist {
+	return &serviceImpl{
+		repo,
+		reporter,
+	}
+}
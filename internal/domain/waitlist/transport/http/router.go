@@ -0,0 +1,39 @@
+package http
+
+import (
+	"context"
+	"maryan_api/config"
+	"maryan_api/internal/domain/waitlist/repo"
+	"maryan_api/internal/domain/waitlist/service"
+	"maryan_api/pkg/auth"
+	ginutil "maryan_api/pkg/ginutils"
+	"maryan_api/pkg/log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func RegisterRoutes(db *gorm.DB, s *gin.Engine, client *http.Client) {
+	customerRouter := ginutil.CreateAuthRouter("/customer", auth.Customer.SecretKey(), s)
+	guestRouter := ginutil.CreateAuthRouter("/guest", auth.Guest.SecretKey(), s)
+	adminRouter := ginutil.CreateAuthRouter("/admin", auth.Admin.SecretKey(), s)
+
+	handler := newWaitlistHandler(service.NewWaitlistService(repo.NewWaitlistRepo(db), log.NewReporter(db)))
+
+	//-----------------------Waitlist Routes---------------------------------------
+
+	customerRouter.POST("/connection/:id/waitlist", handler.join)
+	customerRouter.GET("/waitlist", handler.getEntries)
+	customerRouter.DELETE("/waitlist/:id", handler.leave)
+	guestRouter.POST("/connection/:id/waitlist", handler.join)
+	guestRouter.GET("/waitlist", handler.getEntries)
+	guestRouter.DELETE("/waitlist/:id", handler.leave)
+
+	adminRouter.GET("/waitlist", handler.getDepths)
+}
+
+// Run offers the freed seats to the waitlists until the context is done.
+func Run(ctx context.Context, db *gorm.DB) {
+	service.NewWaitlistService(repo.NewWaitlistRepo(db), log.NewReporter(db)).Run(ctx, config.WaitlistOfferInterval)
+}
@@ -0,0 +1,114 @@
+package http
+
+import (
+	"context"
+	"maryan_api/internal/domain/waitlist/service"
+	"maryan_api/internal/entity"
+	ginutil "maryan_api/pkg/ginutils"
+	"maryan_api/pkg/hypermedia"
+	"maryan_api/pkg/languages"
+	rfc7807 "maryan_api/pkg/problem"
+	"net/http"
+	"time"
+
+	"github.com/d3code/uuid"
+	"github.com/gin-gonic/gin"
+)
+
+type waitlistHandler struct {
+	service service.Waitlist
+}
+
+func newWaitlistHandler(service service.Waitlist) *waitlistHandler {
+	return &waitlistHandler{service}
+}
+
+func (w *waitlistHandler) join(ctx *gin.Context) {
+	var request entity.NewWaitlistEntryJSON
+
+	err := ctx.ShouldBindJSON(&request)
+	if err != nil {
+		ginutil.HandlerProblemAbort(ctx, rfc7807.JSON(err.Error()))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	entry, err := w.service.Join(ctxWithTimeout, ctx.MustGet("userID").(uuid.UUID), ctx.Param("id"), request, ctx.MustGet("lang").(languages.Language).Code())
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, struct {
+		ginutil.Response
+		Entry entity.WaitlistEntry `json:"entry"`
+	}{
+		ginutil.Response{
+			"You have successfuly joined the waitlist.",
+			hypermedia.Links{},
+		},
+		entry,
+	})
+}
+
+func (w *waitlistHandler) getEntries(ctx *gin.Context) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	entries, err := w.service.GetEntries(ctxWithTimeout, ctx.MustGet("userID").(uuid.UUID))
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		ginutil.Response
+		Entries []entity.WaitlistEntry `json:"entries"`
+	}{
+		ginutil.Response{
+			"The waitlist entries have successfuly been found.",
+			hypermedia.Links{},
+		},
+		entries,
+	})
+}
+
+func (w *waitlistHandler) leave(ctx *gin.Context) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	err := w.service.Leave(ctxWithTimeout, ctx.MustGet("userID").(uuid.UUID), ctx.Param("id"))
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, ginutil.Response{
+		"You have successfuly left the waitlist.",
+		hypermedia.Links{},
+	})
+}
+
+func (w *waitlistHandler) getDepths(ctx *gin.Context) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
+	defer cancel()
+
+	depths, err := w.service.GetDepths(ctxWithTimeout)
+	if err != nil {
+		ginutil.ServiceErrorAbort(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		ginutil.Response
+		Depths []entity.WaitlistDepth `json:"depths"`
+	}{
+		ginutil.Response{
+			"The waitlist depths have successfuly been found.",
+			hypermedia.Links{},
+		},
+		depths,
+	})
+}
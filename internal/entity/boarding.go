@@ -0,0 +1,116 @@
+package entity
+
+import (
+	rfc7807 "maryan_api/pkg/problem"
+	"maryan_api/pkg/security"
+	"time"
+
+	"github.com/d3code/uuid"
+	"github.com/skip2/go-qrcode"
+)
+
+// qrCodeValidity is how long after the arrival of the connection its codes stay valid.
+const qrCodeValidity = time.Hour * 12
+
+const (
+	ticketQRType = "T"
+	parcelQRType = "P"
+)
+
+type ScanJSON struct {
+	Code string `json:"code"`
+}
+
+// ParseCode verifies the signature of the scanned code and returns its claims.
+func (s ScanJSON) ParseCode() (security.QRClaims, error) {
+	claims, err := security.VerifyQR(s.Code)
+	if err != nil {
+		return security.QRClaims{}, rfc7807.BadRequest("invalid-code", "Invalid Code Error", "The scanned code is not valid: "+err.Error())
+	}
+	return claims, nil
+}
+
+func TicketQRCode(ticketID uuid.UUID, connection Connection, seats []TicketSeat) ([]byte, error) {
+	return encodeQRCode(security.QRClaims{
+		Type:         ticketQRType,
+		ID:           ticketID,
+		ConnectionID: connection.ID,
+		Seats:        seatNumbers(connection.Bus, seats),
+		ExpiresAt:    connection.ArrivalTime.Add(qrCodeValidity).Unix(),
+	})
+}
+
+func seatNumbers(bus Bus, seats []TicketSeat) []int {
+	var numbers = make([]int, 0, len(seats))
+	for _, ticketSeat := range seats {
+		for _, seat := range bus.Seats {
+			if seat.ID == ticketSeat.SeatID {
+				numbers = append(numbers, seat.Number)
+			}
+		}
+	}
+	return numbers
+}
+
+func ParcelQRCode(parcelID uuid.UUID, connection Connection) ([]byte, error) {
+	return encodeQRCode(security.QRClaims{
+		Type:         parcelQRType,
+		ID:           parcelID,
+		ConnectionID: connection.ID,
+		ExpiresAt:    connection.ArrivalTime.Add(qrCodeValidity).Unix(),
+	})
+}
+
+func encodeQRCode(claims security.QRClaims) ([]byte, error) {
+	token, err := security.SignQR(claims)
+	if err != nil {
+		return nil, rfc7807.Internal("QR-Code Signing Error", err.Error())
+	}
+
+	qrCode, err := qrcode.Encode(token, qrcode.Medium, 256)
+	if err != nil {
+		return nil, rfc7807.Internal("QR-Code Encoding Error", err.Error())
+	}
+
+	return qrCode, nil
+}
+
+type ScannedStop struct {
+	StopID        uuid.UUID        `json:"stopId"`
+	Type          stopType         `json:"type"`
+	LocationType  stopLocationType `json:"locationType"`
+	Passengers    []Passenger      `json:"passengers,omitempty"`
+	Seats         []TicketSeat     `json:"seats,omitempty"`
+	LuggageVolume uint             `json:"luggageVolume"`
+	Luggage       []TicketLuggage  `json:"luggage,omitempty"`
+	Parcel        *Parcel          `json:"parcel,omitempty"`
+}
+
+func (s Stop) Scanned() ScannedStop {
+	scanned := ScannedStop{
+		StopID:       s.ID,
+		Type:         s.Type,
+		LocationType: s.LocationType,
+	}
+
+	if s.Type == ParcelStopType {
+		scanned.Parcel = &s.Parcel
+		scanned.LuggageVolume = s.Parcel.LuggageVolume
+		return scanned
+	}
+
+	scanned.Passengers = s.Ticket.Passengers
+	scanned.Seats = s.Ticket.Seats
+	scanned.LuggageVolume = uint(s.Ticket.LuggageVolume)
+	scanned.Luggage = s.Ticket.Luggage
+	return scanned
+}
+
+func (s Stop) HasStatus(status stopStatus) bool {
+	for _, update := range s.Updates {
+		if update.Status == status {
+			return true
+		}
+	}
+	return false
+}
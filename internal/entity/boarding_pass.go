@@ -0,0 +1,156 @@
+package entity
+
+import (
+	"fmt"
+	"maryan_api/config"
+	"maryan_api/pkg/pdf"
+	rfc7807 "maryan_api/pkg/problem"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var boardingPassFont = sync.OnceValues(func() ([]byte, error) {
+	return os.ReadFile(config.PDFFontPath())
+})
+
+type boardingPassText struct {
+	title, ticket, route, departure, pickUp, dropOff, passengers, seats, luggage, luggageNote, qrNote string
+}
+
+var boardingPassTexts = map[string]boardingPassText{
+	"en": {
+		title:       "Boarding pass",
+		ticket:      "Ticket",
+		route:       "Route",
+		departure:   "Departure (local time)",
+		pickUp:      "Pick-up",
+		dropOff:     "Drop-off",
+		passengers:  "Passengers",
+		seats:       "Seats",
+		luggage:     "Luggage",
+		luggageNote: "One backpack and one large suitcase per passenger are included in the fare.",
+		qrNote:      "Show this code to the driver when boarding.",
+	},
+	"uk": {
+		title:       "Посадковий талон",
+		ticket:      "Квиток",
+		route:       "Маршрут",
+		departure:   "Відправлення (місцевий час)",
+		pickUp:      "Посадка",
+		dropOff:     "Висадка",
+		passengers:  "Пасажири",
+		seats:       "Місця",
+		luggage:     "Багаж",
+		luggageNote: "Один рюкзак і одна велика валіза на пасажира входять у вартість квитка.",
+		qrNote:      "Покажіть цей код водієві під час посадки.",
+	},
+}
+
+const (
+	boardingPassMargin   = 50
+	boardingPassQRSize   = 170
+	boardingPassTextSize = 11
+)
+
+// BoardingPass renders the ticket as a PDF in the given language (falls back to english).
+// The connection must have its bus seats and countries preloaded.
+func (t Ticket) BoardingPass(connection Connection, lang string) ([]byte, error) {
+	text, ok := boardingPassTexts[lang]
+	if !ok {
+		text = boardingPassTexts["en"]
+	}
+
+	fontData, err := boardingPassFont()
+	if err != nil {
+		return nil, rfc7807.Internal("PDF Font Error", err.Error())
+	}
+
+	doc, err := pdf.New(fontData)
+	if err != nil {
+		return nil, rfc7807.Internal("PDF Font Error", err.Error())
+	}
+
+	page := doc.AddPage()
+	y := float64(boardingPassMargin + 20)
+	page.Text(boardingPassMargin, y, 22, text.title)
+	y += 14
+	page.Line(boardingPassMargin, y, pdf.PageWidth-boardingPassMargin, y, 1)
+
+	err = page.Image(t.QRCode, pdf.PageWidth-boardingPassMargin-boardingPassQRSize, y+10, boardingPassQRSize, boardingPassQRSize)
+	if err != nil {
+		return nil, rfc7807.Internal("PDF QR-Code Error", err.Error())
+	}
+	for i, line := range wrapText(doc, text.qrNote, 8, boardingPassQRSize) {
+		page.Text(pdf.PageWidth-boardingPassMargin-boardingPassQRSize, y+boardingPassQRSize+22+float64(i*10), 8, line)
+	}
+
+	var passengers = make([]string, len(t.Passengers))
+	for i, passenger := range t.Passengers {
+		passengers[i] = passenger.FirstName + " " + passenger.LastName
+	}
+
+	segment, err := connection.Segment(t.FromStop, t.ToStop)
+	if err != nil {
+		segment = connection.FullRoute()
+	}
+
+	var seats []string
+	for _, number := range seatNumbers(connection.Bus, t.Seats) {
+		seats = append(seats, strconv.Itoa(number))
+	}
+
+	fields := []struct {
+		label string
+		value []string
+	}{
+		{text.ticket, []string{t.ID.String()}},
+		{text.route, []string{segment.From.Name() + " — " + segment.To.Name()}},
+		{text.departure, []string{config.MustParseToLocalByUUID(segment.From.DepartureTime, segment.From.CountryID).Format("02.01.2006 15:04 MST")}},
+		{text.pickUp, []string{t.PickUpAdress.FormatedAdress}},
+		{text.dropOff, []string{t.DropOffAdress.FormatedAdress}},
+		{text.passengers, passengers},
+		{text.seats, []string{strings.Join(seats, ", ")}},
+		{text.luggage, []string{fmt.Sprintf("%d L", t.LuggageVolume/1000), text.luggageNote}},
+	}
+
+	width := pdf.PageWidth - 2*boardingPassMargin - boardingPassQRSize - 20
+	y += 30
+	for _, field := range fields {
+		page.Text(boardingPassMargin, y, 9, strings.ToUpper(field.label))
+		y += 15
+		for _, value := range field.value {
+			for _, line := range wrapText(doc, value, boardingPassTextSize, width) {
+				page.Text(boardingPassMargin, y, boardingPassTextSize, line)
+				y += 15
+			}
+		}
+		y += 8
+	}
+
+	pdfData, err := doc.Bytes()
+	if err != nil {
+		return nil, rfc7807.Internal("PDF Rendering Error", err.Error())
+	}
+
+	return pdfData, nil
+}
+
+func wrapText(doc *pdf.Document, text string, size, width float64) []string {
+	var lines []string
+	var line string
+	for _, word := range strings.Fields(text) {
+		if line != "" && doc.TextWidth(line+" "+word, size) > width {
+			lines = append(lines, line)
+			line = word
+			continue
+		}
+
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	return append(lines, line)
+}
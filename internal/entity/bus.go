@@ -31,6 +31,10 @@ type Bus struct {
 	MaxWidth           uint           `gorm:"type:SMALLINT UNSIGNED;not null"`
 	MaxHeight          uint           `gorm:"type:SMALLINT UNSIGNED;not null"`
 	MaxLength          uint           `gorm:"type:INT UNSIGNED;not null"`
+	// ParcelWeightCapacity is the weight of the parcels in grams the bus can carry.
+	ParcelWeightCapacity int                 `gorm:"type:MEDIUMINT UNSIGNED;not null;default:500000"`
+	SeatTypeSurcharges   []SeatTypeSurcharge `gorm:"foreignKey:BusID"`
+	SeatSurcharges       []SeatSurcharge     `gorm:"foreignKey:BusID"`
 }
 
 //
@@ -243,6 +247,8 @@ func MigrateBus(db *gorm.DB) error {
 		&Row{},
 		&SeatPosition{},
 		&BusImage{},
+		&SeatTypeSurcharge{},
+		&SeatSurcharge{},
 	)
 }
 
@@ -256,7 +262,9 @@ type CustomerBus struct {
 
 type ResponseCustomerSeat struct {
 	ResponseSeat
-	Taken bool `json:"taken"`
+	Taken     bool `json:"taken"`
+	Surcharge int  `json:"surcharge"`
+	Price     int  `json:"price"`
 }
 
 type ResponseSeat struct {
@@ -266,7 +274,8 @@ type ResponseSeat struct {
 	Direction string    `json:"direction"`
 }
 
-func (b Bus) ToCustomerBus(takenSeatsIDs []uuid.UUID) CustomerBus {
+// ToCustomerBus returns the seat map of the bus, the price of every seat being the fare with its surcharge.
+func (b Bus) ToCustomerBus(takenSeatsIDs []uuid.UUID, fare int) CustomerBus {
 	var imageUrls = make([]string, len(b.Images))
 	for i, image := range b.Images {
 		imageUrls[i] = image.Url
@@ -277,11 +286,11 @@ func (b Bus) ToCustomerBus(takenSeatsIDs []uuid.UUID) CustomerBus {
 		Images:             imageUrls,
 		RegistrationNumber: b.RegistrationNumber,
 		Year:               b.Year,
-		Structure:          b.responseCustomerStructure(takenSeatsIDs),
+		Structure:          b.responseCustomerStructure(takenSeatsIDs, fare),
 	}
 }
 
-func (b Bus) responseCustomerStructure(takenSeatsIDs []uuid.UUID) [][]ResponseCustomerSeat {
+func (b Bus) responseCustomerStructure(takenSeatsIDs []uuid.UUID, fare int) [][]ResponseCustomerSeat {
 	var structure = make([][]ResponseCustomerSeat, len(b.Structure))
 
 	for _, row := range b.Structure {
@@ -297,12 +306,15 @@ func (b Bus) responseCustomerStructure(takenSeatsIDs []uuid.UUID) [][]ResponseCu
 					return seat.Number == rowObject.SeatNumber
 				})
 
+				surcharge := b.SeatSurcharge(b.Seats[seatIndex].ID)
 				structure[row.Number][rowObject.Position] = ResponseCustomerSeat{
 					ResponseSeat: ResponseSeat{Type: string(b.Seats[seatIndex].Type),
 						Number:    b.Seats[seatIndex].Number,
 						ID:        b.Seats[seatIndex].ID,
 						Direction: string(b.Seats[seatIndex].Direction)},
-					Taken: slices.ContainsFunc(takenSeatsIDs, func(id uuid.UUID) bool { return id == b.Seats[seatIndex].ID }),
+					Taken:     slices.ContainsFunc(takenSeatsIDs, func(id uuid.UUID) bool { return id == b.Seats[seatIndex].ID }),
+					Surcharge: surcharge,
+					Price:     fare + surcharge,
 				}
 			}
 
@@ -341,18 +353,20 @@ func (b Bus) responseStructure() [][]ResponseSeat {
 }
 
 type EmployeeBus struct {
-	ID                 uuid.UUID        `json:"id"`
-	Model              string           `json:"model"`
-	ImageUrls          []string         `json:"imageURLs"`
-	RegistrationNumber string           `json:"registrationNumber"`
-	Year               int              `json:"year"`
-	GpsTrackerID       string           `json:"gpsTrackerID"`
-	LeadDriver         User             `json:"leadDriver"`
-	AssistantDriver    User             `json:"assistantDriver"`
-	Structure          [][]ResponseSeat `json:"structure"`
-	CreatedAt          time.Time        `json:"createdAt"`
-	UpdatedAt          time.Time        `json:"updatedAt"`
-	DeletedAt          gorm.DeletedAt   `json:"deletedAt"`
+	ID                   uuid.UUID        `json:"id"`
+	Model                string           `json:"model"`
+	ImageUrls            []string         `json:"imageURLs"`
+	RegistrationNumber   string           `json:"registrationNumber"`
+	Year                 int              `json:"year"`
+	GpsTrackerID         string           `json:"gpsTrackerID"`
+	LeadDriver           User             `json:"leadDriver"`
+	AssistantDriver      User             `json:"assistantDriver"`
+	Structure            [][]ResponseSeat `json:"structure"`
+	SeatSurcharges       SeatSurcharges   `json:"seatSurcharges"`
+	ParcelWeightCapacity int              `json:"parcelWeightCapacity"`
+	CreatedAt            time.Time        `json:"createdAt"`
+	UpdatedAt            time.Time        `json:"updatedAt"`
+	DeletedAt            gorm.DeletedAt   `json:"deletedAt"`
 }
 
 func (b Bus) ToEmployeeBus() EmployeeBus {
@@ -362,28 +376,31 @@ func (b Bus) ToEmployeeBus() EmployeeBus {
 	}
 
 	return EmployeeBus{
-		ID:                 b.ID,
-		Model:              b.Model,
-		ImageUrls:          imageUrls,
-		RegistrationNumber: b.RegistrationNumber,
-		Year:               b.Year,
-		Structure:          b.responseStructure(),
-		LeadDriver:         b.LeadDriver,
-		AssistantDriver:    b.AssistantDriver,
-		CreatedAt:          b.CreatedAt,
-		UpdatedAt:          b.UpdatedAt,
-		DeletedAt:          b.DeletedAt,
+		ID:                   b.ID,
+		Model:                b.Model,
+		ImageUrls:            imageUrls,
+		RegistrationNumber:   b.RegistrationNumber,
+		Year:                 b.Year,
+		Structure:            b.responseStructure(),
+		SeatSurcharges:       b.Surcharges(),
+		ParcelWeightCapacity: b.ParcelWeightCapacity,
+		LeadDriver:           b.LeadDriver,
+		AssistantDriver:      b.AssistantDriver,
+		CreatedAt:            b.CreatedAt,
+		UpdatedAt:            b.UpdatedAt,
+		DeletedAt:            b.DeletedAt,
 	}
 }
 
 type NewBus struct {
-	Model              string        `gorm:"type:varchar(255);not null"                   json:"model"`
-	RegistrationNumber string        `gorm:"type:varchar(8);not null;unique"              json:"registrationNumber"`
-	Year               int           `gorm:"type:smallint;not null"                       json:"year"`
-	GpsTrackerID       string        `gorm:"type:varchar(255);not null"                   json:"gpsTrackerID"`
-	LeadDriverID       uuid.NullUUID `gorm:"type:uuid;not null"                           json:"leadDriverID"`
-	AssistantDriverID  uuid.NullUUID `gorm:"type:uuid;not null"                           json:"assistantDriverID"`
-	Structure          [][]NewSeat   `gorm:"not null"                                     json:"structure"`
+	Model                string        `gorm:"type:varchar(255);not null"                   json:"model"`
+	RegistrationNumber   string        `gorm:"type:varchar(8);not null;unique"              json:"registrationNumber"`
+	Year                 int           `gorm:"type:smallint;not null"                       json:"year"`
+	GpsTrackerID         string        `gorm:"type:varchar(255);not null"                   json:"gpsTrackerID"`
+	LeadDriverID         uuid.NullUUID `gorm:"type:uuid;not null"                           json:"leadDriverID"`
+	AssistantDriverID    uuid.NullUUID `gorm:"type:uuid;not null"                           json:"assistantDriverID"`
+	Structure            [][]NewSeat   `gorm:"not null"                                     json:"structure"`
+	ParcelWeightCapacity int           `json:"parcelWeightCapacity"`
 }
 
 type NewSeat struct {
@@ -394,17 +411,23 @@ type NewSeat struct {
 
 func (nb NewBus) Parse() (Bus, rfc7807.InvalidParams) {
 	var bus = Bus{
-		Model:              nb.Model,
-		RegistrationNumber: nb.RegistrationNumber,
-		Year:               nb.Year,
-		GpsTrackerID:       nb.GpsTrackerID,
-		LeadDriverID:       nb.LeadDriverID,
-		AssistantDriverID:  nb.AssistantDriverID,
-		Structure:          make([]Row, len(nb.Structure)),
+		Model:                nb.Model,
+		RegistrationNumber:   nb.RegistrationNumber,
+		Year:                 nb.Year,
+		GpsTrackerID:         nb.GpsTrackerID,
+		LeadDriverID:         nb.LeadDriverID,
+		AssistantDriverID:    nb.AssistantDriverID,
+		Structure:            make([]Row, len(nb.Structure)),
+		ParcelWeightCapacity: nb.ParcelWeightCapacity,
 	}
 
 	var InvalidParams rfc7807.InvalidParams
 
+	// No capacity leaves the default one of the column.
+	if nb.ParcelWeightCapacity < 0 || nb.ParcelWeightCapacity > 16777215 {
+		InvalidParams.SetInvalidParam("parcelWeightCapacity", "Has to be between 0 and 16777215 grams.")
+	}
+
 	for rowIndex, newRow := range nb.Structure {
 		bus.Structure[rowIndex].Number = rowIndex
 		for seatIndex, newSeat := range newRow {
@@ -538,19 +561,20 @@ func TestBuses() []Bus {
 		}
 
 		return Bus{
-			ID:                 busID,
-			Model:              model,
-			RegistrationNumber: regNumber,
-			Year:               year,
-			GpsTrackerID:       "gps-" + regNumber,
-			Seats:              seats,
-			Structure:          []Row{row1, row2, row3, row4, row5},
-			CreatedAt:          now,
-			UpdatedAt:          now,
-			LuggageVolume:      200 * 250 * 350,
-			MaxWidth:           200,
-			MaxHeight:          250,
-			MaxLength:          350,
+			ID:                   busID,
+			Model:                model,
+			RegistrationNumber:   regNumber,
+			Year:                 year,
+			GpsTrackerID:         "gps-" + regNumber,
+			Seats:                seats,
+			Structure:            []Row{row1, row2, row3, row4, row5},
+			CreatedAt:            now,
+			UpdatedAt:            now,
+			LuggageVolume:        200 * 250 * 350,
+			MaxWidth:             200,
+			MaxHeight:            250,
+			MaxLength:            350,
+			ParcelWeightCapacity: 500000,
 		}
 	}
 
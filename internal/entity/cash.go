@@ -0,0 +1,77 @@
+package entity
+
+import (
+	"slices"
+
+	"github.com/d3code/uuid"
+)
+
+// CashTicket is a ticket of the connection booked to be paid to the driver.
+type CashTicket struct {
+	TicketID    uuid.UUID     `gorm:"column:ticket_id"`
+	Price       int           `gorm:"column:price"`
+	Currency    Currency      `gorm:"column:currency"`
+	Succeeded   bool          `gorm:"column:succeeded"`
+	CollectedBy uuid.NullUUID `gorm:"column:collected_by"`
+}
+
+// DriverCashCollection is the cash one driver collected on the connection.
+type DriverCashCollection struct {
+	DriverID uuid.UUID `json:"driverId"`
+	Tickets  int       `json:"tickets"`
+	Amount   int       `json:"amount"`
+}
+
+// CashReconciliation compares the cash expected on the connection in one currency with the
+// cash the drivers collected.
+type CashReconciliation struct {
+	ConnectionID         uuid.UUID              `json:"connectionId"`
+	Currency             Currency               `json:"currency"`
+	ExpectedTickets      int                    `json:"expectedTickets"`
+	ExpectedAmount       int                    `json:"expectedAmount"`
+	CollectedTickets     int                    `json:"collectedTickets"`
+	CollectedAmount      int                    `json:"collectedAmount"`
+	OutstandingAmount    int                    `json:"outstandingAmount"`
+	Drivers              []DriverCashCollection `json:"drivers"`
+	UncollectedTicketIDs []uuid.UUID            `json:"uncollectedTicketIds"`
+}
+
+// NewCashReconciliations groups the cash tickets of the connection by currency.
+func NewCashReconciliations(connectionID uuid.UUID, tickets []CashTicket) []CashReconciliation {
+	var reconciliations []CashReconciliation
+	for _, ticket := range tickets {
+		i := slices.IndexFunc(reconciliations, func(r CashReconciliation) bool { return r.Currency == ticket.Currency })
+		if i == -1 {
+			reconciliations = append(reconciliations, CashReconciliation{
+				ConnectionID:         connectionID,
+				Currency:             ticket.Currency,
+				Drivers:              []DriverCashCollection{},
+				UncollectedTicketIDs: []uuid.UUID{},
+			})
+			i = len(reconciliations) - 1
+		}
+
+		r := &reconciliations[i]
+		r.ExpectedTickets++
+		r.ExpectedAmount += ticket.Price
+
+		if !ticket.Succeeded {
+			r.OutstandingAmount += ticket.Price
+			r.UncollectedTicketIDs = append(r.UncollectedTicketIDs, ticket.TicketID)
+			continue
+		}
+
+		r.CollectedTickets++
+		r.CollectedAmount += ticket.Price
+
+		j := slices.IndexFunc(r.Drivers, func(d DriverCashCollection) bool { return d.DriverID == ticket.CollectedBy.UUID })
+		if j == -1 {
+			r.Drivers = append(r.Drivers, DriverCashCollection{DriverID: ticket.CollectedBy.UUID})
+			j = len(r.Drivers) - 1
+		}
+		r.Drivers[j].Tickets++
+		r.Drivers[j].Amount += ticket.Price
+	}
+
+	return reconciliations
+}
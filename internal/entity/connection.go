@@ -4,6 +4,7 @@ import (
 	"maryan_api/config"
 	rfc7807 "maryan_api/pkg/problem"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/d3code/uuid"
@@ -32,6 +33,7 @@ type Connection struct {
 	Bus   Bus       `gorm:"foreignKey:BusID" json:"bus"`
 
 	Stops     []Stop             `json:"stops"`
+	Route     []ConnectionStop   `gorm:"constraint:OnDelete:CASCADE" json:"route"`
 	CreatedAt time.Time          `gorm:"not null" json:"createdAt"`
 	Updates   []ConnectionUpdate `gorm:"not null" json:"updates"`
 
@@ -46,6 +48,8 @@ type Connection struct {
 	MaxHeight         int  `gorm:"type:SMALLINT UNSIGNED;not null"`
 	MaxLength         int  `gorm:"type:SMALLINT UNSIGNED;not null"`
 	LuggageVolumeLeft uint `gorm:"-"`
+	// ParcelWeightLeft is the weight of the parcels in grams the bus can still take.
+	ParcelWeightLeft int `gorm:"-"`
 }
 
 func (c *Connection) AfterFind(tx *gorm.DB) (err error) {
@@ -147,13 +151,18 @@ const (
 )
 
 func MigrateConnection(db *gorm.DB) error {
-	return db.AutoMigrate(
+	err := db.AutoMigrate(
 		&Connection{},
 		&ConnectionUpdate{},
 		&Stop{},
 		&StopUpdate{},
+		&ConnectionStop{},
 	)
+	if err != nil {
+		return err
+	}
 
+	return migrateRoutes(db)
 }
 
 func (c *Connection) Validate() rfc7807.InvalidParams {
@@ -163,7 +172,7 @@ func (c *Connection) Validate() rfc7807.InvalidParams {
 		params.SetInvalidParam("DepartureTime", "Past time.")
 	}
 
-	return params
+	return append(params, c.validateRoute()...)
 }
 
 func (c *Connection) PrepareNew() {
@@ -172,6 +181,7 @@ func (c *Connection) PrepareNew() {
 		ConnectionID: c.ID,
 		Status:       RegisteredConnectionStatus,
 	}}
+	c.PrepareRoute()
 }
 
 //
@@ -201,13 +211,19 @@ func (c *Connection) Simplify() ConnectionSimplified {
 
 type CustomerConnection struct {
 	ConnectionSimplified
-	GoogleMapsConnectionURL string      `json:"googleMapsConnectionURL"`
-	Bus                     CustomerBus `json:"bus"`
-	Stops                   []Stop      `json:"stops"`
-	LuggageVolumeLeft       uint        `json:"luggageVolumeLeft"`
-	BackpackPrice           int         `json:"backpackPrice"`
-	SmallLuggagePrice       int         `json:"smallLuggagePrice"`
-	LargeLuggagePrice       int         `json:"largeLuggagePrice"`
+	GoogleMapsConnectionURL string           `json:"googleMapsConnectionURL"`
+	Bus                     CustomerBus      `json:"bus"`
+	Stops                   []Stop           `json:"stops"`
+	LuggageVolumeLeft       uint             `json:"luggageVolumeLeft"`
+	ParcelWeightLeft        int              `json:"parcelWeightLeft"`
+	BackpackPrice           int              `json:"backpackPrice"`
+	SmallLuggagePrice       int              `json:"smallLuggagePrice"`
+	LargeLuggagePrice       int              `json:"largeLuggagePrice"`
+	Fares                   Fares            `json:"fares"`
+	Route                   []ConnectionStop `json:"route"`
+	FromStop                int              `json:"fromStop"`
+	ToStop                  int              `json:"toStop"`
+	Currency                Currency         `json:"currency"`
 }
 
 type ParcelConnection struct {
@@ -215,16 +231,37 @@ type ParcelConnection struct {
 	Price int `json:"price"`
 }
 
+// ConvertPrices shows the prices of the connection in the currency of the rate, the quote stays in the base currency.
+func (c *CustomerConnection) ConvertPrices(rate ExchangeRate) {
+	c.Price = rate.Convert(c.Price).Amount
+	c.BackpackPrice = rate.Convert(c.BackpackPrice).Amount
+	c.SmallLuggagePrice = rate.Convert(c.SmallLuggagePrice).Amount
+	c.LargeLuggagePrice = rate.Convert(c.LargeLuggagePrice).Amount
+	for category, fare := range c.Fares {
+		c.Fares[category] = rate.Convert(fare).Amount
+	}
+	for _, row := range c.Bus.Structure {
+		for i := range row {
+			row[i].Surcharge = rate.Convert(row[i].Surcharge).Amount
+			row[i].Price = rate.Convert(row[i].Price).Amount
+		}
+	}
+	c.Currency = rate.Currency
+}
+
 func (c *Connection) ToCustomer(takenSeatsIDs []uuid.UUID, smallLuggagePrice, mediumLuggagePrice, largeLuggagePrice int) CustomerConnection {
 	return CustomerConnection{
 		ConnectionSimplified:    c.Simplify(),
 		GoogleMapsConnectionURL: c.GoogleMapsURL,
-		Bus:                     c.Bus.ToCustomerBus(takenSeatsIDs),
+		Bus:                     c.Bus.ToCustomerBus(takenSeatsIDs, c.Price),
 		Stops:                   c.Stops,
+		Route:                   c.RouteStops(),
 		LuggageVolumeLeft:       c.LuggageVolumeLeft,
+		ParcelWeightLeft:        c.ParcelWeightLeft,
 		BackpackPrice:           smallLuggagePrice,
 		SmallLuggagePrice:       mediumLuggagePrice,
 		LargeLuggagePrice:       largeLuggagePrice,
+		Currency:                BaseCurrency,
 	}
 }
 
@@ -232,12 +269,18 @@ func PreloadConnection() []string {
 	return []string{
 		clause.Associations,
 
+		"Route.Country",
+		"Stops.Ticket",
+		"Stops.Ticket.Seats",
+		"Stops.Parcel",
 		"Bus.Images",
 		"Bus.LeadDriver",
 		"Bus.AssistantDriver",
 		"Bus.Seats",
 		"Bus.Structure",
 		"Bus.Structure.Positions",
+		"Bus.SeatTypeSurcharges",
+		"Bus.SeatSurcharges",
 	}
 }
 
@@ -249,6 +292,9 @@ type FindConnectionsRequestJSON struct {
 	Children  string `json:"children"`
 	Teenagers string `json:"teenagers"`
 	Range     string `json:"range"`
+	FromCity  string `json:"fromCity"`
+	ToCity    string `json:"toCity"`
+	Currency  string `json:"currency"`
 }
 
 func (r FindConnectionsRequestJSON) Parse() (FindConnectionsRequest, rfc7807.InvalidParams) {
@@ -265,8 +311,6 @@ func (r FindConnectionsRequestJSON) Parse() (FindConnectionsRequest, rfc7807.Inv
 		invalidParams.SetInvalidParam("infants", err.Error())
 	} else if teenagers < 0 {
 		invalidParams.SetInvalidParam("infants", "cannot be less that 0.")
-	} else if teenagers < 1 && adults < 1 {
-		invalidParams.SetInvalidParam("passengers", "There has to be at least one adult or one teenager.")
 	}
 
 	children, err := strconv.Atoi(r.Children)
@@ -274,8 +318,10 @@ func (r FindConnectionsRequestJSON) Parse() (FindConnectionsRequest, rfc7807.Inv
 		invalidParams.SetInvalidParam("children", err.Error())
 	} else if children < 0 {
 		invalidParams.SetInvalidParam("children", "cannot be less that 0.")
-	} else if children > 0 && adults < 1 {
-		invalidParams.SetInvalidParam("children", "cannot be more than 0 if there is no adult.")
+	}
+
+	if invalidParams == nil {
+		invalidParams = ValidatePassengerCategories(adults, teenagers, children)
 	}
 
 	connectionsRange, err := strconv.Atoi(r.Range)
@@ -304,6 +350,12 @@ func (r FindConnectionsRequestJSON) Parse() (FindConnectionsRequest, rfc7807.Inv
 		invalidParams.SetInvalidParam("date", err.Error())
 	}
 
+	currency, ok := DefineCurrency(r.Currency)
+	if !ok {
+		invalidParams.SetInvalidParam("currency", "Must be one of EUR or UAH.")
+		return FindConnectionsRequest{}, invalidParams
+	}
+
 	return FindConnectionsRequest{
 		From:      fromID,
 		To:        toID,
@@ -312,6 +364,9 @@ func (r FindConnectionsRequestJSON) Parse() (FindConnectionsRequest, rfc7807.Inv
 		Children:  children,
 		Teenagers: teenagers,
 		Range:     connectionsRange,
+		FromCity:  strings.TrimSpace(r.FromCity),
+		ToCity:    strings.TrimSpace(r.ToCity),
+		Currency:  currency,
 	}, nil
 
 }
@@ -324,12 +379,16 @@ type FindConnectionsRequest struct {
 	Children  int
 	Teenagers int
 	Range     int
+	FromCity  string
+	ToCity    string
+	Currency  Currency
 }
 
 type FindConnectionsResponse struct {
 	Connections []FoundConnection  `json:"connections"`
 	LeftRange   []ConnectionsRange `json:"leftRange"`
 	RightRange  []ConnectionsRange `json:"rightRange"`
+	Currency    Currency           `json:"currency"`
 }
 
 type FoundConnection struct {
@@ -337,6 +396,9 @@ type FoundConnection struct {
 	TicketsLeft int  `json:"ticketsLeft"`
 	Fits        bool `json:"fits"`
 	Available   bool `json:"available"`
+	TotalPrice  int  `json:"totalPrice"`
+	FromStop    int  `json:"fromStop"`
+	ToStop      int  `json:"toStop"`
 }
 type ConnectionSimplified struct {
 	ID                 uuid.UUID `json:"id"`
@@ -354,6 +416,7 @@ type ConnectionParcel struct {
 	ConnectionSimplified
 	Price             int  `json:"price"`
 	LuggageVolumeLeft uint `json:"luggageVolumeLeft"`
+	ParcelWeightLeft  int  `json:"parcelWeightLeft"`
 	MaxWidth          uint `json:"maxWidth"`
 	MaxHeight         uint `json:"maxHeight"`
 	MaxLength         uint `json:"maxLength"`
@@ -367,7 +430,7 @@ type ConnectionsRange struct {
 	Available  bool      `json:"available"`
 	SellBefore time.Time `gorm:"column:sellBefore" json:"-"`
 	Number     int       `gorm:"column:number" json:"number"`
-	MinPrice   int       `gorm:"column:minPrice" json:"minPrice"`
+	MinPrice   int       `gorm:"-" json:"minPrice"`
 }
 
 func (c *Connection) ToParcelConnection(usable bool, dayNumber, dayMonth int, isCurrentMonth bool, price int) ConnectionParcel {
@@ -376,6 +439,7 @@ func (c *Connection) ToParcelConnection(usable bool, dayNumber, dayMonth int, is
 		Price:                price,
 		ConnectionSimplified: c.Simplify(),
 		LuggageVolumeLeft:    c.LuggageVolumeLeft,
+		ParcelWeightLeft:     c.ParcelWeightLeft,
 		MaxWidth:             c.Bus.MaxWidth,
 		MaxHeight:            c.Bus.MaxHeight,
 		MaxLength:            c.Bus.MaxLength,
@@ -0,0 +1,176 @@
+package entity
+
+import (
+	"maryan_api/config"
+	rfc7807 "maryan_api/pkg/problem"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/d3code/uuid"
+	"gorm.io/gorm"
+)
+
+// ConnectionStop is a scheduled city stop on the route of a connection. Stops are numbered
+// from 0 (departure) to len-1 (destination); segment i is the part between stop i and i+1.
+type ConnectionStop struct {
+	ConnectionID  uuid.UUID `gorm:"type:binary(16);primaryKey"                           json:"-"`
+	Sequence      int       `gorm:"type:TINYINT UNSIGNED;primaryKey;autoIncrement:false" json:"sequence"`
+	CountryID     uuid.UUID `gorm:"type:binary(16);not null;index"                       json:"countryId"`
+	Country       Country   `gorm:"foreignKey:CountryID"                                 json:"country"`
+	City          string    `gorm:"type:varchar(100);not null"                           json:"city"`
+	ArrivalTime   time.Time `gorm:"not null"                                             json:"arrivalTime"`
+	DepartureTime time.Time `gorm:"not null"                                             json:"departureTime"`
+	Fare          int       `gorm:"type:MEDIUMINT UNSIGNED;not null"                     json:"fare"`
+}
+
+// Name is the city and the country of the stop, migrated routes only have the country.
+func (s ConnectionStop) Name() string {
+	if s.City == "" {
+		return s.Country.Name
+	}
+	return s.City + ", " + s.Country.Name
+}
+
+// Segment is the part of the route a ticket or a parcel travels.
+type Segment struct {
+	From ConnectionStop
+	To   ConnectionStop
+}
+
+// Price is the fare between the stops, the fares of the stops are counted from the departure.
+func (s Segment) Price() int {
+	return s.To.Fare - s.From.Fare
+}
+
+// Segments returns the indexes of the segments covered, to be used as [from, to).
+func (s Segment) Segments() (int, int) {
+	return s.From.Sequence, s.To.Sequence
+}
+
+// Overlaps reports whether the range of stops [from, to) shares a segment with s.
+func (s Segment) Overlaps(from, to int) bool {
+	return from < s.To.Sequence && s.From.Sequence < to
+}
+
+// RouteStops returns the stops ordered by their sequence.
+func (c *Connection) RouteStops() []ConnectionStop {
+	stops := slices.Clone(c.Route)
+	slices.SortFunc(stops, func(a, b ConnectionStop) int { return a.Sequence - b.Sequence })
+	return stops
+}
+
+// Segment returns the part of the route between the stops. A toStop of 0 stands for the destination.
+func (c *Connection) Segment(fromStop, toStop int) (Segment, error) {
+	stops := c.RouteStops()
+	if toStop == 0 {
+		toStop = len(stops) - 1
+	}
+
+	if fromStop < 0 || toStop >= len(stops) || fromStop >= toStop {
+		return Segment{}, rfc7807.BadRequest("invalid-segment", "Invalid Segment Error", "The stops are not on the route of the connection or are in the wrong order.")
+	}
+
+	return Segment{stops[fromStop], stops[toStop]}, nil
+}
+
+// FullRoute returns the segment from the departure to the destination.
+func (c *Connection) FullRoute() Segment {
+	segment, _ := c.Segment(0, 0)
+	return segment
+}
+
+// SimplifySegment is Simplify with the times, countries and price of the segment.
+func (c *Connection) SimplifySegment(segment Segment) ConnectionSimplified {
+	simplified := c.Simplify()
+	simplified.Price = segment.Price()
+	simplified.DepartureCountry = segment.From.Country.Name
+	simplified.DestinationCountry = segment.To.Country.Name
+	simplified.DepartureTime = config.MustParseToLocalByUUID(segment.From.DepartureTime, segment.From.CountryID)
+	simplified.ArrivalTime = config.MustParseToLocalByUUID(segment.To.ArrivalTime, segment.To.CountryID)
+	simplified.EstimatedDuration = int(segment.To.ArrivalTime.Sub(segment.From.DepartureTime).Minutes())
+	return simplified
+}
+
+// PrepareRoute numbers the stops of the route. A connection created without stops gets
+// a route of its departure and destination.
+func (c *Connection) PrepareRoute() {
+	if len(c.Route) == 0 {
+		c.Route = []ConnectionStop{
+			{CountryID: c.DepartureCountryID, ArrivalTime: c.DepartureTime, DepartureTime: c.DepartureTime},
+			{CountryID: c.DestinationCountryID, ArrivalTime: c.ArrivalTime, DepartureTime: c.ArrivalTime, Fare: c.Price},
+		}
+	}
+
+	for i := range c.Route {
+		c.Route[i].ConnectionID = c.ID
+		c.Route[i].Sequence = i
+	}
+}
+
+func (c *Connection) validateRoute() rfc7807.InvalidParams {
+	var params rfc7807.InvalidParams
+	if len(c.Route) == 0 {
+		return nil
+	}
+
+	if len(c.Route) < 2 {
+		params.SetInvalidParam("route", "Must contain at least the departure and the destination stops.")
+		return params
+	}
+
+	first, last := c.Route[0], c.Route[len(c.Route)-1]
+	if first.CountryID != c.DepartureCountryID || last.CountryID != c.DestinationCountryID {
+		params.SetInvalidParam("route", "Must start in the departure country and end in the destination country.")
+	}
+
+	if first.Fare != 0 || last.Fare != c.Price {
+		params.SetInvalidParam("route", "The fare of the first stop must be 0 and the fare of the last one must be the price of the connection.")
+	}
+
+	for i, stop := range c.Route {
+		name := "route[" + strconv.Itoa(i) + "]"
+		if strings.TrimSpace(stop.City) == "" {
+			params.SetInvalidParam(name+".city", "Must not be empty.")
+		}
+
+		if stop.DepartureTime.Before(stop.ArrivalTime) {
+			params.SetInvalidParam(name+".departureTime", "Can not be before the arrival time.")
+		}
+
+		if i > 0 {
+			previous := c.Route[i-1]
+			if stop.ArrivalTime.Before(previous.DepartureTime) {
+				params.SetInvalidParam(name+".arrivalTime", "Can not be before the departure from the previous stop.")
+			}
+
+			if stop.Fare < previous.Fare {
+				params.SetInvalidParam(name+".fare", "Can not be less than the fare of the previous stop.")
+			}
+		}
+	}
+
+	return params
+}
+
+// migrateRoutes gives the connections created before the routes were introduced
+// a route of their departure and destination.
+func migrateRoutes(db *gorm.DB) error {
+	err := db.Exec(`
+		INSERT INTO connection_stops (connection_id, sequence, country_id, city, arrival_time, departure_time, fare)
+		SELECT id, 0, departure_country_id, '', departure_time, departure_time, 0
+		FROM connections
+		WHERE id NOT IN (SELECT connection_id FROM connection_stops)
+	`).Error
+	if err != nil {
+		return err
+	}
+
+	return db.Exec(`
+		INSERT INTO connection_stops (connection_id, sequence, country_id, city, arrival_time, departure_time, fare)
+		SELECT id, 1, destination_country_id, '', arrival_time, arrival_time, price
+		FROM connections
+		WHERE id NOT IN (SELECT connection_id FROM connection_stops WHERE sequence > 0)
+	`).Error
+}
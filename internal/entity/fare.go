@@ -0,0 +1,131 @@
+package entity
+
+import (
+	"maryan_api/config"
+	rfc7807 "maryan_api/pkg/problem"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+type fareCategory string
+
+const (
+	AdultFareCategory    fareCategory = "Adult"
+	TeenagerFareCategory fareCategory = "Teenager"
+	ChildFareCategory    fareCategory = "Child"
+)
+
+var fareCategories = []fareCategory{AdultFareCategory, TeenagerFareCategory, ChildFareCategory}
+
+func DefineFareCategory(v string) (fareCategory, bool) {
+	switch fareCategory(v) {
+	case AdultFareCategory, TeenagerFareCategory, ChildFareCategory:
+		return fareCategory(v), true
+	default:
+		return "", false
+	}
+}
+
+// FareDiscount overrides the default discount of the category on a single line.
+type FareDiscount struct {
+	Line       int          `gorm:"type:SMALLINT;primaryKey"                          json:"line"`
+	Category   fareCategory `gorm:"type:enum('Adult','Teenager','Child');primaryKey"  json:"category"`
+	Percentage int          `gorm:"type:TINYINT UNSIGNED;not null"                    json:"percentage"`
+}
+
+type FareDiscounts []FareDiscount
+
+func (d FareDiscounts) percentage(line int, category fareCategory) int {
+	for _, discount := range d {
+		if discount.Line == line && discount.Category == category {
+			return discount.Percentage
+		}
+	}
+	return config.DefaultFareDiscount(string(category))
+}
+
+// Price returns the fare of one passenger of the category.
+func (d FareDiscounts) Price(line, basePrice int, category fareCategory) int {
+	return basePrice * (100 - d.percentage(line, category)) / 100
+}
+
+// Fares maps every passenger category to its price.
+type Fares map[fareCategory]int
+
+func (d FareDiscounts) Fares(line, basePrice int) Fares {
+	var fares = make(Fares, len(fareCategories))
+	for _, category := range fareCategories {
+		fares[category] = d.Price(line, basePrice, category)
+	}
+	return fares
+}
+
+func (d FareDiscounts) Total(line, basePrice, adults, teenagers, children int) int {
+	return adults*d.Price(line, basePrice, AdultFareCategory) +
+		teenagers*d.Price(line, basePrice, TeenagerFareCategory) +
+		children*d.Price(line, basePrice, ChildFareCategory)
+}
+
+func (d FareDiscounts) PassengersTotal(line, basePrice int, passengers []Passenger) int {
+	var total int
+	for _, passenger := range passengers {
+		total += d.Price(line, basePrice, passenger.Category)
+	}
+	return total
+}
+
+// Effective returns the discounts applied on the line, defaults included.
+func (d FareDiscounts) Effective(line int) FareDiscounts {
+	var effective = make(FareDiscounts, len(fareCategories))
+	for i, category := range fareCategories {
+		effective[i] = FareDiscount{line, category, d.percentage(line, category)}
+	}
+	return effective
+}
+
+type FareDiscountJSON struct {
+	Category   string `json:"category"`
+	Percentage int    `json:"percentage"`
+}
+
+func ParseFareDiscounts(line int, discountsJSON []FareDiscountJSON) (FareDiscounts, error) {
+	var params rfc7807.InvalidParams
+	var discounts = make(FareDiscounts, len(discountsJSON))
+	for i, discount := range discountsJSON {
+		category, ok := DefineFareCategory(discount.Category)
+		if !ok {
+			params.SetInvalidParam("discounts["+strconv.Itoa(i)+"].category", "Must be one of Adult, Teenager or Child.")
+		}
+
+		if discount.Percentage < 0 || discount.Percentage > 100 {
+			params.SetInvalidParam("discounts["+strconv.Itoa(i)+"].percentage", "Must be between 0 and 100.")
+		}
+
+		discounts[i] = FareDiscount{line, category, discount.Percentage}
+	}
+
+	if params != nil {
+		return nil, rfc7807.BadRequest("invalid-data", "Invalid Data Error", "The provided discounts are not valid.", params...)
+	}
+
+	return discounts, nil
+}
+
+// ValidatePassengerCategories applies the rules shared by the search and the checkout:
+// there has to be at least one adult or teenager, and children cannot travel without an adult.
+func ValidatePassengerCategories(adults, teenagers, children int) rfc7807.InvalidParams {
+	var params rfc7807.InvalidParams
+	if teenagers < 1 && adults < 1 {
+		params.SetInvalidParam("passengers", "There has to be at least one adult or one teenager.")
+	}
+
+	if children > 0 && adults < 1 {
+		params.SetInvalidParam("children", "cannot be more than 0 if there is no adult.")
+	}
+	return params
+}
+
+func MigrateFareDiscount(db *gorm.DB) error {
+	return db.AutoMigrate(&FareDiscount{})
+}
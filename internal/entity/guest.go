@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/d3code/uuid"
+	"gorm.io/gorm"
+)
+
+// Guest is a customer that books without an account. Its ID is used as the user id of
+// the guest's tickets and parcels until they are claimed by a registered customer.
+type Guest struct {
+	ID          uuid.UUID     `gorm:"type:binary(16);primaryKey" json:"id"`
+	Email       string        `gorm:"type:varchar(255);index"    json:"email"`
+	PhoneNumber string        `gorm:"type:varchar(15)"           json:"phoneNumber"`
+	ClaimedBy   uuid.NullUUID `gorm:"type:binary(16)"            json:"claimedBy"`
+	CreatedAt   time.Time     `gorm:"not null"                   json:"createdAt"`
+}
+
+func NewGuest(email, phoneNumber string) Guest {
+	return Guest{
+		Email:       email,
+		PhoneNumber: phoneNumber,
+	}
+}
+
+func MigrateGuest(db *gorm.DB) error {
+	return db.AutoMigrate(&Guest{})
+}
@@ -0,0 +1,86 @@
+package entity
+
+import (
+	rfc7807 "maryan_api/pkg/problem"
+	"math"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Currency string
+
+const (
+	EUR Currency = "EUR"
+	UAH Currency = "UAH"
+)
+
+// BaseCurrency is the currency the fares, tariffs and promo codes are set in.
+const BaseCurrency = EUR
+
+// DefineCurrency accepts the ISO code of a supported currency in any case, an empty code is the base currency.
+func DefineCurrency(v string) (Currency, bool) {
+	switch Currency(strings.ToUpper(v)) {
+	case "", BaseCurrency:
+		return BaseCurrency, true
+	case UAH:
+		return UAH, true
+	default:
+		return "", false
+	}
+}
+
+func ParseCurrency(v string) (Currency, error) {
+	c, ok := DefineCurrency(v)
+	if !ok {
+		return "", rfc7807.BadRequest("unsupported-currency", "Unsupported currency Error", "The currency must be one of EUR or UAH.")
+	}
+	return c, nil
+}
+
+// Money is an amount in the minor units of its currency.
+type Money struct {
+	Amount   int      `json:"amount"`
+	Currency Currency `json:"currency"`
+}
+
+// ExchangeRate is the number of units of the currency paid for one unit of the base currency.
+type ExchangeRate struct {
+	Currency  Currency  `gorm:"type:enum('EUR','UAH');primaryKey" json:"currency"`
+	Rate      float64   `gorm:"type:DECIMAL(12,6);not null"        json:"rate"`
+	UpdatedAt time.Time `gorm:"not null"                           json:"updatedAt"`
+}
+
+// BaseExchangeRate converts the base currency to itself.
+var BaseExchangeRate = ExchangeRate{Currency: BaseCurrency, Rate: 1}
+
+// Convert turns the amount in the base currency into the currency of the rate.
+func (r ExchangeRate) Convert(amount int) Money {
+	return Money{int(math.Round(float64(amount) * r.Rate)), r.Currency}
+}
+
+type ExchangeRateJSON struct {
+	Rate float64 `json:"rate"`
+}
+
+func (r ExchangeRateJSON) Parse(currencyStr string) (ExchangeRate, error) {
+	c, err := ParseCurrency(currencyStr)
+	if err != nil {
+		return ExchangeRate{}, err
+	}
+
+	if c == BaseCurrency {
+		return ExchangeRate{}, rfc7807.BadRequest("base-currency", "Base Currency Error", "The rate of the base currency is always 1.")
+	}
+
+	if r.Rate <= 0 {
+		return ExchangeRate{}, rfc7807.BadRequest("invalid-data", "Invalid Data Error", "The provided rate is not valid.", rfc7807.InvalidParam{Name: "rate", Reason: "Must be greater than 0."})
+	}
+
+	return ExchangeRate{Currency: c, Rate: r.Rate}, nil
+}
+
+func MigrateExchangeRate(db *gorm.DB) error {
+	return db.AutoMigrate(&ExchangeRate{})
+}
@@ -15,49 +15,83 @@ import (
 )
 
 type Parcel struct {
-	ID                  uuid.UUID      `gorm:"type:binary(16);primaryKey"         json:"id"`
-	UserID              uuid.UUID      `gorm:"type:binary(16);not null"           json:"userId"`
-	ConnectionID        uuid.UUID      `gorm:"type:binary(16);not null"           json:"connectionID"`
-	SenderPhoneNumber   string         `gorm:"type:varchar(15);not null"                                                  json:"senderPhoneNumber"`
-	SenderEmail         string         `gorm:"type:varchar(255);not null"                          json:"senderEmail"`
-	RecieverPhoneNumber string         `gorm:"type:varchar(15);not null"                                                  json:"recieverPhoneNumber"`
-	RecieverEmail       string         `gorm:"type:varchar(255);not null"                          json:"recieverEmail"`
-	SenderName          string         `gorm:"type:varchar(255);not null"                          json:"senderFirstName"`
-	SenderLastName      string         `gorm:"type:varchar(255);not null"                          json:"senderLastName"`
-	RecieverFirstName   string         `gorm:"type:varchar(255);not null"                          json:"recieverFirstName"`
-	RecieverLastName    string         `gorm:"type:varchar(255);not null"                          json:"recieverLastName"`
-	PickUpAdressID      uuid.UUID      `gorm:"type:binary(16);not null"           json:"-"`
-	PickUpAdress        Address        `gorm:"foreignKey:PickUpAdressID"    json:"pickUpAddress"`
-	DropOffAdressID     uuid.UUID      `gorm:"type:binary(16);not null"           json:"-"`
-	DropOffAdress       Address        `gorm:"foreignKey:DropOffAdressID"   json:"dropOffAddress"`
-	CreatedAt           time.Time      `gorm:"not null"                     json:"createdAt"`
-	CompletedAt         sql.NullTime   `                                    json:"completedAt"`
-	Payment             ParcelPayment  `gorm:"foreignKey:ParcelID"    `
-	DeletedAt           gorm.DeletedAt `                                    json:"deletedAt"`
-	LuggageVolume       uint           `gorm:"type:INT UNSIGNED;not null"`
-	Width               int            `gorm:"type:SMALLINT UNSIGNED;not null"  json:"width"`
-	Height              int            `gorm:"type:SMALLINT UNSIGNED;not null" json:"height"`
-	Length              int            `gorm:"type:SMALLINT UNSIGNED;not null" json:"length"`
-	Weight              int            `gorm:"type:SMALLINT UNSIGNED;not null" json:"weight"`
-	Type                ParcelType     `gorm:"type:enum('Documents','Package'); not null" json:"type"`
-	QRCode              []byte         `gorm:"type:blob;not null" json:"qrCode"`
+	ID                  uuid.UUID             `gorm:"type:binary(16);primaryKey"         json:"id"`
+	UserID              uuid.UUID             `gorm:"type:binary(16);not null"           json:"userId"`
+	ConnectionID        uuid.UUID             `gorm:"type:binary(16);not null"           json:"connectionID"`
+	SenderPhoneNumber   string                `gorm:"type:varchar(15);not null"                                                  json:"senderPhoneNumber"`
+	SenderEmail         string                `gorm:"type:varchar(255);not null"                          json:"senderEmail"`
+	RecieverPhoneNumber string                `gorm:"type:varchar(15);not null"                                                  json:"recieverPhoneNumber"`
+	RecieverEmail       string                `gorm:"type:varchar(255);not null"                          json:"recieverEmail"`
+	SenderName          string                `gorm:"type:varchar(255);not null"                          json:"senderFirstName"`
+	SenderLastName      string                `gorm:"type:varchar(255);not null"                          json:"senderLastName"`
+	RecieverFirstName   string                `gorm:"type:varchar(255);not null"                          json:"recieverFirstName"`
+	RecieverLastName    string                `gorm:"type:varchar(255);not null"                          json:"recieverLastName"`
+	PickUpAdressID      uuid.UUID             `gorm:"type:binary(16);not null"           json:"-"`
+	PickUpAdress        Address               `gorm:"foreignKey:PickUpAdressID"    json:"pickUpAddress"`
+	DropOffAdressID     uuid.UUID             `gorm:"type:binary(16);not null"           json:"-"`
+	DropOffAdress       Address               `gorm:"foreignKey:DropOffAdressID"   json:"dropOffAddress"`
+	CreatedAt           time.Time             `gorm:"not null"                     json:"createdAt"`
+	CompletedAt         sql.NullTime          `                                    json:"completedAt"`
+	Payment             ParcelPayment         `gorm:"foreignKey:ParcelID"    `
+	DeletedAt           gorm.DeletedAt        `                                    json:"deletedAt"`
+	LuggageVolume       uint                  `gorm:"type:INT UNSIGNED;not null"`
+	Width               int                   `gorm:"type:SMALLINT UNSIGNED;not null"  json:"width"`
+	Height              int                   `gorm:"type:SMALLINT UNSIGNED;not null" json:"height"`
+	Length              int                   `gorm:"type:SMALLINT UNSIGNED;not null" json:"length"`
+	Weight              int                   `gorm:"type:SMALLINT UNSIGNED;not null" json:"weight"`
+	Type                ParcelType            `gorm:"type:enum('Documents','Package'); not null" json:"type"`
+	QRCode              []byte                `gorm:"type:blob;not null" json:"qrCode"`
+	FromStop            int                   `gorm:"type:TINYINT UNSIGNED;not null;default:0" json:"fromStop"`
+	ToStop              int                   `gorm:"type:TINYINT UNSIGNED;not null;default:1" json:"toStop"`
+	TrackingNumber      string                `gorm:"type:varchar(12);not null;index" json:"trackingNumber"`
+	TrackingCode        string                `gorm:"type:varchar(6);not null" json:"trackingCode"`
+	Status              parcelStatus          `gorm:"type:enum('Accepted','Picked Up','In Transit','Crossed Border','Out For Delivery','Delivered','Returned');not null;default:'Accepted'" json:"status"`
+	Updates             []ParcelStatusUpdate  `gorm:"foreignKey:ParcelID;constraint:OnDelete:CASCADE" json:"updates"`
+	DeliveryCode        string                `gorm:"type:varchar(6);not null;default:''" json:"-"`
+	DeliveryProof       *ParcelDeliveryProof  `gorm:"foreignKey:ParcelID;constraint:OnDelete:CASCADE" json:"deliveryProof,omitempty"`
+	CashOnDelivery      *ParcelCashOnDelivery `gorm:"foreignKey:ParcelID;constraint:OnDelete:CASCADE" json:"cashOnDelivery,omitempty"`
+}
+
+// Active reports whether the parcel is paid and is still to be carried.
+func (p Parcel) Active() bool {
+	return p.Payment.Succeeded && p.Status != DeliveredParcelStatus && p.Status != ReturnedParcelStatus
+}
+
+// Travels reports whether the parcel is carried on the segment of the route.
+func (p Parcel) Travels(segment int) bool {
+	return p.FromStop <= segment && segment < p.ToStop
 }
 
 type ParcelPayment struct {
-	ParcelID  uuid.UUID     `gorm:"type:binary(16);not null"                                                json:"packadeId"`
-	Price     int           `gorm:"type:MEDIUMINT;not null"                                           json:"price"`
-	Method    paymentMethod `gorm:"type:enum('Apple Pay','Card','Cash','Google Pay');not null"        json:"method"`
-	CreatedAt time.Time     `gorm:"not null"                                                          json:"createdAt"`
-	SessionID string        `gorm:"type:varchar(500);not null"                                                          json:"sessionID"`
-	Succeeded bool          `gorm:"not null"                                                          json:"succeeded"`
+	ParcelID       uuid.UUID     `gorm:"type:binary(16);not null"                                                json:"packadeId"`
+	Price          int           `gorm:"type:MEDIUMINT;not null"                                           json:"price"`
+	Method         paymentMethod `gorm:"type:enum('Apple Pay','Card','Cash','Google Pay');not null"        json:"method"`
+	CreatedAt      time.Time     `gorm:"not null"                                                          json:"createdAt"`
+	SessionID      string        `gorm:"type:varchar(500);not null"                                                          json:"sessionID"`
+	Succeeded      bool          `gorm:"not null"                                                          json:"succeeded"`
+	FailureMessage string        `gorm:"type:varchar(500)"                                                 json:"failureMessage"`
+	PromoCodeID    uuid.NullUUID `gorm:"type:binary(16);index"                                             json:"promoCodeId"`
+	Discount       int           `gorm:"type:MEDIUMINT;not null;default:0"                                 json:"discount"`
+	Currency       Currency      `gorm:"type:enum('EUR','UAH');not null;default:'EUR'"                    json:"currency"`
+	ExchangeRate   float64       `gorm:"type:DECIMAL(12,6);not null;default:1"                             json:"exchangeRate"`
+	TariffID       uuid.NullUUID `gorm:"type:binary(16);index"                                             json:"tariffId"`
+	TariffVersion  int           `gorm:"type:SMALLINT UNSIGNED;not null;default:0"                         json:"tariffVersion"`
 }
 
 func MigratePackage(db *gorm.DB) error {
-	return db.AutoMigrate(
+	err := db.AutoMigrate(
 		&Parcel{},
 		&ParcelPayment{},
+		&ParcelStatusUpdate{},
+		&ParcelDeliveryProof{},
+		&ParcelCashOnDelivery{},
+		&ParcelTrackingLock{},
 	)
+	if err != nil {
+		return err
+	}
 
+	return migrateParcelTracking(db)
 }
 
 type ParcelType string
@@ -75,6 +109,8 @@ type FindParcelConnectionsRequest struct {
 	Length string
 	Height string
 	Width  string
+	Weight string
+	Type   string
 }
 
 type CustomerParcel struct {
@@ -88,8 +124,10 @@ type FindParcelConnectionsRequestParsed struct {
 	Length int
 	Height int
 	Width  int
+	Weight int
 	Year   int
 	Month  time.Month
+	Type   ParcelType
 }
 
 func (fpcr FindParcelConnectionsRequest) Parse() (FindParcelConnectionsRequestParsed, error) {
@@ -105,6 +143,16 @@ func (fpcr FindParcelConnectionsRequest) Parse() (FindParcelConnectionsRequestPa
 		params.SetInvalidParam("to", err.Error())
 	}
 
+	parcelType, err := ParseOptionalParcelType(fpcr.Type)
+	if err != nil {
+		params.SetInvalidParam("type", err.Error())
+	}
+
+	weight, err := ParseOptionalParcelWeight(fpcr.Weight)
+	if err != nil {
+		params.SetInvalidParam("weight", err.Error())
+	}
+
 	if params != nil {
 		return FindParcelConnectionsRequestParsed{}, rfc7807.BadRequest("invalid-params", "Invalid Params Error", "Provided params are not valid.", params...)
 	}
@@ -144,8 +192,10 @@ func (fpcr FindParcelConnectionsRequest) Parse() (FindParcelConnectionsRequestPa
 
 		Height: height,
 		Width:  width,
+		Weight: weight,
 		Year:   year,
 		Month:  time.Month(monthNumber),
+		Type:   parcelType,
 	}, nil
 
 }
@@ -166,6 +216,9 @@ type PurchaseParcelRequest struct {
 	Height              int        `json:"height"`
 	Weight              int        `json:"weight"`
 	Type                string     `json:"type"`
+	PromoCode           string     `json:"promoCode"`
+	Currency            string     `json:"currency"`
+	CashOnDelivery      int        `json:"cashOnDelivery"`
 }
 type ContactInfo struct {
 	FirstName   string
@@ -185,6 +238,10 @@ type PurchaseParcelRequestParsed struct {
 	Height        int
 	Weight        int
 	Type          ParcelType
+	PromoCode     string
+	Currency      Currency
+	// CashOnDelivery is what the recipient pays the driver for the goods, in the currency of the purchase.
+	CashOnDelivery int
 }
 
 func (ppr PurchaseParcelRequest) Parse(connectionIdStr string) (PurchaseParcelRequestParsed, rfc7807.InvalidParams) {
@@ -229,8 +286,8 @@ func (ppr PurchaseParcelRequest) Parse(connectionIdStr string) (PurchaseParcelRe
 		params.SetInvalidParam("connectionID", err.Error())
 	}
 
-	if ppr.Weight > 50000 || ppr.Weight < 1000 {
-		params.SetInvalidParam("weight", "Has to be less than or equal to 50.")
+	if !validParcelWeight(ppr.Weight) {
+		params.SetInvalidParam("weight", parcelWeightError)
 	}
 
 	parcelType, ok := defineParcelType(ppr.Type)
@@ -238,6 +295,15 @@ func (ppr PurchaseParcelRequest) Parse(connectionIdStr string) (PurchaseParcelRe
 		params.SetInvalidParam("type", "Invalid parcell type.")
 	}
 
+	currency, ok := DefineCurrency(ppr.Currency)
+	if !ok {
+		params.SetInvalidParam("currency", "Must be one of EUR or UAH.")
+	}
+
+	if !validCashOnDelivery(ppr.CashOnDelivery) {
+		params.SetInvalidParam("cashOnDelivery", fmt.Sprintf("Has to be between 0 and %d.", config.MaxCashOnDeliveryAmount))
+	}
+
 	if params != nil {
 		return PurchaseParcelRequestParsed{}, params
 	}
@@ -257,17 +323,56 @@ func (ppr PurchaseParcelRequest) Parse(connectionIdStr string) (PurchaseParcelRe
 			Email:       ppr.RecieverEmail,
 			PhoneNumber: phonenumbers.Format(senderPhoneNumber, phonenumbers.E164),
 		},
-		DropOffAdress: ppr.DropOffAdress,
-		PickUpAdress:  ppr.PickUpAdress,
-		ConnectionID:  connectionID,
-		Width:         ppr.Width,
-		Length:        ppr.Length,
-		Height:        ppr.Height,
-		Weight:        ppr.Weight,
-		Type:          parcelType,
+		DropOffAdress:  ppr.DropOffAdress,
+		PickUpAdress:   ppr.PickUpAdress,
+		ConnectionID:   connectionID,
+		Width:          ppr.Width,
+		Length:         ppr.Length,
+		Height:         ppr.Height,
+		Weight:         ppr.Weight,
+		Type:           parcelType,
+		PromoCode:      NormalizePromoCode(ppr.PromoCode),
+		Currency:       currency,
+		CashOnDelivery: ppr.CashOnDelivery,
 	}, nil
 }
 
+const (
+	MinParcelWeight   = 1000
+	MaxParcelWeight   = 50000
+	parcelWeightError = "Has to be between 1000 and 50000 grams."
+)
+
+func validParcelWeight(weight int) bool {
+	return weight >= MinParcelWeight && weight <= MaxParcelWeight
+}
+
+// ParseOptionalParcelWeight is used to price the parcels before their weight is known, no weight stands for the lightest parcel.
+func ParseOptionalParcelWeight(weightStr string) (int, error) {
+	if weightStr == "" {
+		return MinParcelWeight, nil
+	}
+
+	weight, err := strconv.Atoi(weightStr)
+	if err != nil || !validParcelWeight(weight) {
+		return 0, fmt.Errorf(parcelWeightError)
+	}
+	return weight, nil
+}
+
+// ParseOptionalParcelType is used to price the parcels before their type is known, no type stands for a package.
+func ParseOptionalParcelType(parcelType string) (ParcelType, error) {
+	if parcelType == "" {
+		return PackageParcelType, nil
+	}
+
+	t, ok := defineParcelType(parcelType)
+	if !ok {
+		return "", fmt.Errorf("Must be either documents or package.")
+	}
+	return t, nil
+}
+
 func defineParcelType(parcelType string) (ParcelType, bool) {
 	switch parcelType {
 	case "package":
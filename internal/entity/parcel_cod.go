@@ -0,0 +1,181 @@
+package entity
+
+import (
+	"database/sql"
+	"maryan_api/config"
+	rfc7807 "maryan_api/pkg/problem"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/d3code/uuid"
+	"gorm.io/gorm"
+)
+
+type codStatus string
+
+const (
+	PendingCodStatus   codStatus = "Pending"
+	CollectedCodStatus codStatus = "Collected"
+	ScheduledCodStatus codStatus = "Scheduled"
+	PaidOutCodStatus   codStatus = "Paid Out"
+	CanceledCodStatus  codStatus = "Canceled"
+)
+
+// ParcelCashOnDelivery is the entry of the ledger of the sender for the goods the recipient
+// pays for on the delivery. The driver collects the cash at the drop-off, the admins pay it
+// out to the sender in batches.
+type ParcelCashOnDelivery struct {
+	ParcelID    uuid.UUID     `gorm:"type:binary(16);primaryKey"                                                  json:"parcelId"`
+	SenderID    uuid.UUID     `gorm:"type:binary(16);not null;index"                                              json:"senderId"`
+	Amount      int           `gorm:"type:MEDIUMINT UNSIGNED;not null"                                            json:"amount"`
+	Currency    Currency      `gorm:"type:enum('EUR','UAH');not null"                                             json:"currency"`
+	Status      codStatus     `gorm:"type:enum('Pending','Collected','Scheduled','Paid Out','Canceled');not null;index" json:"status"`
+	CollectedBy uuid.NullUUID `gorm:"type:binary(16)"                                                             json:"collectedBy"`
+	CollectedAt sql.NullTime  `                                                                                   json:"collectedAt"`
+	PayoutID    uuid.NullUUID `gorm:"type:binary(16);index"                                                       json:"payoutId"`
+	CreatedAt   time.Time     `gorm:"not null"                                                                    json:"createdAt"`
+}
+
+func NewParcelCashOnDelivery(parcelID, senderID uuid.UUID, amount int, currency Currency) *ParcelCashOnDelivery {
+	if amount == 0 {
+		return nil
+	}
+
+	return &ParcelCashOnDelivery{
+		ParcelID:  parcelID,
+		SenderID:  senderID,
+		Amount:    amount,
+		Currency:  currency,
+		Status:    PendingCodStatus,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+// UncollectedCash reports whether the recipient still has to pay the driver for the parcel.
+func (p Parcel) UncollectedCash() bool {
+	return p.CashOnDelivery != nil && p.CashOnDelivery.Status == PendingCodStatus
+}
+
+func validCashOnDelivery(amount int) bool {
+	return amount >= 0 && amount <= config.MaxCashOnDeliveryAmount
+}
+
+// CodBalance sums the ledger of the sender in one currency by the status of the entries.
+type CodBalance struct {
+	Currency  Currency `gorm:"column:currency"  json:"currency"`
+	Pending   int      `gorm:"column:pending"   json:"pending"`
+	Collected int      `gorm:"column:collected" json:"collected"`
+	Scheduled int      `gorm:"column:scheduled" json:"scheduled"`
+	PaidOut   int      `gorm:"column:paid_out"  json:"paidOut"`
+}
+
+type codPayoutStatus string
+
+const (
+	CreatedCodPayoutStatus codPayoutStatus = "Created"
+	PaidCodPayoutStatus    codPayoutStatus = "Paid"
+)
+
+// CodPayoutBatch pays out the cash collected before CollectedBefore in one currency, one payout per sender.
+type CodPayoutBatch struct {
+	ID              uuid.UUID       `gorm:"type:binary(16);primaryKey"               json:"id"`
+	Currency        Currency        `gorm:"type:enum('EUR','UAH');not null"          json:"currency"`
+	CollectedBefore time.Time       `gorm:"not null"                                 json:"collectedBefore"`
+	Status          codPayoutStatus `gorm:"type:enum('Created','Paid');not null"     json:"status"`
+	Amount          int             `gorm:"type:INT UNSIGNED;not null"               json:"amount"`
+	Reference       string          `gorm:"type:varchar(255);not null;default:''"    json:"reference"`
+	Payouts         []CodPayout     `gorm:"foreignKey:BatchID;constraint:OnDelete:CASCADE" json:"payouts"`
+	CreatedBy       uuid.UUID       `gorm:"type:binary(16);not null"                 json:"createdBy"`
+	CreatedAt       time.Time       `gorm:"not null"                                 json:"createdAt"`
+	PaidAt          sql.NullTime    `                                                json:"paidAt"`
+}
+
+// CodPayout is the cash of one sender in the batch.
+type CodPayout struct {
+	ID       uuid.UUID `gorm:"type:binary(16);primaryKey"      json:"id"`
+	BatchID  uuid.UUID `gorm:"type:binary(16);not null;index"  json:"batchId"`
+	SenderID uuid.UUID `gorm:"type:binary(16);not null;index"  json:"senderId"`
+	Amount   int       `gorm:"type:INT UNSIGNED;not null"      json:"amount"`
+	Parcels  int       `gorm:"type:SMALLINT UNSIGNED;not null" json:"parcels"`
+}
+
+// NewCodPayoutBatch groups the collected entries by their senders and assigns them to the payouts.
+func NewCodPayoutBatch(currency Currency, collectedBefore time.Time, adminID uuid.UUID, entries []ParcelCashOnDelivery) CodPayoutBatch {
+	var batch = CodPayoutBatch{
+		ID:              uuid.New(),
+		Currency:        currency,
+		CollectedBefore: collectedBefore,
+		Status:          CreatedCodPayoutStatus,
+		CreatedBy:       adminID,
+		CreatedAt:       time.Now().UTC(),
+	}
+
+	for i, entry := range entries {
+		j := slices.IndexFunc(batch.Payouts, func(p CodPayout) bool { return p.SenderID == entry.SenderID })
+		if j == -1 {
+			batch.Payouts = append(batch.Payouts, CodPayout{ID: uuid.New(), BatchID: batch.ID, SenderID: entry.SenderID})
+			j = len(batch.Payouts) - 1
+		}
+
+		batch.Payouts[j].Amount += entry.Amount
+		batch.Payouts[j].Parcels++
+		batch.Amount += entry.Amount
+		entries[i].PayoutID = uuid.NullUUID{UUID: batch.Payouts[j].ID, Valid: true}
+	}
+
+	return batch
+}
+
+// Payable reports an error if the batch has already been paid out.
+func (b CodPayoutBatch) Payable() error {
+	if b.Status == PaidCodPayoutStatus {
+		return rfc7807.New(http.StatusConflict, "paid-cod-payout", "Paid Payout Error", "The payout batch has already been paid out.")
+	}
+	return nil
+}
+
+type CodPayoutBatchJSON struct {
+	Currency        string    `json:"currency"`
+	CollectedBefore time.Time `json:"collectedBefore"`
+}
+
+// Parse defaults the batch to the cash collected until now.
+func (b CodPayoutBatchJSON) Parse() (Currency, time.Time, error) {
+	var params rfc7807.InvalidParams
+
+	currency, ok := DefineCurrency(b.Currency)
+	if !ok {
+		params.SetInvalidParam("currency", "Must be one of EUR or UAH.")
+	}
+
+	now := time.Now().UTC()
+	collectedBefore := b.CollectedBefore.UTC()
+	if b.CollectedBefore.IsZero() {
+		collectedBefore = now
+	} else if collectedBefore.After(now) {
+		params.SetInvalidParam("collectedBefore", "Can not be in the future.")
+	}
+
+	if params != nil {
+		return "", time.Time{}, rfc7807.BadRequest("invalid-data", "Invalid Data Error", "The provided payout batch is not valid.", params...)
+	}
+
+	return currency, collectedBefore, nil
+}
+
+type CodPayoutPaidJSON struct {
+	Reference string `json:"reference"`
+}
+
+// Parse returns the reference of the transfers the batch was paid out with.
+func (p CodPayoutPaidJSON) Parse() (string, error) {
+	if len(p.Reference) < 1 || len(p.Reference) > 255 {
+		return "", rfc7807.BadRequest("invalid-data", "Invalid Data Error", "The provided payout is not valid.", rfc7807.InvalidParam{"reference", "Has to be between 1 and 255 characters."})
+	}
+	return p.Reference, nil
+}
+
+func MigrateCashOnDelivery(db *gorm.DB) error {
+	return db.AutoMigrate(&CodPayoutBatch{}, &CodPayout{})
+}
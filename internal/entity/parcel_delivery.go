@@ -0,0 +1,117 @@
+package entity
+
+import (
+	"crypto/subtle"
+	"maryan_api/config"
+	rfc7807 "maryan_api/pkg/problem"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/d3code/uuid"
+)
+
+type deliveryProofMethod string
+
+const (
+	CodeDeliveryProofMethod      deliveryProofMethod = "Code"
+	SignatureDeliveryProofMethod deliveryProofMethod = "Signature"
+	PhotoDeliveryProofMethod     deliveryProofMethod = "Photo"
+)
+
+func defineDeliveryProofMethod(v string) (deliveryProofMethod, bool) {
+	switch deliveryProofMethod(v) {
+	case CodeDeliveryProofMethod, SignatureDeliveryProofMethod, PhotoDeliveryProofMethod:
+		return deliveryProofMethod(v), true
+	default:
+		return "", false
+	}
+}
+
+// NeedsEvidence reports whether the method is proven by an uploaded image rather than the code.
+func (m deliveryProofMethod) NeedsEvidence() bool {
+	return m != CodeDeliveryProofMethod
+}
+
+// ParcelDeliveryProof is what the driver collected from the recipient on the drop-off, it is kept for the disputes.
+type ParcelDeliveryProof struct {
+	ParcelID     uuid.UUID           `gorm:"type:binary(16);primaryKey"                     json:"parcelId"`
+	DriverID     uuid.UUID           `gorm:"type:binary(16);not null;index"                 json:"driverId"`
+	Method       deliveryProofMethod `gorm:"type:enum('Code','Signature','Photo');not null" json:"method"`
+	ReceivedBy   string              `gorm:"type:varchar(255);not null"                     json:"receivedBy"`
+	EvidencePath string              `gorm:"type:varchar(255);not null;default:''"          json:"-"`
+	CreatedAt    time.Time           `gorm:"not null"                                       json:"createdAt"`
+}
+
+// HasEvidence reports whether a signature or a photo was uploaded with the proof.
+func (p ParcelDeliveryProof) HasEvidence() bool {
+	return p.EvidencePath != ""
+}
+
+// NewParcelDeliveryCode returns the random 6 digit code texted to the recipient when the parcel goes out for delivery.
+func NewParcelDeliveryCode() (string, error) {
+	return NewParcelTrackingCode()
+}
+
+// VerifyDeliveryCode reports whether the code is the one texted to the recipient of the parcel.
+func (p Parcel) VerifyDeliveryCode(code string) bool {
+	return p.DeliveryCode != "" && subtle.ConstantTimeCompare([]byte(p.DeliveryCode), []byte(code)) == 1
+}
+
+// DeliveryEvidencePath is where the signature or the photo of the delivery is stored, out of the public images.
+// Every upload gets its own file, so the one of a failed drop-off can be removed without touching another.
+func DeliveryEvidencePath(parcelID uuid.UUID, method deliveryProofMethod) string {
+	return filepath.Join(config.RootPath(), "static", "delivery-proofs", parcelID.String()+"-"+strings.ToLower(string(method))+"-"+uuid.New().String()+".jpg")
+}
+
+type DeliveryProofJSON struct {
+	Code         string `form:"code"`
+	Method       string `form:"method"`
+	DeliveryCode string `form:"deliveryCode"`
+	ReceivedBy   string `form:"receivedBy"`
+}
+
+// Parse checks the proof against the parcel, no name of the person who received it stands for the recipient.
+func (d DeliveryProofJSON) Parse(parcel Parcel, driverID uuid.UUID, hasEvidence bool) (ParcelDeliveryProof, error) {
+	var params rfc7807.InvalidParams
+
+	method, ok := defineDeliveryProofMethod(d.Method)
+	if !ok {
+		params.SetInvalidParam("method", "Must be one of Code, Signature or Photo.")
+	} else if method.NeedsEvidence() && !hasEvidence {
+		params.SetInvalidParam("evidence", "The signature or the photo has to be uploaded.")
+	} else if !method.NeedsEvidence() && d.DeliveryCode == "" {
+		params.SetInvalidParam("deliveryCode", "Has not to be blank.")
+	}
+
+	if len(d.ReceivedBy) > 255 {
+		params.SetInvalidParam("receivedBy", "Must not be longer than 255 characters.")
+	}
+
+	if params != nil {
+		return ParcelDeliveryProof{}, rfc7807.BadRequest("invalid-data", "Invalid Data Error", "The provided proof of delivery is not valid.", params...)
+	}
+
+	if method == CodeDeliveryProofMethod && !parcel.VerifyDeliveryCode(d.DeliveryCode) {
+		return ParcelDeliveryProof{}, rfc7807.BadRequest("incorrect-delivery-code", "Incorrect Delivery Code Error", "The code does not match the one sent to the recipient.")
+	}
+
+	receivedBy := strings.TrimSpace(d.ReceivedBy)
+	if receivedBy == "" {
+		receivedBy = parcel.RecieverFirstName + " " + parcel.RecieverLastName
+	}
+
+	var proof = ParcelDeliveryProof{
+		ParcelID:   parcel.ID,
+		DriverID:   driverID,
+		Method:     method,
+		ReceivedBy: receivedBy,
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	if method.NeedsEvidence() {
+		proof.EvidencePath = DeliveryEvidencePath(parcel.ID, method)
+	}
+
+	return proof, nil
+}
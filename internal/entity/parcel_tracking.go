@@ -0,0 +1,185 @@
+package entity
+
+import (
+	"crypto/rand"
+	"fmt"
+	rfc7807 "maryan_api/pkg/problem"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/d3code/uuid"
+	"gorm.io/gorm"
+)
+
+type parcelStatus string
+
+const (
+	AcceptedParcelStatus       parcelStatus = "Accepted"
+	PickedUpParcelStatus       parcelStatus = "Picked Up"
+	InTransitParcelStatus      parcelStatus = "In Transit"
+	CrossedBorderParcelStatus  parcelStatus = "Crossed Border"
+	OutForDeliveryParcelStatus parcelStatus = "Out For Delivery"
+	DeliveredParcelStatus      parcelStatus = "Delivered"
+	ReturnedParcelStatus       parcelStatus = "Returned"
+)
+
+func DefineParcelStatus(v string) (parcelStatus, bool) {
+	switch parcelStatus(v) {
+	case AcceptedParcelStatus, PickedUpParcelStatus, InTransitParcelStatus, CrossedBorderParcelStatus,
+		OutForDeliveryParcelStatus, DeliveredParcelStatus, ReturnedParcelStatus:
+		return parcelStatus(v), true
+	default:
+		return "", false
+	}
+}
+
+// Final reports whether the parcel has left the care of the carrier, its status can not change anymore.
+func (s parcelStatus) Final() bool {
+	return s == DeliveredParcelStatus || s == ReturnedParcelStatus
+}
+
+// Precedes reports whether the parcel with the status s has not reached the status yet.
+func (s parcelStatus) Precedes(status parcelStatus) bool {
+	return !s.Final() && s.step() < status.step()
+}
+
+func (s parcelStatus) step() int {
+	switch s {
+	case AcceptedParcelStatus:
+		return 0
+	case PickedUpParcelStatus:
+		return 1
+	case InTransitParcelStatus:
+		return 2
+	case CrossedBorderParcelStatus:
+		return 3
+	case OutForDeliveryParcelStatus:
+		return 4
+	default:
+		return 5
+	}
+}
+
+// ParcelStatusUpdate is one entry of the timeline of the parcel.
+type ParcelStatusUpdate struct {
+	ParcelID  uuid.UUID    `gorm:"type:binary(16);not null;index"                                                                                  json:"-"`
+	Status    parcelStatus `gorm:"type:enum('Accepted','Picked Up','In Transit','Crossed Border','Out For Delivery','Delivered','Returned');not null" json:"status"`
+	Comment   string       `gorm:"type:varchar(500);not null;default:''"                                                                           json:"comment"`
+	CreatedAt time.Time    `gorm:"not null"                                                                                                        json:"createdAt"`
+}
+
+func NewParcelStatusUpdate(parcelID uuid.UUID, status parcelStatus, comment string) ParcelStatusUpdate {
+	return ParcelStatusUpdate{ParcelID: parcelID, Status: status, Comment: comment, CreatedAt: time.Now().UTC()}
+}
+
+// ParcelTrackingNumber is the public number of the parcel, it is taken from its id.
+func ParcelTrackingNumber(parcelID uuid.UUID) string {
+	return strings.ToUpper(strings.ReplaceAll(parcelID.String(), "-", "")[:12])
+}
+
+// NewParcelTrackingCode returns the random 6 digit code the sender shares with the recipient.
+func NewParcelTrackingCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", rfc7807.Internal("Tracking Code Error", err.Error())
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// Trackable reports whether the tracking code and the last digits of the phone number of the
+// recipient match the paid parcel.
+func (p Parcel) Trackable(code, phoneSuffix string) bool {
+	return p.Payment.Succeeded && code != "" && p.TrackingCode == code &&
+		len(phoneSuffix) >= 4 && strings.HasSuffix(p.RecieverPhoneNumber, phoneSuffix)
+}
+
+// ParcelTrackingLock counts the wrong tracking attempts of a tracking number or of a client, the
+// tracking is locked until LockedUntil once there have been too many of them.
+type ParcelTrackingLock struct {
+	Key         string     `gorm:"type:varchar(64);primaryKey"`
+	Failures    uint       `gorm:"not null"`
+	WindowStart time.Time  `gorm:"not null"`
+	LockedUntil *time.Time `gorm:"index"`
+}
+
+// ParcelTrackingLockKeys returns the keys the tracking attempts of the client are counted by, so
+// neither one tracking number nor the tracking numbers in general can be probed.
+func ParcelTrackingLockKeys(trackingNumber, clientIP string) []string {
+	// The longer numbers are never given, they are cut so the key fits.
+	if len(trackingNumber) > 12 {
+		trackingNumber = trackingNumber[:12]
+	}
+	return []string{"number:" + trackingNumber, "ip:" + clientIP}
+}
+
+// ParcelTracking is the timeline of the parcel shown to anyone with its tracking code.
+type ParcelTracking struct {
+	TrackingNumber string               `json:"trackingNumber"`
+	Status         parcelStatus         `json:"status"`
+	Type           ParcelType           `json:"type"`
+	From           string               `json:"from"`
+	To             string               `json:"to"`
+	DepartureTime  time.Time            `json:"departureTime"`
+	ArrivalTime    time.Time            `json:"arrivalTime"`
+	Updates        []ParcelStatusUpdate `json:"updates"`
+}
+
+func (p Parcel) Tracking(segment Segment) ParcelTracking {
+	return ParcelTracking{
+		TrackingNumber: p.TrackingNumber,
+		Status:         p.Status,
+		Type:           p.Type,
+		From:           segment.From.Name(),
+		To:             segment.To.Name(),
+		DepartureTime:  segment.From.DepartureTime,
+		ArrivalTime:    segment.To.ArrivalTime,
+		Updates:        p.Updates,
+	}
+}
+
+type ParcelStatusJSON struct {
+	Status  string `json:"status"`
+	Comment string `json:"comment"`
+}
+
+// Parse accepts the statuses set by the staff, Accepted is only given when the parcel is bought
+// and Delivered when the driver hands it over with a proof of delivery.
+func (s ParcelStatusJSON) Parse(parcelID uuid.UUID) (ParcelStatusUpdate, error) {
+	var params rfc7807.InvalidParams
+
+	status, ok := DefineParcelStatus(s.Status)
+	if !ok || status == AcceptedParcelStatus || status == DeliveredParcelStatus {
+		params.SetInvalidParam("status", "Must be one of Picked Up, In Transit, Crossed Border, Out For Delivery or Returned.")
+	}
+
+	if len(s.Comment) > 500 {
+		params.SetInvalidParam("comment", "Must not be longer than 500 characters.")
+	}
+
+	if params != nil {
+		return ParcelStatusUpdate{}, rfc7807.BadRequest("invalid-data", "Invalid Data Error", "The provided status is not valid.", params...)
+	}
+
+	return NewParcelStatusUpdate(parcelID, status, s.Comment), nil
+}
+
+// migrateParcelTracking gives the parcels bought before the tracking was introduced
+// a tracking number, a code and the Accepted entry of the timeline.
+func migrateParcelTracking(db *gorm.DB) error {
+	err := db.Exec(`
+		UPDATE parcels
+		SET tracking_number = LEFT(HEX(id), 12), tracking_code = LPAD(FLOOR(RAND() * 1000000), 6, '0')
+		WHERE tracking_number = ''
+	`).Error
+	if err != nil {
+		return err
+	}
+
+	return db.Exec(`
+		INSERT INTO parcel_status_updates (parcel_id, status, comment, created_at)
+		SELECT id, ?, '', created_at
+		FROM parcels
+		WHERE id NOT IN (SELECT parcel_id FROM parcel_status_updates)
+	`, AcceptedParcelStatus).Error
+}
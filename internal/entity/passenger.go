@@ -13,6 +13,7 @@ type Passenger struct {
 	TicketID  uuid.UUID      `gorm:"type:binary(16);not null;constraint:OnDelete:CASCADE"         json:"ticketId"`
 	FirstName string         `gorm:"type:varchar(255); not null"    json:"firstName"`
 	LastName  string         `gorm:"type:varchar(255); not null"    json:"lastName"`
+	Category  fareCategory   `gorm:"type:enum('Adult','Teenager','Child');not null"               json:"category"`
 	CreatedAt time.Time      `gorm:"not null"                       json:"-"`
 	DeletedAt gorm.DeletedAt `                                      json:"-"`
 }
@@ -26,12 +27,14 @@ type PassengerSimplified struct {
 type NewPassenger struct {
 	FirstName string `json:"firstName"`
 	LastName  string `json:"lastName"`
+	Category  string `json:"category"`
 }
 
 func (p NewPassenger) Parse() Passenger {
 	return Passenger{
 		FirstName: p.FirstName,
 		LastName:  p.LastName,
+		Category:  fareCategory(p.Category),
 	}
 }
 
@@ -57,6 +60,10 @@ func (p *Passenger) Validate() rfc7807.InvalidParams {
 		params.SetInvalidParam("surname", "Must not be empty.")
 	}
 
+	if _, ok := DefineFareCategory(string(p.Category)); !ok {
+		params.SetInvalidParam("category", "Must be one of Adult, Teenager or Child.")
+	}
+
 	return params
 }
 
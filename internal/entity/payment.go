@@ -0,0 +1,28 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type StripeEvent struct {
+	ID        string    `gorm:"type:varchar(255);primaryKey" json:"id"`
+	Type      string    `gorm:"type:varchar(100);not null"   json:"type"`
+	CreatedAt time.Time `gorm:"not null"                     json:"createdAt"`
+}
+
+type PaymentProduct string
+
+const (
+	TicketPaymentProduct         PaymentProduct = "Ticket"
+	ParcelPaymentProduct         PaymentProduct = "Parcel"
+	TicketExchangePaymentProduct PaymentProduct = "Ticket Exchange"
+	LuggageOrderPaymentProduct   PaymentProduct = "Luggage Order"
+)
+
+func MigratePayment(db *gorm.DB) error {
+	return db.AutoMigrate(
+		&StripeEvent{},
+	)
+}
@@ -0,0 +1,268 @@
+package entity
+
+import (
+	"maryan_api/config"
+	rfc7807 "maryan_api/pkg/problem"
+	"strconv"
+	"time"
+
+	"github.com/d3code/uuid"
+	"gorm.io/gorm"
+)
+
+type pricingFactor string
+
+const (
+	// LoadFactorPricingFactor is the percentage of the seats of the bus sold on the segment, 0-100.
+	LoadFactorPricingFactor pricingFactor = "Load Factor"
+	// DaysBeforeDeparturePricingFactor is the number of whole days left until the departure.
+	DaysBeforeDeparturePricingFactor pricingFactor = "Days Before Departure"
+	// WeekdayPricingFactor is the local weekday of the departure, 0 being Sunday.
+	WeekdayPricingFactor pricingFactor = "Weekday"
+	// SeasonPricingFactor is the local month of the departure, 1-12.
+	SeasonPricingFactor pricingFactor = "Season"
+)
+
+func DefinePricingFactor(v string) (pricingFactor, bool) {
+	switch pricingFactor(v) {
+	case LoadFactorPricingFactor, DaysBeforeDeparturePricingFactor, WeekdayPricingFactor, SeasonPricingFactor:
+		return pricingFactor(v), true
+	default:
+		return "", false
+	}
+}
+
+// PricingRule changes the fare of the line by the percentage when the factor is within [From, To].
+type PricingRule struct {
+	ID         uuid.UUID     `gorm:"type:binary(16);primaryKey"                                                   json:"id"`
+	Line       int           `gorm:"type:SMALLINT;not null;index"                                                 json:"line"`
+	Factor     pricingFactor `gorm:"type:enum('Load Factor','Days Before Departure','Weekday','Season');not null" json:"factor"`
+	From       int           `gorm:"type:SMALLINT;not null"                                                       json:"from"`
+	To         int           `gorm:"type:SMALLINT;not null"                                                       json:"to"`
+	Percentage int           `gorm:"type:SMALLINT;not null"                                                       json:"percentage"`
+}
+
+// PricingBounds keeps the dynamic fare of the line between the percentages of the base fare.
+type PricingBounds struct {
+	Line          int `gorm:"type:SMALLINT;primaryKey"       json:"line"`
+	MinPercentage int `gorm:"type:SMALLINT UNSIGNED;not null" json:"minPercentage"`
+	MaxPercentage int `gorm:"type:SMALLINT UNSIGNED;not null" json:"maxPercentage"`
+}
+
+// PricingConditions are the values of the factors at the time of the sale.
+type PricingConditions struct {
+	LoadFactor          int
+	DaysBeforeDeparture int
+	Weekday             int
+	Season              int
+}
+
+// NewPricingConditions describes the sale of the segment with takenSeats of the seats already sold or held.
+func NewPricingConditions(segment Segment, seats, takenSeats int, now time.Time) PricingConditions {
+	departure := config.MustParseToLocalByUUID(segment.From.DepartureTime, segment.From.CountryID)
+
+	var loadFactor int
+	if seats > 0 {
+		loadFactor = min(max(takenSeats, 0)*100/seats, 100)
+	}
+
+	return PricingConditions{
+		LoadFactor:          loadFactor,
+		DaysBeforeDeparture: max(int(segment.From.DepartureTime.Sub(now).Hours()/24), 0),
+		Weekday:             int(departure.Weekday()),
+		Season:              int(departure.Month()),
+	}
+}
+
+func (c PricingConditions) value(factor pricingFactor) int {
+	switch factor {
+	case LoadFactorPricingFactor:
+		return c.LoadFactor
+	case DaysBeforeDeparturePricingFactor:
+		return c.DaysBeforeDeparture
+	case WeekdayPricingFactor:
+		return c.Weekday
+	default:
+		return c.Season
+	}
+}
+
+// Pricing holds the rules and the bounds of the lines.
+type Pricing struct {
+	Rules  []PricingRule   `json:"rules"`
+	Bounds []PricingBounds `json:"bounds"`
+}
+
+func (p Pricing) bounds(line int) PricingBounds {
+	for _, bounds := range p.Bounds {
+		if bounds.Line == line {
+			return bounds
+		}
+	}
+	return PricingBounds{line, config.DefaultMinFarePercentage, config.DefaultMaxFarePercentage}
+}
+
+// Fare returns the base fare of the segment adjusted by every matching rule of the line.
+// The percentages of the rules add up and the result is kept within the bounds of the line.
+func (p Pricing) Fare(line, baseFare int, conditions PricingConditions) int {
+	var percentage = 100
+	for _, rule := range p.Rules {
+		value := conditions.value(rule.Factor)
+		if rule.Line == line && value >= rule.From && value <= rule.To {
+			percentage += rule.Percentage
+		}
+	}
+
+	bounds := p.bounds(line)
+	percentage = min(max(percentage, bounds.MinPercentage), bounds.MaxPercentage)
+
+	return baseFare * percentage / 100
+}
+
+// SegmentFare is the fare of one passenger on the segment sold now, seats being the seats of the bus
+// and takenSeats the ones already sold or held on the segment.
+func (p Pricing) SegmentFare(line int, segment Segment, seats, takenSeats int) int {
+	return p.Fare(line, segment.Price(), NewPricingConditions(segment, seats, takenSeats, time.Now().UTC()))
+}
+
+// Line returns the rules and the bounds applied on the line, default bounds included.
+func (p Pricing) Line(line int) Pricing {
+	var effective = Pricing{Rules: []PricingRule{}, Bounds: []PricingBounds{p.bounds(line)}}
+	for _, rule := range p.Rules {
+		if rule.Line == line {
+			effective.Rules = append(effective.Rules, rule)
+		}
+	}
+	return effective
+}
+
+type PricingRuleJSON struct {
+	Factor     string `json:"factor"`
+	From       int    `json:"from"`
+	To         int    `json:"to"`
+	Percentage int    `json:"percentage"`
+}
+
+type PricingJSON struct {
+	Rules         []PricingRuleJSON `json:"rules"`
+	MinPercentage int               `json:"minPercentage"`
+	MaxPercentage int               `json:"maxPercentage"`
+}
+
+func (p PricingJSON) Parse(line int) (Pricing, error) {
+	var params rfc7807.InvalidParams
+	var pricing = Pricing{
+		Rules:  make([]PricingRule, len(p.Rules)),
+		Bounds: []PricingBounds{{line, p.MinPercentage, p.MaxPercentage}},
+	}
+
+	for i, rule := range p.Rules {
+		name := "rules[" + strconv.Itoa(i) + "]"
+		factor, ok := DefinePricingFactor(rule.Factor)
+		if !ok {
+			params.SetInvalidParam(name+".factor", "Must be one of Load Factor, Days Before Departure, Weekday or Season.")
+		}
+
+		if rule.From > rule.To {
+			params.SetInvalidParam(name+".from", "Can not be greater than to.")
+		}
+
+		if rule.Percentage <= -100 || rule.Percentage > 1000 {
+			params.SetInvalidParam(name+".percentage", "Must be greater than -100 and not greater than 1000.")
+		}
+
+		pricing.Rules[i] = PricingRule{uuid.New(), line, factor, rule.From, rule.To, rule.Percentage}
+	}
+
+	if p.MinPercentage < 1 {
+		params.SetInvalidParam("minPercentage", "Must be at least 1.")
+	}
+
+	if p.MaxPercentage < p.MinPercentage {
+		params.SetInvalidParam("maxPercentage", "Can not be less than minPercentage.")
+	}
+
+	if params != nil {
+		return Pricing{}, rfc7807.BadRequest("invalid-data", "Invalid Data Error", "The provided pricing is not valid.", params...)
+	}
+
+	return pricing, nil
+}
+
+// PriceQuote is the fare of the segment offered to the customer at the start of the checkout.
+// It is honored until it expires, so the price does not change while the customer is paying.
+type PriceQuote struct {
+	ID           uuid.UUID `gorm:"type:binary(16);primaryKey"      json:"id"`
+	UserID       uuid.UUID `gorm:"type:binary(16);not null"        json:"userId"`
+	ConnectionID uuid.UUID `gorm:"type:binary(16);not null;index"  json:"connectionId"`
+	FromStop     int       `gorm:"type:TINYINT UNSIGNED;not null"  json:"fromStop"`
+	ToStop       int       `gorm:"type:TINYINT UNSIGNED;not null"  json:"toStop"`
+	Passengers   int       `gorm:"type:TINYINT UNSIGNED;not null"  json:"passengers"`
+	Fare         int       `gorm:"type:MEDIUMINT UNSIGNED;not null" json:"fare"`
+	Currency     Currency  `gorm:"type:enum('EUR','UAH');not null" json:"currency"`
+	ExchangeRate float64   `gorm:"type:DECIMAL(12,6);not null"     json:"exchangeRate"`
+	ExpiresAt    time.Time `gorm:"not null;index"                  json:"expiresAt"`
+	CreatedAt    time.Time `gorm:"not null"                        json:"createdAt"`
+}
+
+func NewPriceQuote(userID, connectionID uuid.UUID, segment Segment, passengers, fare int, rate ExchangeRate) PriceQuote {
+	return PriceQuote{
+		ID:           uuid.New(),
+		UserID:       userID,
+		ConnectionID: connectionID,
+		FromStop:     segment.From.Sequence,
+		ToStop:       segment.To.Sequence,
+		Passengers:   passengers,
+		Fare:         fare,
+		Currency:     rate.Currency,
+		ExchangeRate: rate.Rate,
+		ExpiresAt:    time.Now().UTC().Add(config.PriceQuoteDuration),
+	}
+}
+
+// Rate returns the exchange rate the quoted fare is charged at.
+func (q PriceQuote) Rate() ExchangeRate {
+	return ExchangeRate{Currency: q.Currency, Rate: q.ExchangeRate}
+}
+
+// Covers reports whether the user can use the quote to pay for the passengers on the segment of the connection in the currency.
+func (q PriceQuote) Covers(userID, connectionID uuid.UUID, segment Segment, passengers int, currency Currency) error {
+	if q.UserID != userID || q.ConnectionID != connectionID || q.FromStop != segment.From.Sequence || q.ToStop != segment.To.Sequence ||
+		q.Passengers != passengers || q.Currency != currency {
+		return rfc7807.BadRequest("foreign-price-quote", "Foreign Price Quote Error", "The price quote was given for another checkout.")
+	}
+
+	if q.ExpiresAt.Before(time.Now().UTC()) {
+		return rfc7807.BadRequest("expired-price-quote", "Expired Price Quote Error", "The price quote has expired, please check the price again.")
+	}
+
+	return nil
+}
+
+type PriceQuoteJSON struct {
+	ConnectionID uuid.UUID `json:"connectionId"`
+	FromStop     int       `json:"fromStop"`
+	ToStop       int       `json:"toStop"`
+	Passengers   int       `json:"passengers"`
+	Currency     string    `json:"currency"`
+}
+
+func (q PriceQuoteJSON) Validate() error {
+	var params rfc7807.InvalidParams
+	if q.ConnectionID == uuid.Nil {
+		params.SetInvalidParam("connectionId", "Must not be empty.")
+	}
+
+	if q.Passengers < 1 {
+		params.SetInvalidParam("passengers", "Must be greater than 0.")
+	}
+
+	if params != nil {
+		return rfc7807.BadRequest("invalid-data", "Invalid Data Error", "The provided price quote request is not valid.", params...)
+	}
+	return nil
+}
+
+func MigratePricing(db *gorm.DB) error {
+	return db.AutoMigrate(&PricingRule{}, &PricingBounds{}, &PriceQuote{})
+}
@@ -0,0 +1,197 @@
+package entity
+
+import (
+	"database/sql"
+	"maryan_api/config"
+	rfc7807 "maryan_api/pkg/problem"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/d3code/uuid"
+	"gorm.io/gorm"
+)
+
+type PromoCode struct {
+	ID                   uuid.UUID         `gorm:"type:binary(16);primaryKey"                 json:"id"`
+	Code                 string            `gorm:"type:varchar(32);not null;uniqueIndex"      json:"code"`
+	DiscountType         promoDiscountType `gorm:"type:enum('Percentage','Fixed');not null"   json:"discountType"`
+	Value                int               `gorm:"type:MEDIUMINT UNSIGNED;not null"           json:"value"`
+	ValidFrom            time.Time         `gorm:"not null"                                   json:"validFrom"`
+	ValidUntil           time.Time         `gorm:"not null"                                   json:"validUntil"`
+	UsageLimit           int               `gorm:"type:INT UNSIGNED;not null"                 json:"usageLimit"`
+	PerUserLimit         int               `gorm:"type:INT UNSIGNED;not null"                 json:"perUserLimit"`
+	Product              promoProduct      `gorm:"type:enum('Any','Ticket','Parcel');not null" json:"product"`
+	Line                 sql.NullInt16     `gorm:"type:SMALLINT"                              json:"line"`
+	DepartureCountryID   uuid.NullUUID     `gorm:"type:binary(16)"                            json:"departureCountryId"`
+	DestinationCountryID uuid.NullUUID     `gorm:"type:binary(16)"                            json:"destinationCountryId"`
+	CreatedAt            time.Time         `gorm:"not null"                                   json:"createdAt"`
+	DeletedAt            gorm.DeletedAt    `                                                  json:"-"`
+}
+
+type promoDiscountType string
+
+const (
+	PercentagePromoDiscountType promoDiscountType = "Percentage"
+	FixedPromoDiscountType      promoDiscountType = "Fixed"
+)
+
+type promoProduct string
+
+const (
+	AnyPromoProduct    promoProduct = "Any"
+	TicketPromoProduct promoProduct = "Ticket"
+	ParcelPromoProduct promoProduct = "Parcel"
+)
+
+// minimalCharge is the smallest amount a checkout session can be created for.
+const minimalCharge = 50
+
+// PromoCodeUsage counts the redemptions that are paid or still being paid.
+type PromoCodeUsage struct {
+	Total int
+	User  int
+}
+
+// PromoCodeTarget describes the sale the code is applied to.
+type PromoCodeTarget struct {
+	Product              promoProduct
+	Line                 int
+	DepartureCountryID   uuid.UUID
+	DestinationCountryID uuid.UUID
+	Price                int
+}
+
+// Discount checks that the code can be applied to the sale and returns the amount to subtract from the price.
+func (p PromoCode) Discount(usage PromoCodeUsage, target PromoCodeTarget) (int, error) {
+	now := time.Now().UTC()
+	if now.Before(p.ValidFrom) || now.After(p.ValidUntil) {
+		return 0, rfc7807.BadRequest("expired-promo-code", "Expired Promo Code Error", "The promo code is not valid at this time.")
+	}
+
+	err := p.CheckUsage(usage)
+	if err != nil {
+		return 0, err
+	}
+
+	if (p.Product != AnyPromoProduct && p.Product != target.Product) ||
+		(p.Line.Valid && int(p.Line.Int16) != target.Line) ||
+		(p.DepartureCountryID.Valid && p.DepartureCountryID.UUID != target.DepartureCountryID) ||
+		(p.DestinationCountryID.Valid && p.DestinationCountryID.UUID != target.DestinationCountryID) {
+		return 0, rfc7807.BadRequest("inapplicable-promo-code", "Inapplicable Promo Code Error", "The promo code can not be applied to this purchase.")
+	}
+
+	discount := p.Value
+	if p.DiscountType == PercentagePromoDiscountType {
+		discount = target.Price * p.Value / 100
+	}
+
+	return max(0, min(discount, target.Price-minimalCharge)), nil
+}
+
+// CheckUsage returns an error when the code has been used up in total or by the user.
+func (p PromoCode) CheckUsage(usage PromoCodeUsage) error {
+	if (p.UsageLimit > 0 && usage.Total >= p.UsageLimit) || (p.PerUserLimit > 0 && usage.User >= p.PerUserLimit) {
+		return rfc7807.New(http.StatusConflict, "used-promo-code", "Used Promo Code Error", "The promo code has reached its usage limit.")
+	}
+	return nil
+}
+
+type PromoCodeJSON struct {
+	Code               string    `json:"code"`
+	DiscountType       string    `json:"discountType"`
+	Value              int       `json:"value"`
+	ValidFrom          time.Time `json:"validFrom"`
+	ValidUntil         time.Time `json:"validUntil"`
+	UsageLimit         int       `json:"usageLimit"`
+	PerUserLimit       int       `json:"perUserLimit"`
+	Product            string    `json:"product"`
+	Line               *int      `json:"line"`
+	DepartureCountry   string    `json:"departureCountry"`
+	DestinationCountry string    `json:"destinationCountry"`
+}
+
+func (p PromoCodeJSON) Parse() (PromoCode, error) {
+	var params rfc7807.InvalidParams
+	var promoCode = PromoCode{
+		Code:         NormalizePromoCode(p.Code),
+		DiscountType: promoDiscountType(p.DiscountType),
+		Value:        p.Value,
+		ValidFrom:    p.ValidFrom.UTC(),
+		ValidUntil:   p.ValidUntil.UTC(),
+		UsageLimit:   p.UsageLimit,
+		PerUserLimit: p.PerUserLimit,
+		Product:      promoProduct(p.Product),
+	}
+
+	if len(promoCode.Code) < 3 || len(promoCode.Code) > 32 {
+		params.SetInvalidParam("code", "Must be from 3 to 32 characters long.")
+	}
+
+	switch promoCode.DiscountType {
+	case PercentagePromoDiscountType:
+		if p.Value < 1 || p.Value > 100 {
+			params.SetInvalidParam("value", "Must be between 1 and 100.")
+		}
+	case FixedPromoDiscountType:
+		if p.Value < 1 {
+			params.SetInvalidParam("value", "Must be greater than 0.")
+		}
+	default:
+		params.SetInvalidParam("discountType", "Must be either Percentage or Fixed.")
+	}
+
+	if !p.ValidUntil.After(p.ValidFrom) {
+		params.SetInvalidParam("validUntil", "Must be after validFrom.")
+	}
+
+	if p.UsageLimit < 0 {
+		params.SetInvalidParam("usageLimit", "Can not be less than 0.")
+	}
+
+	if p.PerUserLimit < 0 {
+		params.SetInvalidParam("perUserLimit", "Can not be less than 0.")
+	}
+
+	switch promoCode.Product {
+	case AnyPromoProduct, TicketPromoProduct, ParcelPromoProduct:
+	case "":
+		promoCode.Product = AnyPromoProduct
+	default:
+		params.SetInvalidParam("product", "Must be one of Any, Ticket or Parcel.")
+	}
+
+	if p.Line != nil {
+		promoCode.Line = sql.NullInt16{Int16: int16(*p.Line), Valid: true}
+	}
+
+	if p.DepartureCountry != "" {
+		id, _, err := config.ParseCountry(p.DepartureCountry)
+		if err != nil {
+			params.SetInvalidParam("departureCountry", err.Error())
+		}
+		promoCode.DepartureCountryID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	if p.DestinationCountry != "" {
+		id, _, err := config.ParseCountry(p.DestinationCountry)
+		if err != nil {
+			params.SetInvalidParam("destinationCountry", err.Error())
+		}
+		promoCode.DestinationCountryID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	if params != nil {
+		return PromoCode{}, rfc7807.BadRequest("invalid-data", "Invalid Data Error", "The provided promo code is not valid.", params...)
+	}
+
+	return promoCode, nil
+}
+
+func NormalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func MigratePromoCode(db *gorm.DB) error {
+	return db.AutoMigrate(&PromoCode{})
+}
@@ -1,22 +1,97 @@
 package entity
 
 import (
+	"database/sql"
 	"time"
 
 	"github.com/d3code/uuid"
+	"gorm.io/gorm"
 )
 
 type Refaund struct {
-	ID          uuid.UUID `gorm:"type:binary(16);primaryKey" json:"id"`
-	TicketID    uuid.UUID `gorm:"type:binary(16);not null"   json:"-"`
-	Ticket      Ticket    `gorm:"foreignKey:TicketID"  json:"ticket"`
-	CreatedAt   time.Time `gorm:"not null"             json:"createdAt"`
-	CompletedAt time.Time `                            json:"completedAt"`
+	ID               uuid.UUID     `gorm:"type:binary(16);primaryKey" json:"id"`
+	TicketID         uuid.UUID     `gorm:"type:binary(16);not null;index"   json:"-"`
+	Ticket           Ticket        `gorm:"foreignKey:TicketID"  json:"ticket"`
+	Amount           int           `gorm:"type:MEDIUMINT;not null"  json:"amount"`
+	Status           RefaundStatus `gorm:"type:enum('Pending','Approved','Completed','Rejected','Failed');not null" json:"status"`
+	Method           RefaundMethod `gorm:"type:enum('Provider','Office');not null;default:'Provider'" json:"method"`
+	SessionID        string        `gorm:"type:varchar(500)"   json:"-"`
+	ProviderRefundID string        `gorm:"type:varchar(255)"   json:"-"`
+	FailureMessage   string        `gorm:"type:varchar(500)"   json:"failureMessage"`
+	CreatedAt        time.Time     `gorm:"not null"             json:"createdAt"`
+	CompletedAt      sql.NullTime  `                            json:"completedAt"`
 }
 
-func NewRefaund(ticketID uuid.UUID) Refaund {
+type RefaundStatus string
+
+const (
+	PendingRefaundStatus   RefaundStatus = "Pending"
+	ApprovedRefaundStatus  RefaundStatus = "Approved"
+	CompletedRefaundStatus RefaundStatus = "Completed"
+	RejectedRefaundStatus  RefaundStatus = "Rejected"
+	FailedRefaundStatus    RefaundStatus = "Failed"
+)
+
+// RefaundMethod is how the amount gets back to the customer.
+type RefaundMethod string
+
+const (
+	ProviderRefaundMethod RefaundMethod = "Provider"
+	OfficeRefaundMethod   RefaundMethod = "Office"
+)
+
+func DefineRefaundStatus(v string) (RefaundStatus, bool) {
+	switch RefaundStatus(v) {
+	case PendingRefaundStatus, ApprovedRefaundStatus, CompletedRefaundStatus, RejectedRefaundStatus, FailedRefaundStatus:
+		return RefaundStatus(v), true
+	default:
+		return "", false
+	}
+}
+
+func NewRefaund(ticketID uuid.UUID, amount int) Refaund {
 	return Refaund{
 		ID:       uuid.New(),
 		TicketID: ticketID,
+		Amount:   amount,
+		Status:   PendingRefaundStatus,
+		Method:   ProviderRefaundMethod,
 	}
 }
+
+// NewTicketRefaund returns the refaund of the ticket's own payment. The cash paid to the driver
+// has never been through the payment provider, so it is returned at the office.
+func NewTicketRefaund(ticket Ticket, amount int) Refaund {
+	refaund := NewRefaund(ticket.ID, amount)
+	if ticket.Payment.Method == PaymentMethodCash {
+		refaund.Method = OfficeRefaundMethod
+	}
+	return refaund
+}
+
+// NewLuggageRefaund returns the refaund of the luggage order, it is refunded against the session
+// the order has been paid through.
+func NewLuggageRefaund(order LuggageOrder, amount int) Refaund {
+	refaund := NewRefaund(order.TicketID, amount)
+	refaund.SessionID = order.SessionID
+	return refaund
+}
+
+// AtOffice reports whether the amount is returned by hand instead of through the payment provider.
+// The refaunds saved before the method was recorded are told by the payment of the ticket.
+func (r Refaund) AtOffice() bool {
+	return r.Method == OfficeRefaundMethod || (r.SessionID == "" && r.Ticket.Payment.Method == PaymentMethodCash)
+}
+
+// PaymentSessionID is the session the amount is refunded against. It is the payment of the ticket
+// unless the refaund returns a payment of its own, such as the fare difference of an exchange.
+func (r Refaund) PaymentSessionID() string {
+	if r.SessionID != "" {
+		return r.SessionID
+	}
+	return r.Ticket.Payment.SessionID
+}
+
+func MigrateRefaund(db *gorm.DB) error {
+	return db.AutoMigrate(&Refaund{})
+}
@@ -0,0 +1,74 @@
+package entity
+
+import (
+	"maryan_api/config"
+	rfc7807 "maryan_api/pkg/problem"
+	"strings"
+
+	"github.com/d3code/uuid"
+)
+
+// NewRoundTripJSON buys the tickets of both legs in one checkout. The legs are either the
+// connections of the trip or any two connections where the return goes back after the outbound arrives.
+type NewRoundTripJSON struct {
+	TripID   uuid.UUID     `json:"tripId"`
+	Outbound NewTicketJSON `json:"outbound"`
+	Return   NewTicketJSON `json:"return"`
+}
+
+// Validate checks what the legs have to share: the passengers, the currency and the card payment.
+func (r NewRoundTripJSON) Validate() error {
+	var params rfc7807.InvalidParams
+	if len(r.Outbound.Passengers) != len(r.Return.Passengers) {
+		params.SetInvalidParam("return.passengers", "Must be as many as the passengers of the outbound leg.")
+	}
+
+	if !strings.EqualFold(r.Outbound.Currency, r.Return.Currency) {
+		params.SetInvalidParam("return.currency", "Must be the currency of the outbound leg.")
+	}
+
+	for name, leg := range map[string]NewTicketJSON{"outbound": r.Outbound, "return": r.Return} {
+		if cash, _ := leg.PaysCash(); cash {
+			params.SetInvalidParam(name+".paymentMethod", "A round trip can only be paid by card.")
+		}
+	}
+
+	for name, leg := range map[string]NewTicketJSON{"outbound": r.Outbound, "return": r.Return} {
+		if leg.WaitlistOfferID != uuid.Nil {
+			params.SetInvalidParam(name+".waitlistOfferId", "A waitlist offer can only be used to buy a one-way ticket.")
+		}
+	}
+
+	if r.Return.PromoCode != "" {
+		params.SetInvalidParam("return.promoCode", "The promo code is applied to the outbound leg only.")
+	}
+
+	if params != nil {
+		return rfc7807.BadRequest("invalid-data", "Invalid Data Error", "The provided round trip is not valid.", params...)
+	}
+
+	return nil
+}
+
+// ValidateLegs checks that the return segment goes back from the destination of the outbound one
+// to its departure after the outbound arrival. With a trip the legs have to be its connections.
+func (r NewRoundTripJSON) ValidateLegs(trip *Trip, outbound, back Segment) error {
+	if trip != nil && (trip.OutboundConnectionID != r.Outbound.ConnectionID || trip.ReturnConnectionID != r.Return.ConnectionID) {
+		return rfc7807.BadRequest("foreign-trip-connection", "Foreign Trip Connection Error", "The connections of the legs do not belong to the trip.")
+	}
+
+	if outbound.To.CountryID != back.From.CountryID || outbound.From.CountryID != back.To.CountryID {
+		return rfc7807.BadRequest("unmatched-round-trip", "Unmatched Round Trip Error", "The return leg has to go back from the destination of the outbound leg to its departure.")
+	}
+
+	if !back.From.DepartureTime.After(outbound.To.ArrivalTime) {
+		return rfc7807.BadRequest("unmatched-round-trip", "Unmatched Round Trip Error", "The return leg has to depart after the outbound leg arrives.")
+	}
+
+	return nil
+}
+
+// RoundTripDiscount is the part of the price of a leg taken off for buying the round trip.
+func RoundTripDiscount(price int) int {
+	return price * config.RoundTripDiscountPercentage / 100
+}
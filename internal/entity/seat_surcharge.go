@@ -0,0 +1,141 @@
+package entity
+
+import (
+	rfc7807 "maryan_api/pkg/problem"
+	"slices"
+	"strconv"
+
+	"github.com/d3code/uuid"
+)
+
+// SeatTypeSurcharge is added to the fare of every seat of the type on the bus.
+type SeatTypeSurcharge struct {
+	BusID  uuid.UUID `gorm:"type:binary(16);primaryKey"                                                         json:"-"`
+	Type   seatType  `gorm:"type:enum('Window', 'Single', 'Single-Window', 'Aisle', 'Middle');primaryKey"     json:"type"`
+	Amount int       `gorm:"type:MEDIUMINT UNSIGNED;not null"                                                   json:"amount"`
+}
+
+// SeatSurcharge is added to the fare of one seat of the bus instead of the surcharge of its type.
+type SeatSurcharge struct {
+	SeatID uuid.UUID `gorm:"type:binary(16);primaryKey"       json:"seatId"`
+	BusID  uuid.UUID `gorm:"type:binary(16);not null;index"   json:"-"`
+	Amount int       `gorm:"type:MEDIUMINT UNSIGNED;not null" json:"amount"`
+}
+
+// SeatSurcharges are the surcharges of the seats of one bus, in the base currency.
+type SeatSurcharges struct {
+	Types []SeatTypeSurcharge `json:"types"`
+	Seats []SeatSurcharge     `json:"seats"`
+}
+
+// Surcharges returns the seat surcharges of the bus.
+func (b Bus) Surcharges() SeatSurcharges {
+	return SeatSurcharges{Types: b.SeatTypeSurcharges, Seats: b.SeatSurcharges}
+}
+
+// SeatSurcharge is the surcharge of the seat, the one set for the seat itself comes before the one of its type.
+func (b Bus) SeatSurcharge(seatID uuid.UUID) int {
+	i := slices.IndexFunc(b.SeatSurcharges, func(s SeatSurcharge) bool { return s.SeatID == seatID })
+	if i != -1 {
+		return b.SeatSurcharges[i].Amount
+	}
+
+	i = slices.IndexFunc(b.Seats, func(s Seat) bool { return s.ID == seatID })
+	if i == -1 {
+		return 0
+	}
+
+	j := slices.IndexFunc(b.SeatTypeSurcharges, func(s SeatTypeSurcharge) bool { return s.Type == b.Seats[i].Type })
+	if j == -1 {
+		return 0
+	}
+	return b.SeatTypeSurcharges[j].Amount
+}
+
+// SeatsSurcharge is the sum of the surcharges of the seats.
+func (b Bus) SeatsSurcharge(seats []TicketSeat) int {
+	var surcharge int
+	for _, seat := range seats {
+		surcharge += b.SeatSurcharge(seat.SeatID)
+	}
+	return surcharge
+}
+
+// BaseFareSeats returns the free seats without a surcharge ordered by their number,
+// they are assigned to the passengers who have no seat preference.
+func (b Bus) BaseFareSeats(takenSeats []uuid.UUID) []uuid.UUID {
+	seats := slices.Clone(b.Seats)
+	slices.SortFunc(seats, func(a, b Seat) int { return a.Number - b.Number })
+
+	var seatIDs []uuid.UUID
+	for _, seat := range seats {
+		if seat.Number != 0 && !slices.Contains(takenSeats, seat.ID) && b.SeatSurcharge(seat.ID) == 0 {
+			seatIDs = append(seatIDs, seat.ID)
+		}
+	}
+	return seatIDs
+}
+
+type SeatTypeSurchargeJSON struct {
+	Type   string `json:"type"`
+	Amount int    `json:"amount"`
+}
+
+type SeatSurchargeJSON struct {
+	SeatID uuid.UUID `json:"seatId"`
+	Amount int       `json:"amount"`
+}
+
+type SeatSurchargesJSON struct {
+	Types []SeatTypeSurchargeJSON `json:"types"`
+	Seats []SeatSurchargeJSON     `json:"seats"`
+}
+
+func (s SeatSurchargesJSON) Parse(bus Bus) (SeatSurcharges, error) {
+	var params rfc7807.InvalidParams
+	var surcharges = SeatSurcharges{
+		Types: make([]SeatTypeSurcharge, len(s.Types)),
+		Seats: make([]SeatSurcharge, len(s.Seats)),
+	}
+
+	for i, surcharge := range s.Types {
+		name := "types[" + strconv.Itoa(i) + "]"
+		t, ok := defineSeatType(surcharge.Type)
+		if !ok {
+			params.SetInvalidParam(name+".type", "Must be one of Window, Single, Single-Window, Aisle or Middle.")
+		}
+
+		if slices.ContainsFunc(surcharges.Types[:i], func(s SeatTypeSurcharge) bool { return s.Type == t }) {
+			params.SetInvalidParam(name+".type", "Is repeated.")
+		}
+
+		if surcharge.Amount < 0 {
+			params.SetInvalidParam(name+".amount", "Can not be negative.")
+		}
+
+		surcharges.Types[i] = SeatTypeSurcharge{bus.ID, t, surcharge.Amount}
+	}
+
+	for i, surcharge := range s.Seats {
+		name := "seats[" + strconv.Itoa(i) + "]"
+		if !slices.ContainsFunc(bus.Seats, func(s Seat) bool { return s.ID == surcharge.SeatID }) {
+			params.SetInvalidParam(name+".seatId", "Is not a seat of the bus.")
+		}
+
+		if slices.ContainsFunc(surcharges.Seats[:i], func(s SeatSurcharge) bool { return s.SeatID == surcharge.SeatID }) {
+			params.SetInvalidParam(name+".seatId", "Is repeated.")
+		}
+
+		if surcharge.Amount < 0 {
+			params.SetInvalidParam(name+".amount", "Can not be negative.")
+		}
+
+		surcharges.Seats[i] = SeatSurcharge{surcharge.SeatID, bus.ID, surcharge.Amount}
+	}
+
+	if params != nil {
+		return SeatSurcharges{}, rfc7807.BadRequest("invalid-data", "Invalid Data Error", "The provided seat surcharges are not valid.", params...)
+	}
+
+	return surcharges, nil
+}
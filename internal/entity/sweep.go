@@ -0,0 +1,48 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/d3code/uuid"
+)
+
+// AbandonedBooking is a ticket or a parcel whose checkout session was left unpaid.
+type AbandonedBooking struct {
+	ID        uuid.UUID `gorm:"column:id"`
+	SessionID string    `gorm:"column:session_id"`
+	Seats     int       `gorm:"column:seats"`
+}
+
+type SkippedBooking struct {
+	ID        uuid.UUID `json:"id"`
+	SessionID string    `json:"sessionId"`
+	Reason    string    `json:"reason"`
+}
+
+// SweepReport lists what one run of the sweeper has removed.
+type SweepReport struct {
+	SweptAt       time.Time        `json:"sweptAt"`
+	Tickets       []uuid.UUID      `json:"tickets"`
+	Parcels       []uuid.UUID      `json:"parcels"`
+	ReleasedSeats int              `json:"releasedSeats"`
+	ExpiredQuotes int              `json:"expiredQuotes"`
+	Skipped       []SkippedBooking `json:"skipped"`
+}
+
+func NewSweepReport(sweptAt time.Time) SweepReport {
+	return SweepReport{
+		SweptAt: sweptAt,
+		Tickets: []uuid.UUID{},
+		Parcels: []uuid.UUID{},
+		Skipped: []SkippedBooking{},
+	}
+}
+
+func (r *SweepReport) Skip(booking AbandonedBooking, reason string) {
+	r.Skipped = append(r.Skipped, SkippedBooking{booking.ID, booking.SessionID, reason})
+}
+
+// Empty reports whether the run has found nothing to sweep.
+func (r SweepReport) Empty() bool {
+	return len(r.Tickets) == 0 && len(r.Parcels) == 0 && len(r.Skipped) == 0 && r.ExpiredQuotes == 0
+}
@@ -0,0 +1,354 @@
+package entity
+
+import (
+	"maryan_api/config"
+	rfc7807 "maryan_api/pkg/problem"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/d3code/uuid"
+	"gorm.io/gorm"
+)
+
+// ParcelTariff prices the parcels of the type sent between the countries. Every change of the
+// tariff is a new version, the version in effect is the latest one whose EffectiveFrom has passed.
+// A route with no tariff is priced by the built-in one, which has no id and the version 0.
+// The parcels are priced by both their size and their chargeable weight, whichever costs more.
+type ParcelTariff struct {
+	ID                   uuid.UUID            `gorm:"type:binary(16);primaryKey"                                     json:"id"`
+	DepartureCountryID   uuid.UUID            `gorm:"type:binary(16);not null;uniqueIndex:idx_parcel_tariff_version" json:"departureCountryId"`
+	DestinationCountryID uuid.UUID            `gorm:"type:binary(16);not null;uniqueIndex:idx_parcel_tariff_version" json:"destinationCountryId"`
+	Type                 ParcelType           `gorm:"type:enum('Documents','Package');not null;uniqueIndex:idx_parcel_tariff_version" json:"type"`
+	Version              int                  `gorm:"type:SMALLINT UNSIGNED;not null;uniqueIndex:idx_parcel_tariff_version" json:"version"`
+	EffectiveFrom        time.Time            `gorm:"not null;index"                                                 json:"effectiveFrom"`
+	Sizes                []ParcelTariffSize   `gorm:"foreignKey:TariffID;constraint:OnDelete:CASCADE"                json:"sizes"`
+	VolumetricDivisor    int                  `gorm:"type:SMALLINT UNSIGNED;not null;default:5000"                   json:"volumetricDivisor"`
+	Weights              []ParcelTariffWeight `gorm:"foreignKey:TariffID;constraint:OnDelete:CASCADE"                json:"weights"`
+	OversizePrice        int                  `gorm:"type:MEDIUMINT UNSIGNED;not null"                               json:"oversizePrice"`
+	CreatedAt            time.Time            `gorm:"not null"                                                       json:"createdAt"`
+}
+
+// ParcelTariffSize is the price of the parcels that fit the sides, the sides are kept from the shortest to the longest.
+type ParcelTariffSize struct {
+	TariffID     uuid.UUID `gorm:"type:binary(16);not null;index"   json:"-"`
+	ShortestSide int       `gorm:"type:SMALLINT UNSIGNED;not null"  json:"shortestSide"`
+	MiddleSide   int       `gorm:"type:SMALLINT UNSIGNED;not null"  json:"middleSide"`
+	LongestSide  int       `gorm:"type:SMALLINT UNSIGNED;not null"  json:"longestSide"`
+	Price        int       `gorm:"type:MEDIUMINT UNSIGNED;not null" json:"price"`
+}
+
+func (s ParcelTariffSize) fits(sides []int) bool {
+	return sides[0] <= s.ShortestSide && sides[1] <= s.MiddleSide && sides[2] <= s.LongestSide
+}
+
+// ParcelTariffWeight is the price of the parcels whose chargeable weight in grams does not exceed MaxWeight.
+type ParcelTariffWeight struct {
+	TariffID  uuid.UUID `gorm:"type:binary(16);not null;index"   json:"-"`
+	MaxWeight int       `gorm:"type:MEDIUMINT UNSIGNED;not null" json:"maxWeight"`
+	Price     int       `gorm:"type:MEDIUMINT UNSIGNED;not null" json:"price"`
+}
+
+// ChargeableWeight returns the larger of the actual weight and the volumetric weight of the parcel in grams.
+func (t ParcelTariff) ChargeableWeight(width, height, length, weight int) int {
+	divisor := t.VolumetricDivisor
+	if divisor < 1 {
+		divisor = int(config.GetParcelConfig().VolumetricDivisor)
+	}
+
+	return max(weight, width*height*length*1000/divisor)
+}
+
+// Price returns the higher of the size price and the weight price of the parcel. The size price is
+// the one of the cheapest size the parcel fits, the weight price the one of the cheapest bracket its
+// chargeable weight fits. The parcels that fit no size or no bracket are oversized, the tariffs with
+// no brackets price the parcels by their size only.
+func (t ParcelTariff) Price(width, height, length, weight int) int {
+	sides := []int{width, height, length}
+	slices.Sort(sides)
+
+	var sizePrice = -1
+	for _, size := range t.Sizes {
+		if size.fits(sides) && (sizePrice == -1 || size.Price < sizePrice) {
+			sizePrice = size.Price
+		}
+	}
+
+	if sizePrice == -1 {
+		return t.OversizePrice
+	}
+
+	if len(t.Weights) == 0 {
+		return sizePrice
+	}
+
+	chargeableWeight := t.ChargeableWeight(width, height, length, weight)
+	var weightPrice = -1
+	for _, bracket := range t.Weights {
+		if chargeableWeight <= bracket.MaxWeight && (weightPrice == -1 || bracket.Price < weightPrice) {
+			weightPrice = bracket.Price
+		}
+	}
+
+	if weightPrice == -1 {
+		return t.OversizePrice
+	}
+	return max(sizePrice, weightPrice)
+}
+
+// DefaultParcelTariff is the built-in tariff of the route.
+func DefaultParcelTariff(departureCountryID, destinationCountryID uuid.UUID, parcelType ParcelType) ParcelTariff {
+	parcelConfig := config.GetParcelConfig()
+
+	var sizes = make([]ParcelTariffSize, len(parcelConfig.ParcelTypes))
+	for i, parcel := range parcelConfig.ParcelTypes {
+		sizes[i] = ParcelTariffSize{
+			ShortestSide: int(parcel.SizeParams[0]),
+			MiddleSide:   int(parcel.SizeParams[1]),
+			LongestSide:  int(parcel.SizeParams[2]),
+			Price:        int(parcel.Price),
+		}
+	}
+
+	var weights = make([]ParcelTariffWeight, len(parcelConfig.Weights))
+	for i, bracket := range parcelConfig.Weights {
+		weights[i] = ParcelTariffWeight{
+			MaxWeight: int(bracket.MaxWeight),
+			Price:     int(bracket.Price),
+		}
+	}
+
+	return ParcelTariff{
+		DepartureCountryID:   departureCountryID,
+		DestinationCountryID: destinationCountryID,
+		Type:                 parcelType,
+		Sizes:                sizes,
+		VolumetricDivisor:    int(parcelConfig.VolumetricDivisor),
+		Weights:              weights,
+		OversizePrice:        int(parcelConfig.OverSizePrice),
+	}
+}
+
+// LuggageTariff prices the extra luggage of the tickets between the countries, versioned like ParcelTariff.
+type LuggageTariff struct {
+	ID                   uuid.UUID `gorm:"type:binary(16);primaryKey"                                      json:"id"`
+	DepartureCountryID   uuid.UUID `gorm:"type:binary(16);not null;uniqueIndex:idx_luggage_tariff_version" json:"departureCountryId"`
+	DestinationCountryID uuid.UUID `gorm:"type:binary(16);not null;uniqueIndex:idx_luggage_tariff_version" json:"destinationCountryId"`
+	Version              int       `gorm:"type:SMALLINT UNSIGNED;not null;uniqueIndex:idx_luggage_tariff_version" json:"version"`
+	EffectiveFrom        time.Time `gorm:"not null;index"                                                  json:"effectiveFrom"`
+	BackpackPrice        int       `gorm:"type:MEDIUMINT UNSIGNED;not null"                                json:"backpackPrice"`
+	SmallLuggagePrice    int       `gorm:"type:MEDIUMINT UNSIGNED;not null"                                json:"smallLuggagePrice"`
+	LargeLuggagePrice    int       `gorm:"type:MEDIUMINT UNSIGNED;not null"                                json:"largeLuggagePrice"`
+	CreatedAt            time.Time `gorm:"not null"                                                        json:"createdAt"`
+}
+
+func (t LuggageTariff) price(luggage luggageType) int {
+	switch luggage {
+	case BackpackLuggageType:
+		return t.BackpackPrice
+	case SmallLuggageLuggageType:
+		return t.SmallLuggagePrice
+	default:
+		return t.LargeLuggagePrice
+	}
+}
+
+// DefaultLuggageTariff is the built-in tariff of the route.
+func DefaultLuggageTariff(departureCountryID, destinationCountryID uuid.UUID) LuggageTariff {
+	luggageConfig := config.GetLoggageConfig()
+	return LuggageTariff{
+		DepartureCountryID:   departureCountryID,
+		DestinationCountryID: destinationCountryID,
+		BackpackPrice:        int(luggageConfig.Small.Price),
+		SmallLuggagePrice:    int(luggageConfig.Medium.Price),
+		LargeLuggagePrice:    int(luggageConfig.Large.Price),
+	}
+}
+
+// tariffRef is the id the bookings keep of the tariff, the built-in tariff has none.
+func tariffRef(id uuid.UUID) uuid.NullUUID {
+	return uuid.NullUUID{UUID: id, Valid: id != uuid.Nil}
+}
+
+func (t ParcelTariff) Ref() uuid.NullUUID {
+	return tariffRef(t.ID)
+}
+
+func (t LuggageTariff) Ref() uuid.NullUUID {
+	return tariffRef(t.ID)
+}
+
+// tariffEditable allows changes only to the versions not in effect yet. The versions in effect
+// are kept as they are, since the bookings refer to them.
+func tariffEditable(effectiveFrom time.Time) error {
+	if !effectiveFrom.After(time.Now().UTC()) {
+		return rfc7807.New(http.StatusConflict, "effective-tariff", "Effective Tariff Error", "The tariff version is already in effect, create a new version instead.")
+	}
+	return nil
+}
+
+func (t ParcelTariff) Editable() error {
+	return tariffEditable(t.EffectiveFrom)
+}
+
+func (t LuggageTariff) Editable() error {
+	return tariffEditable(t.EffectiveFrom)
+}
+
+type ParcelTariffSizeJSON struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	Length int `json:"length"`
+	Price  int `json:"price"`
+}
+
+type ParcelTariffWeightJSON struct {
+	MaxWeight int `json:"maxWeight"`
+	Price     int `json:"price"`
+}
+
+type ParcelTariffJSON struct {
+	DepartureCountry   string                   `json:"departureCountry"`
+	DestinationCountry string                   `json:"destinationCountry"`
+	Type               string                   `json:"type"`
+	EffectiveFrom      time.Time                `json:"effectiveFrom"`
+	Sizes              []ParcelTariffSizeJSON   `json:"sizes"`
+	VolumetricDivisor  int                      `json:"volumetricDivisor"`
+	Weights            []ParcelTariffWeightJSON `json:"weights"`
+	OversizePrice      int                      `json:"oversizePrice"`
+}
+
+func (t ParcelTariffJSON) Parse() (ParcelTariff, error) {
+	var params rfc7807.InvalidParams
+	var tariff = ParcelTariff{
+		ID:                uuid.New(),
+		EffectiveFrom:     t.EffectiveFrom.UTC(),
+		Sizes:             make([]ParcelTariffSize, len(t.Sizes)),
+		VolumetricDivisor: t.VolumetricDivisor,
+		Weights:           make([]ParcelTariffWeight, len(t.Weights)),
+		OversizePrice:     t.OversizePrice,
+	}
+
+	tariff.DepartureCountryID, tariff.DestinationCountryID = parseTariffRoute(t.DepartureCountry, t.DestinationCountry, &params)
+
+	parcelType, ok := defineParcelType(t.Type)
+	if !ok {
+		params.SetInvalidParam("type", "Must be either documents or package.")
+	}
+	tariff.Type = parcelType
+
+	if !tariff.EffectiveFrom.After(time.Now().UTC()) {
+		params.SetInvalidParam("effectiveFrom", "Must be in the future.")
+	}
+
+	if len(t.Sizes) == 0 {
+		params.SetInvalidParam("sizes", "Must contain at least one size.")
+	}
+
+	for i, size := range t.Sizes {
+		name := "sizes[" + strconv.Itoa(i) + "]"
+		if size.Width < 1 || size.Height < 1 || size.Length < 1 {
+			params.SetInvalidParam(name, "The sides must be greater than 0.")
+		}
+
+		if size.Price < 0 {
+			params.SetInvalidParam(name+".price", "Can not be less than 0.")
+		}
+
+		sides := []int{size.Width, size.Height, size.Length}
+		slices.Sort(sides)
+		tariff.Sizes[i] = ParcelTariffSize{tariff.ID, sides[0], sides[1], sides[2], size.Price}
+	}
+
+	if t.VolumetricDivisor == 0 {
+		tariff.VolumetricDivisor = int(config.GetParcelConfig().VolumetricDivisor)
+	} else if t.VolumetricDivisor < 1 || t.VolumetricDivisor > 65535 {
+		params.SetInvalidParam("volumetricDivisor", "Has to be between 1 and 65535.")
+	}
+
+	for i, bracket := range t.Weights {
+		name := "weights[" + strconv.Itoa(i) + "]"
+		if bracket.MaxWeight < 1 {
+			params.SetInvalidParam(name+".maxWeight", "Must be greater than 0.")
+		}
+
+		if bracket.Price < 0 {
+			params.SetInvalidParam(name+".price", "Can not be less than 0.")
+		}
+
+		tariff.Weights[i] = ParcelTariffWeight{tariff.ID, bracket.MaxWeight, bracket.Price}
+	}
+
+	if t.OversizePrice < 0 {
+		params.SetInvalidParam("oversizePrice", "Can not be less than 0.")
+	}
+
+	if params != nil {
+		return ParcelTariff{}, rfc7807.BadRequest("invalid-data", "Invalid Data Error", "The provided tariff is not valid.", params...)
+	}
+
+	return tariff, nil
+}
+
+type LuggageTariffJSON struct {
+	DepartureCountry   string    `json:"departureCountry"`
+	DestinationCountry string    `json:"destinationCountry"`
+	EffectiveFrom      time.Time `json:"effectiveFrom"`
+	BackpackPrice      int       `json:"backpackPrice"`
+	SmallLuggagePrice  int       `json:"smallLuggagePrice"`
+	LargeLuggagePrice  int       `json:"largeLuggagePrice"`
+}
+
+func (t LuggageTariffJSON) Parse() (LuggageTariff, error) {
+	var params rfc7807.InvalidParams
+	var tariff = LuggageTariff{
+		ID:                uuid.New(),
+		EffectiveFrom:     t.EffectiveFrom.UTC(),
+		BackpackPrice:     t.BackpackPrice,
+		SmallLuggagePrice: t.SmallLuggagePrice,
+		LargeLuggagePrice: t.LargeLuggagePrice,
+	}
+
+	tariff.DepartureCountryID, tariff.DestinationCountryID = parseTariffRoute(t.DepartureCountry, t.DestinationCountry, &params)
+
+	if !tariff.EffectiveFrom.After(time.Now().UTC()) {
+		params.SetInvalidParam("effectiveFrom", "Must be in the future.")
+	}
+
+	if t.BackpackPrice < 0 {
+		params.SetInvalidParam("backpackPrice", "Can not be less than 0.")
+	}
+
+	if t.SmallLuggagePrice < 0 {
+		params.SetInvalidParam("smallLuggagePrice", "Can not be less than 0.")
+	}
+
+	if t.LargeLuggagePrice < 0 {
+		params.SetInvalidParam("largeLuggagePrice", "Can not be less than 0.")
+	}
+
+	if params != nil {
+		return LuggageTariff{}, rfc7807.BadRequest("invalid-data", "Invalid Data Error", "The provided tariff is not valid.", params...)
+	}
+
+	return tariff, nil
+}
+
+func parseTariffRoute(departureCountry, destinationCountry string, params *rfc7807.InvalidParams) (uuid.UUID, uuid.UUID) {
+	departureCountryID, _, err := config.ParseCountry(departureCountry)
+	if err != nil {
+		params.SetInvalidParam("departureCountry", err.Error())
+	}
+
+	destinationCountryID, _, err := config.ParseCountry(destinationCountry)
+	if err != nil {
+		params.SetInvalidParam("destinationCountry", err.Error())
+	}
+
+	return departureCountryID, destinationCountryID
+}
+
+func MigrateTariff(db *gorm.DB) error {
+	return db.AutoMigrate(&ParcelTariff{}, &ParcelTariffSize{}, &ParcelTariffWeight{}, &LuggageTariff{})
+}
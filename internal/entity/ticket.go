@@ -5,8 +5,8 @@ import (
 	"database/sql"
 	"net/http"
 	"slices"
+	"strconv"
 
-	"maryan_api/config"
 	rfc7807 "maryan_api/pkg/problem"
 	"time"
 
@@ -16,23 +16,39 @@ import (
 )
 
 type Ticket struct {
-	ID              uuid.UUID      `gorm:"type:binary(16);primaryKey"         json:"id"`
-	UserID          uuid.UUID      `gorm:"type:binary(16);not null"           json:"userId"`
-	ConnectionID    uuid.UUID      `gorm:"type:binary(16);not null"           json:"connectionID"`
-	Seats           []TicketSeat   `gorm:"constraint:OnDelete:CASCADE" json:"seats"`
-	PhoneNumber     string         `gorm:"type:varchar(15);not null"                                                  json:"phoneNumber"`
-	Email           string         `gorm:"type:varchar(255);not null"                          json:"email"`
-	Passengers      []Passenger    `gorm:"foreignKey:TicketID;onstraint:OnDelete:CASCADE"      json:"passengers"`
-	PickUpAdressID  uuid.UUID      `gorm:"type:binary(16);not null"           json:"-"`
-	PickUpAdress    Address        `gorm:"foreignKey:PickUpAdressID;onstraint:OnDelete:CASCADE"    json:"pickUpAddress"`
-	DropOffAdressID uuid.UUID      `gorm:"type:binary(16);not null"           json:"-"`
-	DropOffAdress   Address        `gorm:"foreignKey:DropOffAdressID;onstraint:OnDelete:CASCADE"   json:"dropOffAddress"`
-	CreatedAt       time.Time      `gorm:"not null"                     json:"createdAt"`
-	CompletedAt     sql.NullTime   `                                    json:"completedAt"`
-	Payment         TicketPayment  `gorm:"foreignKey:TicketID;onstraint:OnDelete:CASCADE"    `
-	DeletedAt       gorm.DeletedAt `                                    json:"deletedAt"`
-	LuggageVolume   luggage        `gorm:"type:MEDIUMINT UNSIGNED;not null"`
-	QRCode          []byte         `gorm:"type:blob;not null" json:"qrCode"`
+	ID              uuid.UUID       `gorm:"type:binary(16);primaryKey"         json:"id"`
+	UserID          uuid.UUID       `gorm:"type:binary(16);not null"           json:"userId"`
+	ConnectionID    uuid.UUID       `gorm:"type:binary(16);not null"           json:"connectionID"`
+	Seats           []TicketSeat    `gorm:"constraint:OnDelete:CASCADE" json:"seats"`
+	PhoneNumber     string          `gorm:"type:varchar(15);not null"                                                  json:"phoneNumber"`
+	Email           string          `gorm:"type:varchar(255);not null"                          json:"email"`
+	Passengers      []Passenger     `gorm:"foreignKey:TicketID;onstraint:OnDelete:CASCADE"      json:"passengers"`
+	PickUpAdressID  uuid.UUID       `gorm:"type:binary(16);not null"           json:"-"`
+	PickUpAdress    Address         `gorm:"foreignKey:PickUpAdressID;onstraint:OnDelete:CASCADE"    json:"pickUpAddress"`
+	DropOffAdressID uuid.UUID       `gorm:"type:binary(16);not null"           json:"-"`
+	DropOffAdress   Address         `gorm:"foreignKey:DropOffAdressID;onstraint:OnDelete:CASCADE"   json:"dropOffAddress"`
+	CreatedAt       time.Time       `gorm:"not null"                     json:"createdAt"`
+	CompletedAt     sql.NullTime    `                                    json:"completedAt"`
+	CanceledAt      sql.NullTime    `                                    json:"canceledAt"`
+	Payment         TicketPayment   `gorm:"foreignKey:TicketID;onstraint:OnDelete:CASCADE"    `
+	DeletedAt       gorm.DeletedAt  `                                    json:"deletedAt"`
+	LuggageVolume   luggage         `gorm:"type:MEDIUMINT UNSIGNED;not null"`
+	QRCode          []byte          `gorm:"type:blob;not null" json:"qrCode"`
+	Language        string          `gorm:"type:varchar(2);not null;default:'en'" json:"language"`
+	FromStop        int             `gorm:"type:TINYINT UNSIGNED;not null;default:0" json:"fromStop"`
+	ToStop          int             `gorm:"type:TINYINT UNSIGNED;not null;default:1" json:"toStop"`
+	RoundTripID     uuid.NullUUID   `gorm:"type:binary(16);index"                   json:"roundTripId"`
+	Luggage         []TicketLuggage `gorm:"foreignKey:TicketID;constraint:OnDelete:CASCADE" json:"luggage"`
+}
+
+// HeldSeats is the number of the seat holds the ticket takes on its connection, one per seat and segment.
+func (t Ticket) HeldSeats() int {
+	return len(t.Seats) * (t.ToStop - t.FromStop)
+}
+
+// Travels reports whether the ticket covers the segment of the route.
+func (t Ticket) Travels(segment int) bool {
+	return t.FromStop <= segment && segment < t.ToStop
 }
 
 type luggage uint
@@ -55,12 +71,43 @@ type CustomerTicket struct {
 }
 
 type TicketPayment struct {
-	TicketID  uuid.UUID     `gorm:"type:binary(16);not null;primaryKey;constraint:OnDelete:CASCADE"                                              json:"ticketId"`
-	Price     int           `gorm:"type:MEDIUMINT;not null"                                           json:"price"`
-	Method    paymentMethod `gorm:"type:enum('Apple Pay','Card','Cash','Google Pay');not null"        json:"method"`
-	CreatedAt time.Time     `gorm:"not null"                                                          json:"createdAt"`
-	SessionID string        `gorm:"type:varchar(500);not null"                                                          json:"sessionID"`
-	Succeeded bool          `gorm:"not null"                                                          json:"succeeded"`
+	TicketID             uuid.UUID     `gorm:"type:binary(16);not null;primaryKey;constraint:OnDelete:CASCADE"                                              json:"ticketId"`
+	Price                int           `gorm:"type:MEDIUMINT;not null"                                           json:"price"`
+	Method               paymentMethod `gorm:"type:enum('Apple Pay','Card','Cash','Google Pay');not null"        json:"method"`
+	CreatedAt            time.Time     `gorm:"not null"                                                          json:"createdAt"`
+	SessionID            string        `gorm:"type:varchar(500);not null"                                                          json:"sessionID"`
+	Succeeded            bool          `gorm:"not null"                                                          json:"succeeded"`
+	FailureMessage       string        `gorm:"type:varchar(500)"                                                 json:"failureMessage"`
+	PromoCodeID          uuid.NullUUID `gorm:"type:binary(16);index"                                             json:"promoCodeId"`
+	Discount             int           `gorm:"type:MEDIUMINT;not null;default:0"                                 json:"discount"`
+	Fare                 int           `gorm:"type:MEDIUMINT UNSIGNED;not null;default:0"                        json:"fare"`
+	SeatSurcharge        int           `gorm:"type:MEDIUMINT UNSIGNED;not null;default:0"                        json:"seatSurcharge"`
+	LuggageTariffID      uuid.NullUUID `gorm:"type:binary(16);index"                                       json:"luggageTariffId"`
+	LuggageTariffVersion int           `gorm:"type:SMALLINT UNSIGNED;not null;default:0"                   json:"luggageTariffVersion"`
+	Currency             Currency      `gorm:"type:enum('EUR','UAH');not null;default:'EUR'"                    json:"currency"`
+	ExchangeRate         float64       `gorm:"type:DECIMAL(12,6);not null;default:1"                             json:"exchangeRate"`
+	CollectedBy          uuid.NullUUID `gorm:"type:binary(16);index"                                             json:"collectedBy"`
+	CollectedAt          sql.NullTime  `                                                                         json:"collectedAt"`
+}
+
+// Rate returns the exchange rate the ticket has been paid at.
+func (p TicketPayment) Rate() ExchangeRate {
+	return ExchangeRate{Currency: p.Currency, Rate: p.ExchangeRate}
+}
+
+// UncollectedCash reports whether the ticket is booked to be paid to the driver who has not collected the cash yet.
+func (p TicketPayment) UncollectedCash() bool {
+	return p.Method == PaymentMethodCash && !p.Succeeded
+}
+
+// Active reports whether the ticket is paid, or is to be paid to the driver, and has not been canceled.
+func (t Ticket) Active() bool {
+	return (t.Payment.Succeeded || t.Payment.UncollectedCash()) && !t.CanceledAt.Valid
+}
+
+// CashPaymentReference stands for the checkout session of the tickets paid to the driver.
+func CashPaymentReference(ticketID uuid.UUID) string {
+	return "cash_" + ticketID.String()
 }
 
 type paymentMethod string
@@ -81,45 +128,104 @@ func DefinePaymentMethod(v string) (paymentMethod, bool) {
 	}
 }
 
+type SeatHold struct {
+	ConnectionID uuid.UUID      `gorm:"type:binary(16);primaryKey"                 json:"connectionId"`
+	SeatID       uuid.UUID      `gorm:"type:binary(16);primaryKey"                 json:"seatId"`
+	Segment      int            `gorm:"type:TINYINT UNSIGNED;primaryKey;autoIncrement:false" json:"segment"`
+	TicketID     uuid.UUID      `gorm:"type:binary(16);not null;index"             json:"ticketId"`
+	Status       seatHoldStatus `gorm:"type:enum('Held','Sold');not null"          json:"status"`
+	ExpiresAt    time.Time      `gorm:"not null;index"                             json:"expiresAt"`
+	CreatedAt    time.Time      `gorm:"not null"                                   json:"createdAt"`
+}
+
+type seatHoldStatus string
+
+const (
+	HeldSeatHoldStatus seatHoldStatus = "Held"
+	SoldSeatHoldStatus seatHoldStatus = "Sold"
+)
+
+// NewSeatHolds holds every seat on each segment of the route the ticket travels, so the
+// seat stays free for the rest of the route.
+func NewSeatHolds(connectionID, ticketID uuid.UUID, seats []TicketSeat, segment Segment, expiresAt time.Time) []SeatHold {
+	from, to := segment.Segments()
+
+	var holds = make([]SeatHold, 0, len(seats)*(to-from))
+	for _, seat := range seats {
+		for i := from; i < to; i++ {
+			holds = append(holds, SeatHold{
+				ConnectionID: connectionID,
+				SeatID:       seat.SeatID,
+				Segment:      i,
+				TicketID:     ticketID,
+				Status:       HeldSeatHoldStatus,
+				ExpiresAt:    expiresAt,
+			})
+		}
+	}
+	return holds
+}
+
 func MigrateTicket(db *gorm.DB) error {
+	// Seat holds used to lock a seat for the whole connection, the segment is now a part of the key.
+	if db.Migrator().HasTable(&SeatHold{}) && !db.Migrator().HasColumn(&SeatHold{}, "Segment") {
+		err := db.Exec("ALTER TABLE seat_holds ADD COLUMN segment TINYINT UNSIGNED NOT NULL DEFAULT 0, DROP PRIMARY KEY, ADD PRIMARY KEY (connection_id, seat_id, segment)").Error
+		if err != nil {
+			return err
+		}
+	}
+
 	return db.AutoMigrate(
 		&Ticket{},
 		&TicketPayment{},
 		&TicketSeat{},
+		&SeatHold{},
+		&TicketExchange{},
+		&TicketExchangeSeat{},
 	)
 
 }
 
 type NewTicketJSON struct {
-	ConnectionID  uuid.UUID      `json:"connectionId"`
-	SeatIDs       []uuid.UUID    `json:"seatIDs"`
-	Passengers    []NewPassenger `json:"passengers"`
-	DropOffAdress NewAddress     `json:"dropOffAdress"`
-	PickUpAdress  NewAddress     `json:"pickUpAdress"`
-	Email         string         `json:"email"`
-	PhoneNumber   string         `json:"phoneNumber"`
-	Backpacks     int            `json:"backpacks"`
-	SmallLuggage  int            `json:"smallLuggage"`
-	LargeLuggage  int            `json:"largeLuggage"`
+	ConnectionID     uuid.UUID      `json:"connectionId"`
+	SeatIDs          []uuid.UUID    `json:"seatIDs"`
+	Passengers       []NewPassenger `json:"passengers"`
+	DropOffAdress    NewAddress     `json:"dropOffAdress"`
+	PickUpAdress     NewAddress     `json:"pickUpAdress"`
+	Email            string         `json:"email"`
+	PhoneNumber      string         `json:"phoneNumber"`
+	Backpacks        int            `json:"backpacks"`
+	SmallLuggage     int            `json:"smallLuggage"`
+	LargeLuggage     int            `json:"largeLuggage"`
+	PromoCode        string         `json:"promoCode"`
+	FromStop         int            `json:"fromStop"`
+	ToStop           int            `json:"toStop"`
+	PriceQuoteID     uuid.UUID      `json:"priceQuoteId"`
+	Currency         string         `json:"currency"`
+	PaymentMethod    string         `json:"paymentMethod"`
+	WaitlistOfferID  uuid.UUID      `json:"waitlistOfferId"`
+	NoSeatPreference bool           `json:"noSeatPreference"`
 }
 
-func (t NewTicketJSON) LuggagePrice() int {
-
-	var backPacksTotalPrice, largeLuggageTotalPrice int
-
-	luggage := config.GetLoggageConfig()
-	passengersNumber := len(t.Passengers)
-
-	if t.Backpacks > passengersNumber {
-		backPacksTotalPrice = (t.Backpacks - passengersNumber) * int(luggage.Small.Price)
-	}
-
-	if t.LargeLuggage > passengersNumber {
-		largeLuggageTotalPrice = (t.LargeLuggage - passengersNumber) * int(luggage.Large.Price)
+// PaysCash reports whether the ticket is to be paid to the driver, Card is the default payment method.
+func (t NewTicketJSON) PaysCash() (bool, error) {
+	switch t.PaymentMethod {
+	case "", PaymentMethodCard:
+		return false, nil
+	case PaymentMethodCash:
+		return true, nil
+	default:
+		return false, rfc7807.BadRequest("unsupported-payment-method", "Unsupported Payment Method Error", "The payment method must be either Card or Cash.")
 	}
+}
 
-	return backPacksTotalPrice + largeLuggageTotalPrice + t.SmallLuggage*int(luggage.Medium.Price)
+// LuggageItems returns the items of luggage bought with the ticket priced by the tariff.
+func (t NewTicketJSON) LuggageItems(ticketID uuid.UUID, tariff LuggageTariff) []TicketLuggage {
+	return NewLuggageItems(ticketID, uuid.NullUUID{}, len(t.Passengers), nil, tariff, t.Backpacks, t.SmallLuggage, t.LargeLuggage)
+}
 
+func (t NewTicketJSON) LuggagePrice(tariff LuggageTariff) int {
+	return LuggagePrice(t.LuggageItems(uuid.Nil, tariff))
 }
 
 func (t NewTicketJSON) ParseContaanctInfo() (email string, phoneNumber string, err error) {
@@ -135,23 +241,49 @@ func (t NewTicketJSON) ParseContaanctInfo() (email string, phoneNumber string, e
 		params.SetInvalidParam("phoneNumber", err.Error())
 	}
 
-	err = rfc7807.BadRequest("invalid-data", "Invalid Data Error", "The provided data is not valid.", params...)
+	if params != nil {
+		return "", "", rfc7807.BadRequest("invalid-data", "Invalid Data Error", "The provided data is not valid.", params...)
+	}
 
-	return
+	return email, phoneNumber, nil
 }
 
-func (t NewTicketJSON) Validate(connection Connection, takenSeats []uuid.UUID, ticketID uuid.UUID, luggageVolumeLeft uint) ([]TicketSeat, error) {
+func (t NewTicketJSON) Validate(connection Connection, segment Segment, takenSeats []uuid.UUID, ticketID uuid.UUID, luggageVolumeLeft uint) ([]TicketSeat, error) {
 
-	if connection.DepartureTime.Before(time.Now().UTC()) {
+	if segment.From.DepartureTime.Before(time.Now().UTC()) {
 		return nil, rfc7807.BadRequest("unavailable-connection0", "Unavailavble Connection Error", "Connection has alredy departed.")
 	}
 
-	seatsLength := len(t.SeatIDs)
+	seatIDs := t.SeatIDs
+	if t.NoSeatPreference {
+		if len(t.SeatIDs) != 0 {
+			return nil, rfc7807.BadRequest("seat-preference", "Seat Preference Error", "The seats can not be chosen when there is no seat preference.")
+		}
+
+		seatIDs = connection.Bus.BaseFareSeats(takenSeats)
+		if len(seatIDs) < len(t.Passengers) {
+			return nil, rfc7807.New(http.StatusConflict, "base-fare-seats", "Base Fare Seats Error", "There are not enough free seats at the base fare, please choose the seats.")
+		}
+		seatIDs = seatIDs[:len(t.Passengers)]
+	}
+
+	seatsLength := len(seatIDs)
 
 	if seatsLength != len(t.Passengers) {
 		return nil, rfc7807.BadRequest("seats-passengers", "Seats Passengers Error", "The seats number and the passengers number have to be equal.")
 	}
 
+	params := ValidatePassengerCategories(t.PassengerCategories())
+	for i, passenger := range t.Passengers {
+		if _, ok := DefineFareCategory(passenger.Category); !ok {
+			params.SetInvalidParam("passengers["+strconv.Itoa(i)+"].category", "Must be one of Adult, Teenager or Child.")
+		}
+	}
+
+	if params != nil {
+		return nil, rfc7807.BadRequest("passenger-categories", "Passenger Categories Error", "The passenger categories are not valid.", params...)
+	}
+
 	var totalSeats int
 	for _, seat := range connection.Bus.Seats {
 		if seat.Number != 0 {
@@ -168,7 +300,7 @@ func (t NewTicketJSON) Validate(connection Connection, takenSeats []uuid.UUID, t
 	}
 
 	var seats = make([]TicketSeat, seatsLength)
-	for i, seat := range t.SeatIDs {
+	for i, seat := range seatIDs {
 		if slices.Contains(takenSeats, seat) {
 			return nil, rfc7807.New(http.StatusConflict, "taken-seat", "Taken Seat Error", seat.String()+" is already taken.")
 		} else {
@@ -211,6 +343,20 @@ func (t NewTicketJSON) ParsePassengers(ticketID uuid.UUID) ([]Passenger, error)
 	return passengers, nil
 }
 
+func (t NewTicketJSON) PassengerCategories() (adults, teenagers, children int) {
+	for _, passenger := range t.Passengers {
+		switch fareCategory(passenger.Category) {
+		case AdultFareCategory:
+			adults++
+		case TeenagerFareCategory:
+			teenagers++
+		case ChildFareCategory:
+			children++
+		}
+	}
+	return
+}
+
 func (t NewTicketJSON) LuggageVolume() luggage {
 	return luggage(t.SmallLuggage)*(SmallLuggage) + luggage(t.Backpacks)*(Backpack) + luggage(t.LargeLuggage)*(LargeLuggage)
 }
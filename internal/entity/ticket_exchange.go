@@ -0,0 +1,136 @@
+package entity
+
+import (
+	"database/sql"
+	rfc7807 "maryan_api/pkg/problem"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/d3code/uuid"
+)
+
+type TicketExchange struct {
+	ID               uuid.UUID            `gorm:"type:binary(16);primaryKey"        json:"id"`
+	TicketID         uuid.UUID            `gorm:"type:binary(16);not null;index"    json:"-"`
+	FromConnectionID uuid.UUID            `gorm:"type:binary(16);not null"          json:"fromConnectionId"`
+	ToConnectionID   uuid.UUID            `gorm:"type:binary(16);not null"          json:"toConnectionId"`
+	FromStop         int                  `gorm:"type:TINYINT UNSIGNED;not null;default:0" json:"fromStop"`
+	ToStop           int                  `gorm:"type:TINYINT UNSIGNED;not null;default:1" json:"toStop"`
+	Seats            []TicketExchangeSeat `gorm:"constraint:OnDelete:CASCADE"       json:"seats"`
+	Fare             int                  `gorm:"type:MEDIUMINT UNSIGNED;not null;default:0" json:"fare"`
+	SeatSurcharge    int                  `gorm:"type:MEDIUMINT UNSIGNED;not null;default:0" json:"seatSurcharge"`
+	FareDifference   int                  `gorm:"type:MEDIUMINT;not null"           json:"fareDifference"`
+	SessionID        string               `gorm:"type:varchar(500);index"           json:"-"`
+	Status           ticketExchangeStatus `gorm:"type:enum('Pending','Completed','Expired');not null" json:"status"`
+	FailureMessage   string               `gorm:"type:varchar(500)"                 json:"failureMessage"`
+	QRCode           []byte               `gorm:"type:blob"                         json:"-"`
+	CreatedAt        time.Time            `gorm:"not null"                          json:"createdAt"`
+	CompletedAt      sql.NullTime         `                                         json:"completedAt"`
+}
+
+// TicketExchangeSeat keeps both the seats the ticket had before the exchange (Previous)
+// and the ones it gets on the new connection.
+type TicketExchangeSeat struct {
+	TicketExchangeID uuid.UUID `gorm:"type:binary(16);not null"  json:"-"`
+	SeatID           uuid.UUID `gorm:"type:binary(16);not null"  json:"seatId"`
+	Previous         bool      `gorm:"not null"                  json:"previous"`
+}
+
+type ticketExchangeStatus string
+
+const (
+	PendingTicketExchangeStatus   ticketExchangeStatus = "Pending"
+	CompletedTicketExchangeStatus ticketExchangeStatus = "Completed"
+	ExpiredTicketExchangeStatus   ticketExchangeStatus = "Expired"
+)
+
+func NewTicketExchange(ticket Ticket, toConnectionID uuid.UUID, segment Segment, seats []TicketSeat, fare, fareDifference int) TicketExchange {
+	id := uuid.New()
+
+	var exchangeSeats = make([]TicketExchangeSeat, 0, len(ticket.Seats)+len(seats))
+	for _, seat := range ticket.Seats {
+		exchangeSeats = append(exchangeSeats, TicketExchangeSeat{TicketExchangeID: id, SeatID: seat.SeatID, Previous: true})
+	}
+
+	for _, seat := range seats {
+		exchangeSeats = append(exchangeSeats, TicketExchangeSeat{TicketExchangeID: id, SeatID: seat.SeatID})
+	}
+
+	return TicketExchange{
+		ID:               id,
+		TicketID:         ticket.ID,
+		FromConnectionID: ticket.ConnectionID,
+		ToConnectionID:   toConnectionID,
+		FromStop:         segment.From.Sequence,
+		ToStop:           segment.To.Sequence,
+		Seats:            exchangeSeats,
+		Fare:             fare,
+		FareDifference:   fareDifference,
+		Status:           PendingTicketExchangeStatus,
+	}
+}
+
+// HeldSeats is the number of the seat holds the exchange takes on the new connection, one per seat and segment.
+func (te TicketExchange) HeldSeats() int {
+	return len(te.NewSeats()) * (te.ToStop - te.FromStop)
+}
+
+// NewSeats returns the seats the ticket gets on the new connection.
+func (te TicketExchange) NewSeats() []TicketSeat {
+	var seats []TicketSeat
+	for _, seat := range te.Seats {
+		if !seat.Previous {
+			seats = append(seats, TicketSeat{TicketID: te.TicketID, SeatID: seat.SeatID})
+		}
+	}
+	return seats
+}
+
+type TicketExchangeJSON struct {
+	ConnectionID uuid.UUID   `json:"connectionId"`
+	SeatIDs      []uuid.UUID `json:"seatIDs"`
+	FromStop     int         `json:"fromStop"`
+	ToStop       int         `json:"toStop"`
+	PriceQuoteID uuid.UUID   `json:"priceQuoteId"`
+}
+
+func (te TicketExchangeJSON) Validate(ticket Ticket, currentSegment Segment, connection Connection, segment Segment, takenSeats []uuid.UUID, luggageVolumeLeft uint) ([]TicketSeat, error) {
+	if connection.ID == ticket.ConnectionID {
+		return nil, rfc7807.BadRequest("same-connection", "Same Connection Error", "The ticket is already booked for this connection.")
+	}
+
+	if segment.From.CountryID != currentSegment.From.CountryID || segment.To.CountryID != currentSegment.To.CountryID {
+		return nil, rfc7807.BadRequest("different-route", "Different Route Error", "The ticket can only be rebooked between the countries it has been bought for.")
+	}
+
+	if segment.From.DepartureTime.Before(time.Now().UTC()) {
+		return nil, rfc7807.BadRequest("unavailable-connection", "Unavailavble Connection Error", "Connection has alredy departed.")
+	}
+
+	if len(te.SeatIDs) != len(ticket.Seats) {
+		return nil, rfc7807.BadRequest("seats-passengers", "Seats Passengers Error", "The seats number has to be equal to the passengers number of the ticket.")
+	}
+
+	if ticket.LuggageVolume > luggage(luggageVolumeLeft) {
+		return nil, rfc7807.New(http.StatusConflict, "luggage-volume", "Luggage Volume Error", "There is not enough space left to fit the luggage of the ticket.")
+	}
+
+	var seats = make([]TicketSeat, len(te.SeatIDs))
+	for i, seatID := range te.SeatIDs {
+		if !slices.ContainsFunc(connection.Bus.Seats, func(seat Seat) bool { return seat.ID == seatID && seat.Number != 0 }) {
+			return nil, rfc7807.BadRequest("non-existing-seat", "Non-existing Seat Error", seatID.String()+" does not belong to the bus of the connection.")
+		}
+
+		if slices.Contains(takenSeats, seatID) || slices.Contains(te.SeatIDs[:i], seatID) {
+			return nil, rfc7807.New(http.StatusConflict, "taken-seat", "Taken Seat Error", seatID.String()+" is already taken.")
+		}
+
+		seats[i] = TicketSeat{
+			TicketID: ticket.ID,
+			SeatID:   seatID,
+		}
+	}
+
+	return seats, nil
+}
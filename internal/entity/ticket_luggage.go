@@ -0,0 +1,170 @@
+package entity
+
+import (
+	"database/sql"
+	rfc7807 "maryan_api/pkg/problem"
+	"strings"
+	"time"
+
+	"github.com/d3code/uuid"
+	"gorm.io/gorm"
+)
+
+type luggageType string
+
+const (
+	BackpackLuggageType     luggageType = "Backpack"
+	SmallLuggageLuggageType luggageType = "Small Luggage"
+	LargeLuggageLuggageType luggageType = "Large Luggage"
+)
+
+// Volume is the space the item takes in the luggage compartment.
+func (t luggageType) Volume() luggage {
+	switch t {
+	case BackpackLuggageType:
+		return Backpack
+	case SmallLuggageLuggageType:
+		return SmallLuggage
+	default:
+		return LargeLuggage
+	}
+}
+
+// TicketLuggage is one item of luggage of the ticket. The tag is put on the item at the boarding,
+// so the drivers know exactly what to expect. Items added after the purchase are not paid until
+// their order is.
+type TicketLuggage struct {
+	ID        uuid.UUID     `gorm:"type:binary(16);primaryKey"                                        json:"id"`
+	TicketID  uuid.UUID     `gorm:"type:binary(16);not null;index"                                    json:"-"`
+	OrderID   uuid.NullUUID `gorm:"type:binary(16);index"                                             json:"orderId"`
+	Type      luggageType   `gorm:"type:enum('Backpack','Small Luggage','Large Luggage');not null"    json:"type"`
+	Price     int           `gorm:"type:MEDIUMINT UNSIGNED;not null"                                  json:"price"`
+	TagID     string        `gorm:"type:varchar(12);not null;unique"                                  json:"tagId"`
+	Paid      bool          `gorm:"not null;default:true"                                             json:"paid"`
+	CreatedAt time.Time     `gorm:"not null"                                                          json:"createdAt"`
+}
+
+// NewLuggageItems returns the items added to the ticket of passengers priced by the tariff. Every passenger
+// takes one backpack and one large luggage for free, the items the ticket already has included.
+func NewLuggageItems(ticketID uuid.UUID, orderID uuid.NullUUID, passengers int, existing []TicketLuggage, tariff LuggageTariff, backpacks, smallLuggage, largeLuggage int) []TicketLuggage {
+	var free = map[luggageType]int{BackpackLuggageType: passengers, LargeLuggageLuggageType: passengers}
+	for _, item := range existing {
+		free[item.Type]--
+	}
+
+	var items []TicketLuggage
+	for _, added := range []struct {
+		t     luggageType
+		count int
+	}{{BackpackLuggageType, backpacks}, {SmallLuggageLuggageType, smallLuggage}, {LargeLuggageLuggageType, largeLuggage}} {
+		t := added.t
+		for range added.count {
+			id := uuid.New()
+			item := TicketLuggage{
+				ID:       id,
+				TicketID: ticketID,
+				OrderID:  orderID,
+				Type:     t,
+				TagID:    strings.ToUpper(strings.ReplaceAll(id.String(), "-", "")[:12]),
+				Paid:     !orderID.Valid,
+			}
+
+			if free[t] > 0 {
+				free[t]--
+			} else {
+				item.Price = tariff.price(t)
+			}
+
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// LuggagePrice is the sum of the prices of the items.
+func LuggagePrice(items []TicketLuggage) int {
+	var price int
+	for _, item := range items {
+		price += item.Price
+	}
+	return price
+}
+
+// LuggageVolumeOf is the space the items take in the luggage compartment.
+func LuggageVolumeOf(items []TicketLuggage) luggage {
+	var volume luggage
+	for _, item := range items {
+		volume += item.Type.Volume()
+	}
+	return volume
+}
+
+type luggageOrderStatus string
+
+const (
+	PendingLuggageOrderStatus   luggageOrderStatus = "Pending"
+	CompletedLuggageOrderStatus luggageOrderStatus = "Completed"
+	ExpiredLuggageOrderStatus   luggageOrderStatus = "Expired"
+)
+
+// LuggageOrder adds luggage to a paid ticket. The space of the items is taken as soon as the order
+// is made and given back when its payment session expires.
+type LuggageOrder struct {
+	ID             uuid.UUID          `gorm:"type:binary(16);primaryKey"                          json:"id"`
+	TicketID       uuid.UUID          `gorm:"type:binary(16);not null;index"                      json:"ticketId"`
+	Items          []TicketLuggage    `gorm:"foreignKey:OrderID"                                  json:"items"`
+	Volume         luggage            `gorm:"type:MEDIUMINT UNSIGNED;not null"                    json:"volume"`
+	Price          int                `gorm:"type:MEDIUMINT UNSIGNED;not null"                    json:"price"`
+	Currency       Currency           `gorm:"type:enum('EUR','UAH');not null;default:'EUR'"      json:"currency"`
+	SessionID      string             `gorm:"type:varchar(500);index"                             json:"-"`
+	Status         luggageOrderStatus `gorm:"type:enum('Pending','Completed','Expired');not null" json:"status"`
+	FailureMessage string             `gorm:"type:varchar(500)"                                   json:"failureMessage"`
+	TariffID       uuid.NullUUID      `gorm:"type:binary(16);index"                               json:"tariffId"`
+	TariffVersion  int                `gorm:"type:SMALLINT UNSIGNED;not null;default:0"           json:"tariffVersion"`
+	CreatedAt      time.Time          `gorm:"not null"                                            json:"createdAt"`
+	CompletedAt    sql.NullTime       `                                                           json:"completedAt"`
+}
+
+type NewLuggageOrderJSON struct {
+	Backpacks    int `json:"backpacks"`
+	SmallLuggage int `json:"smallLuggage"`
+	LargeLuggage int `json:"largeLuggage"`
+}
+
+// Parse makes the order of the items for the ticket priced by the tariff, the price is charged in the currency of the rate.
+func (o NewLuggageOrderJSON) Parse(ticket Ticket, rate ExchangeRate, tariff LuggageTariff) (LuggageOrder, error) {
+	var params rfc7807.InvalidParams
+	for name, count := range map[string]int{"backpacks": o.Backpacks, "smallLuggage": o.SmallLuggage, "largeLuggage": o.LargeLuggage} {
+		if count < 0 {
+			params.SetInvalidParam(name, "Can not be negative.")
+		}
+	}
+
+	if params == nil && o.Backpacks+o.SmallLuggage+o.LargeLuggage == 0 {
+		params.SetInvalidParam("luggage", "At least one item has to be added.")
+	}
+
+	if params != nil {
+		return LuggageOrder{}, rfc7807.BadRequest("invalid-data", "Invalid Data Error", "The provided luggage is not valid.", params...)
+	}
+
+	id := uuid.New()
+	items := NewLuggageItems(ticket.ID, uuid.NullUUID{UUID: id, Valid: true}, len(ticket.Passengers), ticket.Luggage, tariff, o.Backpacks, o.SmallLuggage, o.LargeLuggage)
+	charge := rate.Convert(LuggagePrice(items))
+
+	return LuggageOrder{
+		ID:            id,
+		TicketID:      ticket.ID,
+		Items:         items,
+		Volume:        LuggageVolumeOf(items),
+		Price:         charge.Amount,
+		Currency:      charge.Currency,
+		Status:        PendingLuggageOrderStatus,
+		TariffID:      tariff.Ref(),
+		TariffVersion: tariff.Version,
+	}, nil
+}
+
+func MigrateTicketLuggage(db *gorm.DB) error {
+	return db.AutoMigrate(&TicketLuggage{}, &LuggageOrder{})
+}
@@ -0,0 +1,131 @@
+package entity
+
+import (
+	"database/sql"
+	rfc7807 "maryan_api/pkg/problem"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/d3code/uuid"
+	"gorm.io/gorm"
+)
+
+type waitlistStatus string
+
+const (
+	WaitingWaitlistStatus   waitlistStatus = "Waiting"
+	OfferedWaitlistStatus   waitlistStatus = "Offered"
+	PurchasedWaitlistStatus waitlistStatus = "Purchased"
+	ExpiredWaitlistStatus   waitlistStatus = "Expired"
+	LeftWaitlistStatus      waitlistStatus = "Left"
+)
+
+// WaitlistPurchase is a ticket bought by card through the offer of the waitlist entry. The seats
+// held for the offer are moved to the ticket when it is saved.
+type WaitlistPurchase struct {
+	OfferID  uuid.UUID
+	TicketID uuid.UUID
+	Holds    []SeatHold
+}
+
+// WaitlistEntry queues the customer for the segment of a sold-out connection. When the seats
+// free up the entry gets an offer: the seats are held for it until the offer expires.
+type WaitlistEntry struct {
+	ID             uuid.UUID      `gorm:"type:binary(16);primaryKey"                                                    json:"id"`
+	ConnectionID   uuid.UUID      `gorm:"type:binary(16);not null;index"                                                json:"connectionId"`
+	UserID         uuid.UUID      `gorm:"type:binary(16);not null;index"                                                json:"-"`
+	Email          string         `gorm:"type:varchar(255);not null"                                                    json:"email"`
+	Language       string         `gorm:"type:varchar(2);not null;default:'en'"                                         json:"language"`
+	Passengers     int            `gorm:"type:TINYINT UNSIGNED;not null"                                                json:"passengers"`
+	FromStop       int            `gorm:"type:TINYINT UNSIGNED;not null"                                                json:"fromStop"`
+	ToStop         int            `gorm:"type:TINYINT UNSIGNED;not null"                                                json:"toStop"`
+	Status         waitlistStatus `gorm:"type:enum('Waiting','Offered','Purchased','Expired','Left');not null;index"   json:"status"`
+	OfferExpiresAt sql.NullTime   `                                                                                     json:"offerExpiresAt"`
+	TicketID       uuid.NullUUID  `gorm:"type:binary(16)"                                                               json:"ticketId"`
+	CreatedAt      time.Time      `gorm:"not null"                                                                      json:"createdAt"`
+}
+
+// ValidateOffer checks that the ticket is bought by the owner of the entry through a valid offer.
+func (e WaitlistEntry) ValidateOffer(userID uuid.UUID, newTicket NewTicketJSON, segment Segment) error {
+	if e.UserID != userID {
+		return rfc7807.New(http.StatusForbidden, "foreign-waitlist-offer", "Foreign Waitlist Offer Error", "The waitlist offer belongs to another user.")
+	}
+
+	if e.Status != OfferedWaitlistStatus || e.OfferExpiresAt.Time.Before(time.Now().UTC()) {
+		return rfc7807.BadRequest("expired-waitlist-offer", "Expired Waitlist Offer Error", "The waitlist offer has expired or has already been used.")
+	}
+
+	if e.ConnectionID != newTicket.ConnectionID || e.FromStop != segment.From.Sequence || e.ToStop != segment.To.Sequence || e.Passengers != len(newTicket.Passengers) {
+		return rfc7807.BadRequest("unmatched-waitlist-offer", "Unmatched Waitlist Offer Error", "The ticket does not match the connection, the stops or the passengers of the waitlist offer.")
+	}
+
+	return nil
+}
+
+// WaitlistDepth is the queue of one connection.
+type WaitlistDepth struct {
+	ConnectionID uuid.UUID `gorm:"column:connection_id" json:"connectionId"`
+	Entries      int       `gorm:"column:entries"       json:"entries"`
+	Passengers   int       `gorm:"column:passengers"    json:"passengers"`
+	Offered      int       `gorm:"column:offered"       json:"offered"`
+}
+
+type NewWaitlistEntryJSON struct {
+	Email      string `json:"email"`
+	Passengers int    `json:"passengers"`
+	FromStop   int    `json:"fromStop"`
+	ToStop     int    `json:"toStop"`
+}
+
+func (e NewWaitlistEntryJSON) Parse(connection Connection, userID uuid.UUID, lang string) (WaitlistEntry, error) {
+	var params rfc7807.InvalidParams
+	if !govalidator.IsEmail(e.Email) {
+		params.SetInvalidParam("email", "Contains invalid characters or is not an email.")
+	}
+
+	if e.Passengers < 1 || e.Passengers > len(connection.Bus.Seats) {
+		params.SetInvalidParam("passengers", "Must be at least 1 and not more than the seats of the bus.")
+	}
+
+	if params != nil {
+		return WaitlistEntry{}, rfc7807.BadRequest("invalid-data", "Invalid Data Error", "The provided data is not valid.", params...)
+	}
+
+	segment, err := connection.Segment(e.FromStop, e.ToStop)
+	if err != nil {
+		return WaitlistEntry{}, err
+	}
+
+	if segment.From.DepartureTime.Before(time.Now().UTC()) {
+		return WaitlistEntry{}, rfc7807.BadRequest("unavailable-connection", "Unavailavble Connection Error", "Connection has alredy departed.")
+	}
+
+	return WaitlistEntry{
+		ID:           uuid.New(),
+		ConnectionID: connection.ID,
+		UserID:       userID,
+		Email:        e.Email,
+		Language:     lang,
+		Passengers:   e.Passengers,
+		FromStop:     segment.From.Sequence,
+		ToStop:       segment.To.Sequence,
+		Status:       WaitingWaitlistStatus,
+	}, nil
+}
+
+// FreeSeats returns the seats of the bus that are not taken, in the order of the bus.
+func (c Connection) FreeSeats(takenSeats []uuid.UUID) []TicketSeat {
+	var seats []TicketSeat
+	for _, seat := range c.Bus.Seats {
+		if seat.Number != 0 && !slices.Contains(takenSeats, seat.ID) {
+			seats = append(seats, TicketSeat{SeatID: seat.ID})
+		}
+	}
+	return seats
+}
+
+func MigrateWaitlist(db *gorm.DB) error {
+	return db.AutoMigrate(&WaitlistEntry{})
+}
@@ -14,10 +14,11 @@ import (
 )
 
 type UserInfoOAUTH struct {
-	FirstName   string
-	LastName    string
-	Email       string
-	DateOfBirth time.Time
+	FirstName     string
+	LastName      string
+	Email         string
+	EmailVerified bool
+	DateOfBirth   time.Time
 }
 
 func GetCredentialsByCode(code string, ctx context.Context, client *http.Client) (UserInfoOAUTH, error) {
@@ -59,7 +60,10 @@ func GetCredentialsByCode(code string, ctx context.Context, client *http.Client)
 			GivenName  string `json:"givenName"`
 		} `json:"names"`
 		EmailAddresses []struct {
-			Value string `json:"value"`
+			Value    string `json:"value"`
+			Metadata struct {
+				Verified bool `json:"verified"`
+			} `json:"metadata"`
 		} `json:"emailAddresses"`
 		Birthdays []struct {
 			Date struct {
@@ -76,7 +80,7 @@ func GetCredentialsByCode(code string, ctx context.Context, client *http.Client)
 	}
 
 	// Default safe values
-	firstName, lastName, email := "", "", ""
+	firstName, lastName, email, emailVerified := "", "", "", false
 	dob := time.Now().AddDate(-18, 0, 0) // fallback = assume 18 y/o
 
 	if len(parsed.Names) > 0 {
@@ -85,6 +89,7 @@ func GetCredentialsByCode(code string, ctx context.Context, client *http.Client)
 	}
 	if len(parsed.EmailAddresses) > 0 {
 		email = parsed.EmailAddresses[0].Value
+		emailVerified = parsed.EmailAddresses[0].Metadata.Verified
 	}
 	if len(parsed.Birthdays) > 0 {
 		d := parsed.Birthdays[0].Date
@@ -92,9 +97,10 @@ func GetCredentialsByCode(code string, ctx context.Context, client *http.Client)
 	}
 
 	return UserInfoOAUTH{
-		FirstName:   firstName,
-		LastName:    lastName,
-		Email:       email,
-		DateOfBirth: dob,
+		FirstName:     firstName,
+		LastName:      lastName,
+		Email:         email,
+		EmailVerified: emailVerified,
+		DateOfBirth:   dob,
 	}, nil
 }
@@ -0,0 +1,172 @@
+// Package paymenttest provides an in-memory payment provider for the tests of the purchases.
+package paymenttest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"maryan_api/config"
+	"maryan_api/internal/infrastructure/clients/payment"
+	"strconv"
+	"sync"
+)
+
+// fakeWebhookSecret signs the webhooks of the fake provider, it is only ever built into the tests.
+const fakeWebhookSecret = "fake-webhook-secret"
+
+type fakeSession struct {
+	amount   int64
+	currency string
+	paid     bool
+	expired  bool
+	refunded int64
+}
+
+// Fake is an in-memory provider for running the purchases offline. The sessions and refunds
+// are numbered in the order they are created, so the same calls always give the same ids.
+type Fake struct {
+	mu       sync.Mutex
+	sessions map[string]*fakeSession
+	count    int
+	events   int
+}
+
+func NewFakeProvider() *Fake {
+	return &Fake{sessions: make(map[string]*fakeSession)}
+}
+
+func (f *Fake) Name() string {
+	return "fake"
+}
+
+func (f *Fake) SignatureHeader() string {
+	return "Fake-Signature"
+}
+
+// CreateSession redirects the customer straight to the success URL, the payment itself is
+// confirmed by the webhook returned from Complete.
+func (f *Fake) CreateSession(amount int64, currency, base, token string) (string, string, error) {
+	if amount <= 0 {
+		return "", "", errors.New("The amount must be greater than 0.")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.count++
+	sessionID := "fake_cs_" + strconv.Itoa(f.count)
+	f.sessions[sessionID] = &fakeSession{amount: amount, currency: currency}
+
+	return config.APIURL() + base + "/succeded/" + sessionID + "/" + token, sessionID, nil
+}
+
+func (f *Fake) CancelSession(sessionID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	session, ok := f.sessions[sessionID]
+	if !ok {
+		return errors.New("No such checkout session.")
+	}
+
+	if session.paid {
+		return errors.New("A paid checkout session can not be canceled.")
+	}
+
+	session.expired = true
+	return nil
+}
+
+func (f *Fake) SessionPaid(sessionID string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	session, ok := f.sessions[sessionID]
+	if !ok {
+		return false, errors.New("No such checkout session.")
+	}
+
+	return session.paid, nil
+}
+
+func (f *Fake) Refund(sessionID string, amount int64, idempotencyKey string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	session, ok := f.sessions[sessionID]
+	if !ok || !session.paid {
+		return "", errors.New("The checkout session has no payment to refund.")
+	}
+
+	if session.refunded+amount > session.amount {
+		return "", errors.New("The refund exceeds the amount paid.")
+	}
+
+	session.refunded += amount
+	return "fake_re_" + idempotencyKey, nil
+}
+
+func (f *Fake) VerifyWebhook(payload []byte, signature string) (payment.Event, error) {
+	if !hmac.Equal([]byte(signature), []byte(fakeSignature(payload))) {
+		return payment.Event{}, errors.New("The signature of the webhook is not valid.")
+	}
+
+	var event payment.Event
+	return event, json.Unmarshal(payload, &event)
+}
+
+// SessionByPaymentIntent finds no session, the fake sessions are paid without payment intents.
+func (f *Fake) SessionByPaymentIntent(paymentIntentID string) (string, error) {
+	return "", nil
+}
+
+// Complete pays the session and returns the signed webhook of the payment.
+func (f *Fake) Complete(sessionID string) ([]byte, string, error) {
+	return f.settle(sessionID, payment.EventSessionCompleted)
+}
+
+// Expire expires the session and returns the signed webhook of the expiration.
+func (f *Fake) Expire(sessionID string) ([]byte, string, error) {
+	return f.settle(sessionID, payment.EventSessionExpired)
+}
+
+func (f *Fake) settle(sessionID, eventType string) ([]byte, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	session, ok := f.sessions[sessionID]
+	if !ok {
+		return nil, "", errors.New("No such checkout session.")
+	}
+
+	if session.paid || session.expired {
+		return nil, "", errors.New("The checkout session has already been settled.")
+	}
+
+	if eventType == payment.EventSessionCompleted {
+		session.paid = true
+	} else {
+		session.expired = true
+	}
+
+	f.events++
+	payload, err := json.Marshal(payment.Event{
+		ID:        "fake_evt_" + strconv.Itoa(f.events),
+		Type:      eventType,
+		SessionID: sessionID,
+		Paid:      session.paid,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return payload, fakeSignature(payload), nil
+}
+
+func fakeSignature(payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(fakeWebhookSecret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
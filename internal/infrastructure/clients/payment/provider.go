@@ -0,0 +1,44 @@
+package payment
+
+// The event types are the ones of Stripe, the other providers translate their events into them.
+const (
+	EventSessionCompleted = "checkout.session.completed"
+	EventSessionExpired   = "checkout.session.expired"
+	EventPaymentFailed    = "payment_intent.payment_failed"
+)
+
+// Event is a verified webhook event of the provider.
+type Event struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	SessionID string `json:"sessionId"`
+	// PaymentIntentID is set instead of the session on the events of the payment, the session
+	// is looked up by it through SessionByPaymentIntent.
+	PaymentIntentID string `json:"paymentIntentId"`
+	Paid            bool   `json:"paid"`
+	FailureMessage  string `json:"failureMessage"`
+}
+
+// PaymentProvider takes the payments of the tickets and parcels. Amounts are in the minor
+// units of the currency.
+type PaymentProvider interface {
+	// Name is used in the path of the webhook of the provider.
+	Name() string
+	// SignatureHeader is the header the provider signs its webhooks in.
+	SignatureHeader() string
+	// CreateSession starts a checkout session and returns the URL to redirect the customer to
+	// and the id of the session. The customer is sent back to base+"/succeded" or base+"/failed".
+	CreateSession(amount int64, currency, base, token string) (string, string, error)
+	// CancelSession expires a checkout session that has not been paid.
+	CancelSession(sessionID string) error
+	// SessionPaid reports whether the customer has paid through the session.
+	SessionPaid(sessionID string) (bool, error)
+	// Refund returns the amount paid through the session, the idempotency key keeps a retried
+	// request from refunding the same payment twice.
+	Refund(sessionID string, amount int64, idempotencyKey string) (string, error)
+	// VerifyWebhook checks the signature of the webhook and parses its event, it makes no requests
+	// to the provider.
+	VerifyWebhook(payload []byte, signature string) (Event, error)
+	// SessionByPaymentIntent returns the checkout session the payment has been made through.
+	SessionByPaymentIntent(paymentIntentID string) (string, error)
+}
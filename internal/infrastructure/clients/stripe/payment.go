@@ -2,25 +2,42 @@ package stripe
 
 import (
 	"maryan_api/config"
+	"maryan_api/internal/infrastructure/clients/payment"
+	"strings"
+	"time"
 
 	"github.com/stripe/stripe-go/v76"
 	"github.com/stripe/stripe-go/v76/checkout/session"
 )
 
-func InitStripe() {
+// Provider takes the payments through Stripe Checkout.
+type Provider struct{}
+
+func NewStripeProvider() payment.PaymentProvider {
 	stripe.Key = config.StripSekretKey()
+	return Provider{}
+}
+
+func (Provider) Name() string {
+	return "stripe"
+}
+
+func (Provider) SignatureHeader() string {
+	return "Stripe-Signature"
 }
 
-func CreateStripeCheckoutSession(amount int64, base, token string) (string, string, error) {
+// CreateSession charges the amount given in the minor units of the currency.
+func (Provider) CreateSession(amount int64, currency, base, token string) (string, string, error) {
 	params := &stripe.CheckoutSessionParams{
 		Mode:       stripe.String("payment"),
+		ExpiresAt:  stripe.Int64(time.Now().Add(config.PaymentSessionDuration).Unix()),
 		SuccessURL: stripe.String(config.APIURL() + base + "/succeded/{CHECKOUT_SESSION_ID}/" + token),
 		CancelURL:  stripe.String(config.APIURL() + base + "/failed/{CHECKOUT_SESSION_ID}/" + token),
 
 		LineItems: []*stripe.CheckoutSessionLineItemParams{
 			{
 				PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
-					Currency: stripe.String("eur"),
+					Currency: stripe.String(strings.ToLower(currency)),
 					ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
 						Name: stripe.String("Ticket"),
 					},
@@ -39,7 +56,16 @@ func CreateStripeCheckoutSession(amount int64, base, token string) (string, stri
 	return s.URL, s.ID, nil
 }
 
-func CancelPaymentIntent(sessionID string) error {
+func (Provider) CancelSession(sessionID string) error {
 	_, err := session.Expire(sessionID, nil)
 	return err
 }
+
+func (Provider) SessionPaid(sessionID string) (bool, error) {
+	s, err := session.Get(sessionID, nil)
+	if err != nil {
+		return false, err
+	}
+
+	return s.PaymentStatus == stripe.CheckoutSessionPaymentStatusPaid, nil
+}
@@ -0,0 +1,36 @@
+package stripe
+
+import (
+	"errors"
+
+	"github.com/stripe/stripe-go/v76"
+	"github.com/stripe/stripe-go/v76/checkout/session"
+	"github.com/stripe/stripe-go/v76/refund"
+)
+
+// Refund refunds the amount paid through the checkout session. The idempotency key
+// keeps a retried request from refunding the same payment twice.
+func (Provider) Refund(sessionID string, amount int64, idempotencyKey string) (string, error) {
+	s, err := session.Get(sessionID, nil)
+	if err != nil {
+		return "", err
+	}
+
+	if s.PaymentIntent == nil {
+		return "", errors.New("The checkout session has no payment to refund.")
+	}
+
+	params := &stripe.RefundParams{
+		PaymentIntent: stripe.String(s.PaymentIntent.ID),
+		Amount:        stripe.Int64(amount),
+		Reason:        stripe.String(string(stripe.RefundReasonRequestedByCustomer)),
+	}
+	params.SetIdempotencyKey(idempotencyKey)
+
+	r, err := refund.New(params)
+	if err != nil {
+		return "", err
+	}
+
+	return r.ID, nil
+}
@@ -0,0 +1,64 @@
+package stripe
+
+import (
+	"encoding/json"
+	"maryan_api/config"
+	"maryan_api/internal/infrastructure/clients/payment"
+
+	"github.com/stripe/stripe-go/v76"
+	"github.com/stripe/stripe-go/v76/checkout/session"
+	"github.com/stripe/stripe-go/v76/webhook"
+)
+
+func (Provider) VerifyWebhook(payload []byte, signature string) (payment.Event, error) {
+	event, err := webhook.ConstructEventWithOptions(payload, signature, config.StripeWebhookSecret(), webhook.ConstructEventOptions{
+		IgnoreAPIVersionMismatch: true,
+	})
+	if err != nil {
+		return payment.Event{}, err
+	}
+
+	parsed := payment.Event{
+		ID:   event.ID,
+		Type: string(event.Type),
+	}
+
+	switch parsed.Type {
+	case payment.EventSessionCompleted, payment.EventSessionExpired:
+		var checkoutSession stripe.CheckoutSession
+		err = json.Unmarshal(event.Data.Raw, &checkoutSession)
+		if err != nil {
+			return payment.Event{}, err
+		}
+
+		parsed.SessionID = checkoutSession.ID
+		parsed.Paid = checkoutSession.PaymentStatus == stripe.CheckoutSessionPaymentStatusPaid
+
+	case payment.EventPaymentFailed:
+		var paymentIntent stripe.PaymentIntent
+		err = json.Unmarshal(event.Data.Raw, &paymentIntent)
+		if err != nil {
+			return payment.Event{}, err
+		}
+
+		if paymentIntent.LastPaymentError != nil {
+			parsed.FailureMessage = paymentIntent.LastPaymentError.Msg
+		}
+
+		parsed.PaymentIntentID = paymentIntent.ID
+	}
+
+	return parsed, nil
+}
+
+func (Provider) SessionByPaymentIntent(paymentIntentID string) (string, error) {
+	iter := session.List(&stripe.CheckoutSessionListParams{
+		PaymentIntent: stripe.String(paymentIntentID),
+	})
+
+	for iter.Next() {
+		return iter.CheckoutSession().ID, nil
+	}
+
+	return "", iter.Err()
+}
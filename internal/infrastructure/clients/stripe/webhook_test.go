@@ -0,0 +1,86 @@
+package stripe
+
+import (
+	"maryan_api/internal/infrastructure/clients/payment"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stripe/stripe-go/v76/webhook"
+)
+
+const testWebhookSecret = "whsec_test_secret"
+
+func signedFixture(t *testing.T, name string) ([]byte, string) {
+	t.Helper()
+	t.Setenv("STRIPE_WEBHOOK_SECRET", testWebhookSecret)
+
+	payload, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed := webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{Payload: payload, Secret: testWebhookSecret})
+	return signed.Payload, signed.Header
+}
+
+func TestVerifyWebhookFixtures(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    payment.Event
+	}{
+		{
+			fixture: "checkout_session_completed.json",
+			want: payment.Event{
+				ID:        "evt_1PfQx2Ku7XkT3bZq0a9h1c2d",
+				Type:      payment.EventSessionCompleted,
+				SessionID: "cs_test_a1CrwsqWkRwxQ3RGa7YPrNeryQ3zqEEORgWmyFtg8WNX1xPziGQ1BhoQtx",
+				Paid:      true,
+			},
+		},
+		{
+			fixture: "checkout_session_expired.json",
+			want: payment.Event{
+				ID:        "evt_1PfR4mKu7XkT3bZqA1n2b3c4",
+				Type:      payment.EventSessionExpired,
+				SessionID: "cs_test_b2DsxtrXlSxyR4SHb8ZQsOfszR4arFFPShXnzGuh9XOY2yQAjHR2CipRuy",
+			},
+		},
+		{
+			// The session of a failed payment is looked up apart from the verification, so no request is made here.
+			fixture: "payment_intent_payment_failed.json",
+			want: payment.Event{
+				ID:              "evt_3PfR9aKu7XkT3bZq0d5e6f7g",
+				Type:            payment.EventPaymentFailed,
+				PaymentIntentID: "pi_3PfR8zKu7XkT3bZq0h8i9j0k",
+				FailureMessage:  "Your card has insufficient funds.",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			payload, signature := signedFixture(t, tt.fixture)
+
+			event, err := Provider{}.VerifyWebhook(payload, signature)
+			if err != nil {
+				t.Fatalf("VerifyWebhook() error = %v", err)
+			}
+
+			if event != tt.want {
+				t.Errorf("VerifyWebhook() = %+v, want %+v", event, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyWebhookRejectsForgedSignature(t *testing.T) {
+	payload, _ := signedFixture(t, "checkout_session_completed.json")
+
+	forged := webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{Payload: payload, Secret: "whsec_forged"})
+
+	_, err := Provider{}.VerifyWebhook(payload, forged.Header)
+	if err == nil {
+		t.Fatal("VerifyWebhook() accepted the webhook signed with another secret")
+	}
+}
@@ -1,6 +1,50 @@
 package verification
 
+import (
+	"context"
+	"fmt"
+	"io"
+	"maryan_api/config"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// twilioURL is the Twilio REST API the text messages are sent through.
+var twilioURL = "https://api.twilio.com"
+
 func VerifyNumber(number string) (string, error) {
 	return "000000", nil
 
 }
+
+// SendDeliveryCode texts the code the recipient gives the driver when the parcel is handed over.
+func SendDeliveryCode(ctx context.Context, number, code string) error {
+	return sendSMS(ctx, number, fmt.Sprintf("Your parcel delivery code is %s. Give it to the driver when the parcel is handed over.", code))
+}
+
+// sendSMS sends the text message through Twilio, the message is only sent once Twilio has accepted it.
+func sendSMS(ctx context.Context, to, body string) error {
+	cfg := config.SMS()
+
+	form := url.Values{"To": {to}, "From": {cfg.From}, "Body": {body}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, twilioURL+"/2010-04-01/Accounts/"+url.PathEscape(cfg.AccountSID)+"/Messages.json", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(cfg.AccountSID, cfg.AuthToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("twilio responded with %s: %s", resp.Status, detail)
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package verification
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func setupTwilio(t *testing.T, handler http.HandlerFunc) {
+	t.Setenv("TWILIO_ACCOUNT_SID", "AC_test")
+	t.Setenv("TWILIO_AUTH_TOKEN", "test_token")
+	t.Setenv("TWILIO_FROM", "+15005550006")
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	previous := twilioURL
+	twilioURL = server.URL
+	t.Cleanup(func() { twilioURL = previous })
+}
+
+func TestSendDeliveryCode(t *testing.T) {
+	var to, body string
+	setupTwilio(t, func(w http.ResponseWriter, r *http.Request) {
+		if sid, token, ok := r.BasicAuth(); !ok || sid != "AC_test" || token != "test_token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/2010-04-01/Accounts/AC_test/Messages.json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		to, body = r.PostFormValue("To"), r.PostFormValue("Body")
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	err := SendDeliveryCode(context.Background(), "+380501234567", "482913")
+	if err != nil {
+		t.Fatalf("SendDeliveryCode() error = %v", err)
+	}
+
+	if to != "+380501234567" || !strings.Contains(body, "482913") {
+		t.Errorf("texted %q to %s, want the code to +380501234567", body, to)
+	}
+}
+
+func TestSendDeliveryCodeRejected(t *testing.T) {
+	setupTwilio(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code": 21211, "message": "The 'To' number is not a valid phone number."}`))
+	})
+
+	err := SendDeliveryCode(context.Background(), "+380", "482913")
+	if err == nil || !strings.Contains(err.Error(), "21211") {
+		t.Errorf("SendDeliveryCode() error = %v, want the rejection of Twilio", err)
+	}
+}
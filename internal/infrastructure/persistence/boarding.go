@@ -0,0 +1,155 @@
+package dataStore
+
+import (
+	"context"
+	"maryan_api/internal/entity"
+	"maryan_api/pkg/dbutil"
+	rfc7807 "maryan_api/pkg/problem"
+	"net/http"
+	"time"
+
+	"github.com/d3code/uuid"
+	"gorm.io/gorm"
+)
+
+type Boarding interface {
+	GetStops(ctx context.Context, id, driverID uuid.UUID, from, to time.Time) ([]entity.Stop, error)
+	CompleteStop(ctx context.Context, stopID uuid.UUID, update *entity.ParcelStatusUpdate) error
+	CompleteDelivery(ctx context.Context, stopID uuid.UUID, proof *entity.ParcelDeliveryProof, update *entity.ParcelStatusUpdate) error
+	GetCurrentConnections(ctx context.Context, arrivingAfter time.Time) ([]entity.Connection, error)
+	UpdateQRCodes(ctx context.Context, tickets, parcels map[uuid.UUID][]byte) error
+	CollectCash(ctx context.Context, ticketID, driverID uuid.UUID) error
+	GetCashTickets(ctx context.Context, connectionID uuid.UUID) ([]entity.CashTicket, error)
+	CollectParcelCash(ctx context.Context, parcelID, driverID uuid.UUID) error
+}
+
+type boardingMySQL struct {
+	db *gorm.DB
+}
+
+// GetStops returns the stops of the ticket or the parcel with provided id, but only on the
+// connections served by the driver that are on the road between from and to.
+func (ds *boardingMySQL) GetStops(ctx context.Context, id, driverID uuid.UUID, from, to time.Time) ([]entity.Stop, error) {
+	var stops []entity.Stop
+	return stops, dbutil.PossibleDbError(
+		dbutil.Preload(ds.db, "Updates", "Ticket", "Ticket.Payment", "Ticket.Passengers", "Ticket.Seats", "Ticket.Seats.Seat", "Parcel", "Parcel.Payment", "Parcel.CashOnDelivery").
+			Preload("Ticket.Luggage", "paid = ?", true).
+			WithContext(ctx).
+			Joins("JOIN connections ON connections.id = stops.connection_id").
+			Joins("JOIN buses ON buses.id = connections.bus_id").
+			Where("(stops.ticket_id = ? OR stops.parcel_id = ?)", id, id).
+			Where("(buses.lead_driver_id = ? OR buses.assistant_driver_id = ?)", driverID, driverID).
+			Where("connections.departure_time <= ? AND connections.arrival_time >= ?", to, from).
+			Where("NOT EXISTS (SELECT 1 FROM connection_updates WHERE connection_updates.connection_id = connections.id AND connection_updates.status = ?)", entity.CanceledConnectionStatus).
+			Find(&stops),
+	)
+}
+
+// CompleteStop inserts the Completed update only if the stop has not been completed yet,
+// so the same code can not be used twice even when scanned by both drivers at once. The update of
+// the parcel of the stop is added with it, the stop is left open if the parcel can not be updated.
+func (ds *boardingMySQL) CompleteStop(ctx context.Context, stopID uuid.UUID, update *entity.ParcelStatusUpdate) error {
+	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Exec(`
+			INSERT INTO stop_updates (stop_id, status, comment, created_at)
+			SELECT ?, ?, '', ? FROM DUAL
+			WHERE NOT EXISTS (SELECT 1 FROM stop_updates WHERE stop_id = ? AND status = ?)
+		`, stopID, entity.CompletedStopStatus, time.Now().UTC(), stopID, entity.CompletedStopStatus)
+		if result.Error != nil {
+			return rfc7807.DB(result.Error.Error())
+		}
+
+		if result.RowsAffected == 0 {
+			return rfc7807.New(http.StatusConflict, "used-code", "Used Code Error", "The code has already been used.")
+		}
+
+		if update == nil {
+			return nil
+		}
+
+		return NewParsel(tx).UpdateStatus(ctx, *update)
+	})
+}
+
+// CompleteDelivery completes the drop-off of the parcel together with its proof of delivery
+// and the update of its status, none of them is kept if any fails.
+func (ds *boardingMySQL) CompleteDelivery(ctx context.Context, stopID uuid.UUID, proof *entity.ParcelDeliveryProof, update *entity.ParcelStatusUpdate) error {
+	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := NewBoarding(tx).CompleteStop(ctx, stopID, update)
+		if err != nil {
+			return err
+		}
+
+		return NewParsel(tx).CreateDeliveryProof(ctx, proof)
+	})
+}
+
+func (ds *boardingMySQL) GetCurrentConnections(ctx context.Context, arrivingAfter time.Time) ([]entity.Connection, error) {
+	var connections []entity.Connection
+	return connections, dbutil.PossibleDbError(
+		dbutil.Preload(ds.db, "Bus.Seats", "Stops", "Stops.Ticket", "Stops.Ticket.Payment", "Stops.Ticket.Seats", "Stops.Parcel", "Stops.Parcel.Payment").
+			WithContext(ctx).
+			Where("arrival_time >= ?", arrivingAfter).
+			Find(&connections),
+	)
+}
+
+func (ds *boardingMySQL) UpdateQRCodes(ctx context.Context, tickets, parcels map[uuid.UUID][]byte) error {
+	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for id, qrCode := range tickets {
+			err := tx.Model(&entity.Ticket{}).Where("id = ?", id).Update("qr_code", qrCode).Error
+			if err != nil {
+				return rfc7807.DB(err.Error())
+			}
+		}
+
+		for id, qrCode := range parcels {
+			err := tx.Model(&entity.Parcel{}).Where("id = ?", id).Update("qr_code", qrCode).Error
+			if err != nil {
+				return rfc7807.DB(err.Error())
+			}
+		}
+
+		return nil
+	})
+}
+
+// CollectCash marks the cash of the ticket as paid to the driver, the succeeded condition keeps
+// the cash from being collected twice.
+func (ds *boardingMySQL) CollectCash(ctx context.Context, ticketID, driverID uuid.UUID) error {
+	return dbutil.PossibleRawsAffectedError(
+		ds.db.WithContext(ctx).
+			Model(&entity.TicketPayment{}).
+			Where("ticket_id = ? AND method = ? AND succeeded = ?", ticketID, entity.PaymentMethodCash, false).
+			Updates(map[string]any{"succeeded": true, "collected_by": driverID, "collected_at": time.Now().UTC()}),
+		"collected-cash",
+	)
+}
+
+// CollectParcelCash marks the cash on delivery of the parcel as paid to the driver, the status
+// condition keeps the cash from being collected twice.
+func (ds *boardingMySQL) CollectParcelCash(ctx context.Context, parcelID, driverID uuid.UUID) error {
+	return dbutil.PossibleRawsAffectedError(
+		ds.db.WithContext(ctx).
+			Model(&entity.ParcelCashOnDelivery{}).
+			Where("parcel_id = ? AND status = ?", parcelID, entity.PendingCodStatus).
+			Updates(map[string]any{"status": entity.CollectedCodStatus, "collected_by": driverID, "collected_at": time.Now().UTC()}),
+		"collected-cash",
+	)
+}
+
+func (ds *boardingMySQL) GetCashTickets(ctx context.Context, connectionID uuid.UUID) ([]entity.CashTicket, error) {
+	var tickets []entity.CashTicket
+	return tickets, dbutil.PossibleDbError(
+		ds.db.WithContext(ctx).
+			Table("tickets").
+			Select("tickets.id AS ticket_id, ticket_payments.price, ticket_payments.currency, ticket_payments.succeeded, ticket_payments.collected_by").
+			Joins("JOIN ticket_payments ON ticket_payments.ticket_id = tickets.id").
+			Where("tickets.connection_id = ? AND tickets.canceled_at IS NULL AND ticket_payments.method = ?", connectionID, entity.PaymentMethodCash).
+			Scan(&tickets),
+	)
+}
+
+func NewBoarding(db *gorm.DB) Boarding {
+	return &boardingMySQL{db}
+}
@@ -0,0 +1,67 @@
+package dataStore
+
+import (
+	"context"
+	"errors"
+	"maryan_api/internal/entity"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/d3code/uuid"
+)
+
+func TestCompleteStopIsUndoneWithParcelStatus(t *testing.T) {
+	t.Setenv("API_URL", "http://localhost:8080")
+
+	db, mock := NewMockDB()
+
+	stopID, parcelID := uuid.New(), uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO stop_updates").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("SAVEPOINT").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE `parcels` SET").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	update := entity.NewParcelStatusUpdate(parcelID, entity.PickedUpParcelStatus, "")
+	err := NewBoarding(db).CompleteStop(context.Background(), stopID, &update)
+	if err == nil {
+		t.Fatal("CompleteStop() completed the stop of a parcel whose status can not change")
+	}
+
+	// The stop is left open, so the pick-up can be scanned again.
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCompleteDeliveryIsUndoneWithProof(t *testing.T) {
+	t.Setenv("API_URL", "http://localhost:8080")
+
+	db, mock := NewMockDB()
+
+	proof := entity.ParcelDeliveryProof{ParcelID: uuid.New()}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SAVEPOINT").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO stop_updates").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO `parcel_delivery_proofs`").
+		WillReturnError(errors.New("connection reset by peer"))
+	mock.ExpectRollback()
+
+	err := NewBoarding(db).CompleteDelivery(context.Background(), uuid.New(), &proof, nil)
+	if err == nil {
+		t.Fatal("CompleteDelivery() completed the drop-off without the proof of delivery")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
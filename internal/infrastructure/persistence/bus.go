@@ -25,6 +25,7 @@ type Bus interface {
 	SetSchedule(ctx context.Context, schedule []entity.BusAvailability) error
 	IsAvailable(ctx context.Context, id uuid.UUID, dates []time.Time) (bool, error)
 	GetAll(ctx context.Context) ([]entity.Bus, error)
+	SetSeatSurcharges(ctx context.Context, busID uuid.UUID, surcharges entity.SeatSurcharges) error
 }
 
 type busMySQL struct {
@@ -139,6 +140,34 @@ func (dbs *busMySQL) GetAll(ctx context.Context) ([]entity.Bus, error) {
 }
 
 // ------------------------Repos Initialization Functions--------------
+// SetSeatSurcharges replaces the seat surcharges of the bus.
+func (dbs *busMySQL) SetSeatSurcharges(ctx context.Context, busID uuid.UUID, surcharges entity.SeatSurcharges) error {
+	return dbs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("bus_id = ?", busID).Delete(&entity.SeatTypeSurcharge{}).Error
+		if err != nil {
+			return rfc7807.DB(err.Error())
+		}
+
+		err = tx.Where("bus_id = ?", busID).Delete(&entity.SeatSurcharge{}).Error
+		if err != nil {
+			return rfc7807.DB(err.Error())
+		}
+
+		if len(surcharges.Types) != 0 {
+			err = dbutil.PossibleCreateError(tx.Create(&surcharges.Types), "seat-surcharge-data")
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(surcharges.Seats) != 0 {
+			return dbutil.PossibleCreateError(tx.Create(&surcharges.Seats), "seat-surcharge-data")
+		}
+
+		return nil
+	})
+}
+
 func NewBus(db *gorm.DB) Bus {
 	return &busMySQL{db}
 }
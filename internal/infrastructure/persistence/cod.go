@@ -0,0 +1,112 @@
+package dataStore
+
+import (
+	"context"
+	"maryan_api/internal/entity"
+	"maryan_api/pkg/dbutil"
+	rfc7807 "maryan_api/pkg/problem"
+	"net/http"
+	"time"
+
+	"github.com/d3code/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CashOnDelivery interface {
+	GetLedger(ctx context.Context, pagination dbutil.Pagination) ([]entity.ParcelCashOnDelivery, int, error, bool)
+	CreatePayoutBatch(ctx context.Context, currency entity.Currency, collectedBefore time.Time, adminID uuid.UUID) (entity.CodPayoutBatch, error)
+	PayOutBatch(ctx context.Context, id uuid.UUID, reference string) error
+	GetPayoutBatch(ctx context.Context, id uuid.UUID) (entity.CodPayoutBatch, error)
+	GetPayoutBatches(ctx context.Context, pagination dbutil.Pagination) ([]entity.CodPayoutBatch, int, error, bool)
+}
+
+type cashOnDeliveryMySQL struct {
+	db *gorm.DB
+}
+
+func (ds *cashOnDeliveryMySQL) GetLedger(ctx context.Context, pagination dbutil.Pagination) ([]entity.ParcelCashOnDelivery, int, error, bool) {
+	return dbutil.Paginate[entity.ParcelCashOnDelivery](ctx, ds.db, pagination)
+}
+
+// CreatePayoutBatch takes the cash collected before the time in the currency into a new batch,
+// the entries are locked so that no cash is paid out twice by the concurrent batches.
+func (ds *cashOnDeliveryMySQL) CreatePayoutBatch(ctx context.Context, currency entity.Currency, collectedBefore time.Time, adminID uuid.UUID) (entity.CodPayoutBatch, error) {
+	var batch entity.CodPayoutBatch
+	return batch, ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var entries []entity.ParcelCashOnDelivery
+		err := dbutil.PossibleDbError(
+			tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("status = ? AND currency = ? AND collected_at <= ?", entity.CollectedCodStatus, currency, collectedBefore).
+				Order("sender_id, collected_at").
+				Find(&entries),
+		)
+		if err != nil {
+			return err
+		}
+
+		if len(entries) == 0 {
+			return rfc7807.New(http.StatusConflict, "no-collected-cash", "No Collected Cash Error", "There is no collected cash to pay out in the currency.")
+		}
+
+		batch = entity.NewCodPayoutBatch(currency, collectedBefore, adminID, entries)
+		err = dbutil.PossibleCreateError(tx.Create(&batch), "cod-payout-data")
+		if err != nil {
+			return err
+		}
+
+		for _, payout := range batch.Payouts {
+			var parcelIDs []uuid.UUID
+			for _, entry := range entries {
+				if entry.PayoutID.UUID == payout.ID {
+					parcelIDs = append(parcelIDs, entry.ParcelID)
+				}
+			}
+
+			err = dbutil.PossibleDbError(
+				tx.Model(&entity.ParcelCashOnDelivery{}).
+					Where("parcel_id IN ?", parcelIDs).
+					Updates(map[string]any{"status": entity.ScheduledCodStatus, "payout_id": payout.ID}),
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// PayOutBatch records the batch as transferred to the senders, the status condition keeps it from being paid out twice.
+func (ds *cashOnDeliveryMySQL) PayOutBatch(ctx context.Context, id uuid.UUID, reference string) error {
+	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := dbutil.PossibleRawsAffectedError(
+			tx.Model(&entity.CodPayoutBatch{}).
+				Where("id = ? AND status = ?", id, entity.CreatedCodPayoutStatus).
+				Updates(map[string]any{"status": entity.PaidCodPayoutStatus, "reference": reference, "paid_at": time.Now().UTC()}),
+			"paid-cod-payout",
+		)
+		if err != nil {
+			return err
+		}
+
+		return dbutil.PossibleDbError(
+			tx.Model(&entity.ParcelCashOnDelivery{}).
+				Where("status = ? AND payout_id IN (SELECT id FROM cod_payouts WHERE batch_id = ?)", entity.ScheduledCodStatus, id).
+				Update("status", entity.PaidOutCodStatus),
+		)
+	})
+}
+
+func (ds *cashOnDeliveryMySQL) GetPayoutBatch(ctx context.Context, id uuid.UUID) (entity.CodPayoutBatch, error) {
+	var batch = entity.CodPayoutBatch{ID: id}
+	return batch, dbutil.PossibleFirstError(ds.db.WithContext(ctx).Preload("Payouts").First(&batch), "non-existing-cod-payout")
+}
+
+func (ds *cashOnDeliveryMySQL) GetPayoutBatches(ctx context.Context, pagination dbutil.Pagination) ([]entity.CodPayoutBatch, int, error, bool) {
+	return dbutil.Paginate[entity.CodPayoutBatch](ctx, ds.db, pagination, "Payouts")
+}
+
+func NewCashOnDelivery(db *gorm.DB) CashOnDelivery {
+	return &cashOnDeliveryMySQL{db}
+}
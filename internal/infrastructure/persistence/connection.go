@@ -7,6 +7,7 @@ import (
 	"maryan_api/internal/entity"
 	"maryan_api/pkg/dbutil"
 	rfc7807 "maryan_api/pkg/problem"
+	"slices"
 	"time"
 
 	"github.com/d3code/uuid"
@@ -16,6 +17,7 @@ import (
 
 type Connection interface {
 	GetByID(ctx context.Context, id uuid.UUID, passengerNumber int) (entity.Connection, []uuid.UUID, error)
+	GetSegmentByID(ctx context.Context, id uuid.UUID, passengerNumber, fromStop, toStop int) (entity.Connection, entity.Segment, []uuid.UUID, error)
 	GetConnections(ctx context.Context, pagination dbutil.Pagination) ([]entity.Connection, int, error, bool)
 	ChangeDepartureTime(ctx context.Context, id uuid.UUID, departureTime time.Time) error
 	ChangeGoogleMapsURL(ctx context.Context, id uuid.UUID, url string) error
@@ -31,10 +33,13 @@ type connectionMySQL struct {
 }
 
 type FoundConnections struct {
-	Connections []entity.Connection
-	TicketsLeft []TicketsLeft
-	LeftRange   []entity.ConnectionsRange
-	RightRange  []entity.ConnectionsRange
+	Connections      []entity.Connection
+	Segments         []FoundSegment
+	TicketsLeft      []TicketsLeft
+	LeftRange        []entity.ConnectionsRange
+	RightRange       []entity.ConnectionsRange
+	RangeSegments    []RangeSegment
+	RangeConnections []entity.Connection
 }
 
 func (fc FoundConnections) ConnectionsIDs() []uuid.UUID {
@@ -46,8 +51,26 @@ func (fc FoundConnections) ConnectionsIDs() []uuid.UUID {
 	return connectionsIDs
 }
 
+// FoundSegment is the part of the route of the connection that matches the search.
+type FoundSegment struct {
+	ID       uuid.UUID `gorm:"column:id"`
+	FromStop int       `gorm:"column:from_stop"`
+	ToStop   int       `gorm:"column:to_stop"`
+}
+
+// RangeSegment is a segment found on one of the dates of the ranges, it is used to price the ranges.
+type RangeSegment struct {
+	ID         uuid.UUID `gorm:"column:id"`
+	Date       time.Time `gorm:"column:date"`
+	FromStop   int       `gorm:"column:from_stop"`
+	ToStop     int       `gorm:"column:to_stop"`
+	Seats      int       `gorm:"column:seats"`
+	TakenSeats int       `gorm:"column:taken_seats"`
+}
+
 type TicketsLeft struct {
 	ID     uuid.UUID `gorm:"column:id"`
+	Seats  int       `gorm:"column:seats"`
 	Number float64   `gorm:"column:tickets_left"`
 }
 
@@ -77,56 +100,99 @@ func (ds *connectionMySQL) FindConnections(
 		return FoundConnections{}, err
 	}
 
+	// 5. Segments of the ranges
+	if err := ds.findRangeSegments(ctx, request, &foundConnections); err != nil {
+		return FoundConnections{}, err
+	}
+
 	return foundConnections, nil
 }
 
 // --- private helpers ---
 
+// segmentsFrom joins the stops of the routes into the pairs going from the searched place to
+// the searched destination, fs being the boarding stop and ts the leaving one.
+const segmentsFrom = `
+	FROM connection_stops fs
+	JOIN connection_stops ts ON ts.connection_id = fs.connection_id AND ts.sequence > fs.sequence
+	JOIN connections c ON c.id = fs.connection_id
+	WHERE fs.country_id = ? AND ts.country_id = ? AND (? = '' OR fs.city = ?) AND (? = '' OR ts.city = ?)
+`
+
+func segmentsArgs(request entity.FindConnectionsRequest, args ...any) []any {
+	return append([]any{request.From, request.To, request.FromCity, request.FromCity, request.ToCity, request.ToCity}, args...)
+}
+
 func (ds *connectionMySQL) findBaseConnections(
 	ctx context.Context,
 	request entity.FindConnectionsRequest,
 	foundConnections *FoundConnections,
 ) error {
+	err := dbutil.PossibleDbError(
+		ds.db.WithContext(ctx).Raw(`
+			SELECT fs.connection_id AS id, MIN(fs.sequence) AS from_stop, MAX(ts.sequence) AS to_stop
+		`+segmentsFrom+`
+			AND DATE(CONVERT_TZ(fs.departure_time, 'UTC', ?)) = ?
+			GROUP BY fs.connection_id
+		`, segmentsArgs(request, config.MustGetLocationFromCountryID(request.From).String(), request.Date.Format("2006-01-02"))...).
+			Scan(&foundConnections.Segments),
+	)
+	if err != nil || len(foundConnections.Segments) == 0 {
+		return err
+	}
+
+	var ids = make([]uuid.UUID, len(foundConnections.Segments))
+	for i, segment := range foundConnections.Segments {
+		ids[i] = segment.ID
+	}
+
 	return dbutil.PossibleDbError(
 		ds.db.WithContext(ctx).
 			Preload(clause.Associations).
-			Where(
-				"DATE(CONVERT_TZ(departure_time, 'UTC', ?)) = ? AND destination_country_id = ? AND departure_country_id = ?",
-				config.MustGetLocationFromCountryID(request.From).String(),
-				request.Date.Format("2006-01-02"),
-				request.To,
-				request.From,
-			).
+			Preload("Route.Country").
+			Where("id IN ?", ids).
 			Find(&foundConnections.Connections),
 	)
 }
 
+// findTicketsLeft counts the seats that are free on every segment of the found part of the route.
 func (ds *connectionMySQL) findTicketsLeft(
 	ctx context.Context,
 	foundConnections *FoundConnections,
 ) error {
-	return dbutil.PossibleDbError(
-		ds.db.WithContext(ctx).Raw(`
-			SELECT 
-				c.id AS id,
-				COALESCE((
-					SELECT COUNT(s.id)
-					FROM buses b
-					JOIN seats s ON b.id = s.bus_id
-					WHERE b.id = c.bus_id
-				), 0)
-				-
-				COALESCE((
-					SELECT COUNT(st.id) / 2
-					FROM stops st
-					WHERE st.connection_id = c.id
-					GROUP BY st.connection_id
-				), 0) AS tickets_left
-			FROM connections c
-			WHERE c.id IN ?
-		`, foundConnections.ConnectionsIDs()).
-			Scan(&foundConnections.TicketsLeft),
-	)
+	foundConnections.TicketsLeft = make([]TicketsLeft, len(foundConnections.Segments))
+	for i, segment := range foundConnections.Segments {
+		err := dbutil.PossibleDbError(
+			ds.db.WithContext(ctx).Raw(`
+				SELECT
+					c.id AS id,
+					COALESCE((
+						SELECT COUNT(s.id)
+						FROM seats s
+						WHERE s.bus_id = c.bus_id
+					), 0) AS seats,
+					COALESCE((
+						SELECT COUNT(s.id)
+						FROM seats s
+						WHERE s.bus_id = c.bus_id
+					), 0)
+					-
+					COALESCE((
+						SELECT COUNT(DISTINCT sh.seat_id)
+						FROM seat_holds sh
+						WHERE sh.connection_id = c.id AND sh.segment >= ? AND sh.segment < ? AND (sh.status = ? OR sh.expires_at > ?)
+					), 0) AS tickets_left
+				FROM connections c
+				WHERE c.id = ?
+			`, segment.FromStop, segment.ToStop, entity.SoldSeatHoldStatus, time.Now().UTC(), segment.ID).
+				Scan(&foundConnections.TicketsLeft[i]),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func (ds *connectionMySQL) findLeftRange(
@@ -135,24 +201,17 @@ func (ds *connectionMySQL) findLeftRange(
 	foundConnections *FoundConnections,
 ) error {
 	return dbutil.PossibleDbError(
-		ds.db.WithContext(ctx).
-			Table("connections").
-			Select(
-				"DATE(departure_time) AS date",
-				"COUNT(id) AS number",
-				"MIN(price) AS minPrice",
-				"MAX(sell_before) AS sellBefore",
-			).
-			Where(
-				"DATE(CONVERT_TZ(departure_time, 'UTC', ?)) < DATE(?) AND destination_country_id = ? AND departure_country_id = ?",
-				config.MustGetLocationFromCountryID(request.From).String(),
-				request.Date.Format("2006-01-02"),
-				request.To,
-				request.From,
-			).
-			Group("DATE(departure_time)").
-			Order("DATE(departure_time) DESC").
-			Limit(request.Range).
+		ds.db.WithContext(ctx).Raw(`
+			SELECT
+				DATE(fs.departure_time) AS date,
+				COUNT(DISTINCT c.id) AS number,
+				MAX(c.sell_before) AS sellBefore
+		`+segmentsFrom+`
+			AND DATE(CONVERT_TZ(fs.departure_time, 'UTC', ?)) < DATE(?)
+			GROUP BY DATE(fs.departure_time)
+			ORDER BY DATE(fs.departure_time) DESC
+			LIMIT ?
+		`, segmentsArgs(request, config.MustGetLocationFromCountryID(request.From).String(), request.Date.Format("2006-01-02"), request.Range)...).
 			Scan(&foundConnections.LeftRange),
 	)
 }
@@ -163,60 +222,147 @@ func (ds *connectionMySQL) findRightRange(
 	foundConnections *FoundConnections,
 ) error {
 	return dbutil.PossibleDbError(
-		ds.db.WithContext(ctx).
-			Table("connections").
-			Select(
-				"DATE(departure_time) AS date",
-				"COUNT(id) AS number",
-				"MIN(price) AS minPrice",
-				"MAX(sell_before) AS sellBefore",
-			).
-			Where(
-				"DATE(CONVERT_TZ(departure_time, 'UTC', ?)) > ? AND destination_country_id = ? AND departure_country_id = ?",
-				config.MustGetLocationFromCountryID(request.From).String(),
-				request.Date.Format("2006-01-02"),
-				request.To,
-				request.From,
-			).
-			Group("DATE(departure_time)").
-			Order("DATE(departure_time) ASC").
-			Limit(request.Range).
+		ds.db.WithContext(ctx).Raw(`
+			SELECT
+				DATE(fs.departure_time) AS date,
+				COUNT(DISTINCT c.id) AS number,
+				MAX(c.sell_before) AS sellBefore
+		`+segmentsFrom+`
+			AND DATE(CONVERT_TZ(fs.departure_time, 'UTC', ?)) > ?
+			GROUP BY DATE(fs.departure_time)
+			ORDER BY DATE(fs.departure_time) ASC
+			LIMIT ?
+		`, segmentsArgs(request, config.MustGetLocationFromCountryID(request.From).String(), request.Date.Format("2006-01-02"), request.Range)...).
 			Scan(&foundConnections.RightRange),
 	)
 }
 
+// findRangeSegments loads the segments found on the dates of the ranges with their connections,
+// the minimal price of a date depends on the pricing of every segment.
+func (ds *connectionMySQL) findRangeSegments(
+	ctx context.Context,
+	request entity.FindConnectionsRequest,
+	foundConnections *FoundConnections,
+) error {
+	var dates []string
+	for _, connectionsRange := range slices.Concat(foundConnections.LeftRange, foundConnections.RightRange) {
+		dates = append(dates, connectionsRange.Date.Format("2006-01-02"))
+	}
+
+	if len(dates) == 0 {
+		return nil
+	}
+
+	err := dbutil.PossibleDbError(
+		ds.db.WithContext(ctx).Raw(`
+			SELECT
+				c.id AS id,
+				DATE(fs.departure_time) AS date,
+				fs.sequence AS from_stop,
+				ts.sequence AS to_stop,
+				COALESCE((
+					SELECT COUNT(s.id)
+					FROM seats s
+					WHERE s.bus_id = c.bus_id
+				), 0) AS seats,
+				COALESCE((
+					SELECT COUNT(DISTINCT sh.seat_id)
+					FROM seat_holds sh
+					WHERE sh.connection_id = c.id AND sh.segment >= fs.sequence AND sh.segment < ts.sequence AND (sh.status = ? OR sh.expires_at > ?)
+				), 0) AS taken_seats
+		`+segmentsFrom+`
+			AND DATE(fs.departure_time) IN ?
+		`, append([]any{entity.SoldSeatHoldStatus, time.Now().UTC()}, segmentsArgs(request, dates)...)...).
+			Scan(&foundConnections.RangeSegments),
+	)
+	if err != nil || len(foundConnections.RangeSegments) == 0 {
+		return err
+	}
+
+	var ids []uuid.UUID
+	for _, segment := range foundConnections.RangeSegments {
+		if !slices.Contains(ids, segment.ID) {
+			ids = append(ids, segment.ID)
+		}
+	}
+
+	return dbutil.PossibleDbError(ds.db.WithContext(ctx).Preload("Route").Where("id IN ?", ids).Find(&foundConnections.RangeConnections))
+}
+
 func (ds *connectionMySQL) GetByID(ctx context.Context, id uuid.UUID, passengersNumber int) (entity.Connection, []uuid.UUID, error) {
+	connection, _, takenSeatsIDs, err := ds.GetSegmentByID(ctx, id, passengersNumber, 0, 0)
+	return connection, takenSeatsIDs, err
+}
+
+// GetSegmentByID returns the connection with the seats taken and the luggage volume left on the
+// part of the route between the stops. A toStop of 0 stands for the destination.
+func (ds *connectionMySQL) GetSegmentByID(ctx context.Context, id uuid.UUID, passengersNumber, fromStop, toStop int) (entity.Connection, entity.Segment, []uuid.UUID, error) {
 	var connection = entity.Connection{ID: id}
 	err := dbutil.PossibleFirstError(dbutil.Preload(ds.db, entity.PreloadConnection()...).WithContext(ctx).First(&connection), "non-existing-connection")
 	if err != nil {
-		return entity.Connection{}, nil, err
+		return entity.Connection{}, entity.Segment{}, nil, err
 	}
 
+	segment, err := connection.Segment(fromStop, toStop)
+	if err != nil {
+		return entity.Connection{}, entity.Segment{}, nil, err
+	}
+	from, to := segment.Segments()
+
 	var takenSeatsIDs []uuid.UUID
-	var takenLuggageVolume uint
 	for _, stop := range connection.Stops {
-		if stop.LocationType == entity.PickUpStopType {
+		if stop.LocationType == entity.PickUpStopType && stop.Type == entity.PassengerStopType && segment.Overlaps(stop.Ticket.FromStop, stop.Ticket.ToStop) {
+			for _, seat := range stop.Ticket.Seats {
+				takenSeatsIDs = append(takenSeatsIDs, seat.SeatID)
+			}
+		}
+	}
 
-			if stop.Type == entity.PassengerStopType {
+	// The luggage space and the parcel weight are taken on the busiest segment of the part travelled.
+	var takenLuggageVolume uint
+	var takenParcelWeight int
+	for i := from; i < to; i++ {
+		var volume uint
+		var weight int
+		for _, stop := range connection.Stops {
+			if stop.LocationType != entity.PickUpStopType {
+				continue
+			}
+
+			if stop.Type == entity.PassengerStopType && stop.Ticket.Travels(i) {
+				volume += uint(stop.Ticket.LuggageVolume)
+			}
 
-				for _, seat := range stop.Ticket.Seats {
-					takenSeatsIDs = append(takenSeatsIDs, seat.SeatID)
-				}
+			if stop.Type == entity.ParcelStopType && stop.Parcel.Travels(i) {
+				volume += stop.Parcel.LuggageVolume
+				weight += stop.Parcel.Weight
 			}
+		}
+		takenLuggageVolume = max(takenLuggageVolume, volume)
+		takenParcelWeight = max(takenParcelWeight, weight)
+	}
+
+	heldSeatsIDs, err := NewSeatHold(ds.db).GetTakenSeatIDs(ctx, id, from, to)
+	if err != nil {
+		return entity.Connection{}, entity.Segment{}, nil, err
+	}
 
-			takenLuggageVolume += uint(stop.Ticket.LuggageVolume) + uint(stop.Parcel.LuggageVolume)
+	for _, seatID := range heldSeatsIDs {
+		if !slices.Contains(takenSeatsIDs, seatID) {
+			takenSeatsIDs = append(takenSeatsIDs, seatID)
 		}
 	}
 
 	busSeats := len(connection.Bus.Seats)
 	takenSeatsLength := len(takenSeatsIDs)
 	if busSeats < passengersNumber {
-		return entity.Connection{}, nil, rfc7807.BadRequest("too-big-passengers-number", "Too Big Passengers Number Error", fmt.Sprintf("For this connections maximum is %s.", busSeats-takenSeatsLength))
+		return entity.Connection{}, entity.Segment{}, nil, rfc7807.BadRequest("too-big-passengers-number", "Too Big Passengers Number Error", fmt.Sprintf("For this connections maximum is %d.", busSeats-takenSeatsLength))
 	}
 	luggageConfig := config.GetLoggageConfig()
 	connection.LuggageVolumeLeft = uint(connection.Bus.LuggageVolume) - takenLuggageVolume - uint((busSeats)-takenSeatsLength+passengersNumber)*(uint(luggageConfig.Small.Volume)+uint(luggageConfig.Large.Volume))
+	connection.ParcelWeightLeft = max(connection.Bus.ParcelWeightCapacity-takenParcelWeight, 0)
 
-	return connection, takenSeatsIDs, nil
+	return connection, segment, takenSeatsIDs, nil
 }
 
 func (ds *connectionMySQL) GetConnections(ctx context.Context, pagination dbutil.Pagination) ([]entity.Connection, int, error, bool) {
@@ -0,0 +1,45 @@
+package dataStore
+
+import (
+	"context"
+	"maryan_api/internal/entity"
+	"maryan_api/pkg/dbutil"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ExchangeRate interface {
+	Get(ctx context.Context, currency entity.Currency) (entity.ExchangeRate, error)
+	GetAll(ctx context.Context) ([]entity.ExchangeRate, error)
+	Save(ctx context.Context, rate *entity.ExchangeRate) error
+}
+
+type exchangeRateMySQL struct {
+	db *gorm.DB
+}
+
+// Get returns the rate of the currency, the base currency is not stored.
+func (ds *exchangeRateMySQL) Get(ctx context.Context, currency entity.Currency) (entity.ExchangeRate, error) {
+	if currency == entity.BaseCurrency {
+		return entity.BaseExchangeRate, nil
+	}
+
+	var rate entity.ExchangeRate
+	return rate, dbutil.PossibleFirstError(ds.db.WithContext(ctx).Where("currency = ?", currency).First(&rate), "missing-exchange-rate")
+}
+
+func (ds *exchangeRateMySQL) GetAll(ctx context.Context) ([]entity.ExchangeRate, error) {
+	var rates []entity.ExchangeRate
+	return rates, dbutil.PossibleDbError(ds.db.WithContext(ctx).Order("currency").Find(&rates))
+}
+
+func (ds *exchangeRateMySQL) Save(ctx context.Context, rate *entity.ExchangeRate) error {
+	return dbutil.PossibleDbError(ds.db.WithContext(ctx).Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "updated_at"}),
+	}).Create(rate))
+}
+
+func NewExchangeRate(db *gorm.DB) ExchangeRate {
+	return &exchangeRateMySQL{db}
+}
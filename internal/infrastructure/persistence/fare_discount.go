@@ -0,0 +1,38 @@
+package dataStore
+
+import (
+	"context"
+	"maryan_api/internal/entity"
+	"maryan_api/pkg/dbutil"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type FareDiscount interface {
+	GetByLines(ctx context.Context, lines ...int) (entity.FareDiscounts, error)
+	Save(ctx context.Context, discounts entity.FareDiscounts) error
+}
+
+type fareDiscountMySQL struct {
+	db *gorm.DB
+}
+
+func (ds *fareDiscountMySQL) GetByLines(ctx context.Context, lines ...int) (entity.FareDiscounts, error) {
+	var discounts entity.FareDiscounts
+	return discounts, dbutil.PossibleDbError(ds.db.WithContext(ctx).Where("line IN ?", lines).Find(&discounts))
+}
+
+func (ds *fareDiscountMySQL) Save(ctx context.Context, discounts entity.FareDiscounts) error {
+	if len(discounts) == 0 {
+		return nil
+	}
+
+	return dbutil.PossibleDbError(ds.db.WithContext(ctx).Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"percentage"}),
+	}).Create(&discounts))
+}
+
+func NewFareDiscount(db *gorm.DB) FareDiscount {
+	return &fareDiscountMySQL{db}
+}
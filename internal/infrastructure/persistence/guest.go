@@ -0,0 +1,83 @@
+package dataStore
+
+import (
+	"context"
+	"maryan_api/internal/entity"
+	"maryan_api/pkg/dbutil"
+	rfc7807 "maryan_api/pkg/problem"
+
+	"github.com/d3code/uuid"
+	"gorm.io/gorm"
+)
+
+type Guest interface {
+	FindOrCreate(ctx context.Context, guest *entity.Guest) error
+	Claim(ctx context.Context, email, phoneNumber string, userID uuid.UUID) error
+}
+
+type guestMySQL struct {
+	db *gorm.DB
+}
+
+// FindOrCreate reuses the unclaimed guest with the same contact, so repeated guest sessions
+// see the same bookings. A missing email or phone number is not a contact to match on.
+func (ds *guestMySQL) FindOrCreate(ctx context.Context, guest *entity.Guest) error {
+	query := ds.db.WithContext(ctx).Where("claimed_by IS NULL")
+	switch {
+	case guest.Email != "" && guest.PhoneNumber != "":
+		query = query.Where("email = ? AND phone_number = ?", guest.Email, guest.PhoneNumber)
+	case guest.Email != "":
+		query = query.Where("email = ?", guest.Email)
+	case guest.PhoneNumber != "":
+		query = query.Where("phone_number = ?", guest.PhoneNumber)
+	default:
+		return rfc7807.BadRequest("missing-guest-contact", "Missing Guest Contact Error", "A guest has to have an email or a phone number.")
+	}
+
+	return dbutil.PossibleCreateError(
+		query.Attrs(entity.Guest{ID: uuid.New()}).FirstOrCreate(guest),
+		"invalid-guest-data",
+	)
+}
+
+// Claim moves the tickets and parcels booked by unclaimed guests with the given email or phone number
+// to the user, both have to be verified by the caller. A missing one is not a contact to match on.
+func (ds *guestMySQL) Claim(ctx context.Context, email, phoneNumber string, userID uuid.UUID) error {
+	if email == "" && phoneNumber == "" {
+		return nil
+	}
+
+	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&entity.Guest{}).Where("claimed_by IS NULL")
+		switch {
+		case email != "" && phoneNumber != "":
+			query = query.Where("(email = ? OR phone_number = ?)", email, phoneNumber)
+		case email != "":
+			query = query.Where("email = ?", email)
+		default:
+			query = query.Where("phone_number = ?", phoneNumber)
+		}
+
+		var guestIDs []uuid.UUID
+		err := dbutil.PossibleDbError(query.Pluck("id", &guestIDs))
+		if err != nil || len(guestIDs) == 0 {
+			return err
+		}
+
+		err = dbutil.PossibleDbError(tx.Model(&entity.Ticket{}).Where("user_id IN ?", guestIDs).Update("user_id", userID))
+		if err != nil {
+			return err
+		}
+
+		err = dbutil.PossibleDbError(tx.Model(&entity.Parcel{}).Where("user_id IN ?", guestIDs).Update("user_id", userID))
+		if err != nil {
+			return err
+		}
+
+		return dbutil.PossibleDbError(tx.Model(&entity.Guest{}).Where("id IN ?", guestIDs).Update("claimed_by", userID))
+	})
+}
+
+func NewGuest(db *gorm.DB) Guest {
+	return &guestMySQL{db}
+}
@@ -0,0 +1,54 @@
+package dataStore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/d3code/uuid"
+)
+
+func TestClaimPhoneOnlyGuest(t *testing.T) {
+	db, mock := NewMockDB()
+
+	guestID, userID := uuid.New(), uuid.New()
+
+	// The guest has booked with a verified phone number only, its email is empty.
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT `id` FROM `guests` WHERE claimed_by IS NULL AND phone_number = \\?").
+		WithArgs("+380501234567").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(guestID))
+	mock.ExpectExec("UPDATE `tickets` SET `user_id`=\\? WHERE user_id IN \\(\\?\\)").
+		WithArgs(userID, guestID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE `parcels` SET `user_id`=\\? WHERE user_id IN \\(\\?\\)").
+		WithArgs(userID, guestID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE `guests` SET `claimed_by`=\\? WHERE id IN \\(\\?\\)").
+		WithArgs(userID, guestID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := NewGuest(db).Claim(context.Background(), "", "+380501234567", userID)
+	if err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestClaimWithoutContactIsNoOp(t *testing.T) {
+	db, mock := NewMockDB()
+
+	err := NewGuest(db).Claim(context.Background(), "", "", uuid.New())
+	if err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+
+	// No guest is matched on an empty contact.
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
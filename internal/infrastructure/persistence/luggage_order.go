@@ -0,0 +1,115 @@
+package dataStore
+
+import (
+	"context"
+	"maryan_api/internal/entity"
+	"maryan_api/pkg/dbutil"
+	rfc7807 "maryan_api/pkg/problem"
+	"net/http"
+	"time"
+
+	"github.com/d3code/uuid"
+	"gorm.io/gorm"
+)
+
+type LuggageOrder interface {
+	Create(ctx context.Context, order *entity.LuggageOrder, ticket entity.Ticket) error
+	GetBySession(ctx context.Context, paymentSessionID string) (entity.LuggageOrder, error)
+	GetByTicket(ctx context.Context, ticketID uuid.UUID) ([]entity.LuggageOrder, error)
+	Complete(ctx context.Context, id uuid.UUID) error
+	Expire(ctx context.Context, paymentSessionID string) error
+}
+
+type luggageOrderMySQL struct {
+	db *gorm.DB
+}
+
+// Create saves the order with its items and takes their space on the ticket. The connection row
+// is locked while the space left on the segment of the ticket is checked, so concurrent orders
+// and bookings can not overfill the bus.
+func (ds *luggageOrderMySQL) Create(ctx context.Context, order *entity.LuggageOrder, ticket entity.Ticket) error {
+	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("SELECT id FROM connections WHERE id = ? FOR UPDATE", ticket.ConnectionID).Error
+		if err != nil {
+			return rfc7807.DB(err.Error())
+		}
+
+		connection, _, _, err := NewConnection(tx).GetSegmentByID(ctx, ticket.ConnectionID, 0, ticket.FromStop, ticket.ToStop)
+		if err != nil {
+			return err
+		}
+
+		if uint(order.Volume) > connection.LuggageVolumeLeft {
+			return rfc7807.New(http.StatusConflict, "luggage-volume", "Luggage Volume Error", "There is not enough space left to fit provided luggage volume.")
+		}
+
+		err = dbutil.PossibleCreateError(tx.Create(order), "luggage-order-data")
+		if err != nil {
+			return err
+		}
+
+		return dbutil.PossibleRawsAffectedError(
+			tx.Model(&entity.Ticket{}).
+				Where("id = ?", order.TicketID).
+				Update("luggage_volume", gorm.Expr("luggage_volume + ?", order.Volume)),
+			"non-existing-ticket",
+		)
+	})
+}
+
+func (ds *luggageOrderMySQL) GetBySession(ctx context.Context, paymentSessionID string) (entity.LuggageOrder, error) {
+	var order entity.LuggageOrder
+	return order, dbutil.PossibleFirstError(ds.db.WithContext(ctx).Where("session_id = ?", paymentSessionID).First(&order), "non-existing-luggage-order")
+}
+
+func (ds *luggageOrderMySQL) GetByTicket(ctx context.Context, ticketID uuid.UUID) ([]entity.LuggageOrder, error) {
+	var orders []entity.LuggageOrder
+	return orders, dbutil.PossibleDbError(ds.db.WithContext(ctx).Preload("Items").Where("ticket_id = ?", ticketID).Order("created_at DESC").Find(&orders))
+}
+
+func (ds *luggageOrderMySQL) Complete(ctx context.Context, id uuid.UUID) error {
+	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := dbutil.PossibleRawsAffectedError(
+			tx.Model(&entity.LuggageOrder{}).
+				Where("id = ? AND status = ?", id, entity.PendingLuggageOrderStatus).
+				Updates(map[string]any{"status": entity.CompletedLuggageOrderStatus, "completed_at": time.Now().UTC()}),
+			"completed-luggage-order",
+		)
+		if err != nil {
+			return err
+		}
+
+		return dbutil.PossibleDbError(tx.Model(&entity.TicketLuggage{}).Where("order_id = ?", id).Update("paid", true))
+	})
+}
+
+// Expire removes the items of the unpaid order and gives their space back.
+func (ds *luggageOrderMySQL) Expire(ctx context.Context, paymentSessionID string) error {
+	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var order entity.LuggageOrder
+		err := dbutil.PossibleFirstError(tx.Where("session_id = ? AND status = ?", paymentSessionID, entity.PendingLuggageOrderStatus).First(&order), "non-existing-luggage-order")
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&order).Update("status", entity.ExpiredLuggageOrderStatus).Error
+		if err != nil {
+			return rfc7807.DB(err.Error())
+		}
+
+		err = tx.Where("order_id = ?", order.ID).Delete(&entity.TicketLuggage{}).Error
+		if err != nil {
+			return rfc7807.DB(err.Error())
+		}
+
+		return dbutil.PossibleDbError(
+			tx.Model(&entity.Ticket{}).
+				Where("id = ?", order.TicketID).
+				Update("luggage_volume", gorm.Expr("GREATEST(CAST(luggage_volume AS SIGNED) - ?, 0)", order.Volume)),
+		)
+	})
+}
+
+func NewLuggageOrder(db *gorm.DB) LuggageOrder {
+	return &luggageOrderMySQL{db}
+}
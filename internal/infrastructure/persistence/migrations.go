@@ -20,10 +20,21 @@ func Migrate(db *gorm.DB) error {
 	errCheck(entity.MigratePassenger(db))
 	errCheck(entity.MigrateAddress(db))
 	errCheck(entity.MigratePackage(db))
+	errCheck(entity.MigrateCashOnDelivery(db))
 	errCheck(entity.MigrateTrip(db))
 	errCheck(valueobject.MigrateVerifications(db))
 	errCheck(log.Migrate(db))
 	errCheck(entity.MigrateTicket(db))
+	errCheck(entity.MigrateTicketLuggage(db))
+	errCheck(entity.MigratePayment(db))
+	errCheck(entity.MigrateRefaund(db))
+	errCheck(entity.MigrateFareDiscount(db))
+	errCheck(entity.MigratePricing(db))
+	errCheck(entity.MigrateTariff(db))
+	errCheck(entity.MigrateExchangeRate(db))
+	errCheck(entity.MigratePromoCode(db))
+	errCheck(entity.MigrateGuest(db))
+	errCheck(entity.MigrateWaitlist(db))
 
 	errCheck(entity.MigrateConnection(db))
 	// testdata.CreateTestData(db)
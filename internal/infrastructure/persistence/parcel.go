@@ -6,6 +6,7 @@ import (
 	"maryan_api/config"
 	"maryan_api/internal/entity"
 	"maryan_api/pkg/dbutil"
+	rfc7807 "maryan_api/pkg/problem"
 	"time"
 
 	"github.com/d3code/uuid"
@@ -21,6 +22,16 @@ type Parsel interface {
 	RemoveParcelStops(ctx context.Context, paymentSessionID string) error
 	PaymentSucceeded(ctx context.Context, paymentSessionID string) error
 	DeleteParcels(ctx context.Context, paymentSessionID string) error
+	GetAbandoned(ctx context.Context, createdBefore time.Time) ([]entity.AbandonedBooking, error)
+	Abandon(ctx context.Context, id uuid.UUID) error
+	GetByID(ctx context.Context, id uuid.UUID) (entity.Parcel, error)
+	GetByTrackingNumber(ctx context.Context, trackingNumber string) ([]entity.Parcel, error)
+	UpdateStatus(ctx context.Context, update entity.ParcelStatusUpdate) error
+	SetDeliveryCode(ctx context.Context, id uuid.UUID, code string) error
+	CreateDeliveryProof(ctx context.Context, proof *entity.ParcelDeliveryProof) error
+	GetCodBalances(ctx context.Context, senderID uuid.UUID) ([]entity.CodBalance, error)
+	TrackingLocked(ctx context.Context, keys []string, now time.Time) (bool, error)
+	RegisterTrackingFailure(ctx context.Context, keys []string, now time.Time, maxFailures uint, window, lockout time.Duration) error
 }
 
 type parselMysql struct {
@@ -58,6 +69,7 @@ func (pds *parselMysql) GetConnectionsByMonth(ctx context.Context, fromCountry,
 
 	for i, connection := range connections {
 		var takenLuggageVolume uint
+		var takenParcelWeight int
 		var passengersNumber int
 		for _, stop := range connection.Stops {
 			if stop.LocationType == entity.PickUpStopType {
@@ -65,6 +77,7 @@ func (pds *parselMysql) GetConnectionsByMonth(ctx context.Context, fromCountry,
 					passengersNumber++
 				}
 				takenLuggageVolume += uint(stop.Ticket.LuggageVolume) + uint(stop.Parcel.LuggageVolume)
+				takenParcelWeight += stop.Parcel.Weight
 
 			}
 		}
@@ -72,6 +85,7 @@ func (pds *parselMysql) GetConnectionsByMonth(ctx context.Context, fromCountry,
 		luggage := config.GetLoggageConfig()
 
 		connections[i].LuggageVolumeLeft = uint(connection.Bus.LuggageVolume) - takenLuggageVolume - uint((len(connection.Bus.Seats)-passengersNumber)*(luggage.Small.Volume+luggage.Large.Volume))
+		connections[i].ParcelWeightLeft = max(connection.Bus.ParcelWeightCapacity-takenParcelWeight, 0)
 	}
 	return connections, nil
 }
@@ -127,7 +141,16 @@ func (ds *parselMysql) CreateParcelStops(ctx context.Context, paymentSessionID s
 }
 
 func (ds *parselMysql) Create(ctx context.Context, parcel *entity.Parcel) error {
-	return dbutil.PossibleCreateError(ds.db.WithContext(ctx).Session(&gorm.Session{FullSaveAssociations: true}).Create(parcel), "parcel-data")
+	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if parcel.Payment.PromoCodeID.Valid {
+			err := NewPromoCode(tx).Redeem(ctx, parcel.Payment.PromoCodeID.UUID, parcel.UserID)
+			if err != nil {
+				return err
+			}
+		}
+
+		return dbutil.PossibleCreateError(tx.Session(&gorm.Session{FullSaveAssociations: true}).Create(parcel), "parcel-data")
+	})
 }
 
 func (ds *parselMysql) GetParcels(ctx context.Context, pagination dbutil.Pagination) ([]entity.Parcel, []entity.Connection, int, error, bool) {
@@ -155,8 +178,9 @@ func (ds *parselMysql) GetParcels(ctx context.Context, pagination dbutil.Paginat
 	), false
 }
 
+// PaymentSucceeded records the payment of the session, the succeeded condition makes a repeated webhook a no-op.
 func (ds *parselMysql) PaymentSucceeded(ctx context.Context, paymentSessionID string) error {
-	return dbutil.PossibleRawsAffectedError(ds.db.Table("parcel_payments").Where("session_id = ?", paymentSessionID).Update("succeeded", true))
+	return dbutil.PossibleDbError(ds.db.WithContext(ctx).Table("parcel_payments").Where("session_id = ? AND succeeded = ?", paymentSessionID, false).Update("succeeded", true))
 }
 
 //cs_test_a1CrwsqWkRwxQ3RGa7YPrNeryQ3zqEEORgWmyFtg8WNX1xPziGQ1BhoQtx
@@ -170,6 +194,41 @@ func (ds *parselMysql) RemoveParcelStops(ctx context.Context, paymentSessionID s
 	return dbutil.PossibleRawsAffectedError(ds.db.Unscoped().Table("stops").Where("parcel_id IN (SELECT parcel_id FROM parcel_payments WHERE session_id = ?)", paymentSessionID).Delete(&entity.Stop{}), "non-existing-data")
 }
 
+// GetAbandoned returns the parcels whose payment has not succeeded since before createdBefore.
+func (ds *parselMysql) GetAbandoned(ctx context.Context, createdBefore time.Time) ([]entity.AbandonedBooking, error) {
+	var bookings []entity.AbandonedBooking
+	return bookings, dbutil.PossibleDbError(
+		ds.db.WithContext(ctx).
+			Table("parcels").
+			Select("parcels.id, parcel_payments.session_id").
+			Joins("JOIN parcel_payments ON parcel_payments.parcel_id = parcels.id").
+			Where("parcel_payments.succeeded = ? AND parcel_payments.created_at < ? AND parcels.deleted_at IS NULL", false, createdBefore).
+			Scan(&bookings),
+	)
+}
+
+// Abandon frees the luggage volume and the stops of the unpaid parcel and soft-deletes it.
+func (ds *parselMysql) Abandon(ctx context.Context, id uuid.UUID) error {
+	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := dbutil.PossibleDbError(tx.Model(&entity.Parcel{}).Where("id = ?", id).Update("luggage_volume", 0))
+		if err != nil {
+			return err
+		}
+
+		err = dbutil.PossibleDbError(tx.Table("stop_updates").Where("stop_id IN (SELECT id FROM stops WHERE parcel_id = ?)", id).Delete(&entity.StopUpdate{}))
+		if err != nil {
+			return err
+		}
+
+		err = dbutil.PossibleDbError(tx.Where("parcel_id = ?", id).Delete(&entity.Stop{}))
+		if err != nil {
+			return err
+		}
+
+		return dbutil.PossibleDbError(tx.Delete(&entity.Parcel{ID: id}))
+	})
+}
+
 func (ds *parselMysql) DeleteParcels(ctx context.Context, paymentSessionID string) error {
 	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		var parcelIDs []uuid.UUID
@@ -209,6 +268,143 @@ func (ds *parselMysql) DeleteParcels(ctx context.Context, paymentSessionID strin
 		return dbutil.PossibleRawsAffectedError(tx.Where("id IN (?)", addressIDs).Unscoped().Delete(&entity.Address{}))
 	})
 }
+
+func (ds *parselMysql) GetByID(ctx context.Context, id uuid.UUID) (entity.Parcel, error) {
+	var parcel entity.Parcel
+	return parcel, dbutil.PossibleFirstError(
+		ds.db.WithContext(ctx).
+			Preload("Payment").
+			Preload("Updates", func(db *gorm.DB) *gorm.DB { return db.Order("created_at") }).
+			Preload("DeliveryProof").
+			Preload("CashOnDelivery").
+			First(&parcel, "id = ?", id),
+		"non-existing-parcel",
+	)
+}
+
+// GetByTrackingNumber returns every parcel with the tracking number, the numbers are short
+// enough to be shared by more than one parcel.
+func (ds *parselMysql) GetByTrackingNumber(ctx context.Context, trackingNumber string) ([]entity.Parcel, error) {
+	var parcels []entity.Parcel
+	return parcels, dbutil.PossibleDbError(
+		ds.db.WithContext(ctx).
+			Preload("Payment").
+			Preload("Updates", func(db *gorm.DB) *gorm.DB { return db.Order("created_at") }).
+			Where("tracking_number = ?", trackingNumber).
+			Find(&parcels),
+	)
+}
+
+// UpdateStatus adds the update to the timeline of the parcel unless the parcel has already been
+// delivered or returned. A delivered parcel gets completed, its cash on delivery has to be collected first.
+func (ds *parselMysql) UpdateStatus(ctx context.Context, update entity.ParcelStatusUpdate) error {
+	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		values := map[string]any{"status": update.Status}
+		if update.Status == entity.DeliveredParcelStatus {
+			values["completed_at"] = update.CreatedAt
+
+			var uncollected int64
+			err := dbutil.PossibleDbError(
+				tx.Model(&entity.ParcelCashOnDelivery{}).
+					Where("parcel_id = ? AND status = ?", update.ParcelID, entity.PendingCodStatus).
+					Count(&uncollected),
+			)
+			if err != nil {
+				return err
+			}
+
+			if uncollected > 0 {
+				return rfc7807.BadRequest("uncollected-cash", "Uncollected Cash Error", "The cash on delivery has to be collected before the parcel is delivered.")
+			}
+		}
+
+		err := dbutil.PossibleRawsAffectedError(
+			tx.Model(&entity.Parcel{}).
+				Where("id = ? AND status NOT IN ?", update.ParcelID, []string{string(entity.DeliveredParcelStatus), string(entity.ReturnedParcelStatus)}).
+				Updates(values),
+			"final-parcel-status",
+		)
+		if err != nil {
+			return err
+		}
+
+		// The recipient of a returned parcel never pays for the goods.
+		if update.Status == entity.ReturnedParcelStatus {
+			err = dbutil.PossibleDbError(
+				tx.Model(&entity.ParcelCashOnDelivery{}).
+					Where("parcel_id = ? AND status = ?", update.ParcelID, entity.PendingCodStatus).
+					Update("status", entity.CanceledCodStatus),
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		return dbutil.PossibleCreateError(tx.Create(&update), "parcel-status-data")
+	})
+}
+
+func (ds *parselMysql) SetDeliveryCode(ctx context.Context, id uuid.UUID, code string) error {
+	return dbutil.PossibleRawsAffectedError(
+		ds.db.WithContext(ctx).Model(&entity.Parcel{}).Where("id = ?", id).Update("delivery_code", code),
+		"non-existing-parcel",
+	)
+}
+
+func (ds *parselMysql) CreateDeliveryProof(ctx context.Context, proof *entity.ParcelDeliveryProof) error {
+	return dbutil.ErrDuplicatedKey(ds.db.WithContext(ctx).Create(proof), "delivered-parcel", "delivery-proof-data")
+}
+
+// GetCodBalances sums the cash on delivery of the paid parcels of the sender.
+func (ds *parselMysql) GetCodBalances(ctx context.Context, senderID uuid.UUID) ([]entity.CodBalance, error) {
+	var balances []entity.CodBalance
+	return balances, dbutil.PossibleDbError(
+		ds.db.WithContext(ctx).
+			Model(&entity.ParcelCashOnDelivery{}).
+			Select(`currency,
+				SUM(CASE WHEN status = ? THEN amount ELSE 0 END) AS pending,
+				SUM(CASE WHEN status = ? THEN amount ELSE 0 END) AS collected,
+				SUM(CASE WHEN status = ? THEN amount ELSE 0 END) AS scheduled,
+				SUM(CASE WHEN status = ? THEN amount ELSE 0 END) AS paid_out`,
+				entity.PendingCodStatus, entity.CollectedCodStatus, entity.ScheduledCodStatus, entity.PaidOutCodStatus,
+			).
+			Where("sender_id = ? AND parcel_id IN (SELECT parcel_id FROM parcel_payments WHERE succeeded)", senderID).
+			Group("currency").
+			Scan(&balances),
+	)
+}
+
+// TrackingLocked reports whether the tracking is locked for any of the keys.
+func (ds *parselMysql) TrackingLocked(ctx context.Context, keys []string, now time.Time) (bool, error) {
+	var count int64
+	err := dbutil.PossibleDbError(
+		ds.db.WithContext(ctx).Model(&entity.ParcelTrackingLock{}).Where("`key` IN ? AND locked_until > ?", keys, now).Count(&count),
+	)
+	return count > 0, err
+}
+
+// RegisterTrackingFailure counts the wrong tracking attempt for every key, the count starts over
+// once the window has passed. The key reaching maxFailures gets locked for the lockout.
+func (ds *parselMysql) RegisterTrackingFailure(ctx context.Context, keys []string, now time.Time, maxFailures uint, window, lockout time.Duration) error {
+	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, key := range keys {
+			// The assignments are made from left to right, so the lock sees the updated failures.
+			err := dbutil.PossibleDbError(tx.Exec(`
+				INSERT INTO parcel_tracking_locks (`+"`key`"+`, failures, window_start)
+				VALUES (?, 1, ?)
+				ON DUPLICATE KEY UPDATE
+					failures = IF(window_start <= ?, 1, failures + 1),
+					window_start = IF(window_start <= ?, VALUES(window_start), window_start),
+					locked_until = IF(failures >= ?, ?, locked_until)
+			`, key, now, now.Add(-window), now.Add(-window), maxFailures, now.Add(lockout)))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func NewParsel(db *gorm.DB) Parsel {
 	return &parselMysql{db}
 }
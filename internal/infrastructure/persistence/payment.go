@@ -0,0 +1,90 @@
+package dataStore
+
+import (
+	"context"
+	"maryan_api/internal/entity"
+	"maryan_api/pkg/dbutil"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Payment interface {
+	RegisterEvent(ctx context.Context, event *entity.StripeEvent) (bool, error)
+	ForgetEvent(ctx context.Context, id string) error
+	DefineProduct(ctx context.Context, paymentSessionID string) (entity.PaymentProduct, bool, error)
+	RegisterFailure(ctx context.Context, product entity.PaymentProduct, paymentSessionID, message string) error
+}
+
+type paymentMySQL struct {
+	db *gorm.DB
+}
+
+// RegisterEvent reports false when the event has already been registered,
+// which means it has been (or is being) processed by another delivery.
+func (ds *paymentMySQL) RegisterEvent(ctx context.Context, event *entity.StripeEvent) (bool, error) {
+	result := ds.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(event)
+	if result.Error != nil {
+		return false, dbutil.PossibleCreateError(result, "stripe-event-data")
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (ds *paymentMySQL) ForgetEvent(ctx context.Context, id string) error {
+	return dbutil.PossibleDbError(ds.db.WithContext(ctx).Delete(&entity.StripeEvent{ID: id}))
+}
+
+// DefineProduct does not find the bookings removed by the sweeper, so the late events of
+// their sessions are ignored.
+func (ds *paymentMySQL) DefineProduct(ctx context.Context, paymentSessionID string) (entity.PaymentProduct, bool, error) {
+	var product struct {
+		Ticket         bool `gorm:"column:ticket"`
+		Parcel         bool `gorm:"column:parcel"`
+		TicketExchange bool `gorm:"column:ticket_exchange"`
+		LuggageOrder   bool `gorm:"column:luggage_order"`
+	}
+
+	err := dbutil.PossibleDbError(ds.db.WithContext(ctx).Raw(`
+		SELECT
+			EXISTS(SELECT 1 FROM ticket_payments JOIN tickets ON tickets.id = ticket_payments.ticket_id WHERE session_id = ? AND tickets.deleted_at IS NULL) AS ticket,
+			EXISTS(SELECT 1 FROM parcel_payments JOIN parcels ON parcels.id = parcel_payments.parcel_id WHERE session_id = ? AND parcels.deleted_at IS NULL) AS parcel,
+			EXISTS(SELECT 1 FROM ticket_exchanges WHERE session_id = ?) AS ticket_exchange,
+			EXISTS(SELECT 1 FROM luggage_orders WHERE session_id = ?) AS luggage_order
+	`, paymentSessionID, paymentSessionID, paymentSessionID, paymentSessionID).Scan(&product))
+
+	switch {
+	case err != nil:
+		return "", false, err
+	case product.Ticket:
+		return entity.TicketPaymentProduct, true, nil
+	case product.Parcel:
+		return entity.ParcelPaymentProduct, true, nil
+	case product.TicketExchange:
+		return entity.TicketExchangePaymentProduct, true, nil
+	case product.LuggageOrder:
+		return entity.LuggageOrderPaymentProduct, true, nil
+	default:
+		return "", false, nil
+	}
+}
+
+func (ds *paymentMySQL) RegisterFailure(ctx context.Context, product entity.PaymentProduct, paymentSessionID, message string) error {
+	var table string
+	switch product {
+	case entity.ParcelPaymentProduct:
+		table = "parcel_payments"
+	case entity.TicketExchangePaymentProduct:
+		table = "ticket_exchanges"
+	case entity.LuggageOrderPaymentProduct:
+		table = "luggage_orders"
+	default:
+		table = "ticket_payments"
+	}
+
+	return dbutil.PossibleDbError(ds.db.WithContext(ctx).Table(table).Where("session_id = ?", paymentSessionID).Update("failure_message", message))
+}
+
+func NewPayment(db *gorm.DB) Payment {
+	return &paymentMySQL{db}
+}
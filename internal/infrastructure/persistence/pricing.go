@@ -0,0 +1,77 @@
+package dataStore
+
+import (
+	"context"
+	"maryan_api/internal/entity"
+	"maryan_api/pkg/dbutil"
+	"time"
+
+	"github.com/d3code/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Pricing interface {
+	GetByLines(ctx context.Context, lines ...int) (entity.Pricing, error)
+	Save(ctx context.Context, line int, pricing entity.Pricing) error
+	CreateQuote(ctx context.Context, quote *entity.PriceQuote) error
+	GetQuote(ctx context.Context, id uuid.UUID) (entity.PriceQuote, error)
+	DeleteExpiredQuotes(ctx context.Context, expiredBefore time.Time) (int, error)
+}
+
+type pricingMySQL struct {
+	db *gorm.DB
+}
+
+func (ds *pricingMySQL) GetByLines(ctx context.Context, lines ...int) (entity.Pricing, error) {
+	var pricing entity.Pricing
+	err := dbutil.PossibleDbError(ds.db.WithContext(ctx).Where("line IN ?", lines).Find(&pricing.Rules))
+	if err != nil {
+		return entity.Pricing{}, err
+	}
+
+	return pricing, dbutil.PossibleDbError(ds.db.WithContext(ctx).Where("line IN ?", lines).Find(&pricing.Bounds))
+}
+
+// Save replaces the rules and the bounds of the line.
+func (ds *pricingMySQL) Save(ctx context.Context, line int, pricing entity.Pricing) error {
+	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := dbutil.PossibleDbError(tx.Where("line = ?", line).Delete(&entity.PricingRule{}))
+		if err != nil {
+			return err
+		}
+
+		if len(pricing.Rules) > 0 {
+			err = dbutil.PossibleDbError(tx.Create(&pricing.Rules))
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(pricing.Bounds) == 0 {
+			return nil
+		}
+
+		return dbutil.PossibleDbError(tx.Clauses(clause.OnConflict{
+			DoUpdates: clause.AssignmentColumns([]string{"min_percentage", "max_percentage"}),
+		}).Create(&pricing.Bounds))
+	})
+}
+
+func (ds *pricingMySQL) CreateQuote(ctx context.Context, quote *entity.PriceQuote) error {
+	return dbutil.PossibleCreateError(ds.db.WithContext(ctx).Create(quote), "invalid-price-quote")
+}
+
+func (ds *pricingMySQL) GetQuote(ctx context.Context, id uuid.UUID) (entity.PriceQuote, error) {
+	var quote entity.PriceQuote
+	return quote, dbutil.PossibleFirstError(ds.db.WithContext(ctx).Where("id = ?", id).First(&quote), "non-existing-price-quote")
+}
+
+func (ds *pricingMySQL) DeleteExpiredQuotes(ctx context.Context, expiredBefore time.Time) (int, error) {
+	result := ds.db.WithContext(ctx).Where("expires_at < ?", expiredBefore).Delete(&entity.PriceQuote{})
+	return int(result.RowsAffected), dbutil.PossibleDbError(result)
+}
+
+func NewPricing(db *gorm.DB) Pricing {
+	return &pricingMySQL{db}
+}
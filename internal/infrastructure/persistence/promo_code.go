@@ -0,0 +1,129 @@
+package dataStore
+
+import (
+	"context"
+	"maryan_api/config"
+	"maryan_api/internal/entity"
+	"maryan_api/pkg/dbutil"
+	"time"
+
+	"github.com/d3code/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PromoCode interface {
+	Create(ctx context.Context, promoCode *entity.PromoCode) error
+	Update(ctx context.Context, promoCode *entity.PromoCode) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	GetByID(ctx context.Context, id uuid.UUID) (entity.PromoCode, error)
+	GetByCode(ctx context.Context, code string) (entity.PromoCode, error)
+	GetPromoCodes(ctx context.Context, pagination dbutil.Pagination) ([]entity.PromoCode, int, error, bool)
+	GetUsage(ctx context.Context, id, userID uuid.UUID, pendingSince time.Time) (entity.PromoCodeUsage, error)
+	Apply(ctx context.Context, code string, userID uuid.UUID, target entity.PromoCodeTarget) (uuid.NullUUID, int, error)
+	Redeem(ctx context.Context, id, userID uuid.UUID) error
+}
+
+type promoCodeMySQL struct {
+	db *gorm.DB
+}
+
+func (ds *promoCodeMySQL) Create(ctx context.Context, promoCode *entity.PromoCode) error {
+	return dbutil.ErrDuplicatedKey(ds.db.WithContext(ctx).Create(promoCode), "existing-promo-code", "invalid-promo-code")
+}
+
+func (ds *promoCodeMySQL) Update(ctx context.Context, promoCode *entity.PromoCode) error {
+	return dbutil.PossibleRawsAffectedError(
+		ds.db.WithContext(ctx).Model(promoCode).Select("*").Omit("id", "created_at", "deleted_at").Updates(promoCode),
+		"non-existing-promo-code",
+	)
+}
+
+func (ds *promoCodeMySQL) Delete(ctx context.Context, id uuid.UUID) error {
+	return dbutil.PossibleRawsAffectedError(ds.db.WithContext(ctx).Delete(&entity.PromoCode{ID: id}), "non-existing-promo-code")
+}
+
+func (ds *promoCodeMySQL) GetByID(ctx context.Context, id uuid.UUID) (entity.PromoCode, error) {
+	var promoCode = entity.PromoCode{ID: id}
+	return promoCode, dbutil.PossibleFirstError(ds.db.WithContext(ctx).First(&promoCode), "non-existing-promo-code")
+}
+
+func (ds *promoCodeMySQL) GetByCode(ctx context.Context, code string) (entity.PromoCode, error) {
+	var promoCode entity.PromoCode
+	return promoCode, dbutil.PossibleFirstError(ds.db.WithContext(ctx).Where("code = ?", code).First(&promoCode), "non-existing-promo-code")
+}
+
+func (ds *promoCodeMySQL) GetPromoCodes(ctx context.Context, pagination dbutil.Pagination) ([]entity.PromoCode, int, error, bool) {
+	return dbutil.Paginate[entity.PromoCode](ctx, ds.db, pagination)
+}
+
+// GetUsage counts the payments made with the code. Unpaid ones are counted only while
+// their checkout session may still be completed.
+func (ds *promoCodeMySQL) GetUsage(ctx context.Context, id, userID uuid.UUID, pendingSince time.Time) (entity.PromoCodeUsage, error) {
+	var usage entity.PromoCodeUsage
+	return usage, dbutil.PossibleDbError(ds.db.WithContext(ctx).Raw(`
+		SELECT
+			(SELECT COUNT(*) FROM ticket_payments WHERE promo_code_id = @id AND (succeeded OR created_at > @since)) +
+			(SELECT COUNT(*) FROM parcel_payments WHERE promo_code_id = @id AND (succeeded OR created_at > @since)) AS total,
+			(SELECT COUNT(*) FROM ticket_payments JOIN tickets ON tickets.id = ticket_payments.ticket_id
+				WHERE promo_code_id = @id AND tickets.user_id = @user AND (succeeded OR ticket_payments.created_at > @since)) +
+			(SELECT COUNT(*) FROM parcel_payments JOIN parcels ON parcels.id = parcel_payments.parcel_id
+				WHERE promo_code_id = @id AND parcels.user_id = @user AND (succeeded OR parcel_payments.created_at > @since)) AS user
+	`, map[string]any{"id": id, "user": userID, "since": pendingSince}).Scan(&usage))
+}
+
+// Apply returns the discount of the promo code, no code means no discount.
+func (ds *promoCodeMySQL) Apply(ctx context.Context, code string, userID uuid.UUID, target entity.PromoCodeTarget) (uuid.NullUUID, int, error) {
+	code = entity.NormalizePromoCode(code)
+	if code == "" {
+		return uuid.NullUUID{}, 0, nil
+	}
+
+	promoCode, err := ds.GetByCode(ctx, code)
+	if err != nil {
+		return uuid.NullUUID{}, 0, err
+	}
+
+	usage, err := ds.GetUsage(ctx, promoCode.ID, userID, pendingPaymentsSince())
+	if err != nil {
+		return uuid.NullUUID{}, 0, err
+	}
+
+	discount, err := promoCode.Discount(usage, target)
+	if err != nil {
+		return uuid.NullUUID{}, 0, err
+	}
+
+	return uuid.NullUUID{UUID: promoCode.ID, Valid: true}, discount, nil
+}
+
+// Redeem checks the limits of the code again with its row locked. It has to be the first statement
+// of the transaction the payment is created in, so that the concurrent checkouts are counted
+// one after another and can not exceed the limits together.
+func (ds *promoCodeMySQL) Redeem(ctx context.Context, id, userID uuid.UUID) error {
+	var promoCode entity.PromoCode
+	err := dbutil.PossibleFirstError(
+		ds.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&promoCode),
+		"non-existing-promo-code",
+	)
+	if err != nil {
+		return err
+	}
+
+	usage, err := ds.GetUsage(ctx, id, userID, pendingPaymentsSince())
+	if err != nil {
+		return err
+	}
+
+	return promoCode.CheckUsage(usage)
+}
+
+// pendingPaymentsSince is the creation time of the oldest unpaid payment whose checkout session may still be completed,
+// the payment is counted as long as the seats of its booking are held, so a late webhook still finds the code used.
+func pendingPaymentsSince() time.Time {
+	return time.Now().UTC().Add(-config.SeatHoldDuration)
+}
+
+func NewPromoCode(db *gorm.DB) PromoCode {
+	return &promoCodeMySQL{db}
+}
@@ -0,0 +1,34 @@
+package dataStore
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	rfc7807 "maryan_api/pkg/problem"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/d3code/uuid"
+)
+
+func TestRedeemLocksCodeAndRejectsUsedUp(t *testing.T) {
+	t.Setenv("API_URL", "http://localhost:8080")
+	db, mock := NewMockDB()
+
+	id := uuid.New()
+	mock.ExpectQuery("SELECT \\* FROM `promo_codes` WHERE id = \\? AND `promo_codes`.`deleted_at` IS NULL ORDER BY `promo_codes`.`id` LIMIT \\? FOR UPDATE").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "usage_limit", "per_user_limit"}).AddRow(id, 10, 1))
+	mock.ExpectQuery("SELECT").
+		WillReturnRows(sqlmock.NewRows([]string{"total", "user"}).AddRow(3, 1))
+
+	err := NewPromoCode(db).Redeem(context.Background(), id, uuid.New())
+
+	problem, ok := rfc7807.Is(err)
+	if !ok || problem.Status != http.StatusConflict {
+		t.Fatalf("Redeem() error = %v, want a %d problem", err, http.StatusConflict)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
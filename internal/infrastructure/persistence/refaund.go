@@ -1 +1,75 @@
 package dataStore
+
+import (
+	"context"
+	"maryan_api/internal/entity"
+	"maryan_api/pkg/dbutil"
+	rfc7807 "maryan_api/pkg/problem"
+	"net/http"
+	"time"
+
+	"github.com/d3code/uuid"
+	"gorm.io/gorm"
+)
+
+type Refaund interface {
+	GetByID(ctx context.Context, id uuid.UUID) (entity.Refaund, error)
+	GetRefaunds(ctx context.Context, pagination dbutil.Pagination) ([]entity.Refaund, int, error, bool)
+	ChangeStatus(ctx context.Context, id uuid.UUID, from []entity.RefaundStatus, to entity.RefaundStatus) error
+	Complete(ctx context.Context, id uuid.UUID, providerRefundID string) error
+	Fail(ctx context.Context, id uuid.UUID, message string) error
+}
+
+type refaundMySQL struct {
+	db *gorm.DB
+}
+
+func (ds *refaundMySQL) GetByID(ctx context.Context, id uuid.UUID) (entity.Refaund, error) {
+	var refaund = entity.Refaund{ID: id}
+	return refaund, dbutil.PossibleFirstError(ds.db.WithContext(ctx).Preload("Ticket.Payment").First(&refaund), "non-existing-refaund")
+}
+
+func (ds *refaundMySQL) GetRefaunds(ctx context.Context, pagination dbutil.Pagination) ([]entity.Refaund, int, error, bool) {
+	return dbutil.Paginate[entity.Refaund](ctx, ds.db, pagination, "Ticket", "Ticket.Payment")
+}
+
+// ChangeStatus moves the refaund to the new status only if it is still in one of the expected
+// ones, so two admins can not process the same refaund at the same time.
+func (ds *refaundMySQL) ChangeStatus(ctx context.Context, id uuid.UUID, from []entity.RefaundStatus, to entity.RefaundStatus) error {
+	result := ds.db.WithContext(ctx).Model(&entity.Refaund{}).Where("id = ? AND status IN (?)", id, from).Update("status", to)
+	if result.Error != nil {
+		return rfc7807.DB(result.Error.Error())
+	}
+
+	if result.RowsAffected == 0 {
+		return rfc7807.New(http.StatusConflict, "refaund-status", "Refaund Status Error", "The refaund has already been processed.")
+	}
+
+	return nil
+}
+
+func (ds *refaundMySQL) Complete(ctx context.Context, id uuid.UUID, providerRefundID string) error {
+	return dbutil.PossibleRawsAffectedError(
+		ds.db.WithContext(ctx).Model(&entity.Refaund{}).Where("id = ?", id).Updates(map[string]any{
+			"status":             entity.CompletedRefaundStatus,
+			"provider_refund_id": providerRefundID,
+			"failure_message":    "",
+			"completed_at":       time.Now().UTC(),
+		}),
+		"non-existing-refaund",
+	)
+}
+
+func (ds *refaundMySQL) Fail(ctx context.Context, id uuid.UUID, message string) error {
+	return dbutil.PossibleRawsAffectedError(
+		ds.db.WithContext(ctx).Model(&entity.Refaund{}).Where("id = ?", id).Updates(map[string]any{
+			"status":          entity.FailedRefaundStatus,
+			"failure_message": message,
+		}),
+		"non-existing-refaund",
+	)
+}
+
+func NewRefaund(db *gorm.DB) Refaund {
+	return &refaundMySQL{db}
+}
@@ -0,0 +1,144 @@
+package dataStore
+
+import (
+	"context"
+	"maryan_api/internal/entity"
+	"maryan_api/pkg/dbutil"
+	rfc7807 "maryan_api/pkg/problem"
+	"net/http"
+	"time"
+
+	"github.com/d3code/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SeatHold interface {
+	Hold(ctx context.Context, holds []entity.SeatHold) error
+	HoldAll(ctx context.Context, groups ...[]entity.SeatHold) error
+	Rehold(ctx context.Context, heldFor uuid.UUID, holds []entity.SeatHold) error
+	GetTakenSeatIDs(ctx context.Context, connectionID uuid.UUID, fromSegment, toSegment int) ([]uuid.UUID, error)
+	GetHeldSeatIDs(ctx context.Context, ticketID uuid.UUID) ([]uuid.UUID, error)
+	ReleaseByTicket(ctx context.Context, ticketID uuid.UUID) error
+	ReleaseBySession(ctx context.Context, paymentSessionID string) error
+	SellBySession(ctx context.Context, paymentSessionID string) (int64, error)
+}
+
+type seatHoldMySQL struct {
+	db *gorm.DB
+}
+
+// Hold locks the seats on the segments of the connection. The (connection_id, seat_id, segment)
+// primary key makes the lock atomic across API instances: if any of the seats is held or sold
+// by someone else, nothing is inserted and a conflict is returned.
+func (ds *seatHoldMySQL) Hold(ctx context.Context, holds []entity.SeatHold) error {
+	if len(holds) == 0 {
+		return nil
+	}
+
+	var seatIDs = make([]uuid.UUID, len(holds))
+	for i, hold := range holds {
+		seatIDs[i] = hold.SeatID
+	}
+
+	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where(
+			"connection_id = ? AND seat_id IN (?) AND status = ? AND expires_at < ?",
+			holds[0].ConnectionID, seatIDs, entity.HeldSeatHoldStatus, time.Now().UTC(),
+		).Delete(&entity.SeatHold{}).Error
+		if err != nil {
+			return rfc7807.DB(err.Error())
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&holds)
+		if result.Error != nil {
+			return dbutil.PossibleCreateError(result, "seat-hold-data")
+		}
+
+		if result.RowsAffected != int64(len(holds)) {
+			return rfc7807.New(http.StatusConflict, "taken-seat", "Taken Seat Error", "One or more of the selected seats have just been taken.")
+		}
+
+		return nil
+	})
+}
+
+// HoldAll holds the seats of several bookings in one transaction, either all of them are held or none.
+func (ds *seatHoldMySQL) HoldAll(ctx context.Context, groups ...[]entity.SeatHold) error {
+	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, holds := range groups {
+			err := NewSeatHold(tx).Hold(ctx, holds)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Rehold moves the seats held for heldFor, a waitlist offer, to the new holds in one transaction,
+// so no one else can take them in between.
+func (ds *seatHoldMySQL) Rehold(ctx context.Context, heldFor uuid.UUID, holds []entity.SeatHold) error {
+	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		seatHold := NewSeatHold(tx)
+		err := seatHold.ReleaseByTicket(ctx, heldFor)
+		if err != nil {
+			return err
+		}
+
+		return seatHold.Hold(ctx, holds)
+	})
+}
+
+// GetTakenSeatIDs returns the seats held or sold on any of the segments in [fromSegment, toSegment).
+func (ds *seatHoldMySQL) GetTakenSeatIDs(ctx context.Context, connectionID uuid.UUID, fromSegment, toSegment int) ([]uuid.UUID, error) {
+	var seatIDs []uuid.UUID
+	return seatIDs, dbutil.PossibleDbError(
+		ds.db.WithContext(ctx).
+			Model(&entity.SeatHold{}).
+			Distinct("seat_id").
+			Where("connection_id = ? AND segment >= ? AND segment < ? AND (status = ? OR expires_at > ?)", connectionID, fromSegment, toSegment, entity.SoldSeatHoldStatus, time.Now().UTC()).
+			Pluck("seat_id", &seatIDs),
+	)
+}
+
+// GetHeldSeatIDs returns the seats still held for the ticket or the waitlist offer.
+func (ds *seatHoldMySQL) GetHeldSeatIDs(ctx context.Context, ticketID uuid.UUID) ([]uuid.UUID, error) {
+	var seatIDs []uuid.UUID
+	return seatIDs, dbutil.PossibleDbError(
+		ds.db.WithContext(ctx).
+			Model(&entity.SeatHold{}).
+			Distinct("seat_id").
+			Where("ticket_id = ? AND status = ? AND expires_at > ?", ticketID, entity.HeldSeatHoldStatus, time.Now().UTC()).
+			Pluck("seat_id", &seatIDs),
+	)
+}
+
+func (ds *seatHoldMySQL) ReleaseByTicket(ctx context.Context, ticketID uuid.UUID) error {
+	return dbutil.PossibleDbError(
+		ds.db.WithContext(ctx).
+			Where("ticket_id = ? AND status = ?", ticketID, entity.HeldSeatHoldStatus).
+			Delete(&entity.SeatHold{}),
+	)
+}
+
+func (ds *seatHoldMySQL) ReleaseBySession(ctx context.Context, paymentSessionID string) error {
+	return dbutil.PossibleDbError(
+		ds.db.WithContext(ctx).
+			Where("ticket_id IN (SELECT ticket_id FROM ticket_payments WHERE session_id = ?) AND status = ?", paymentSessionID, entity.HeldSeatHoldStatus).
+			Delete(&entity.SeatHold{}),
+	)
+}
+
+// SellBySession sells the seats held for the tickets of the session and returns how many seat holds have been sold.
+func (ds *seatHoldMySQL) SellBySession(ctx context.Context, paymentSessionID string) (int64, error) {
+	result := ds.db.WithContext(ctx).
+		Model(&entity.SeatHold{}).
+		Where("ticket_id IN (SELECT ticket_id FROM ticket_payments WHERE session_id = ?)", paymentSessionID).
+		Update("status", entity.SoldSeatHoldStatus)
+	return result.RowsAffected, dbutil.PossibleDbError(result)
+}
+
+func NewSeatHold(db *gorm.DB) SeatHold {
+	return &seatHoldMySQL{db}
+}
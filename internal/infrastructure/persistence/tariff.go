@@ -0,0 +1,182 @@
+package dataStore
+
+import (
+	"context"
+	"errors"
+	"maryan_api/internal/entity"
+	"maryan_api/pkg/dbutil"
+	rfc7807 "maryan_api/pkg/problem"
+	"time"
+
+	"github.com/d3code/uuid"
+	"gorm.io/gorm"
+)
+
+type Tariff interface {
+	CreateParcelTariff(ctx context.Context, tariff *entity.ParcelTariff) error
+	UpdateParcelTariff(ctx context.Context, tariff *entity.ParcelTariff) error
+	DeleteParcelTariff(ctx context.Context, id uuid.UUID) error
+	GetParcelTariff(ctx context.Context, id uuid.UUID) (entity.ParcelTariff, error)
+	GetParcelTariffs(ctx context.Context, pagination dbutil.Pagination) ([]entity.ParcelTariff, int, error, bool)
+	GetActiveParcelTariff(ctx context.Context, departureCountryID, destinationCountryID uuid.UUID, parcelType entity.ParcelType, at time.Time) (entity.ParcelTariff, error)
+
+	CreateLuggageTariff(ctx context.Context, tariff *entity.LuggageTariff) error
+	UpdateLuggageTariff(ctx context.Context, tariff *entity.LuggageTariff) error
+	DeleteLuggageTariff(ctx context.Context, id uuid.UUID) error
+	GetLuggageTariff(ctx context.Context, id uuid.UUID) (entity.LuggageTariff, error)
+	GetLuggageTariffs(ctx context.Context, pagination dbutil.Pagination) ([]entity.LuggageTariff, int, error, bool)
+	GetActiveLuggageTariff(ctx context.Context, departureCountryID, destinationCountryID uuid.UUID, at time.Time) (entity.LuggageTariff, error)
+}
+
+type tariffMySQL struct {
+	db *gorm.DB
+}
+
+// CreateParcelTariff saves the tariff as the next version of the tariffs of its route and type.
+func (ds *tariffMySQL) CreateParcelTariff(ctx context.Context, tariff *entity.ParcelTariff) error {
+	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := dbutil.PossibleDbError(
+			tx.Model(&entity.ParcelTariff{}).
+				Select("COALESCE(MAX(version), 0) + 1").
+				Where("departure_country_id = ? AND destination_country_id = ? AND type = ?", tariff.DepartureCountryID, tariff.DestinationCountryID, tariff.Type).
+				Scan(&tariff.Version),
+		)
+		if err != nil {
+			return err
+		}
+
+		return dbutil.ErrDuplicatedKey(tx.Create(tariff), "concurrent-tariff-version", "invalid-tariff")
+	})
+}
+
+// UpdateParcelTariff replaces the prices and the effective date of the version, but only until it comes into effect.
+func (ds *tariffMySQL) UpdateParcelTariff(ctx context.Context, tariff *entity.ParcelTariff) error {
+	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := dbutil.PossibleRawsAffectedError(
+			tx.Model(tariff).
+				Where("effective_from > ?", time.Now().UTC()).
+				Select("effective_from", "volumetric_divisor", "oversize_price").
+				Updates(tariff),
+			"effective-tariff",
+		)
+		if err != nil {
+			return err
+		}
+
+		err = dbutil.PossibleDbError(tx.Where("tariff_id = ?", tariff.ID).Delete(&entity.ParcelTariffSize{}))
+		if err != nil {
+			return err
+		}
+
+		err = dbutil.PossibleCreateError(tx.Create(&tariff.Sizes), "invalid-tariff")
+		if err != nil {
+			return err
+		}
+
+		err = dbutil.PossibleDbError(tx.Where("tariff_id = ?", tariff.ID).Delete(&entity.ParcelTariffWeight{}))
+		if err != nil || len(tariff.Weights) == 0 {
+			return err
+		}
+
+		return dbutil.PossibleCreateError(tx.Create(&tariff.Weights), "invalid-tariff")
+	})
+}
+
+func (ds *tariffMySQL) DeleteParcelTariff(ctx context.Context, id uuid.UUID) error {
+	return dbutil.PossibleRawsAffectedError(
+		ds.db.WithContext(ctx).Where("effective_from > ?", time.Now().UTC()).Delete(&entity.ParcelTariff{ID: id}),
+		"non-existing-tariff",
+	)
+}
+
+func (ds *tariffMySQL) GetParcelTariff(ctx context.Context, id uuid.UUID) (entity.ParcelTariff, error) {
+	var tariff = entity.ParcelTariff{ID: id}
+	return tariff, dbutil.PossibleFirstError(ds.db.WithContext(ctx).Preload("Sizes").Preload("Weights").First(&tariff), "non-existing-tariff")
+}
+
+func (ds *tariffMySQL) GetParcelTariffs(ctx context.Context, pagination dbutil.Pagination) ([]entity.ParcelTariff, int, error, bool) {
+	return dbutil.Paginate[entity.ParcelTariff](ctx, ds.db, pagination, "Sizes", "Weights")
+}
+
+// GetActiveParcelTariff returns the version in effect at the time, a route with no tariff gets the built-in one.
+func (ds *tariffMySQL) GetActiveParcelTariff(ctx context.Context, departureCountryID, destinationCountryID uuid.UUID, parcelType entity.ParcelType, at time.Time) (entity.ParcelTariff, error) {
+	var tariff entity.ParcelTariff
+	err := ds.db.WithContext(ctx).
+		Preload("Sizes").
+		Preload("Weights").
+		Where("departure_country_id = ? AND destination_country_id = ? AND type = ? AND effective_from <= ?", departureCountryID, destinationCountryID, parcelType, at).
+		Order("effective_from DESC, version DESC").
+		First(&tariff).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return entity.DefaultParcelTariff(departureCountryID, destinationCountryID, parcelType), nil
+	} else if err != nil {
+		return entity.ParcelTariff{}, rfc7807.DB(err.Error())
+	}
+
+	return tariff, nil
+}
+
+// CreateLuggageTariff saves the tariff as the next version of the tariffs of its route.
+func (ds *tariffMySQL) CreateLuggageTariff(ctx context.Context, tariff *entity.LuggageTariff) error {
+	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := dbutil.PossibleDbError(
+			tx.Model(&entity.LuggageTariff{}).
+				Select("COALESCE(MAX(version), 0) + 1").
+				Where("departure_country_id = ? AND destination_country_id = ?", tariff.DepartureCountryID, tariff.DestinationCountryID).
+				Scan(&tariff.Version),
+		)
+		if err != nil {
+			return err
+		}
+
+		return dbutil.ErrDuplicatedKey(tx.Create(tariff), "concurrent-tariff-version", "invalid-tariff")
+	})
+}
+
+// UpdateLuggageTariff replaces the prices and the effective date of the version, but only until it comes into effect.
+func (ds *tariffMySQL) UpdateLuggageTariff(ctx context.Context, tariff *entity.LuggageTariff) error {
+	return dbutil.PossibleRawsAffectedError(
+		ds.db.WithContext(ctx).
+			Model(tariff).
+			Where("effective_from > ?", time.Now().UTC()).
+			Select("effective_from", "backpack_price", "small_luggage_price", "large_luggage_price").
+			Updates(tariff),
+		"effective-tariff",
+	)
+}
+
+func (ds *tariffMySQL) DeleteLuggageTariff(ctx context.Context, id uuid.UUID) error {
+	return dbutil.PossibleRawsAffectedError(
+		ds.db.WithContext(ctx).Where("effective_from > ?", time.Now().UTC()).Delete(&entity.LuggageTariff{ID: id}),
+		"non-existing-tariff",
+	)
+}
+
+func (ds *tariffMySQL) GetLuggageTariff(ctx context.Context, id uuid.UUID) (entity.LuggageTariff, error) {
+	var tariff = entity.LuggageTariff{ID: id}
+	return tariff, dbutil.PossibleFirstError(ds.db.WithContext(ctx).First(&tariff), "non-existing-tariff")
+}
+
+func (ds *tariffMySQL) GetLuggageTariffs(ctx context.Context, pagination dbutil.Pagination) ([]entity.LuggageTariff, int, error, bool) {
+	return dbutil.Paginate[entity.LuggageTariff](ctx, ds.db, pagination)
+}
+
+// GetActiveLuggageTariff returns the version in effect at the time, a route with no tariff gets the built-in one.
+func (ds *tariffMySQL) GetActiveLuggageTariff(ctx context.Context, departureCountryID, destinationCountryID uuid.UUID, at time.Time) (entity.LuggageTariff, error) {
+	var tariff entity.LuggageTariff
+	err := ds.db.WithContext(ctx).
+		Where("departure_country_id = ? AND destination_country_id = ? AND effective_from <= ?", departureCountryID, destinationCountryID, at).
+		Order("effective_from DESC, version DESC").
+		First(&tariff).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return entity.DefaultLuggageTariff(departureCountryID, destinationCountryID), nil
+	} else if err != nil {
+		return entity.LuggageTariff{}, rfc7807.DB(err.Error())
+	}
+
+	return tariff, nil
+}
+
+func NewTariff(db *gorm.DB) Tariff {
+	return &tariffMySQL{db}
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"maryan_api/internal/entity"
 	"maryan_api/pkg/dbutil"
+	rfc7807 "maryan_api/pkg/problem"
+	"net/http"
+	"time"
 
 	"github.com/d3code/uuid"
 	"gorm.io/gorm"
@@ -12,7 +15,10 @@ import (
 
 type Ticket interface {
 	Create(ctx context.Context, ticket *entity.Ticket) error
+	CreateAll(ctx context.Context, tickets []*entity.Ticket, purchases []entity.WaitlistPurchase) error
+	CreateCash(ctx context.Context, ticket *entity.Ticket, holds []entity.SeatHold, maxUnpaidSeats int, offerID uuid.UUID) error
 	GetByID(ctx context.Context, id uuid.UUID) (entity.Ticket, error)
+	GetBySession(ctx context.Context, paymentSessionID string) ([]entity.Ticket, error)
 	GetTickets(ctx context.Context, pagination dbutil.Pagination) ([]entity.Ticket, []entity.Connection, int, error, bool)
 	// Delete(ctx context.Context, id uuid.UUID) error
 	// ChangeConnection(ctx context.Context, id, connectionID uuid.UUID) error
@@ -21,15 +27,175 @@ type Ticket interface {
 	DeleteTickets(ctx context.Context, paymentSessionID string) error
 	CreatePassengerStops(ctx context.Context, paymentSessionID string) error
 	RemovePassengerStops(ctx context.Context, paymentSessionID string) error
-	PaymentSucceeded(ctx context.Context, paymentSessionID string) error
+	PaymentSucceeded(ctx context.Context, paymentSessionID string) (bool, error)
+	Cancel(ctx context.Context, id uuid.UUID, refaunds []entity.Refaund) error
+	GetAbandoned(ctx context.Context, createdBefore time.Time) ([]entity.AbandonedBooking, error)
+	Abandon(ctx context.Context, id uuid.UUID) error
 }
 
 type ticketMySQL struct {
 	db *gorm.DB
 }
 
-func (ds *ticketMySQL) PaymentSucceeded(ctx context.Context, paymentSessionID string) error {
-	return dbutil.PossibleRawsAffectedError(ds.db.Table("ticket_payments").Where("session_id = ?", paymentSessionID).Update("succeeded", true))
+// PaymentSucceeded records the payment of the session, sells its held seats and closes the waitlist offers
+// the tickets have been bought through. The succeeded condition makes a repeated webhook a no-op, false is
+// returned when the payment has already been recorded.
+// The holds may have expired and the seats been taken by someone else while the customer was paying, then
+// the tickets are canceled and their price is registered as a refaund of the session instead, false is returned.
+func (ds *ticketMySQL) PaymentSucceeded(ctx context.Context, paymentSessionID string) (bool, error) {
+	var recorded bool
+	return recorded, ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Table("ticket_payments").Where("session_id = ? AND succeeded = ?", paymentSessionID, false).Update("succeeded", true)
+		err := dbutil.PossibleDbError(result)
+		if err != nil || result.RowsAffected == 0 {
+			return err
+		}
+
+		// The abandoned tickets are loaded too, their seats have been released.
+		var tickets []entity.Ticket
+		err = dbutil.PossibleDbError(
+			tx.Unscoped().
+				Preload("Seats").
+				Preload("Payment").
+				Where("id IN (SELECT ticket_id FROM ticket_payments WHERE session_id = ?)", paymentSessionID).
+				Find(&tickets),
+		)
+		if err != nil {
+			return err
+		}
+
+		sold, err := NewSeatHold(tx).SellBySession(ctx, paymentSessionID)
+		if err != nil {
+			return err
+		}
+
+		var held int
+		var abandoned bool
+		for _, ticket := range tickets {
+			held += ticket.HeldSeats()
+			abandoned = abandoned || ticket.DeletedAt.Valid
+		}
+
+		if abandoned || int(sold) < held {
+			return refundUnsoldTickets(ctx, tx, tickets, paymentSessionID)
+		}
+
+		recorded = true
+		return NewWaitlist(tx).PurchasedBySession(ctx, paymentSessionID)
+	})
+}
+
+// refundUnsoldTickets cancels the paid tickets whose seats could not be sold and registers their
+// price as the refaunds of the session.
+func refundUnsoldTickets(ctx context.Context, tx *gorm.DB, tickets []entity.Ticket, paymentSessionID string) error {
+	for _, ticket := range tickets {
+		refaund := entity.NewTicketRefaund(ticket, ticket.Payment.Price)
+		refaund.SessionID = paymentSessionID
+
+		if ticket.DeletedAt.Valid {
+			err := dbutil.PossibleCreateError(tx.Create(&refaund), "refaund-data")
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		err := NewTicket(tx).Cancel(ctx, ticket.ID, []entity.Refaund{refaund})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Cancel frees everything the ticket takes on the connection (stops, seats and luggage volume)
+// and registers the refaunds in one transaction. The canceled_at condition keeps a ticket
+// from being canceled twice.
+func (ds *ticketMySQL) Cancel(ctx context.Context, id uuid.UUID, refaunds []entity.Refaund) error {
+	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := dbutil.PossibleRawsAffectedError(
+			tx.Model(&entity.Ticket{}).
+				Where("id = ? AND canceled_at IS NULL", id).
+				Updates(map[string]any{"canceled_at": time.Now().UTC(), "luggage_volume": 0}),
+			"canceled-ticket",
+		)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Table("stop_updates").Where("stop_id IN (SELECT id FROM stops WHERE ticket_id = ?)", id).Delete(&entity.StopUpdate{}).Error
+		if err != nil {
+			return rfc7807.DB(err.Error())
+		}
+
+		err = tx.Where("ticket_id = ?", id).Delete(&entity.Stop{}).Error
+		if err != nil {
+			return rfc7807.DB(err.Error())
+		}
+
+		err = tx.Where("ticket_id = ?", id).Delete(&entity.TicketSeat{}).Error
+		if err != nil {
+			return rfc7807.DB(err.Error())
+		}
+
+		err = tx.Where("ticket_id = ?", id).Delete(&entity.SeatHold{}).Error
+		if err != nil {
+			return rfc7807.DB(err.Error())
+		}
+
+		if len(refaunds) == 0 {
+			return nil
+		}
+
+		return dbutil.PossibleCreateError(tx.Create(&refaunds), "refaund-data")
+	})
+}
+
+// GetAbandoned returns the tickets paid by card whose payment has not succeeded since before createdBefore.
+func (ds *ticketMySQL) GetAbandoned(ctx context.Context, createdBefore time.Time) ([]entity.AbandonedBooking, error) {
+	var bookings []entity.AbandonedBooking
+	return bookings, dbutil.PossibleDbError(
+		ds.db.WithContext(ctx).
+			Table("tickets").
+			Select("tickets.id, ticket_payments.session_id, (SELECT COUNT(*) FROM ticket_seats WHERE ticket_seats.ticket_id = tickets.id) AS seats").
+			Joins("JOIN ticket_payments ON ticket_payments.ticket_id = tickets.id").
+			Where(
+				"ticket_payments.succeeded = ? AND ticket_payments.method = ? AND ticket_payments.created_at < ? AND tickets.canceled_at IS NULL AND tickets.deleted_at IS NULL",
+				false, entity.PaymentMethodCard, createdBefore,
+			).
+			Scan(&bookings),
+	)
+}
+
+// Abandon frees everything the unpaid ticket takes on the connection and soft-deletes the ticket
+// with its passengers in one transaction.
+func (ds *ticketMySQL) Abandon(ctx context.Context, id uuid.UUID) error {
+	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := dbutil.PossibleDbError(tx.Model(&entity.Ticket{}).Where("id = ?", id).Update("luggage_volume", 0))
+		if err != nil {
+			return err
+		}
+
+		err = tx.Table("stop_updates").Where("stop_id IN (SELECT id FROM stops WHERE ticket_id = ?)", id).Delete(&entity.StopUpdate{}).Error
+		if err != nil {
+			return rfc7807.DB(err.Error())
+		}
+
+		for _, model := range []any{&entity.Stop{}, &entity.TicketSeat{}, &entity.SeatHold{}, &entity.Passenger{}} {
+			err = tx.Where("ticket_id = ?", id).Delete(model).Error
+			if err != nil {
+				return rfc7807.DB(err.Error())
+			}
+		}
+
+		err = NewWaitlist(tx).ExpireByTicket(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return dbutil.PossibleDbError(tx.Delete(&entity.Ticket{ID: id}))
+	})
 }
 
 func (ds *ticketMySQL) CreatePassengerStops(ctx context.Context, paymentSessionID string) error {
@@ -93,19 +259,27 @@ func (ds *ticketMySQL) RemovePassengerStops(ctx context.Context, paymentSessionI
 	return dbutil.PossibleRawsAffectedError(ds.db.Unscoped().Table("stops").Where("ticket_id IN (SELECT ticket_id FROM ticket_payments WHERE session_id = ?)", paymentSessionID).Delete(&entity.Stop{}), "non-existing-data")
 }
 
+// DeleteTickets abandons every unpaid ticket of the session in one transaction, a round trip has two of them.
 func (ds *ticketMySQL) DeleteTickets(ctx context.Context, paymentSessionID string) error {
 	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		var session = entity.TicketPayment{
-			SessionID: paymentSessionID,
-		}
-		err := dbutil.PossibleRawsAffectedError(
-			tx.Where("session_id = ?", paymentSessionID).First(&session),
+		var ticketIDs []uuid.UUID
+		err := dbutil.PossibleDbError(
+			tx.Table("ticket_payments").
+				Where("session_id = ? AND succeeded = ?", paymentSessionID, false).
+				Pluck("ticket_id", &ticketIDs),
 		)
 		if err != nil {
 			return err
 		}
-		return dbutil.PossibleRawsAffectedError(tx.Unscoped().Delete(&entity.Ticket{ID: session.TicketID}))
 
+		for _, id := range ticketIDs {
+			err = NewTicket(tx).Abandon(ctx, id)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
 	})
 }
 
@@ -114,11 +288,118 @@ func (ds *ticketMySQL) Create(ctx context.Context, ticket *entity.Ticket) error
 	return dbutil.PossibleCreateError(ds.db.WithContext(ctx).Session(&gorm.Session{FullSaveAssociations: true}).Create(ticket), "ticket-data")
 }
 
+// CreateAll creates the tickets of one checkout in one transaction. The seats of the tickets bought
+// through waitlist offers are moved to them from the offers in the same transaction.
+func (ds *ticketMySQL) CreateAll(ctx context.Context, tickets []*entity.Ticket, purchases []entity.WaitlistPurchase) error {
+	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, ticket := range tickets {
+			err := redeemPromoCode(ctx, tx, ticket)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, purchase := range purchases {
+			err := NewWaitlist(tx).StartPurchase(ctx, purchase)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, ticket := range tickets {
+			err := NewTicket(tx).Create(ctx, ticket)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// CreateCash books the ticket to be paid to the driver. The connection row is locked while the
+// unpaid cash seats are counted, so concurrent bookings can not exceed maxUnpaidSeats. A ticket
+// bought through a waitlist offer takes the seats of the offer and closes it.
+func (ds *ticketMySQL) CreateCash(ctx context.Context, ticket *entity.Ticket, holds []entity.SeatHold, maxUnpaidSeats int, offerID uuid.UUID) error {
+	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := redeemPromoCode(ctx, tx, ticket)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Exec("SELECT id FROM connections WHERE id = ? FOR UPDATE", ticket.ConnectionID).Error
+		if err != nil {
+			return rfc7807.DB(err.Error())
+		}
+
+		var unpaidSeats int64
+		err = tx.Model(&entity.TicketSeat{}).
+			Joins("JOIN tickets ON tickets.id = ticket_seats.ticket_id").
+			Joins("JOIN ticket_payments ON ticket_payments.ticket_id = tickets.id").
+			Where("tickets.connection_id = ? AND tickets.canceled_at IS NULL AND ticket_payments.method = ? AND ticket_payments.succeeded = ?", ticket.ConnectionID, entity.PaymentMethodCash, false).
+			Count(&unpaidSeats).Error
+		if err != nil {
+			return rfc7807.DB(err.Error())
+		}
+
+		if int(unpaidSeats)+len(ticket.Seats) > maxUnpaidSeats {
+			return rfc7807.New(http.StatusConflict, "cash-limit-reached", "Cash Limit Reached Error", "No more seats of this connection can be paid to the driver, please pay by card.")
+		}
+
+		if offerID != uuid.Nil {
+			err = NewSeatHold(tx).Rehold(ctx, offerID, holds)
+		} else {
+			err = NewSeatHold(tx).Hold(ctx, holds)
+		}
+		if err != nil {
+			return err
+		}
+
+		err = dbutil.PossibleCreateError(tx.Session(&gorm.Session{FullSaveAssociations: true}).Create(ticket), "ticket-data")
+		if err != nil {
+			return err
+		}
+
+		_, err = NewSeatHold(tx).SellBySession(ctx, ticket.Payment.SessionID)
+		if err != nil {
+			return err
+		}
+
+		if offerID != uuid.Nil {
+			err = NewWaitlist(tx).Purchased(ctx, offerID, ticket.ID)
+			if err != nil {
+				return err
+			}
+		}
+
+		return NewTicket(tx).CreatePassengerStops(ctx, ticket.Payment.SessionID)
+	})
+}
+
+func redeemPromoCode(ctx context.Context, tx *gorm.DB, ticket *entity.Ticket) error {
+	if !ticket.Payment.PromoCodeID.Valid {
+		return nil
+	}
+	return NewPromoCode(tx).Redeem(ctx, ticket.Payment.PromoCodeID.UUID, ticket.UserID)
+}
+
 func (ds *ticketMySQL) GetByID(ctx context.Context, id uuid.UUID) (entity.Ticket, error) {
 	var ticket = entity.Ticket{ID: id}
 	return ticket, dbutil.PossibleFirstError(ds.db.WithContext(ctx).Preload(clause.Associations).First(&ticket), "non-existing-ticket")
 }
 
+// GetBySession returns the tickets paid through the session, a round trip has one for every leg.
+func (ds *ticketMySQL) GetBySession(ctx context.Context, paymentSessionID string) ([]entity.Ticket, error) {
+	var tickets []entity.Ticket
+	return tickets, dbutil.PossibleRawsAffectedError(
+		ds.db.WithContext(ctx).
+			Preload(clause.Associations).
+			Where("id IN (SELECT ticket_id FROM ticket_payments WHERE session_id = ?)", paymentSessionID).
+			Order("created_at").
+			Find(&tickets),
+		"non-existing-ticket",
+	)
+}
+
 func (ds *ticketMySQL) GetTickets(ctx context.Context, pagination dbutil.Pagination) ([]entity.Ticket, []entity.Connection, int, error, bool) {
 	// 	if len(pagination.Condition.Where) > 0{
 	// pagination.Condition.Where += " && "
@@ -139,6 +420,7 @@ func (ds *ticketMySQL) GetTickets(ctx context.Context, pagination dbutil.Paginat
 	return tickets, connections, total, dbutil.PossibleDbError(
 		ds.db.WithContext(ctx).
 			Preload(clause.Associations).
+			Preload("Route.Country").
 			Where(
 				"id IN (?)", connectionIDs,
 			).
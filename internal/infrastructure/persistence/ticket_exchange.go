@@ -0,0 +1,183 @@
+package dataStore
+
+import (
+	"context"
+	"maryan_api/internal/entity"
+	"maryan_api/pkg/dbutil"
+	rfc7807 "maryan_api/pkg/problem"
+	"net/http"
+	"time"
+
+	"github.com/d3code/uuid"
+	"gorm.io/gorm"
+)
+
+type TicketExchange interface {
+	Create(ctx context.Context, exchange *entity.TicketExchange) error
+	GetBySession(ctx context.Context, paymentSessionID string) (entity.TicketExchange, error)
+	GetByTicket(ctx context.Context, ticketID uuid.UUID) ([]entity.TicketExchange, error)
+	Complete(ctx context.Context, id uuid.UUID, refaund *entity.Refaund) error
+	CompletePaid(ctx context.Context, paymentSessionID string) error
+	Expire(ctx context.Context, paymentSessionID string) error
+}
+
+type ticketExchangeMySQL struct {
+	db *gorm.DB
+}
+
+func (ds *ticketExchangeMySQL) Create(ctx context.Context, exchange *entity.TicketExchange) error {
+	return dbutil.PossibleCreateError(ds.db.WithContext(ctx).Create(exchange), "ticket-exchange-data")
+}
+
+func (ds *ticketExchangeMySQL) GetBySession(ctx context.Context, paymentSessionID string) (entity.TicketExchange, error) {
+	var exchange entity.TicketExchange
+	return exchange, dbutil.PossibleFirstError(ds.db.WithContext(ctx).Where("session_id = ?", paymentSessionID).First(&exchange), "non-existing-ticket-exchange")
+}
+
+func (ds *ticketExchangeMySQL) GetByTicket(ctx context.Context, ticketID uuid.UUID) ([]entity.TicketExchange, error) {
+	var exchanges []entity.TicketExchange
+	return exchanges, dbutil.PossibleDbError(ds.db.WithContext(ctx).Preload("Seats").Where("ticket_id = ?", ticketID).Order("created_at DESC").Find(&exchanges))
+}
+
+// Complete moves the ticket with its seats, seat holds and stops to the new connection.
+// The refaund is registered in the same transaction when part of the fare has to be returned.
+func (ds *ticketExchangeMySQL) Complete(ctx context.Context, id uuid.UUID, refaund *entity.Refaund) error {
+	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var exchange = entity.TicketExchange{ID: id}
+		err := dbutil.PossibleFirstError(tx.Preload("Seats").First(&exchange), "non-existing-ticket-exchange")
+		if err != nil {
+			return err
+		}
+
+		return completeTicketExchange(tx, exchange, refaund)
+	})
+}
+
+// CompletePaid completes the exchange paid through the session, a repeated webhook finds it completed and does nothing.
+// The seats held for the exchange may have been taken by someone else while the customer was paying, then the exchange
+// fails and the paid difference is registered as a refaund of its session instead.
+func (ds *ticketExchangeMySQL) CompletePaid(ctx context.Context, paymentSessionID string) error {
+	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var exchange entity.TicketExchange
+		err := dbutil.PossibleFirstError(tx.Preload("Seats").Where("session_id = ?", paymentSessionID).First(&exchange), "non-existing-ticket-exchange")
+		if err != nil || exchange.Status != entity.PendingTicketExchangeStatus {
+			return err
+		}
+
+		var held int64
+		err = tx.Model(&entity.SeatHold{}).
+			Where("ticket_id = ? AND connection_id = ? AND status = ?", exchange.TicketID, exchange.ToConnectionID, entity.HeldSeatHoldStatus).
+			Count(&held).Error
+		if err != nil {
+			return rfc7807.DB(err.Error())
+		}
+
+		if int(held) == exchange.HeldSeats() {
+			return completeTicketExchange(tx, exchange, nil)
+		}
+
+		err = tx.Model(&exchange).Updates(map[string]any{"status": entity.ExpiredTicketExchangeStatus, "failure_message": "The seats have been taken before the payment succeeded."}).Error
+		if err != nil {
+			return rfc7807.DB(err.Error())
+		}
+
+		err = tx.Where("ticket_id = ? AND connection_id = ? AND status = ?", exchange.TicketID, exchange.ToConnectionID, entity.HeldSeatHoldStatus).Delete(&entity.SeatHold{}).Error
+		if err != nil {
+			return rfc7807.DB(err.Error())
+		}
+
+		refaund := entity.NewRefaund(exchange.TicketID, exchange.FareDifference)
+		refaund.SessionID = paymentSessionID
+		return dbutil.PossibleCreateError(tx.Create(&refaund), "refaund-data")
+	})
+}
+
+// completeTicketExchange moves the ticket of the pending exchange in the transaction. The seats are sold only
+// if all of their holds are still there, a conflict is returned otherwise.
+func completeTicketExchange(tx *gorm.DB, exchange entity.TicketExchange, refaund *entity.Refaund) error {
+	err := dbutil.PossibleRawsAffectedError(
+		tx.Model(&entity.TicketExchange{}).
+			Where("id = ? AND status = ?", exchange.ID, entity.PendingTicketExchangeStatus).
+			Updates(map[string]any{"status": entity.CompletedTicketExchangeStatus, "completed_at": time.Now().UTC()}),
+		"completed-ticket-exchange",
+	)
+	if err != nil {
+		return err
+	}
+
+	err = dbutil.PossibleRawsAffectedError(
+		tx.Model(&entity.Ticket{}).
+			Where("id = ? AND connection_id = ? AND canceled_at IS NULL", exchange.TicketID, exchange.FromConnectionID).
+			Updates(map[string]any{"connection_id": exchange.ToConnectionID, "qr_code": exchange.QRCode, "from_stop": exchange.FromStop, "to_stop": exchange.ToStop}),
+		"changed-ticket",
+	)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Model(&entity.TicketPayment{}).Where("ticket_id = ?", exchange.TicketID).Updates(map[string]any{"price": gorm.Expr("price + ?", exchange.FareDifference), "fare": exchange.Fare, "seat_surcharge": exchange.SeatSurcharge}).Error
+	if err != nil {
+		return rfc7807.DB(err.Error())
+	}
+
+	err = tx.Where("ticket_id = ?", exchange.TicketID).Delete(&entity.TicketSeat{}).Error
+	if err != nil {
+		return rfc7807.DB(err.Error())
+	}
+
+	err = dbutil.PossibleCreateError(tx.Create(exchange.NewSeats()), "ticket-seat-data")
+	if err != nil {
+		return err
+	}
+
+	err = tx.Where("ticket_id = ? AND connection_id = ?", exchange.TicketID, exchange.FromConnectionID).Delete(&entity.SeatHold{}).Error
+	if err != nil {
+		return rfc7807.DB(err.Error())
+	}
+
+	result := tx.Model(&entity.SeatHold{}).
+		Where("ticket_id = ? AND connection_id = ? AND status = ?", exchange.TicketID, exchange.ToConnectionID, entity.HeldSeatHoldStatus).
+		Update("status", entity.SoldSeatHoldStatus)
+	if result.Error != nil {
+		return rfc7807.DB(result.Error.Error())
+	}
+
+	if int(result.RowsAffected) != exchange.HeldSeats() {
+		return rfc7807.New(http.StatusConflict, "taken-seat", "Taken Seat Error", "One or more of the selected seats have been taken in the meantime.")
+	}
+
+	err = tx.Model(&entity.Stop{}).Where("ticket_id = ?", exchange.TicketID).Update("connection_id", exchange.ToConnectionID).Error
+	if err != nil {
+		return rfc7807.DB(err.Error())
+	}
+
+	if refaund == nil {
+		return nil
+	}
+
+	return dbutil.PossibleCreateError(tx.Create(refaund), "refaund-data")
+}
+
+func (ds *ticketExchangeMySQL) Expire(ctx context.Context, paymentSessionID string) error {
+	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var exchange entity.TicketExchange
+		err := dbutil.PossibleFirstError(tx.Where("session_id = ? AND status = ?", paymentSessionID, entity.PendingTicketExchangeStatus).First(&exchange), "non-existing-ticket-exchange")
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&exchange).Update("status", entity.ExpiredTicketExchangeStatus).Error
+		if err != nil {
+			return rfc7807.DB(err.Error())
+		}
+
+		return dbutil.PossibleDbError(
+			tx.Where("ticket_id = ? AND connection_id = ? AND status = ?", exchange.TicketID, exchange.ToConnectionID, entity.HeldSeatHoldStatus).
+				Delete(&entity.SeatHold{}),
+		)
+	})
+}
+
+func NewTicketExchange(db *gorm.DB) TicketExchange {
+	return &ticketExchangeMySQL{db}
+}
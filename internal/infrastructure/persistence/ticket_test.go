@@ -0,0 +1,164 @@
+package dataStore
+
+import (
+	"context"
+	"maryan_api/internal/entity"
+	rfc7807 "maryan_api/pkg/problem"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/d3code/uuid"
+)
+
+func TestTicketPaymentSucceededIsIdempotent(t *testing.T) {
+	db, mock := NewMockDB()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `ticket_payments` SET `succeeded`=\\? WHERE session_id = \\? AND succeeded = \\?").
+		WithArgs(true, "cs_test_paid", false).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	recorded, err := NewTicket(db).PaymentSucceeded(context.Background(), "cs_test_paid")
+	if err != nil {
+		t.Fatalf("PaymentSucceeded() error = %v", err)
+	}
+
+	if recorded {
+		t.Error("PaymentSucceeded() recorded an already succeeded payment again")
+	}
+
+	// No seats are sold for the repeated webhook.
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParcelPaymentSucceededIsIdempotent(t *testing.T) {
+	db, mock := NewMockDB()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `parcel_payments` SET `succeeded`=\\? WHERE session_id = \\? AND succeeded = \\?").
+		WithArgs(true, "cs_test_paid", false).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := NewParsel(db).PaymentSucceeded(context.Background(), "cs_test_paid")
+	if err != nil {
+		t.Fatalf("PaymentSucceeded() error = %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+// expectPaidTicket expects the payment of the session to be recorded for a ticket with one seat on
+// two segments, of which the sold seat holds are left.
+func expectPaidTicket(mock sqlmock.Sqlmock, ticketID uuid.UUID, sold int64) {
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `ticket_payments` SET `succeeded`=\\? WHERE session_id = \\? AND succeeded = \\?").
+		WithArgs(true, "cs_test_paid", false).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT \\* FROM `tickets` WHERE id IN \\(SELECT ticket_id FROM ticket_payments WHERE session_id = \\?\\)").
+		WithArgs("cs_test_paid").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "from_stop", "to_stop"}).AddRow(ticketID, 0, 2))
+	mock.ExpectQuery("SELECT \\* FROM `ticket_payments` WHERE `ticket_payments`.`ticket_id` = \\?").
+		WithArgs(ticketID).
+		WillReturnRows(sqlmock.NewRows([]string{"ticket_id", "price", "method", "session_id"}).AddRow(ticketID, 4200, "Card", "cs_test_paid"))
+	mock.ExpectQuery("SELECT \\* FROM `ticket_seats` WHERE `ticket_seats`.`ticket_id` = \\?").
+		WithArgs(ticketID).
+		WillReturnRows(sqlmock.NewRows([]string{"ticket_id", "seat_id"}).AddRow(ticketID, uuid.New()))
+	mock.ExpectExec("UPDATE `seat_holds` SET `status`=\\? WHERE ticket_id IN \\(SELECT ticket_id FROM ticket_payments WHERE session_id = \\?\\)").
+		WithArgs("Sold", "cs_test_paid").
+		WillReturnResult(sqlmock.NewResult(0, sold))
+}
+
+func TestTicketPaymentSucceededClosesWaitlistOffers(t *testing.T) {
+	db, mock := NewMockDB()
+
+	expectPaidTicket(mock, uuid.New(), 2)
+	mock.ExpectExec("UPDATE `waitlist_entries` SET `status`=\\? WHERE ticket_id IN \\(SELECT ticket_id FROM ticket_payments WHERE session_id = \\?\\) AND status IN \\(\\?,\\?\\)").
+		WithArgs("Purchased", "cs_test_paid", "Offered", "Expired").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	recorded, err := NewTicket(db).PaymentSucceeded(context.Background(), "cs_test_paid")
+	if err != nil {
+		t.Fatalf("PaymentSucceeded() error = %v", err)
+	}
+
+	if !recorded {
+		t.Error("PaymentSucceeded() has not recorded the payment")
+	}
+
+	// The waitlist offer is closed only once its ticket has been paid.
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParcelDeliveredWithUncollectedCashIsRejected(t *testing.T) {
+	t.Setenv("API_URL", "http://localhost:8080")
+
+	db, mock := NewMockDB()
+
+	parcelID := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `parcel_cash_on_deliveries` WHERE parcel_id = \\? AND status = \\?").
+		WithArgs(parcelID, "Pending").
+		WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(1))
+	mock.ExpectRollback()
+
+	err := NewParsel(db).UpdateStatus(context.Background(), entity.NewParcelStatusUpdate(parcelID, entity.DeliveredParcelStatus, ""))
+
+	problem, ok := rfc7807.Is(err)
+	if !ok || problem.Type != "http://localhost:8080/problems/uncollected-cash" {
+		t.Fatalf("UpdateStatus() error = %v, want the uncollected-cash problem", err)
+	}
+
+	// The parcel is not delivered before the driver has the cash.
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTicketPaymentSucceededAfterSeatsWereTakenIsRefunded(t *testing.T) {
+	db, mock := NewMockDB()
+
+	ticketID := uuid.New()
+
+	// One of the two holds has expired and its seat has been taken by someone else.
+	expectPaidTicket(mock, ticketID, 1)
+	mock.ExpectExec("SAVEPOINT").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE `tickets` SET `canceled_at`=\\?,`luggage_volume`=\\? WHERE \\(id = \\? AND canceled_at IS NULL\\)").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM `stop_updates`").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("DELETE FROM `stops`").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("DELETE FROM `ticket_seats`").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM `seat_holds`").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO `refaunds`").
+		WithArgs(sqlmock.AnyArg(), ticketID, 4200, "Pending", "Provider", "cs_test_paid", "", "", sqlmock.AnyArg(), nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	recorded, err := NewTicket(db).PaymentSucceeded(context.Background(), "cs_test_paid")
+	if err != nil {
+		t.Fatalf("PaymentSucceeded() error = %v", err)
+	}
+
+	if recorded {
+		t.Error("PaymentSucceeded() recorded the ticket whose seat has been sold to someone else")
+	}
+
+	// The ticket is canceled and the payment refunded, the waitlist offer is left open.
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
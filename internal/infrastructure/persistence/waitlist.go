@@ -0,0 +1,179 @@
+package dataStore
+
+import (
+	"context"
+	"maryan_api/internal/entity"
+	"maryan_api/pkg/dbutil"
+	"time"
+
+	"github.com/d3code/uuid"
+	"gorm.io/gorm"
+)
+
+type Waitlist interface {
+	Create(ctx context.Context, entry *entity.WaitlistEntry) error
+	GetByID(ctx context.Context, id uuid.UUID) (entity.WaitlistEntry, error)
+	GetByUser(ctx context.Context, userID uuid.UUID) ([]entity.WaitlistEntry, error)
+	IsQueued(ctx context.Context, connectionID, userID uuid.UUID) (bool, error)
+	Leave(ctx context.Context, id, userID uuid.UUID) error
+	GetQueues(ctx context.Context, departingAfter time.Time) ([]entity.WaitlistEntry, error)
+	ExpireOffers(ctx context.Context, now time.Time) error
+	Offer(ctx context.Context, id uuid.UUID, holds []entity.SeatHold, expiresAt time.Time) error
+	StartPurchase(ctx context.Context, purchase entity.WaitlistPurchase) error
+	Purchased(ctx context.Context, id, ticketID uuid.UUID) error
+	PurchasedBySession(ctx context.Context, paymentSessionID string) error
+	ExpireByTicket(ctx context.Context, ticketID uuid.UUID) error
+	GetDepths(ctx context.Context) ([]entity.WaitlistDepth, error)
+}
+
+type waitlistMySQL struct {
+	db *gorm.DB
+}
+
+var activeWaitlistStatuses = []any{entity.WaitingWaitlistStatus, entity.OfferedWaitlistStatus}
+
+func (ds *waitlistMySQL) Create(ctx context.Context, entry *entity.WaitlistEntry) error {
+	return dbutil.PossibleCreateError(ds.db.WithContext(ctx).Create(entry), "waitlist-entry-data")
+}
+
+func (ds *waitlistMySQL) GetByID(ctx context.Context, id uuid.UUID) (entity.WaitlistEntry, error) {
+	var entry = entity.WaitlistEntry{ID: id}
+	return entry, dbutil.PossibleFirstError(ds.db.WithContext(ctx).First(&entry), "non-existing-waitlist-entry")
+}
+
+func (ds *waitlistMySQL) GetByUser(ctx context.Context, userID uuid.UUID) ([]entity.WaitlistEntry, error) {
+	var entries []entity.WaitlistEntry
+	return entries, dbutil.PossibleDbError(ds.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&entries))
+}
+
+func (ds *waitlistMySQL) IsQueued(ctx context.Context, connectionID, userID uuid.UUID) (bool, error) {
+	var count int64
+	return count > 0, dbutil.PossibleDbError(
+		ds.db.WithContext(ctx).
+			Model(&entity.WaitlistEntry{}).
+			Where("connection_id = ? AND user_id = ? AND status IN ?", connectionID, userID, activeWaitlistStatuses).
+			Count(&count),
+	)
+}
+
+// Leave takes the entry out of the queue and releases the seats of its offer.
+func (ds *waitlistMySQL) Leave(ctx context.Context, id, userID uuid.UUID) error {
+	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := dbutil.PossibleRawsAffectedError(
+			tx.Model(&entity.WaitlistEntry{}).
+				Where("id = ? AND user_id = ? AND status IN ?", id, userID, activeWaitlistStatuses).
+				Update("status", entity.LeftWaitlistStatus),
+			"non-existing-waitlist-entry",
+		)
+		if err != nil {
+			return err
+		}
+
+		return NewSeatHold(tx).ReleaseByTicket(ctx, id)
+	})
+}
+
+// GetQueues returns the active entries of the connections departing after departingAfter,
+// every queue in the order the customers have joined it.
+func (ds *waitlistMySQL) GetQueues(ctx context.Context, departingAfter time.Time) ([]entity.WaitlistEntry, error) {
+	var entries []entity.WaitlistEntry
+	return entries, dbutil.PossibleDbError(
+		ds.db.WithContext(ctx).
+			Where("status IN ? AND connection_id IN (SELECT id FROM connections WHERE departure_time > ?)", activeWaitlistStatuses, departingAfter).
+			Order("connection_id, created_at").
+			Find(&entries),
+	)
+}
+
+// ExpireOffers closes the offers that have not been used in time, their seat holds expire at the same moment.
+func (ds *waitlistMySQL) ExpireOffers(ctx context.Context, now time.Time) error {
+	return dbutil.PossibleDbError(
+		ds.db.WithContext(ctx).
+			Model(&entity.WaitlistEntry{}).
+			Where("status = ? AND offer_expires_at < ?", entity.OfferedWaitlistStatus, now).
+			Update("status", entity.ExpiredWaitlistStatus),
+	)
+}
+
+// Offer holds the seats for the entry, the holds are kept under the id of the entry until it buys the ticket.
+func (ds *waitlistMySQL) Offer(ctx context.Context, id uuid.UUID, holds []entity.SeatHold, expiresAt time.Time) error {
+	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := NewSeatHold(tx).Hold(ctx, holds)
+		if err != nil {
+			return err
+		}
+
+		return dbutil.PossibleRawsAffectedError(
+			tx.Model(&entity.WaitlistEntry{}).
+				Where("id = ? AND status = ?", id, entity.WaitingWaitlistStatus).
+				Updates(map[string]any{"status": entity.OfferedWaitlistStatus, "offer_expires_at": expiresAt}),
+			"non-existing-waitlist-entry",
+		)
+	})
+}
+
+// StartPurchase moves the seats of the offer to the ticket and links the ticket to the entry in one
+// transaction. The entry stays offered until the ticket is paid.
+func (ds *waitlistMySQL) StartPurchase(ctx context.Context, purchase entity.WaitlistPurchase) error {
+	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := dbutil.PossibleRawsAffectedError(
+			tx.Model(&entity.WaitlistEntry{}).
+				Where("id = ? AND status = ?", purchase.OfferID, entity.OfferedWaitlistStatus).
+				Update("ticket_id", purchase.TicketID),
+			"expired-waitlist-offer",
+		)
+		if err != nil {
+			return err
+		}
+
+		return NewSeatHold(tx).Rehold(ctx, purchase.OfferID, purchase.Holds)
+	})
+}
+
+func (ds *waitlistMySQL) Purchased(ctx context.Context, id, ticketID uuid.UUID) error {
+	return dbutil.PossibleRawsAffectedError(
+		ds.db.WithContext(ctx).
+			Model(&entity.WaitlistEntry{}).
+			Where("id = ? AND status = ?", id, entity.OfferedWaitlistStatus).
+			Updates(map[string]any{"status": entity.PurchasedWaitlistStatus, "ticket_id": ticketID}),
+		"expired-waitlist-offer",
+	)
+}
+
+// PurchasedBySession closes the entries whose tickets have been paid through the session. An offer
+// that has expired while its ticket was being paid for is closed as well.
+func (ds *waitlistMySQL) PurchasedBySession(ctx context.Context, paymentSessionID string) error {
+	return dbutil.PossibleDbError(
+		ds.db.WithContext(ctx).
+			Model(&entity.WaitlistEntry{}).
+			Where("ticket_id IN (SELECT ticket_id FROM ticket_payments WHERE session_id = ?) AND status IN ?", paymentSessionID, []any{entity.OfferedWaitlistStatus, entity.ExpiredWaitlistStatus}).
+			Update("status", entity.PurchasedWaitlistStatus),
+	)
+}
+
+// ExpireByTicket closes the offer whose ticket has been left unpaid, its seats have gone with the ticket.
+func (ds *waitlistMySQL) ExpireByTicket(ctx context.Context, ticketID uuid.UUID) error {
+	return dbutil.PossibleDbError(
+		ds.db.WithContext(ctx).
+			Model(&entity.WaitlistEntry{}).
+			Where("ticket_id = ? AND status = ?", ticketID, entity.OfferedWaitlistStatus).
+			Update("status", entity.ExpiredWaitlistStatus),
+	)
+}
+
+func (ds *waitlistMySQL) GetDepths(ctx context.Context) ([]entity.WaitlistDepth, error) {
+	var depths []entity.WaitlistDepth
+	return depths, dbutil.PossibleDbError(
+		ds.db.WithContext(ctx).
+			Model(&entity.WaitlistEntry{}).
+			Select("connection_id, COUNT(*) AS entries, SUM(passengers) AS passengers, SUM(status = ?) AS offered", entity.OfferedWaitlistStatus).
+			Where("status IN ?", activeWaitlistStatuses).
+			Group("connection_id").
+			Order("entries DESC").
+			Scan(&depths),
+	)
+}
+
+func NewWaitlist(db *gorm.DB) Waitlist {
+	return &waitlistMySQL{db}
+}
@@ -1,23 +1,34 @@
 package router
 
 import (
+	"context"
 	adress "maryan_api/internal/domain/adress/transport/http"
+	boarding "maryan_api/internal/domain/boarding/transport/http"
 	bus "maryan_api/internal/domain/bus/transport/http"
+	cod "maryan_api/internal/domain/cod/transport/http"
 	connection "maryan_api/internal/domain/connection/transport/http"
 	"maryan_api/internal/domain/documents"
 	parcel "maryan_api/internal/domain/parcel/transport/http"
 	passenger "maryan_api/internal/domain/passenger/transport/http"
+	payment "maryan_api/internal/domain/payment/transport/http"
+	promoCode "maryan_api/internal/domain/promo_code/transport/http"
+	refaund "maryan_api/internal/domain/refaund/transport/http"
+	sweeper "maryan_api/internal/domain/sweeper/transport/http"
+	tariff "maryan_api/internal/domain/tariff/transport/http"
 	ticket "maryan_api/internal/domain/tickets/transport/http"
 	trip "maryan_api/internal/domain/trip/transport/http"
 	user "maryan_api/internal/domain/user/transport/http"
+	waitlist "maryan_api/internal/domain/waitlist/transport/http"
+	paymentClient "maryan_api/internal/infrastructure/clients/payment"
 	ginutil "maryan_api/pkg/ginutils"
 	"net/http"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func RegisterRoutes(s *gin.Engine, db *gorm.DB, client *http.Client) {
+func RegisterRoutes(s *gin.Engine, db *gorm.DB, client *http.Client, payments paymentClient.PaymentProvider) {
 	s.Use(ginutil.LogMiddlewear(db))
 
 	passenger.RegisterRoutes(db, s, client)
@@ -26,7 +37,30 @@ func RegisterRoutes(s *gin.Engine, db *gorm.DB, client *http.Client) {
 	adress.RegisterRoutes(db, s, client)
 	connection.RegisterRoutes(db, s, client)
 	trip.RegisterRoutes(db, s, client)
-	ticket.RegisterRoutes(db, s, client)
+	ticket.RegisterRoutes(db, s, client, payments)
 	documents.RegisterRoutes(db, s, client)
-	parcel.RegisterRoutes(db, s, client)
+	parcel.RegisterRoutes(db, s, client, payments)
+	payment.RegisterRoutes(db, s, client, payments)
+	refaund.RegisterRoutes(db, s, client, payments)
+	boarding.RegisterRoutes(db, s, client)
+	promoCode.RegisterRoutes(db, s, client)
+	sweeper.RegisterRoutes(db, s, client, payments)
+	waitlist.RegisterRoutes(db, s, client)
+	tariff.RegisterRoutes(db, s, client)
+	cod.RegisterRoutes(db, s, client)
+}
+
+// RunJobs runs the background jobs until the context is done and returns once all of them have stopped.
+func RunJobs(ctx context.Context, db *gorm.DB, payments paymentClient.PaymentProvider) {
+	var jobs sync.WaitGroup
+	jobs.Add(2)
+	go func() {
+		defer jobs.Done()
+		sweeper.Run(ctx, db, payments)
+	}()
+	go func() {
+		defer jobs.Done()
+		waitlist.Run(ctx, db)
+	}()
+	jobs.Wait()
 }
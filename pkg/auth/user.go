@@ -21,12 +21,14 @@ type CustomerRole string
 type AdminRole string
 type DriverRole string
 type SupportRole string
+type GuestRole string
 
 const (
 	Customer CustomerRole = "Customer"
 	Admin    AdminRole    = "Admin"
 	Driver   DriverRole   = "Driver"
 	Support  SupportRole  = "Support"
+	Guest    GuestRole    = "Guest"
 )
 
 func (r CustomerRole) Name() string                 { return string(r) }
@@ -57,6 +59,13 @@ func (r SupportRole) GenerateToken(email string, id uuid.UUID) (string, error) {
 	return generateToken(email, id, r)
 }
 
+func (r GuestRole) Name() string                 { return string(r) }
+func (r GuestRole) SecretKey() []byte            { return config.GuestCustomerSecretKey() }
+func (r GuestRole) TokenDuration() time.Duration { return 2 * time.Hour }
+func (r GuestRole) GenerateToken(email string, id uuid.UUID) (string, error) {
+	return generateToken(email, id, r)
+}
+
 func DefineRole(role string) (Role, error) {
 	switch {
 	case strings.EqualFold(role, Customer.Name()):
@@ -67,6 +76,8 @@ func DefineRole(role string) (Role, error) {
 		return Driver, nil
 	case strings.EqualFold(role, Support.Name()):
 		return Support, nil
+	case strings.EqualFold(role, Guest.Name()):
+		return Guest, nil
 	default:
 		return nil, fmt.Errorf("unknown role: %s", role)
 	}
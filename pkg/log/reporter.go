@@ -0,0 +1,44 @@
+package log
+
+import (
+	"encoding/json"
+	rfc7807 "maryan_api/pkg/problem"
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+// Reporter logs the errors nobody gets a response for, such as the ones of the background jobs
+// or of the cleanup after a failed request. They are logged the way the failed requests are.
+type Reporter interface {
+	Report(route string, err error)
+}
+
+type reporterImpl struct {
+	db *gorm.DB
+}
+
+// Report logs the error under the route of the work it has happened in.
+func (r reporterImpl) Report(route string, err error) {
+	if err == nil {
+		return
+	}
+
+	logger := New("", route, nil, json.RawMessage("{}"), nil, "JOB")
+	SetErr(logger, err)
+	logger.Do(r.db)
+}
+
+// SetErr sets the error on the logger, a problem keeps its type and status.
+func SetErr(logger Logger, err error) {
+	problem, ok := rfc7807.Is(err)
+	if !ok {
+		logger.SetError(err, http.StatusInternalServerError)
+		return
+	}
+	logger.SetProblem(problem)
+}
+
+func NewReporter(db *gorm.DB) Reporter {
+	return reporterImpl{db}
+}
@@ -0,0 +1,248 @@
+// Package pdf writes simple single-font PDF documents: text, lines and PNG images on A4 pages.
+// The TrueType font is embedded as a whole, so any script it covers (Cyrillic included) can be used.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/png"
+	"slices"
+	"strings"
+)
+
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+type Document struct {
+	font   *font
+	pages  []*Page
+	images [][]byte
+	used   map[uint16]rune
+}
+
+type Page struct {
+	doc     *Document
+	content bytes.Buffer
+	images  []int
+}
+
+func New(fontData []byte) (*Document, error) {
+	f, err := parseFont(fontData)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Document{font: f, used: make(map[uint16]rune)}, nil
+}
+
+func (d *Document) AddPage() *Page {
+	page := &Page{doc: d}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// TextWidth returns the width of the text in points.
+func (d *Document) TextWidth(text string, size float64) float64 {
+	var width int
+	for _, r := range text {
+		width += d.font.scale(d.font.width(d.font.glyphs[r]))
+	}
+	return float64(width) * size / 1000
+}
+
+// Text draws the text with its baseline at y. Coordinates start at the top left corner of the page.
+func (p *Page) Text(x, y, size float64, text string) {
+	var glyphs strings.Builder
+	for _, r := range text {
+		glyph := p.doc.font.glyphs[r]
+		p.doc.used[glyph] = r
+		fmt.Fprintf(&glyphs, "%04X", glyph)
+	}
+
+	fmt.Fprintf(&p.content, "BT /F1 %.2f Tf %.2f %.2f Td <%s> Tj ET\n", size, x, PageHeight-y, glyphs.String())
+}
+
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%.2f w %.2f %.2f m %.2f %.2f l S\n", width, x1, PageHeight-y1, x2, PageHeight-y2)
+}
+
+// Image draws a PNG image (converted to grayscale) with its top left corner at x, y.
+func (p *Page) Image(data []byte, x, y, width, height float64) error {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	bounds := img.Bounds()
+	var pixels bytes.Buffer
+	writer := zlib.NewWriter(&pixels)
+	for py := bounds.Min.Y; py < bounds.Max.Y; py++ {
+		for px := bounds.Min.X; px < bounds.Max.X; px++ {
+			writer.Write([]byte{color.GrayModel.Convert(img.At(px, py)).(color.Gray).Y})
+		}
+	}
+	writer.Close()
+
+	var object bytes.Buffer
+	fmt.Fprintf(&object, "<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceGray /BitsPerComponent 8 /Filter /FlateDecode /Length %d >>\nstream\n",
+		bounds.Dx(), bounds.Dy(), pixels.Len())
+	object.Write(pixels.Bytes())
+	object.WriteString("\nendstream")
+
+	p.doc.images = append(p.doc.images, object.Bytes())
+	index := len(p.doc.images)
+	p.images = append(p.images, index)
+
+	fmt.Fprintf(&p.content, "q %.2f 0 0 %.2f %.2f %.2f cm /Im%d Do Q\n", width, height, x, PageHeight-y-height, index)
+	return nil
+}
+
+func (d *Document) Bytes() ([]byte, error) {
+	var objects [][]byte
+	add := func(object []byte) int {
+		objects = append(objects, object)
+		return len(objects)
+	}
+	reserve := func() int { return add(nil) }
+
+	catalog := reserve()
+	pages := reserve()
+	objects[catalog-1] = []byte(fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pages))
+
+	fontFile, err := d.fontFileObject()
+	if err != nil {
+		return nil, err
+	}
+
+	fontFileRef := add(fontFile)
+	descriptor := add([]byte(fmt.Sprintf(
+		"<< /Type /FontDescriptor /FontName /EmbeddedFont /Flags 32 /FontBBox [%d %d %d %d] /ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+		d.font.scale(d.font.bbox[0]), d.font.scale(d.font.bbox[1]), d.font.scale(d.font.bbox[2]), d.font.scale(d.font.bbox[3]),
+		d.font.scale(d.font.ascent), d.font.scale(d.font.descent), d.font.scale(d.font.ascent), fontFileRef,
+	)))
+	cidFont := add([]byte(fmt.Sprintf(
+		"<< /Type /Font /Subtype /CIDFontType2 /BaseFont /EmbeddedFont /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /CIDToGIDMap /Identity /W [%s] >>",
+		descriptor, d.widthsArray(),
+	)))
+	toUnicode := add(stream(d.toUnicodeCMap()))
+	fontRef := add([]byte(fmt.Sprintf(
+		"<< /Type /Font /Subtype /Type0 /BaseFont /EmbeddedFont /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		cidFont, toUnicode,
+	)))
+
+	var imageRefs = make([]int, len(d.images))
+	for i, img := range d.images {
+		imageRefs[i] = add(img)
+	}
+
+	var kids []string
+	for _, page := range d.pages {
+		var xObjects strings.Builder
+		for _, index := range page.images {
+			fmt.Fprintf(&xObjects, "/Im%d %d 0 R ", index, imageRefs[index-1])
+		}
+
+		content := add(stream(page.content.Bytes()))
+		pageRef := add([]byte(fmt.Sprintf(
+			"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 %d 0 R >> /XObject << %s>> >> /Contents %d 0 R >>",
+			pages, PageWidth, PageHeight, fontRef, xObjects.String(), content,
+		)))
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageRef))
+	}
+
+	objects[pages-1] = []byte(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+
+	var offsets = make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n", i+1)
+		out.Write(object)
+		out.WriteString("\nendobj\n")
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, catalog, xref)
+
+	return out.Bytes(), nil
+}
+
+func (d *Document) fontFileObject() ([]byte, error) {
+	var compressed bytes.Buffer
+	writer := zlib.NewWriter(&compressed)
+	_, err := writer.Write(d.font.data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var object bytes.Buffer
+	fmt.Fprintf(&object, "<< /Length %d /Length1 %d /Filter /FlateDecode >>\nstream\n", compressed.Len(), len(d.font.data))
+	object.Write(compressed.Bytes())
+	object.WriteString("\nendstream")
+	return object.Bytes(), nil
+}
+
+func (d *Document) usedGlyphs() []uint16 {
+	var glyphs = make([]uint16, 0, len(d.used))
+	for glyph := range d.used {
+		glyphs = append(glyphs, glyph)
+	}
+	slices.Sort(glyphs)
+	return glyphs
+}
+
+func (d *Document) widthsArray() string {
+	var widths strings.Builder
+	for _, glyph := range d.usedGlyphs() {
+		fmt.Fprintf(&widths, "%d [%d] ", glyph, d.font.scale(d.font.width(glyph)))
+	}
+	return widths.String()
+}
+
+func (d *Document) toUnicodeCMap() []byte {
+	var cmap bytes.Buffer
+	cmap.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+
+	glyphs := d.usedGlyphs()
+	for chunk := range slices.Chunk(glyphs, 100) {
+		fmt.Fprintf(&cmap, "%d beginbfchar\n", len(chunk))
+		for _, glyph := range chunk {
+			fmt.Fprintf(&cmap, "<%04X> <%s>\n", glyph, utf16Hex(d.used[glyph]))
+		}
+		cmap.WriteString("endbfchar\n")
+	}
+
+	cmap.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend")
+	return cmap.Bytes()
+}
+
+func utf16Hex(r rune) string {
+	if r < 0x10000 {
+		return fmt.Sprintf("%04X", r)
+	}
+	r -= 0x10000
+	return fmt.Sprintf("%04X%04X", 0xD800+(r>>10), 0xDC00+(r&0x3FF))
+}
+
+func stream(data []byte) []byte {
+	return append(fmt.Appendf(nil, "<< /Length %d >>\nstream\n", len(data)), append(data, []byte("\nendstream")...)...)
+}
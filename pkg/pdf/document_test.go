@@ -0,0 +1,80 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestDocumentBytes(t *testing.T) {
+	doc, err := New(testFont())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	var img bytes.Buffer
+	err = png.Encode(&img, image.NewGray(image.Rect(0, 0, 4, 4)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page := doc.AddPage()
+	page.Text(40, 40, 12, "Boarding pass: Київ")
+	page.Line(40, 50, 200, 50, 1)
+	err = page.Image(img.Bytes(), 40, 60, 40, 40)
+	if err != nil {
+		t.Fatalf("Image() error = %v", err)
+	}
+	doc.AddPage().Text(40, 40, 12, "€ is drawn as the missing glyph")
+
+	out, err := doc.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() error = %v", err)
+	}
+
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+		t.Fatal("Bytes() has no PDF header or trailer")
+	}
+
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(out)
+	if startxref == nil {
+		t.Fatal("Bytes() has no startxref")
+	}
+
+	xref, _ := strconv.Atoi(string(startxref[1]))
+	if !bytes.HasPrefix(out[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point at the xref table", xref)
+	}
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n \n`).FindAllSubmatch(out[xref:], -1)
+	if len(entries) == 0 {
+		t.Fatal("the xref table has no objects")
+	}
+
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(out[offset:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q, want %q", i+1, out[offset:min(offset+len(want), len(out))], want)
+		}
+	}
+
+	if !bytes.Contains(out, []byte("/Count 2")) {
+		t.Error("Bytes() does not have the 2 pages")
+	}
+}
+
+func TestTextWidth(t *testing.T) {
+	doc, err := New(testFont())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	// Every glyph of the test font, the missing one included, is half an em wide.
+	if got := doc.TextWidth("Aж€", 10); got != 15 {
+		t.Errorf("TextWidth() = %v, want 15", got)
+	}
+}
@@ -0,0 +1,211 @@
+package pdf
+
+import (
+	"encoding/binary"
+	"errors"
+	"unicode"
+)
+
+// font keeps only what is needed to lay out text and describe an embedded TrueType font.
+type font struct {
+	data       []byte
+	unitsPerEm int
+	ascent     int
+	descent    int
+	bbox       [4]int
+	widths     []int
+	glyphs     map[rune]uint16
+}
+
+type ttfTable struct {
+	offset int
+	length int
+}
+
+func parseFont(data []byte) (*font, error) {
+	if len(data) < 12 {
+		return nil, errors.New("pdf: font file is too short")
+	}
+
+	tables := make(map[string]ttfTable)
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < numTables; i++ {
+		record := 12 + i*16
+		if record+16 > len(data) {
+			return nil, errors.New("pdf: malformed font table directory")
+		}
+
+		tables[string(data[record:record+4])] = ttfTable{
+			offset: int(binary.BigEndian.Uint32(data[record+8:])),
+			length: int(binary.BigEndian.Uint32(data[record+12:])),
+		}
+	}
+
+	// The minimum lengths are the ones of the fields read from the tables.
+	var parsed = make(map[string][]byte)
+	for tag, minLength := range map[string]int{"head": 44, "hhea": 36, "hmtx": 4, "maxp": 6, "cmap": 4} {
+		table, ok := tables[tag]
+		if !ok || table.length < minLength || table.offset+table.length > len(data) {
+			return nil, errors.New("pdf: font has no valid " + tag + " table")
+		}
+		parsed[tag] = data[table.offset : table.offset+table.length]
+	}
+
+	f := &font{data: data}
+
+	head := parsed["head"]
+	f.unitsPerEm = int(binary.BigEndian.Uint16(head[18:]))
+	if f.unitsPerEm == 0 {
+		return nil, errors.New("pdf: font has no units per em")
+	}
+
+	for i := range f.bbox {
+		f.bbox[i] = int(int16(binary.BigEndian.Uint16(head[36+i*2:])))
+	}
+
+	hhea := parsed["hhea"]
+	f.ascent = int(int16(binary.BigEndian.Uint16(hhea[4:])))
+	f.descent = int(int16(binary.BigEndian.Uint16(hhea[6:])))
+	numberOfHMetrics := int(binary.BigEndian.Uint16(hhea[34:]))
+
+	numGlyphs := int(binary.BigEndian.Uint16(parsed["maxp"][4:]))
+	hmtx := parsed["hmtx"]
+	if numGlyphs == 0 || numberOfHMetrics == 0 || numberOfHMetrics > numGlyphs || numberOfHMetrics*4 > len(hmtx) {
+		return nil, errors.New("pdf: font has no valid horizontal metrics")
+	}
+
+	f.widths = make([]int, numGlyphs)
+	for i := range f.widths {
+		metric := min(i, numberOfHMetrics-1)
+		f.widths[i] = int(binary.BigEndian.Uint16(hmtx[metric*4:]))
+	}
+
+	glyphs, err := parseCmap(parsed["cmap"])
+	if err != nil {
+		return nil, err
+	}
+
+	// The glyphs the font has no metrics for are drawn as the missing glyph.
+	for r, glyph := range glyphs {
+		if int(glyph) >= numGlyphs {
+			delete(glyphs, r)
+		}
+	}
+	f.glyphs = glyphs
+
+	return f, nil
+}
+
+// parseCmap reads the unicode mapping from the first format 4 or format 12 subtable.
+func parseCmap(cmap []byte) (map[rune]uint16, error) {
+	numTables := int(binary.BigEndian.Uint16(cmap[2:]))
+	if 4+numTables*8 > len(cmap) {
+		return nil, errors.New("pdf: malformed font cmap table")
+	}
+
+	for i := 0; i < numTables; i++ {
+		record := 4 + i*8
+		platformID := binary.BigEndian.Uint16(cmap[record:])
+		encodingID := binary.BigEndian.Uint16(cmap[record+2:])
+		if platformID != 0 && !(platformID == 3 && (encodingID == 1 || encodingID == 10)) {
+			continue
+		}
+
+		offset := int(binary.BigEndian.Uint32(cmap[record+4:]))
+		if offset+2 > len(cmap) {
+			return nil, errors.New("pdf: malformed font cmap subtable")
+		}
+
+		subtable := cmap[offset:]
+		switch binary.BigEndian.Uint16(subtable) {
+		case 4:
+			return parseCmapFormat4(subtable)
+		case 12:
+			return parseCmapFormat12(subtable)
+		}
+	}
+
+	return nil, errors.New("pdf: font has no unicode cmap")
+}
+
+func parseCmapFormat4(subtable []byte) (map[rune]uint16, error) {
+	if len(subtable) < 14 {
+		return nil, errors.New("pdf: malformed font cmap subtable")
+	}
+
+	glyphs := make(map[rune]uint16)
+	segCount := int(binary.BigEndian.Uint16(subtable[6:])) / 2
+	endCodes := 14
+	startCodes := endCodes + segCount*2 + 2
+	idDeltas := startCodes + segCount*2
+	idRangeOffsets := idDeltas + segCount*2
+	if idRangeOffsets+segCount*2 > len(subtable) {
+		return nil, errors.New("pdf: malformed font cmap subtable")
+	}
+
+	for i := 0; i < segCount; i++ {
+		end := int(binary.BigEndian.Uint16(subtable[endCodes+i*2:]))
+		start := int(binary.BigEndian.Uint16(subtable[startCodes+i*2:]))
+		delta := int(binary.BigEndian.Uint16(subtable[idDeltas+i*2:]))
+		rangeOffset := int(binary.BigEndian.Uint16(subtable[idRangeOffsets+i*2:]))
+
+		for c := start; c <= end && c != 0xFFFF; c++ {
+			var glyph int
+			if rangeOffset == 0 {
+				glyph = (c + delta) & 0xFFFF
+			} else {
+				address := idRangeOffsets + i*2 + rangeOffset + (c-start)*2
+				if address+2 > len(subtable) {
+					continue
+				}
+				glyph = int(binary.BigEndian.Uint16(subtable[address:]))
+				if glyph != 0 {
+					glyph = (glyph + delta) & 0xFFFF
+				}
+			}
+
+			if glyph != 0 {
+				glyphs[rune(c)] = uint16(glyph)
+			}
+		}
+	}
+
+	return glyphs, nil
+}
+
+func parseCmapFormat12(subtable []byte) (map[rune]uint16, error) {
+	if len(subtable) < 16 {
+		return nil, errors.New("pdf: malformed font cmap subtable")
+	}
+
+	glyphs := make(map[rune]uint16)
+	numGroups := int(binary.BigEndian.Uint32(subtable[12:]))
+	if numGroups > (len(subtable)-16)/12 {
+		return nil, errors.New("pdf: malformed font cmap subtable")
+	}
+
+	for i := 0; i < numGroups; i++ {
+		group := subtable[16+i*12:]
+		start := binary.BigEndian.Uint32(group)
+		end := min(binary.BigEndian.Uint32(group[4:]), unicode.MaxRune)
+		glyph := binary.BigEndian.Uint32(group[8:])
+		for c := start; c <= end && glyph+c-start <= 0xFFFF; c++ {
+			glyphs[rune(c)] = uint16(glyph + c - start)
+		}
+	}
+
+	return glyphs, nil
+}
+
+// width returns the advance of the glyph, a glyph out of the font has the one of the missing glyph.
+func (f *font) width(glyph uint16) int {
+	if int(glyph) >= len(f.widths) {
+		return f.widths[0]
+	}
+	return f.widths[glyph]
+}
+
+// scale converts font units to the 1000 units per em used by PDF.
+func (f *font) scale(v int) int {
+	return v * 1000 / f.unitsPerEm
+}
@@ -0,0 +1,219 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// Glyphs of the test font: the missing glyph, the printable ASCII and the basic Cyrillic letters.
+const (
+	testASCIIGlyph    = 1
+	testCyrillicGlyph = testASCIIGlyph + 0x7E - 0x20 + 1
+	testNumGlyphs     = testCyrillicGlyph + 0x44F - 0x410 + 1
+	testUnitsPerEm    = 2048
+	testAdvance       = 1024
+)
+
+type testTable struct {
+	tag  string
+	data []byte
+}
+
+// testFont builds a TrueType font with the tables the documents are laid out with.
+func testFont() []byte {
+	return buildFont(
+		testTable{"cmap", testCmapFormat4()},
+		testTable{"head", testHead(testUnitsPerEm)},
+		testTable{"hhea", testHhea(testNumGlyphs)},
+		testTable{"hmtx", testHmtx(testNumGlyphs)},
+		testTable{"maxp", testMaxp(testNumGlyphs)},
+	)
+}
+
+func buildFont(tables ...testTable) []byte {
+	var directory, data bytes.Buffer
+	directory.Write(be32(0x00010000))
+	directory.Write(be16(uint16(len(tables))))
+	directory.Write(make([]byte, 6))
+
+	offset := 12 + len(tables)*16
+	for _, table := range tables {
+		directory.WriteString(table.tag)
+		directory.Write(be32(0))
+		directory.Write(be32(uint32(offset + data.Len())))
+		directory.Write(be32(uint32(len(table.data))))
+
+		data.Write(table.data)
+		for data.Len()%4 != 0 {
+			data.WriteByte(0)
+		}
+	}
+
+	return append(directory.Bytes(), data.Bytes()...)
+}
+
+func testHead(unitsPerEm uint16) []byte {
+	head := make([]byte, 54)
+	binary.BigEndian.PutUint32(head, 0x00010000)
+	binary.BigEndian.PutUint32(head[12:], 0x5F0F3CF5)
+	binary.BigEndian.PutUint16(head[18:], unitsPerEm)
+	binary.BigEndian.PutUint16(head[40:], 2048)
+	binary.BigEndian.PutUint16(head[42:], 1638)
+	return head
+}
+
+func testHhea(numberOfHMetrics uint16) []byte {
+	hhea := make([]byte, 36)
+	binary.BigEndian.PutUint32(hhea, 0x00010000)
+	binary.BigEndian.PutUint16(hhea[4:], 1638)
+	binary.BigEndian.PutUint16(hhea[6:], uint16(0xFFFF-410+1))
+	binary.BigEndian.PutUint16(hhea[34:], numberOfHMetrics)
+	return hhea
+}
+
+func testHmtx(numberOfHMetrics int) []byte {
+	hmtx := make([]byte, numberOfHMetrics*4)
+	for i := range numberOfHMetrics {
+		binary.BigEndian.PutUint16(hmtx[i*4:], testAdvance)
+	}
+	return hmtx
+}
+
+func testMaxp(numGlyphs uint16) []byte {
+	maxp := make([]byte, 6)
+	binary.BigEndian.PutUint32(maxp, 0x00005000)
+	binary.BigEndian.PutUint16(maxp[4:], numGlyphs)
+	return maxp
+}
+
+// testCmap wraps the subtable into a cmap table with a single Windows Unicode record.
+func testCmap(subtable []byte) []byte {
+	var cmap bytes.Buffer
+	cmap.Write(be16(0))
+	cmap.Write(be16(1))
+	cmap.Write(be16(3))
+	cmap.Write(be16(1))
+	cmap.Write(be32(12))
+	cmap.Write(subtable)
+	return cmap.Bytes()
+}
+
+func testCmapFormat4() []byte {
+	segments := []struct{ start, end, glyph uint16 }{
+		{0x20, 0x7E, testASCIIGlyph},
+		{0x410, 0x44F, testCyrillicGlyph},
+		{0xFFFF, 0xFFFF, 0},
+	}
+
+	var endCodes, startCodes, idDeltas bytes.Buffer
+	for _, segment := range segments {
+		endCodes.Write(be16(segment.end))
+		startCodes.Write(be16(segment.start))
+		if segment.glyph == 0 {
+			idDeltas.Write(be16(1))
+		} else {
+			idDeltas.Write(be16(segment.glyph - segment.start))
+		}
+	}
+
+	var subtable bytes.Buffer
+	subtable.Write(be16(4))
+	subtable.Write(be16(uint16(16 + len(segments)*8)))
+	subtable.Write(be16(0))
+	subtable.Write(be16(uint16(len(segments) * 2)))
+	subtable.Write(make([]byte, 6))
+	subtable.Write(endCodes.Bytes())
+	subtable.Write(be16(0))
+	subtable.Write(startCodes.Bytes())
+	subtable.Write(idDeltas.Bytes())
+	subtable.Write(make([]byte, len(segments)*2))
+	return testCmap(subtable.Bytes())
+}
+
+func testCmapFormat12(groups ...[3]uint32) []byte {
+	var subtable bytes.Buffer
+	subtable.Write(be16(12))
+	subtable.Write(be16(0))
+	subtable.Write(be32(uint32(16 + len(groups)*12)))
+	subtable.Write(be32(0))
+	subtable.Write(be32(uint32(len(groups))))
+	for _, group := range groups {
+		for _, value := range group {
+			subtable.Write(be32(value))
+		}
+	}
+	return testCmap(subtable.Bytes())
+}
+
+func be16(v uint16) []byte { return binary.BigEndian.AppendUint16(nil, v) }
+func be32(v uint32) []byte { return binary.BigEndian.AppendUint32(nil, v) }
+
+func TestParseFont(t *testing.T) {
+	f, err := parseFont(testFont())
+	if err != nil {
+		t.Fatalf("parseFont() error = %v", err)
+	}
+
+	if f.unitsPerEm != testUnitsPerEm || len(f.widths) != testNumGlyphs {
+		t.Fatalf("parseFont() = %d units per em and %d glyphs, want %d and %d", f.unitsPerEm, len(f.widths), testUnitsPerEm, testNumGlyphs)
+	}
+
+	for r, want := range map[rune]uint16{'A': testASCIIGlyph + 'A' - 0x20, 'Ж': testCyrillicGlyph + 'Ж' - 0x410, '€': 0} {
+		if got := f.glyphs[r]; got != want {
+			t.Errorf("glyph of %q = %d, want %d", r, got, want)
+		}
+	}
+}
+
+func TestParseFontCmapFormat12(t *testing.T) {
+	data := buildFont(
+		testTable{"cmap", testCmapFormat12([3]uint32{0x41, 0x5A, 1}, [3]uint32{0x1F600, 0xFFFFFFFF, 27})},
+		testTable{"head", testHead(testUnitsPerEm)},
+		testTable{"hhea", testHhea(30)},
+		testTable{"hmtx", testHmtx(30)},
+		testTable{"maxp", testMaxp(30)},
+	)
+
+	f, err := parseFont(data)
+	if err != nil {
+		t.Fatalf("parseFont() error = %v", err)
+	}
+
+	if f.glyphs['Z'] != 26 || f.glyphs[0x1F601] != 28 {
+		t.Errorf("glyphs of Z and U+1F601 = %d and %d, want 26 and 28", f.glyphs['Z'], f.glyphs[0x1F601])
+	}
+
+	// The glyphs mapped past the glyphs of the font are left to the missing glyph.
+	if _, ok := f.glyphs[0x1F600+10]; ok {
+		t.Error("a glyph out of the font has been mapped")
+	}
+}
+
+func TestParseFontMalformed(t *testing.T) {
+	truncated := func(data []byte) []byte { return data[:len(data)/2] }
+
+	tests := map[string][]byte{
+		"empty":                  nil,
+		"truncated":              truncated(testFont()),
+		"short head":             buildFont(testTable{"cmap", testCmapFormat4()}, testTable{"head", testHead(testUnitsPerEm)[:20]}, testTable{"hhea", testHhea(1)}, testTable{"hmtx", testHmtx(1)}, testTable{"maxp", testMaxp(1)}),
+		"zero units per em":      buildFont(testTable{"cmap", testCmapFormat4()}, testTable{"head", testHead(0)}, testTable{"hhea", testHhea(1)}, testTable{"hmtx", testHmtx(1)}, testTable{"maxp", testMaxp(1)}),
+		"short hhea":             buildFont(testTable{"cmap", testCmapFormat4()}, testTable{"head", testHead(testUnitsPerEm)}, testTable{"hhea", testHhea(1)[:34]}, testTable{"hmtx", testHmtx(1)}, testTable{"maxp", testMaxp(1)}),
+		"no horizontal metrics":  buildFont(testTable{"cmap", testCmapFormat4()}, testTable{"head", testHead(testUnitsPerEm)}, testTable{"hhea", testHhea(0)}, testTable{"hmtx", testHmtx(1)}, testTable{"maxp", testMaxp(1)}),
+		"short hmtx":             buildFont(testTable{"cmap", testCmapFormat4()}, testTable{"head", testHead(testUnitsPerEm)}, testTable{"hhea", testHhea(8)}, testTable{"hmtx", testHmtx(2)}, testTable{"maxp", testMaxp(8)}),
+		"short maxp":             buildFont(testTable{"cmap", testCmapFormat4()}, testTable{"head", testHead(testUnitsPerEm)}, testTable{"hhea", testHhea(1)}, testTable{"hmtx", testHmtx(1)}, testTable{"maxp", testMaxp(1)[:4]}),
+		"cmap records past end":  buildFont(testTable{"cmap", append(be16(0), be16(40)...)}, testTable{"head", testHead(testUnitsPerEm)}, testTable{"hhea", testHhea(1)}, testTable{"hmtx", testHmtx(1)}, testTable{"maxp", testMaxp(1)}),
+		"cmap subtable past end": buildFont(testTable{"cmap", testCmap(nil)}, testTable{"head", testHead(testUnitsPerEm)}, testTable{"hhea", testHhea(1)}, testTable{"hmtx", testHmtx(1)}, testTable{"maxp", testMaxp(1)}),
+		"short format 4":         buildFont(testTable{"cmap", truncated(testCmapFormat4())}, testTable{"head", testHead(testUnitsPerEm)}, testTable{"hhea", testHhea(1)}, testTable{"hmtx", testHmtx(1)}, testTable{"maxp", testMaxp(1)}),
+		"short format 12":        buildFont(testTable{"cmap", truncated(testCmapFormat12([3]uint32{0x41, 0x5A, 1}, [3]uint32{0x61, 0x7A, 1}))}, testTable{"head", testHead(testUnitsPerEm)}, testTable{"hhea", testHhea(1)}, testTable{"hmtx", testHmtx(1)}, testTable{"maxp", testMaxp(1)}),
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseFont(data)
+			if err == nil {
+				t.Error("parseFont() accepted a malformed font")
+			}
+		})
+	}
+}
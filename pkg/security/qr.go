@@ -0,0 +1,169 @@
+package security
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"maryan_api/config"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/d3code/uuid"
+)
+
+// QRClaims is the payload of the QR codes printed on tickets and parcels. Field names are kept
+// short so the code stays readable for the scanners.
+type QRClaims struct {
+	KeyID        string    `json:"k"`
+	Type         string    `json:"t"`
+	ID           uuid.UUID `json:"i"`
+	ConnectionID uuid.UUID `json:"c"`
+	Seats        []int     `json:"s,omitempty"`
+	ExpiresAt    int64     `json:"e"`
+}
+
+type QRPublicKey struct {
+	ID      string `json:"id"`
+	Key     string `json:"key"`
+	Current bool   `json:"current"`
+}
+
+type qrKey struct {
+	id      string
+	private ed25519.PrivateKey
+}
+
+// qrKeyCache keeps the keys parsed from the configured value, they are parsed again once the value changes.
+var qrKeyCache struct {
+	mu   sync.Mutex
+	raw  string
+	keys []qrKey
+}
+
+// qrKeys returns the configured keys. They are read on every use, so the rotated ones are used without a restart.
+func qrKeys() ([]qrKey, error) {
+	raw, err := config.QRSigningKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	qrKeyCache.mu.Lock()
+	defer qrKeyCache.mu.Unlock()
+
+	if qrKeyCache.keys == nil || raw != qrKeyCache.raw {
+		keys, err := parseQRKeys(raw)
+		if err != nil {
+			return nil, err
+		}
+		qrKeyCache.raw, qrKeyCache.keys = raw, keys
+	}
+
+	return qrKeyCache.keys, nil
+}
+
+// parseQRKeys reads "keyID:base64Seed" pairs. The first key signs new codes, the rest are only
+// used for verification, so a key can be rotated by putting a new one in front of it.
+func parseQRKeys(raw string) ([]qrKey, error) {
+	var keys []qrKey
+	for _, pair := range strings.Split(raw, ",") {
+		id, encodedSeed, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || id == "" {
+			return nil, errors.New("QR signing key has to be in the keyID:base64Seed format")
+		}
+
+		seed, err := base64.StdEncoding.DecodeString(encodedSeed)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(seed) != ed25519.SeedSize {
+			return nil, errors.New("QR signing key " + id + " has invalid seed size")
+		}
+
+		keys = append(keys, qrKey{id, ed25519.NewKeyFromSeed(seed)})
+	}
+
+	return keys, nil
+}
+
+func SignQR(claims QRClaims) (string, error) {
+	keys, err := qrKeys()
+	if err != nil {
+		return "", err
+	}
+
+	claims.KeyID = keys[0].id
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(ed25519.Sign(keys[0].private, payload)), nil
+}
+
+func VerifyQR(token string) (QRClaims, error) {
+	encodedPayload, encodedSignature, ok := strings.Cut(token, ".")
+	if !ok {
+		return QRClaims{}, errors.New("malformed code")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return QRClaims{}, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return QRClaims{}, err
+	}
+
+	var claims QRClaims
+	err = json.Unmarshal(payload, &claims)
+	if err != nil {
+		return QRClaims{}, err
+	}
+
+	keys, err := qrKeys()
+	if err != nil {
+		return QRClaims{}, err
+	}
+
+	for _, key := range keys {
+		if key.id != claims.KeyID {
+			continue
+		}
+
+		if !ed25519.Verify(key.private.Public().(ed25519.PublicKey), payload, signature) {
+			return QRClaims{}, errors.New("invalid signature")
+		}
+
+		if time.Now().Unix() > claims.ExpiresAt {
+			return QRClaims{}, errors.New("expired code")
+		}
+
+		return claims, nil
+	}
+
+	return QRClaims{}, errors.New("unknown signing key")
+}
+
+func QRPublicKeys() ([]QRPublicKey, error) {
+	keys, err := qrKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	var publicKeys = make([]QRPublicKey, len(keys))
+	for i, key := range keys {
+		publicKeys[i] = QRPublicKey{
+			ID:      key.id,
+			Key:     base64.StdEncoding.EncodeToString(key.private.Public().(ed25519.PublicKey)),
+			Current: i == 0,
+		}
+	}
+
+	return publicKeys, nil
+}